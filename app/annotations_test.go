@@ -0,0 +1,153 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestAnnotations(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	annotations := app.NewAnnotationStore()
+	router := mux.NewRouter().SkipClean(true)
+	app.RegisterAnnotationRoutes(router, annotations, false)
+	app.RegisterTopologyRoutes(router, app.WebReporter{Reporter: app.StaticCollector(fixture.Report), Annotations: annotations}, nil)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	res, body := checkRequest(t, ts, "POST", "/api/annotations", []byte(`{
+		"topology": "container",
+		"selector": {"`+docker.LabelPrefix+fixture.TestLabelKey1+`": "`+fixture.ApplicationLabelValue1+`"},
+		"key": "deploy", "value": "v1.2.3", "badge": true, "ttl": "1h"
+	}`))
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the annotation to be added, got %d: %s", res.StatusCode, body)
+	}
+	var added detailed.Annotation
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&added); err != nil {
+		t.Fatal(err)
+	}
+	if !added.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected the annotation to expire in an hour, got %v", added.Expires)
+	}
+
+	for _, invalid := range []string{`{"key": "deploy"}`, `{"node": "n", "key": "deploy", "ttl": "soon"}`, `{"node": "n", "key": "deploy", "ttl": "1000h"}`} {
+		if res, _ := checkRequest(t, ts, "POST", "/api/annotations", []byte(invalid)); res.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected %s to be rejected, got %d", invalid, res.StatusCode)
+		}
+	}
+
+	var topology app.APITopology
+	if err := codec.NewDecoderBytes(getRawJSON(t, ts, "/api/topology/containers"), &codec.JsonHandle{}).Decode(&topology); err != nil {
+		t.Fatal(err)
+	}
+	want := []detailed.NodeAnnotation{{Key: "deploy", Value: "v1.2.3", Badge: true}}
+	if have := topology.Nodes[fixture.ClientContainerNodeID].Annotations; len(have) != 1 || have[0] != want[0] {
+		t.Errorf("Expected %v on the client container, got %v", want, have)
+	}
+	if have := topology.Nodes[fixture.ServerContainerNodeID].Annotations; len(have) != 0 {
+		t.Errorf("Expected no annotations on the server container, got %v", have)
+	}
+
+	// Expired annotations are dropped
+	mtime.NowForce(now.Add(2 * time.Hour))
+	if have := annotations.Annotations(context.Background()); len(have) != 0 {
+		t.Errorf("Expected the annotation to have expired, got %v", have)
+	}
+	if res, _ := checkRequest(t, ts, "DELETE", "/api/annotations/"+added.ID, nil); res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the expired annotation to be gone, got %d", res.StatusCode)
+	}
+}
+
+func TestAnnotationTenants(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant red
+user redtoken reduser write
+tenant blue
+user bluetoken blueuser write
+`)
+	f.Close()
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	app.RegisterAnnotationRoutes(router, app.NewAnnotationStore(), false)
+	server := httptest.NewServer(auth.Wrap(router))
+	defer server.Close()
+	do := func(token, method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := do("redtoken", "POST", "/api/annotations", `{"node": "n", "key": "deploy"}`)
+	var added detailed.Annotation
+	err = codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&added)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blue neither sees nor removes the annotation of red
+	resp = do("bluetoken", "GET", "/api/annotations", "")
+	var annotations []detailed.Annotation
+	err = codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&annotations)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotations) != 0 {
+		t.Errorf("Expected no annotations for blue, got %v", annotations)
+	}
+	if resp := do("bluetoken", "DELETE", "/api/annotations/"+added.ID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected blue not to find the annotation of red, got %d", resp.StatusCode)
+	}
+	if resp := do("redtoken", "DELETE", "/api/annotations/"+added.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected red to remove its annotation, got %d", resp.StatusCode)
+	}
+}
+
+func TestAnnotationsReadOnly(t *testing.T) {
+	router := mux.NewRouter()
+	app.RegisterAnnotationRoutes(router, app.NewAnnotationStore(), true)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	if res, _ := checkRequest(t, ts, "POST", "/api/annotations", []byte(`{"node": "n", "key": "deploy"}`)); res.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected annotations to be refused, got %d", res.StatusCode)
+	}
+	if res, _ := checkRequest(t, ts, "DELETE", "/api/annotations/1", nil); res.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected removals to be refused, got %d", res.StatusCode)
+	}
+}
@@ -154,13 +154,21 @@ func MakeAPITopologyOption(value string, label string, filterFunc render.FilterF
 // Registry is a threadsafe store of the available topologies
 type Registry struct {
 	sync.RWMutex
-	items map[string]APITopologyDesc
+	items  map[string]APITopologyDesc
+	custom map[string]CustomTopology
+
+	// The registries of the tenants which changed their custom
+	// topologies, each made from a copy of this one
+	tenantsMtx sync.Mutex
+	tenants    map[string]*Registry
 }
 
 // MakeRegistry returns a new Registry
 func MakeRegistry() *Registry {
 	registry := &Registry{
-		items: map[string]APITopologyDesc{},
+		items:   map[string]APITopologyDesc{},
+		custom:  map[string]CustomTopology{},
+		tenants: map[string]*Registry{},
 	}
 	containerFilters := []APITopologyOptionGroup{
 		{
@@ -190,6 +198,15 @@ func MakeRegistry() *Registry {
 		},
 	}
 
+	proxiesFilter := APITopologyOptionGroup{
+		ID:      "proxies",
+		Default: "collapse",
+		Options: []APITopologyOption{
+			{Value: "collapse", Label: "Collapse mesh proxies", transformer: render.CollapseProxies},
+			{Value: "show", Label: "Show mesh proxies"},
+		},
+	}
+
 	unconnectedFilter := []APITopologyOptionGroup{
 		{
 			ID:      "unconnected",
@@ -225,7 +242,7 @@ func MakeRegistry() *Registry {
 			renderer: render.ContainerWithImageNameRenderer,
 			Name:     "Containers",
 			Rank:     2,
-			Options:  containerFilters,
+			Options:  append(append([]APITopologyOptionGroup{}, containerFilters...), proxiesFilter),
 		},
 		APITopologyDesc{
 			id:       containersByHostnameID,
@@ -243,7 +260,7 @@ func MakeRegistry() *Registry {
 		},
 		APITopologyDesc{
 			id:          podsID,
-			renderer:    render.PodRenderer,
+			renderer:    render.PodNetworkPolicyRenderer,
 			Name:        "Pods",
 			Rank:        3,
 			Options:     []APITopologyOptionGroup{snapshotFilter, storageFilter, unmanagedFilter},
@@ -343,6 +360,16 @@ type APITopologyOptionGroup struct {
 	NoneLabel string `json:"noneLabel,omitempty"`
 }
 
+// Get the transformer to use for this option group, if any, or nil otherwise.
+func (g APITopologyOptionGroup) transformer(value string) render.Transformer {
+	for _, opt := range g.Options {
+		if opt.Value == value {
+			return opt.transformer
+		}
+	}
+	return nil
+}
+
 // Get the render filters to use for this option group, if any, or nil otherwise.
 func (g APITopologyOptionGroup) filter(value string) render.FilterFunc {
 	var values []string
@@ -388,6 +415,7 @@ type APITopologyOption struct {
 
 	filter       render.FilterFunc
 	filterPseudo bool
+	transformer  render.Transformer // applied before the filters, if any
 }
 
 type topologyStats struct {
@@ -398,16 +426,16 @@ type topologyStats struct {
 }
 
 // deserializeTimestamp converts the ISO8601 query param into a proper timestamp.
-func deserializeTimestamp(timestamp string) time.Time {
+func deserializeTimestamp(timestamp string) (time.Time, error) {
 	if timestamp != "" {
 		result, err := time.Parse(time.RFC3339, timestamp)
 		if err != nil {
-			log.Errorf("Error parsing timestamp '%s' - make sure the time format is correct", timestamp)
+			return time.Time{}, fmt.Errorf("Error parsing timestamp '%s' - make sure the time format is RFC3339", timestamp)
 		}
-		return result
+		return result, nil
 	}
 	// Default to current time if no timestamp is provided.
-	return time.Now()
+	return time.Now(), nil
 }
 
 // AddContainerFilters adds to the default Registry (topologyRegistry)'s containerFilters
@@ -433,6 +461,10 @@ func (r *Registry) AddContainerFilters(newFilters ...APITopologyOption) {
 func (r *Registry) Add(ts ...APITopologyDesc) {
 	r.Lock()
 	defer r.Unlock()
+	r.add(ts...)
+}
+
+func (r *Registry) add(ts ...APITopologyDesc) {
 	for _, t := range ts {
 		t.URL = apiTopologyURL + t.id
 		t.renderer = render.Memoise(t.renderer)
@@ -447,6 +479,50 @@ func (r *Registry) Add(ts ...APITopologyDesc) {
 	}
 }
 
+// forTenant returns the registry of the topologies of the tenant of the
+// request in ctx: this one, unless the tenant changed its custom
+// topologies.
+func (r *Registry) forTenant(ctx context.Context) *Registry {
+	r.tenantsMtx.Lock()
+	defer r.tenantsMtx.Unlock()
+	if t, ok := r.tenants[AuthTenant(ctx)]; ok {
+		return t
+	}
+	return r
+}
+
+// ownTenant returns the registry of the tenant of the request in ctx, to
+// change its custom topologies in, copying this one the first time.
+func (r *Registry) ownTenant(ctx context.Context) *Registry {
+	r.tenantsMtx.Lock()
+	defer r.tenantsMtx.Unlock()
+	tenant := AuthTenant(ctx)
+	t, ok := r.tenants[tenant]
+	if !ok {
+		t = r.copy()
+		r.tenants[tenant] = t
+	}
+	return t
+}
+
+func (r *Registry) copy() *Registry {
+	r.RLock()
+	defer r.RUnlock()
+	c := &Registry{
+		items:   make(map[string]APITopologyDesc, len(r.items)),
+		custom:  make(map[string]CustomTopology, len(r.custom)),
+		tenants: map[string]*Registry{},
+	}
+	for id, t := range r.items {
+		t.SubTopologies = append([]APITopologyDesc(nil), t.SubTopologies...)
+		c.items[id] = t
+	}
+	for id, custom := range r.custom {
+		c.custom[id] = custom
+	}
+	return c
+}
+
 func (r *Registry) get(name string) (APITopologyDesc, bool) {
 	r.RLock()
 	defer r.RUnlock()
@@ -454,6 +530,19 @@ func (r *Registry) get(name string) (APITopologyDesc, bool) {
 	return t, ok
 }
 
+// ids returns the IDs of all the topologies, sub-topologies included, in
+// order.
+func (r *Registry) ids() []string {
+	r.RLock()
+	defer r.RUnlock()
+	ids := make([]string, 0, len(r.items))
+	for id := range r.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func (r *Registry) walk(f func(APITopologyDesc)) {
 	r.RLock()
 	defer r.RUnlock()
@@ -473,13 +562,24 @@ func (r *Registry) walk(f func(APITopologyDesc)) {
 // makeTopologyList returns a handler that yields an APITopologyList.
 func (r *Registry) makeTopologyList(rep Reporter) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
-		timestamp := deserializeTimestamp(req.URL.Query().Get("timestamp"))
+		timestamp, err := deserializeTimestamp(req.URL.Query().Get("timestamp"))
+		if err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		// The query filters the stats of every topology the same way
+		if query := req.URL.Query().Get("query"); query != "" {
+			if _, err := render.ParseQuery(query); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+		}
 		report, err := rep.Report(ctx, timestamp)
 		if err != nil {
 			respondWith(w, http.StatusInternalServerError, err)
 			return
 		}
-		respondWith(w, http.StatusOK, r.renderTopologies(ctx, report, req))
+		respondWith(w, http.StatusOK, r.forTenant(ctx).renderTopologies(ctx, report, req))
 	}
 }
 
@@ -537,18 +637,34 @@ func (r *Registry) RendererForTopology(topologyID string, values url.Values, rpt
 		return topology.renderer, render.FilterUnconnectedPseudo, nil
 	}
 
-	var filters []render.FilterFunc
+	var (
+		transformers []render.Transformer
+		filters      []render.FilterFunc
+	)
 	for _, group := range topology.Options {
 		value := group.Default
 		if vs := values[group.ID]; len(vs) > 0 {
 			value = vs[0]
 		}
+		if transformer := group.transformer(value); transformer != nil {
+			transformers = append(transformers, transformer)
+		}
 		if filter := group.filter(value); filter != nil {
 			filters = append(filters, filter)
 		}
 	}
+	if query := values.Get("query"); query != "" {
+		filter, err := render.ParseQuery(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, filter)
+	}
 	if len(filters) > 0 {
-		return topology.renderer, render.Transformers([]render.Transformer{render.ComposeFilterFuncs(filters...), render.FilterUnconnectedPseudo}), nil
+		transformers = append(transformers, render.ComposeFilterFuncs(filters...))
+	}
+	if len(transformers) > 0 {
+		return topology.renderer, render.Transformers(append(transformers, render.FilterUnconnectedPseudo)), nil
 	}
 	return topology.renderer, render.FilterUnconnectedPseudo, nil
 }
@@ -563,14 +679,17 @@ func captureReporter(rep Reporter, f reporterHandler) CtxHandlerFunc {
 
 func (r *Registry) captureRenderer(rep Reporter, f rendererHandler) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
-		var (
-			topologyID = mux.Vars(req)["topology"]
-			timestamp  = deserializeTimestamp(req.URL.Query().Get("timestamp"))
-		)
+		r := r.forTenant(ctx)
+		topologyID := mux.Vars(req)["topology"]
 		if _, ok := r.get(topologyID); !ok {
 			http.NotFound(w, req)
 			return
 		}
+		timestamp, err := deserializeTimestamp(req.URL.Query().Get("timestamp"))
+		if err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
 		rpt, err := rep.Report(ctx, timestamp)
 		if err != nil {
 			respondWith(w, http.StatusInternalServerError, err)
@@ -579,9 +698,10 @@ func (r *Registry) captureRenderer(rep Reporter, f rendererHandler) CtxHandlerFu
 		req.ParseForm()
 		renderer, filter, err := r.RendererForTopology(topologyID, req.Form, rpt)
 		if err != nil {
-			respondWith(w, http.StatusInternalServerError, err)
+			// The topology is there, so it's the query
+			respondWith(w, http.StatusBadRequest, err)
 			return
 		}
-		f(ctx, renderer, filter, RenderContextForReporter(rep, rpt), w, req)
+		f(ctx, renderer, filter, RenderContextForReporter(ctx, rep, rpt), w, req)
 	}
 }
@@ -159,6 +159,29 @@ func TestRendererForTopologyNoFiltering(t *testing.T) {
 	}
 }
 
+func TestRendererForTopologyWithQuery(t *testing.T) {
+	topologyRegistry := app.MakeRegistry()
+	urlvalues := url.Values{}
+	urlvalues.Set("query", "name:"+fixture.ClientContainerName)
+	renderer, filter, err := topologyRegistry.RendererForTopology("containers", urlvalues, fixture.Report)
+	if err != nil {
+		t.Fatalf("Topology Registry Report error: %s", err)
+	}
+
+	have := render.Render(context.Background(), fixture.Report, renderer, filter).Nodes
+	if _, ok := have[fixture.ClientContainerNodeID]; !ok {
+		t.Errorf("Expected the client container, got %v", have)
+	}
+	if _, ok := have[fixture.ServerContainerNodeID]; ok {
+		t.Errorf("Expected no server container, got %v", have)
+	}
+
+	urlvalues.Set("query", "name:(")
+	if _, _, err := topologyRegistry.RendererForTopology("containers", urlvalues, fixture.Report); err == nil {
+		t.Error("Expected an error for a bad query")
+	}
+}
+
 func getTestContainerLabelFilterTopologySummary(t *testing.T, exclude bool) (detailed.NodeSummaries, error) {
 	ts := topologyServer()
 	defer ts.Close()
@@ -191,7 +214,7 @@ func getTestContainerLabelFilterTopologySummary(t *testing.T, exclude bool) (det
 func TestAPITopologyAddsKubernetes(t *testing.T) {
 	router := mux.NewRouter()
 	c := app.NewCollector(1 * time.Minute)
-	app.RegisterReportPostHandler(c, router)
+	app.RegisterReportPostHandler(c, router, app.DefaultDeltaBases)
 	app.RegisterTopologyRoutes(router, c, map[string]bool{"foo_capability": true})
 	ts := httptest.NewServer(router)
 	defer ts.Close()
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// APITopologyDiff is returned by the /api/topology/{name}/diff handler.
+type APITopologyDiff struct {
+	From  time.Time         `json:"from"`
+	To    time.Time         `json:"to"`
+	Nodes detailed.Diff     `json:"nodes"`
+	Edges detailed.EdgeDiff `json:"edges"`
+	// Cursor is to pass as from, for the changes since to
+	Cursor string `json:"cursor"`
+}
+
+// Diff of a topology between two times, for tools keeping track of what
+// Scope sees. from is required, to defaults to now. Metrics, and the rest
+// of what changes with time alone, are left out unless metrics=true.
+func handleTopologyDiff(ctx context.Context, rep Reporter, w http.ResponseWriter, r *http.Request) {
+	topologyID := mux.Vars(r)["topology"]
+	if _, ok := topologyRegistry.forTenant(ctx).get(topologyID); !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		respondWith(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Form.Get("from") == "" {
+		respondWith(w, http.StatusBadRequest, fmt.Errorf("from is required"))
+		return
+	}
+	from, err := deserializeTimestamp(r.Form.Get("from"))
+	if err != nil {
+		respondWith(w, http.StatusBadRequest, err)
+		return
+	}
+	to, err := deserializeTimestamp(r.Form.Get("to"))
+	if err != nil {
+		respondWith(w, http.StatusBadRequest, err)
+		return
+	}
+	if to.Before(from) {
+		respondWith(w, http.StatusBadRequest, fmt.Errorf("to is before from"))
+		return
+	}
+
+	censorCfg := report.GetCensorConfigFromRequest(r)
+	before, status, err := renderSummariesAt(ctx, rep, topologyID, r.Form, from, censorCfg)
+	if err != nil {
+		respondWith(w, status, err)
+		return
+	}
+	after, status, err := renderSummariesAt(ctx, rep, topologyID, r.Form, to, censorCfg)
+	if err != nil {
+		respondWith(w, status, err)
+		return
+	}
+	// Unless asked for, what changes with time alone isn't a change
+	if r.Form.Get("metrics") != "true" {
+		before, after = before.WithoutMetrics(), after.WithoutMetrics()
+	}
+	nodes := detailed.TopoDiff(before, after)
+	// The diff of the websocket is in no particular order
+	nodes.Reset = false
+	sort.Slice(nodes.Add, func(i, j int) bool { return nodes.Add[i].ID < nodes.Add[j].ID })
+	sort.Slice(nodes.Update, func(i, j int) bool { return nodes.Update[i].ID < nodes.Update[j].ID })
+	sort.Strings(nodes.Remove)
+	respondWith(w, http.StatusOK, APITopologyDiff{
+		From:   from,
+		To:     to,
+		Nodes:  nodes,
+		Edges:  detailed.EdgesDiff(before, after),
+		Cursor: to.Format(time.RFC3339Nano),
+	})
+}
+
+// renderSummariesAt renders the summaries of the nodes of a topology as of
+// timestamp, returning the status to respond with if it can't.
+func renderSummariesAt(ctx context.Context, rep Reporter, topologyID string, values url.Values, timestamp time.Time, censorCfg report.CensorConfig) (detailed.NodeSummaries, int, error) {
+	rpt, err := rep.Report(ctx, timestamp)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	renderer, filter, err := topologyRegistry.forTenant(ctx).RendererForTopology(topologyID, values, rpt)
+	if err != nil {
+		// The topology is there, so it's the query
+		return nil, http.StatusBadRequest, err
+	}
+	summaries := detailed.Summaries(ctx, RenderContextForReporter(ctx, rep, rpt), renderTopology(ctx, topologyID, rpt, renderer, filter).Nodes)
+	return detailed.CensorNodeSummaries(summaries, censorCfg), http.StatusOK, nil
+}
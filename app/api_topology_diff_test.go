@@ -0,0 +1,100 @@
+package app_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestAPITopologyDiff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	// Nothing at first, then the fixture
+	store := app.NewMemoryReportStore(time.Hour)
+	collector := app.NewClusteredCollector(store, time.Second, time.Hour)
+	defer collector.Stop()
+	before, after := now.Add(-time.Minute), now
+	collector.Add(app.WithReportTimestamp(ctx, before), report.MakeReport(), nil)
+	collector.Add(app.WithReportTimestamp(ctx, after), fixture.Report, nil)
+
+	// Then the same, but for the metrics of the containers
+	later := now.Add(time.Minute)
+	changed := fixture.Report.Copy()
+	for id, n := range changed.Container.Nodes {
+		changed.Container.Nodes[id] = n.WithMetrics(report.Metrics{
+			docker.CPUTotalUsage: report.MakeSingletonMetric(later, 42),
+		})
+	}
+	collector.Add(app.WithReportTimestamp(ctx, later), changed, nil)
+
+	router := mux.NewRouter().SkipClean(true)
+	app.RegisterTopologyRoutes(router, collector, nil)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	is404(t, ts, "/api/topology/foobar/diff?from="+url.QueryEscape(before.Format(time.RFC3339)))
+	is400(t, ts, "/api/topology/containers/diff")
+	is400(t, ts, "/api/topology/containers/diff?from=yesterday")
+	is400(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(after.Format(time.RFC3339))+"&to="+url.QueryEscape(before.Format(time.RFC3339)))
+
+	var diff app.APITopologyDiff
+	_, body := checkGet(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(before.Format(time.RFC3339))+"&to="+url.QueryEscape(after.Format(time.RFC3339)))
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Add) == 0 || len(diff.Nodes.Update) != 0 || len(diff.Nodes.Remove) != 0 {
+		t.Errorf("Expected only added nodes, got %v", diff.Nodes)
+	}
+	edge := detailed.Edge{Source: fixture.ClientContainerNodeID, Target: fixture.ServerContainerNodeID}
+	found := false
+	for _, e := range diff.Edges.Add {
+		found = found || e == edge
+	}
+	if !found || len(diff.Edges.Remove) != 0 {
+		t.Errorf("Expected the edge %v to be added, got %v", edge, diff.Edges)
+	}
+
+	// Nothing changed since the cursor
+	_, body = checkGet(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(diff.Cursor)+"&to="+url.QueryEscape(after.Format(time.RFC3339)))
+	diff = app.APITopologyDiff{}
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Add)+len(diff.Nodes.Update)+len(diff.Nodes.Remove)+len(diff.Edges.Add)+len(diff.Edges.Remove) != 0 {
+		t.Errorf("Expected no changes, got %v", diff)
+	}
+
+	// Nor when only the metrics changed, unless they are asked for
+	path := "/api/topology/containers/diff?from=" + url.QueryEscape(after.Format(time.RFC3339)) + "&to=" + url.QueryEscape(later.Format(time.RFC3339))
+	_, body = checkGet(t, ts, path)
+	diff = app.APITopologyDiff{}
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Add)+len(diff.Nodes.Update)+len(diff.Nodes.Remove) != 0 {
+		t.Errorf("Expected no changes of the metrics alone, got %v", diff.Nodes)
+	}
+	_, body = checkGet(t, ts, path+"&metrics=true")
+	diff = app.APITopologyDiff{}
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Update) == 0 {
+		t.Errorf("Expected the metrics to update nodes, got %v", diff.Nodes)
+	}
+}
@@ -0,0 +1,65 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestAPITopologyExport(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+
+	is400(t, ts, "/api/topology/containers/export")
+	is400(t, ts, "/api/topology/containers/export?format=svg")
+	is404(t, ts, "/api/topology/foobar/export?format=dot")
+
+	edge := strconv.Quote(fixture.ClientContainerNodeID) + " -> " + strconv.Quote(fixture.ServerContainerNodeID)
+	res, body := checkGet(t, ts, "/api/topology/containers/export?format=dot")
+	if ct := res.Header.Get("Content-Type"); ct != "text/vnd.graphviz" {
+		t.Errorf("Wrong Content-Type for DOT: %s", ct)
+	}
+	if !bytes.HasPrefix(body, []byte(`digraph "containers" {`)) || !bytes.Contains(body, []byte(edge)) {
+		t.Errorf("Expected a digraph with the edge %s, got:\n%s", edge, body)
+	}
+
+	var graph struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"graph>edge"`
+	}
+	_, body = checkGet(t, ts, "/api/topology/containers/export?format=graphml")
+	if err := xml.Unmarshal(body, &graph); err != nil {
+		t.Fatalf("GraphML parse error: %s", err)
+	}
+	found := false
+	for _, e := range graph.Edges {
+		found = found || (e.Source == fixture.ClientContainerNodeID && e.Target == fixture.ServerContainerNodeID)
+	}
+	if len(graph.Nodes) == 0 || !found {
+		t.Errorf("Expected nodes and the edge %s, got %v", edge, graph)
+	}
+
+	_, body = checkGet(t, ts, "/api/topology/containers/export?format=csv")
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV parse error: %s", err)
+	}
+	if len(rows) != len(graph.Nodes)+1 || rows[0][0] != "id" {
+		t.Fatalf("Expected a header and a row per node, got %v", rows)
+	}
+	for _, row := range rows[1:] {
+		if row[0] == fixture.ClientContainerNodeID && !strings.Contains(row[6], fixture.ServerContainerNodeID) {
+			t.Errorf("Expected the client container to be adjacent to the server one, got %v", row)
+		}
+	}
+}
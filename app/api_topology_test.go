@@ -1,16 +1,21 @@
 package app_test
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/ugorji/go/codec"
 
 	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/kubernetes"
 	"github.com/weaveworks/scope/render/detailed"
 	"github.com/weaveworks/scope/render/expected"
+	"github.com/weaveworks/scope/report"
 	"github.com/weaveworks/scope/test/fixture"
 )
 
@@ -88,6 +93,9 @@ func TestAPITopologyHosts(t *testing.T) {
 	ts := topologyServer()
 	defer ts.Close()
 	is404(t, ts, "/api/topology/hosts/foobar")
+	is400(t, ts, "/api/topology/hosts?timestamp=yesterday")
+	is400(t, ts, "/api/topology/hosts?query=cpu%3Elots")
+	is400(t, ts, "/api/topology?query=(host")
 	{
 		body := getRawJSON(t, ts, "/api/topology/hosts")
 		var topo app.APITopology
@@ -117,6 +125,60 @@ func TestAPITopologyHosts(t *testing.T) {
 	}
 }
 
+func TestRenderTopologies(t *testing.T) {
+	topologies, err := app.RenderTopologies(context.Background(), fixture.Report, []string{"hosts", "containers"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topologies) != 2 {
+		t.Fatalf("Expected hosts and containers, got %v", topologies)
+	}
+	for id := range expected.RenderedHosts {
+		if _, ok := topologies["hosts"].Nodes[id]; !ok {
+			t.Errorf("Expected output to include node: %s, but wasn't found", id)
+		}
+	}
+
+	if _, err := app.RenderTopologies(context.Background(), fixture.Report, []string{"foobar"}, nil); err == nil {
+		t.Error("Expected an error rendering an unknown topology")
+	}
+	all, err := app.RenderTopologies(context.Background(), fixture.Report, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all["processes-by-name"]; !ok {
+		t.Errorf("Expected all the topologies, sub-topologies included, got %d", len(all))
+	}
+}
+
+func TestAPITopologyLayout(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+
+	layout := func() map[string]detailed.Position {
+		var topo app.APITopology
+		if err := codec.NewDecoderBytes(getRawJSON(t, ts, "/api/topology/hosts?layout=true"), &codec.JsonHandle{}).Decode(&topo); err != nil {
+			t.Fatal(err)
+		}
+		if len(topo.Layout) != len(topo.Nodes) {
+			t.Fatalf("Expected a position for every node, got %v", topo.Layout)
+		}
+		return topo.Layout
+	}
+	// Nodes stay where they were from one request to the next
+	if first, second := layout(), layout(); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same layout, got %v and %v", first, second)
+	}
+
+	var topo app.APITopology
+	if err := codec.NewDecoderBytes(getRawJSON(t, ts, "/api/topology/hosts"), &codec.JsonHandle{}).Decode(&topo); err != nil {
+		t.Fatal(err)
+	}
+	if topo.Layout != nil {
+		t.Errorf("Expected no layout unless asked for, got %v", topo.Layout)
+	}
+}
+
 // Basic websocket test
 func TestAPITopologyWebsocket(t *testing.T) {
 	ts := topologyServer()
@@ -152,4 +214,67 @@ func TestAPITopologyWebsocket(t *testing.T) {
 	equals(t, 0, len(d.Remove))
 }
 
+func TestAPINodeWebsocket(t *testing.T) {
+	ts := topologyServer()
+	defer ts.Close()
+	wsURL := "ws" + ts.URL[len("http"):]
+
+	read := func(nodeID string) app.APINodeUpdate {
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL+"/api/topology/hosts/"+url.QueryEscape(nodeID)+"/ws", nil)
+		ok(t, err)
+		defer ws.Close()
+		_, p, err := ws.ReadMessage()
+		ok(t, err)
+		var update app.APINodeUpdate
+		if err := codec.NewDecoderBytes(p, &codec.JsonHandle{}).Decode(&update); err != nil {
+			t.Fatalf("JSON parse error: %s", err)
+		}
+		return update
+	}
+
+	if update := read(fixture.ServerHostNodeID); update.Node == nil || update.Node.ID != fixture.ServerHostNodeID {
+		t.Errorf("Expected the details of %s, got %v", fixture.ServerHostNodeID, update)
+	}
+	if update := read("nonexistent"); !update.Removed {
+		t.Errorf("Expected the node to be removed, got %v", update)
+	}
+}
+
 func newu64(value uint64) *uint64 { return &value }
+
+func TestAPITopologyUnmanagedReplicaSet(t *testing.T) {
+	// A replica set no deployment owns, as new probes report them
+	var (
+		replicaSetID = report.MakeReplicaSetNodeID("rs-uid")
+		podID        = report.MakePodNodeID("pod-uid")
+	)
+	rpt := report.MakeReport()
+	rpt.ReplicaSet.AddNode(report.MakeNodeWith(replicaSetID, map[string]string{
+		kubernetes.Name:      "rs",
+		kubernetes.Namespace: "default",
+	}).WithTopology(report.ReplicaSet))
+	rpt.Pod.AddNode(report.MakeNodeWith(podID, map[string]string{
+		kubernetes.Name:      "rs-abcde",
+		kubernetes.Namespace: "default",
+	}).WithTopology(report.Pod).WithParent(report.ReplicaSet, replicaSetID))
+
+	collector := app.NewCollector(time.Minute)
+	if err := collector.Add(context.Background(), rpt, nil); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := collector.Report(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	topologies, err := app.RenderTopologies(context.Background(), merged, []string{"kube-controllers"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := topologies["kube-controllers"].Nodes
+	// The pod stays under its replica set
+	if node, ok := nodes[replicaSetID]; !ok {
+		t.Errorf("Expected the replica set in the controllers, got %v", nodes)
+	} else if want := "ReplicaSet of 1 pod"; node.LabelMinor != want {
+		t.Errorf("Expected %q, got %q", want, node.LabelMinor)
+	}
+}
@@ -0,0 +1,30 @@
+// +build !windows
+
+package app
+
+import (
+	"encoding/json"
+	"log/syslog"
+)
+
+// NewSyslogAuditSink sends audit entries to syslog, as JSON. network and
+// raddr are as for syslog.Dial; leave them empty for the local syslog.
+func NewSyslogAuditSink(network, raddr string) (AuditSink, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_NOTICE|syslog.LOG_AUTH, "scope")
+	if err != nil {
+		return nil, err
+	}
+	return syslogAuditSink{w}, nil
+}
+
+type syslogAuditSink struct {
+	w *syslog.Writer
+}
+
+func (s syslogAuditSink) Record(entry AuditEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.w.Notice(string(buf))
+}
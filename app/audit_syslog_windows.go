@@ -0,0 +1,8 @@
+package app
+
+import "fmt"
+
+// NewSyslogAuditSink fails: there is no syslog on Windows.
+func NewSyslogAuditSink(network, raddr string) (AuditSink, error) {
+	return nil, fmt.Errorf("syslog audit sink not supported on windows")
+}
@@ -0,0 +1,155 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+type mockAuditSink []app.AuditEntry
+
+func (s *mockAuditSink) Record(entry app.AuditEntry) error {
+	*s = append(*s, entry)
+	return nil
+}
+
+func TestAuditLog(t *testing.T) {
+	sink := &mockAuditSink{}
+	auditLog := app.NewAuditLog(sink)
+	controlRouter := app.AuditingControlRouter(app.NewLocalControlRouter(), auditLog)
+	controlRouter.Register(context.Background(), "probe", func(req xfer.Request) xfer.Response {
+		if req.Control == "fail" {
+			return xfer.ResponseErrorf("failed")
+		}
+		return xfer.Response{}
+	})
+
+	router := mux.NewRouter()
+	app.RegisterControlRoutes(router, controlRouter)
+	app.RegisterAuditRoutes(router, auditLog)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, control := range []string{"restart", "fail"} {
+		req, err := http.NewRequest("POST", server.URL+"/api/control/probe/node/"+control, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.SetBasicAuth("alice", "secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(*sink) != 2 {
+		t.Fatalf("expected 2 entries in the sink, got %v", *sink)
+	}
+
+	resp, err := http.Get(server.URL + "/api/audit?user=alice&control=fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var entries []app.AuditEntry
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %v", entries)
+	}
+	if have := entries[0]; have.ProbeID != "probe" || have.NodeID != "node" || have.Error != "failed" || have.RemoteAddr == "" {
+		t.Errorf("unexpected entry %v", have)
+	}
+}
+
+func TestWebhookAuditSink(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan app.AuditEntry, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var entry app.AuditEntry
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&entry); err != nil {
+			t.Error(err)
+		}
+		received <- entry
+	}))
+	defer server.Close()
+
+	// Entries are recorded without waiting for the webhook
+	sink := app.NewWebhookAuditSink(server.URL)
+	for _, control := range []string{"restart", "pause"} {
+		if err := sink.Record(app.AuditEntry{Control: control}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	for _, control := range []string{"restart", "pause"} {
+		select {
+		case entry := <-received:
+			if entry.Control != control {
+				t.Errorf("expected %s, got %v", control, entry)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", control)
+		}
+	}
+}
+
+func TestAuditLogTenants(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant red
+user redtoken reduser
+tenant blue
+user bluetoken blueuser
+`)
+	f.Close()
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auditLog := app.NewAuditLog(nil)
+	auditLog.Record(app.AuditEntry{Tenant: "red", User: "reduser", Control: "restart"})
+	auditLog.Record(app.AuditEntry{Tenant: "blue", User: "blueuser", Control: "pause"})
+	router := mux.NewRouter()
+	app.RegisterAuditRoutes(router, auditLog)
+	server := httptest.NewServer(auth.Wrap(router))
+	defer server.Close()
+
+	for token, control := range map[string]string{"redtoken": "restart", "bluetoken": "pause"} {
+		req, err := http.NewRequest("GET", server.URL+"/api/audit", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entries []app.AuditEntry
+		err = codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&entries)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Control != control {
+			t.Errorf("expected only the %s entry of the tenant of %s, got %v", control, token, entries)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/weaveworks/scope/app"
+)
+
+func TestAuth(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`# tokens
+probe probetoken
+user admintoken admin *
+user devtoken dev docker_attach_container,docker_pause_container
+user writertoken writer write
+user operatortoken operator write,probe_set_settings
+user superusertoken superuser admin
+user viewertoken viewer
+`)
+	f.Close()
+
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(auth.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+
+	for _, c := range []struct {
+		method, path, authorization string
+		status                      int
+	}{
+		{"POST", "/api/report", "Scope-Probe token=probetoken", http.StatusOK},
+		{"POST", "/api/report", "Scope-Probe token=devtoken", http.StatusUnauthorized},
+		{"POST", "/api/report", "Bearer admintoken", http.StatusUnauthorized},
+		{"GET", "/api", "Scope-Probe token=probetoken", http.StatusOK},
+		{"DELETE", "/api/pipe/pipeid", "Scope-Probe token=probetoken", http.StatusOK},
+		{"GET", "/api/topology", "Scope-Probe token=probetoken", http.StatusUnauthorized},
+		{"GET", "/api/topology", "", http.StatusUnauthorized},
+		{"GET", "/api/topology", "Bearer viewertoken", http.StatusOK},
+		{"GET", "/api/topology?access_token=viewertoken", "", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_exec_container", "Bearer admintoken", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_pause_container", "Bearer devtoken", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_exec_container", "Bearer devtoken", http.StatusForbidden},
+		{"POST", "/api/control/probe/node/docker_pause_container", "Bearer viewertoken", http.StatusForbidden},
+		{"POST", "/api/control/probe/node/docker_pause_container", "Bearer writertoken", http.StatusForbidden},
+		{"DELETE", "/api/pipe/pipeid", "Bearer devtoken", http.StatusOK},
+
+		// Only writers change the app's settings
+		{"POST", "/api/probes/settings", "Bearer viewertoken", http.StatusForbidden},
+		{"PUT", "/api/health/rules", "Bearer viewertoken", http.StatusForbidden},
+		{"POST", "/api/annotations", "Bearer viewertoken", http.StatusForbidden},
+		{"DELETE", "/api/annotations/id", "Bearer viewertoken", http.StatusForbidden},
+		{"PUT", "/api/custom-topology/id", "Bearer viewertoken", http.StatusForbidden},
+		{"DELETE", "/api/custom-topology/id", "Bearer viewertoken", http.StatusForbidden},
+		{"PUT", "/api/health/rules", "Bearer devtoken", http.StatusForbidden},
+		{"GET", "/api/health/rules", "Bearer viewertoken", http.StatusOK},
+		{"POST", "/api/probes/settings", "Bearer writertoken", http.StatusForbidden},
+		{"POST", "/api/probes/settings", "Bearer operatortoken", http.StatusOK},
+		{"POST", "/api/probes/settings", "Bearer admintoken", http.StatusOK},
+		{"PUT", "/api/health/rules", "Bearer writertoken", http.StatusOK},
+		{"POST", "/api/annotations", "Bearer writertoken", http.StatusOK},
+		{"DELETE", "/api/annotations/id", "Bearer writertoken", http.StatusOK},
+		{"PUT", "/api/custom-topology/id", "Bearer writertoken", http.StatusOK},
+		{"DELETE", "/api/custom-topology/id", "Bearer writertoken", http.StatusOK},
+		{"PUT", "/api/health/rules", "Bearer admintoken", http.StatusOK},
+
+		// Only admins read the recordings of sessions
+		{"GET", "/api/recordings", "Bearer viewertoken", http.StatusForbidden},
+		{"GET", "/api/recordings/id", "Bearer viewertoken", http.StatusForbidden},
+		{"GET", "/api/recordings/id", "Bearer writertoken", http.StatusForbidden},
+		{"GET", "/api/recordings", "Bearer devtoken", http.StatusForbidden},
+		{"GET", "/api/recordings", "Bearer superusertoken", http.StatusOK},
+		{"GET", "/api/recordings/id", "Bearer superusertoken", http.StatusOK},
+		{"GET", "/api/recordings/id", "Bearer admintoken", http.StatusOK},
+	} {
+		req, err := http.NewRequest(c.method, server.URL+c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s %s (%q): expected %d, got %d", c.method, c.path, c.authorization, c.status, resp.StatusCode)
+		}
+	}
+
+	// The token handed over in the URL is kept in a cookie scripts can't
+	// read, which is only secure over HTTPS, for browsers to keep it over
+	// plain HTTP too.
+	tlsServer := httptest.NewTLSServer(server.Config.Handler)
+	defer tlsServer.Close()
+	for _, c := range []struct {
+		client *http.Client
+		url    string
+		secure bool
+	}{
+		{http.DefaultClient, server.URL, false},
+		{tlsServer.Client(), tlsServer.URL, true},
+	} {
+		resp, err := c.client.Get(c.url + "/api/topology?access_token=viewertoken")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		cookies := resp.Cookies()
+		if len(cookies) != 1 || cookies[0].Name != app.AuthCookie || cookies[0].Secure != c.secure || !cookies[0].HttpOnly {
+			t.Errorf("%s: expected an HTTP-only %s cookie, secure: %v, got %v", c.url, app.AuthCookie, c.secure, cookies)
+		}
+	}
+
+	// The cookie keeps the browser authenticated over plain HTTP
+	req, err := http.NewRequest("GET", server.URL+"/api/topology", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.AddCookie(&http.Cookie{Name: app.AuthCookie, Value: "viewertoken"})
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the cookie to authenticate, got %d", resp.StatusCode)
+	}
+}
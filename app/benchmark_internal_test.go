@@ -6,8 +6,6 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/weaveworks/scope/render"
@@ -21,22 +19,8 @@ var (
 )
 
 func readReportFiles(b *testing.B, path string) []report.Report {
-	reports := []report.Report{}
-	if err := filepath.Walk(path,
-		func(p string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			rpt, err := report.MakeFromFile(p)
-			if err != nil {
-				return err
-			}
-			reports = append(reports, rpt)
-			return nil
-		}); err != nil {
+	reports, err := ReadReports(path)
+	if err != nil {
 		b.Fatal(err)
 	}
 	return reports
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"context"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// ClusteredCollector is a Collector for running several replicas of the
+// app, e.g. behind a load balancer. It keeps no reports of its own: every
+// report goes into a store shared by the replicas, and reports are
+// rendered from the reports of the window in that store, in the order of
+// their timestamps, so that any replica serves the same merged view
+// whichever replica the probes published to. Recent reports are rendered
+// from those the replica polled the store for, so the view lags the other
+// replicas by up to the poll interval.
+//
+// The bases of delta reports are kept by each replica, not shared, so
+// probes publishing deltas through a load balancer which isn't sticky have
+// them refused, and publish them again in full.
+type ClusteredCollector struct {
+	store  ReportStore
+	window time.Duration
+	merger Merger
+
+	mtx      sync.Mutex
+	fetched  map[int64]report.Report // by the nanoseconds of their timestamp
+	polled   time.Time               // when fetched last caught up with the store
+	cached   *report.Report          // merge of cachedOf
+	cachedOf []int64
+	waitableCondition
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClusteredCollector returns a Collector keeping reports in store,
+// which the replicas of the app share, rendering those received within
+// window. It polls the store every pollInterval for reports added by
+// other replicas, to wake up the clients waiting on shortcut reports, until
+// stopped.
+func NewClusteredCollector(store ReportStore, window, pollInterval time.Duration) *ClusteredCollector {
+	c := &ClusteredCollector{
+		store:   store,
+		window:  window,
+		merger:  NewFastMerger(),
+		fetched: map[int64]report.Report{},
+		waitableCondition: waitableCondition{
+			waiters: map[chan struct{}]struct{}{},
+		},
+		quit: make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.loop(pollInterval)
+	return c
+}
+
+// Stop stops polling the store.
+func (c *ClusteredCollector) Stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// Add implements Adder
+func (c *ClusteredCollector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	timestamp := ReportTimestamp(ctx)
+	if err := c.store.Put(ctx, timestamp, rpt, buf); err != nil {
+		return err
+	}
+	// This replica needn't fetch it back; the others will
+	c.mtx.Lock()
+	c.fetched[timestamp.UnixNano()] = rpt.Upgrade()
+	c.mtx.Unlock()
+	if rpt.Shortcut {
+		c.Broadcast()
+	}
+	return nil
+}
+
+// timestamps returns, in order, the times of the reports of the window
+// ending at timestamp: from those fetched if they are recent enough, or
+// else from the store.
+func (c *ClusteredCollector) timestamps(ctx context.Context, timestamp time.Time) ([]time.Time, error) {
+	start := timestamp.Add(-c.window)
+	c.mtx.Lock()
+	if c.polled.IsZero() || timestamp.Before(c.polled) {
+		c.mtx.Unlock()
+		return c.store.Range(ctx, start, timestamp.Add(time.Nanosecond))
+	}
+	defer c.mtx.Unlock()
+	timestamps := []time.Time{}
+	for key := range c.fetched {
+		if t := time.Unix(0, key); !t.Before(start) && !t.After(timestamp) {
+			timestamps = append(timestamps, t)
+		}
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	return timestamps, nil
+}
+
+// Report implements Reporter
+func (c *ClusteredCollector) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	timestamps, err := c.timestamps(ctx, timestamp)
+	if err != nil {
+		return report.Report{}, err
+	}
+	keys := make([]int64, len(timestamps))
+	for i, t := range timestamps {
+		keys[i] = t.UnixNano()
+	}
+
+	c.mtx.Lock()
+	if c.cached != nil && equalKeys(keys, c.cachedOf) {
+		defer c.mtx.Unlock()
+		return *c.cached, nil
+	}
+	c.mtx.Unlock()
+
+	reports, err := c.fetch(ctx, timestamps)
+	if err != nil {
+		return report.Report{}, err
+	}
+	rpt := c.merger.Merge(reports)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.cached, c.cachedOf = &rpt, keys
+	return rpt, nil
+}
+
+// fetch returns the reports stored at timestamps, using those fetched
+// already. Only reports recent enough to be rendered again are kept.
+func (c *ClusteredCollector) fetch(ctx context.Context, timestamps []time.Time) ([]report.Report, error) {
+	oldest := mtime.Now().Add(-c.window)
+	reports := make([]report.Report, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		c.mtx.Lock()
+		rpt, ok := c.fetched[timestamp.UnixNano()]
+		c.mtx.Unlock()
+		if !ok {
+			stored, err := c.store.Fetch(ctx, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			rpt = stored.Upgrade()
+			if timestamp.After(oldest) {
+				c.mtx.Lock()
+				c.fetched[timestamp.UnixNano()] = rpt
+				c.mtx.Unlock()
+			}
+		}
+		reports = append(reports, rpt)
+	}
+	return reports, nil
+}
+
+// HasReports implements Reporter
+func (c *ClusteredCollector) HasReports(ctx context.Context, timestamp time.Time) (bool, error) {
+	timestamps, err := c.timestamps(ctx, timestamp)
+	if err != nil {
+		return false, err
+	}
+	return len(timestamps) > 0, nil
+}
+
+// HasHistoricReports implements Reporter
+func (c *ClusteredCollector) HasHistoricReports() bool {
+	return true
+}
+
+func (c *ClusteredCollector) loop(pollInterval time.Duration) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.poll(context.Background()); err != nil {
+			log.Errorf("Error polling the report store: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// poll fetches the reports of the window added to the store by the other
+// replicas, waking up the waiters if any of them is a shortcut report, and
+// forgets those which dropped out of the window. The whole window is
+// listed, for the reports of replicas whose clocks are a little behind.
+func (c *ClusteredCollector) poll(ctx context.Context) error {
+	now := mtime.Now()
+	oldest := now.Add(-c.window)
+	c.mtx.Lock()
+	for key := range c.fetched {
+		if !time.Unix(0, key).After(oldest) {
+			delete(c.fetched, key)
+		}
+	}
+	c.mtx.Unlock()
+
+	// The clocks of the replicas can be a little ahead, too
+	timestamps, err := c.store.Range(ctx, oldest.Add(time.Nanosecond), now.Add(c.window))
+	if err != nil {
+		return err
+	}
+	missing := []time.Time{}
+	c.mtx.Lock()
+	for _, timestamp := range timestamps {
+		if _, ok := c.fetched[timestamp.UnixNano()]; !ok {
+			missing = append(missing, timestamp)
+		}
+	}
+	c.mtx.Unlock()
+	reports, err := c.fetch(ctx, missing)
+	if err != nil {
+		return err
+	}
+	c.mtx.Lock()
+	c.polled = now
+	c.mtx.Unlock()
+	for _, rpt := range reports {
+		if rpt.Shortcut {
+			c.Broadcast()
+			break
+		}
+	}
+	return nil
+}
+
+func equalKeys(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package app_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+	scopetest "github.com/weaveworks/scope/test"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestClusteredCollector(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// Two replicas of the app, sharing a store
+	window := 10 * time.Second
+	store := app.NewMemoryReportStore(time.Minute)
+	c1 := app.NewClusteredCollector(store, window, 10*time.Millisecond)
+	defer c1.Stop()
+	c2 := app.NewClusteredCollector(store, window, 10*time.Millisecond)
+	defer c2.Stop()
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	if err := c1.Add(app.WithReportTimestamp(ctx, now), r1, nil); err != nil {
+		t.Fatal(err)
+	}
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("bar"))
+	if err := c2.Add(app.WithReportTimestamp(ctx, now.Add(time.Second)), r2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both render both reports, once they polled the store
+	want := report.MakeReport()
+	want.Endpoint.AddNode(report.MakeNode("foo"))
+	want.Endpoint.AddNode(report.MakeNode("bar"))
+	for _, c := range []app.Collector{c1, c2} {
+		scopetest.Poll(t, time.Second, want, func() interface{} {
+			have, _ := c.Report(ctx, now.Add(time.Second))
+			return have
+		})
+	}
+
+	// Reports out of the window are not rendered, but kept for history
+	have, err := c2.Report(ctx, now.Add(window+time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r2, have) {
+		t.Error(test.Diff(r2, have))
+	}
+	if ok, _ := c2.HasReports(ctx, now.Add(-time.Second)); ok {
+		t.Error("expected no reports before the first one")
+	}
+}
+
+func TestClusteredCollectorShortcut(t *testing.T) {
+	ctx := context.Background()
+	store := app.NewMemoryReportStore(time.Minute)
+	c1 := app.NewClusteredCollector(store, 15*time.Second, time.Hour)
+	defer c1.Stop()
+	c2 := app.NewClusteredCollector(store, 15*time.Second, 10*time.Millisecond)
+	defer c2.Stop()
+
+	waiter := make(chan struct{}, 1)
+	c2.WaitOn(ctx, waiter)
+	defer c2.UnWait(ctx, waiter)
+
+	// A shortcut report published to one replica wakes up the clients
+	// of the other
+	rpt := report.MakeReport()
+	rpt.Shortcut = true
+	if err := c1.Add(ctx, rpt, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-waiter:
+	case <-time.After(time.Second):
+		t.Fatal("Didn't unblock")
+	}
+}
+
+// rangeCountingStore counts the listings of a store.
+type rangeCountingStore struct {
+	app.ReportStore
+	mtx    sync.Mutex
+	ranges int
+}
+
+func (s *rangeCountingStore) Range(ctx context.Context, start, end time.Time) ([]time.Time, error) {
+	s.mtx.Lock()
+	s.ranges++
+	s.mtx.Unlock()
+	return s.ReportStore.Range(ctx, start, end)
+}
+
+func (s *rangeCountingStore) count() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.ranges
+}
+
+func TestClusteredCollectorPolls(t *testing.T) {
+	ctx := context.Background()
+	store := &rangeCountingStore{ReportStore: app.NewMemoryReportStore(time.Minute)}
+	c := app.NewClusteredCollector(store, 15*time.Second, time.Hour)
+	defer c.Stop()
+	scopetest.Poll(t, time.Second, 1, func() interface{} { return store.count() })
+
+	// Recent reports are rendered from the poll, without listing the store
+	rpt := report.MakeReport()
+	if err := c.Add(ctx, rpt, nil); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.Report(ctx, mtime.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := c.HasReports(ctx, mtime.Now()); err != nil || !ok {
+			t.Fatalf("expected reports, got %v: %v", ok, err)
+		}
+	}
+	if ranges := store.count(); ranges != 1 {
+		t.Errorf("expected the store to be listed once, by the poll, got %d", ranges)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 
 	"context"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/weaveworks/common/mtime"
 	"github.com/weaveworks/scope/report"
 )
@@ -25,6 +27,26 @@ import (
 // as soon as there is more than one probe.
 const reportQuantisationInterval = 3 * time.Second
 
+var (
+	collectorReports = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "collector_reports",
+		Help:      "Number of reports held in memory by the collector.",
+	})
+	collectorReportBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "collector_report_bytes",
+		Help:      "Serialised size of the reports held in memory by the collector, in bytes.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(collectorReports)
+	prometheus.MustRegister(collectorReportBytes)
+}
+
 // Reporter is something that can produce reports on demand. It's a convenient
 // interface for parts of the app, and several experimental components.
 type Reporter interface {
@@ -41,6 +63,9 @@ type Reporter interface {
 type WebReporter struct {
 	Reporter
 	MetricsGraphURL string
+	ReadOnly        bool
+	Health          *HealthConfig
+	Annotations     *AnnotationStore
 }
 
 // Adder is something that can accept reports. It's a convenient interface for
@@ -53,6 +78,24 @@ type Adder interface {
 	Add(context.Context, report.Report, []byte) error
 }
 
+const reportTimestampCtxKey contextKey = contextKey("reportTimestamp")
+
+// WithReportTimestamp returns a context in which reports are added as of
+// timestamp, rather than as of now: the time a probe made a report it
+// could only publish later.
+func WithReportTimestamp(ctx context.Context, timestamp time.Time) context.Context {
+	return context.WithValue(ctx, reportTimestampCtxKey, timestamp)
+}
+
+// ReportTimestamp returns the time the report added in ctx was made, which
+// is now unless the context says otherwise.
+func ReportTimestamp(ctx context.Context) time.Time {
+	if timestamp, ok := ctx.Value(reportTimestampCtxKey).(time.Time); ok {
+		return timestamp
+	}
+	return mtime.Now()
+}
+
 // A Collector is a Reporter and an Adder
 type Collector interface {
 	Reporter
@@ -65,6 +108,7 @@ type collector struct {
 	mtx        sync.Mutex
 	reports    []report.Report
 	timestamps []time.Time
+	sizes      []int // serialised size of each report, when known
 	window     time.Duration
 	cached     *report.Report
 	merger     Merger
@@ -112,11 +156,24 @@ func NewCollector(window time.Duration) Collector {
 }
 
 // Add adds a report to the collector's internal state. It implements Adder.
-func (c *collector) Add(_ context.Context, rpt report.Report, _ []byte) error {
+func (c *collector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	timestamp := ReportTimestamp(ctx)
+	if !timestamp.After(mtime.Now().Add(-c.window)) {
+		return nil // too late to be rendered
+	}
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
-	c.reports = append(c.reports, rpt)
-	c.timestamps = append(c.timestamps, mtime.Now())
+	// Reports published late go in order of their timestamps
+	i := sort.Search(len(c.timestamps), func(i int) bool { return c.timestamps[i].After(timestamp) })
+	c.reports = append(c.reports, report.Report{})
+	copy(c.reports[i+1:], c.reports[i:])
+	c.reports[i] = rpt
+	c.timestamps = append(c.timestamps, time.Time{})
+	copy(c.timestamps[i+1:], c.timestamps[i:])
+	c.timestamps[i] = timestamp
+	c.sizes = append(c.sizes, 0)
+	copy(c.sizes[i+1:], c.sizes[i:])
+	c.sizes[i] = len(buf)
 
 	c.clean()
 	c.cached = nil
@@ -177,16 +234,23 @@ func (c *collector) clean() {
 	var (
 		cleanedReports    = make([]report.Report, 0, len(c.reports))
 		cleanedTimestamps = make([]time.Time, 0, len(c.timestamps))
+		cleanedSizes      = make([]int, 0, len(c.sizes))
 		oldest            = mtime.Now().Add(-c.window)
+		totalSize         = 0
 	)
 	for i, r := range c.reports {
 		if c.timestamps[i].After(oldest) {
 			cleanedReports = append(cleanedReports, r)
 			cleanedTimestamps = append(cleanedTimestamps, c.timestamps[i])
+			cleanedSizes = append(cleanedSizes, c.sizes[i])
+			totalSize += c.sizes[i]
 		}
 	}
 	c.reports = cleanedReports
 	c.timestamps = cleanedTimestamps
+	c.sizes = cleanedSizes
+	collectorReports.Set(float64(len(c.reports)))
+	collectorReportBytes.Set(float64(totalSize))
 }
 
 // Merge reports received within the same reportQuantisationInterval.
@@ -203,6 +267,7 @@ func (c *collector) quantise() {
 	var (
 		quantisedReports    = make([]report.Report, 0, len(c.reports))
 		quantisedTimestamps = make([]time.Time, 0, len(c.timestamps))
+		quantisedSizes      = make([]int, 0, len(c.sizes))
 	)
 	quantumStartIdx := 0
 	quantumStartTimestamp := c.timestamps[0]
@@ -212,11 +277,22 @@ func (c *collector) quantise() {
 		}
 		quantisedReports = append(quantisedReports, c.merger.Merge(c.reports[quantumStartIdx:i]))
 		quantisedTimestamps = append(quantisedTimestamps, quantumStartTimestamp)
+		quantisedSizes = append(quantisedSizes, sum(c.sizes[quantumStartIdx:i]))
 		quantumStartIdx = i
 		quantumStartTimestamp = t
 	}
 	c.reports = append(quantisedReports, c.merger.Merge(c.reports[quantumStartIdx:]))
 	c.timestamps = append(quantisedTimestamps, c.timestamps[quantumStartIdx])
+	c.sizes = append(quantisedSizes, sum(c.sizes[quantumStartIdx:]))
+	collectorReports.Set(float64(len(c.reports)))
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
 }
 
 // StaticCollector always returns the given report.
@@ -259,12 +335,37 @@ func (c StaticCollector) UnWait(context.Context, chan struct{}) {}
 // a loop at a sequence and speed determined by the timestamps.
 // Otherwise the collector always returns the merger of all reports.
 func NewFileCollector(path string, window time.Duration) (Collector, error) {
+	timestamps, reports, err := readReportDir(path)
+	if err != nil {
+		return nil, err
+	}
+	allTimestamped := true
+	for _, t := range timestamps {
+		allTimestamped = allTimestamped && !t.IsZero()
+	}
+	if len(reports) > 1 && allTimestamped {
+		collector := NewCollector(window)
+		go replay(collector, timestamps, reports)
+		return collector, nil
+	}
+	return StaticCollector(NewFastMerger().Merge(reports).Upgrade()), nil
+}
+
+// ReadReports reads and parses the files at path (a file or directory)
+// as reports.
+func ReadReports(path string) ([]report.Report, error) {
+	_, reports, err := readReportDir(path)
+	return reports, err
+}
+
+// readReportDir returns the reports at path, with the timestamps of
+// their file names, zero for the names which aren't timestamps.
+func readReportDir(path string) ([]time.Time, []report.Report, error) {
 	var (
 		timestamps []time.Time
 		reports    []report.Report
 	)
-	allTimestamped := true
-	if err := filepath.Walk(path,
+	err := filepath.Walk(path,
 		func(p string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -272,10 +373,7 @@ func NewFileCollector(path string, window time.Duration) (Collector, error) {
 			if info.IsDir() {
 				return nil
 			}
-			t, err := timestampFromFilepath(p)
-			if err != nil {
-				allTimestamped = false
-			}
+			t, _ := timestampFromFilepath(p)
 			timestamps = append(timestamps, t)
 
 			rpt, err := report.MakeFromFile(p)
@@ -284,15 +382,8 @@ func NewFileCollector(path string, window time.Duration) (Collector, error) {
 			}
 			reports = append(reports, rpt)
 			return nil
-		}); err != nil {
-		return nil, err
-	}
-	if len(reports) > 1 && allTimestamped {
-		collector := NewCollector(window)
-		go replay(collector, timestamps, reports)
-		return collector, nil
-	}
-	return StaticCollector(NewFastMerger().Merge(reports).Upgrade()), nil
+		})
+	return timestamps, reports, err
 }
 
 func timestampFromFilepath(path string) (time.Time, error) {
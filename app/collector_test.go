@@ -71,6 +71,40 @@ func TestCollector(t *testing.T) {
 	}
 }
 
+func TestCollectorLateReports(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	ctx := context.Background()
+	c := app.NewCollector(10 * time.Second)
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	c.Add(ctx, r1, nil)
+
+	// Too late to be rendered
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("bar"))
+	c.Add(app.WithReportTimestamp(ctx, now.Add(-20*time.Second)), r2, nil)
+
+	// Late, but still in the window, and before r1
+	r3 := report.MakeReport()
+	r3.Endpoint.AddNode(report.MakeNode("baz"))
+	c.Add(app.WithReportTimestamp(ctx, now.Add(-5*time.Second)), r3, nil)
+
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := report.MakeReport().Merge(r1).Merge(r3); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if ok, err := c.HasReports(ctx, now.Add(-5*time.Second)); !ok || err != nil {
+		t.Errorf("Expected reports as of r3, got %v: %v", ok, err)
+	}
+}
+
 func TestCollectorExpire(t *testing.T) {
 	now := time.Now()
 	mtime.NowForce(now)
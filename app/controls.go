@@ -25,6 +25,22 @@ func RegisterControlRoutes(router *mux.Router, cr ControlRouter) {
 		HandlerFunc(requestContextDecorator(handleControl(cr)))
 }
 
+// RegisterReadOnlyControlRoutes registers the control routes of a read-only
+// app: probes still connect, but control requests are refused.
+func RegisterReadOnlyControlRoutes(router *mux.Router, cr ControlRouter) {
+	router.
+		Methods("GET").
+		Path("/api/control/ws").
+		HandlerFunc(requestContextDecorator(handleProbeWS(cr)))
+	router.
+		Methods("POST").
+		Name("api_control_probeid_nodeid_control").
+		MatcherFunc(URLMatcher("/api/control/{probeID}/{nodeID}/{control}")).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+		})
+}
+
 // handleControl routes control requests from the client to the appropriate
 // probe.  Its is blocking.
 func handleControl(cr ControlRouter) CtxHandlerFunc {
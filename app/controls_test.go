@@ -77,3 +77,19 @@ func TestControl(t *testing.T) {
 		t.Fatalf("'%s' != 'foo'", response.Value)
 	}
 }
+
+func TestReadOnlyControl(t *testing.T) {
+	router := mux.NewRouter()
+	app.RegisterReadOnlyControlRoutes(router, app.NewLocalControlRouter())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/api/control/foo/nodeid/control", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
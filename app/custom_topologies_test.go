@@ -0,0 +1,57 @@
+package app_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestCustomTopology(t *testing.T) {
+	registry := app.MakeRegistry()
+	err := registry.AddCustomTopology(app.CustomTopology{
+		ID:     "containers-by-role",
+		Parent: "containers",
+		Key:    docker.LabelPrefix + fixture.TestLabelKey1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderer, filter, err := registry.RendererForTopology("containers-by-role", url.Values{}, fixture.Report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := render.Render(context.Background(), fixture.Report, renderer, filter).Nodes
+	node, ok := have[fixture.ApplicationLabelValue1]
+	if !ok {
+		t.Fatalf("expected a node for %s, got %v", fixture.ApplicationLabelValue1, have)
+	}
+	if want := render.MakeGroupNodeTopology(report.Container, docker.LabelPrefix+fixture.TestLabelKey1); node.Topology != want {
+		t.Errorf("expected topology %s, got %s", want, node.Topology)
+	}
+	if count, _ := node.Counters.Lookup(report.Container); count != 1 {
+		t.Errorf("expected 1 container, got %d", count)
+	}
+
+	if have := registry.CustomTopologies(); len(have) != 1 || have[0].Name != "by "+docker.LabelPrefix+fixture.TestLabelKey1 {
+		t.Errorf("unexpected custom topologies %v", have)
+	}
+	if err := registry.AddCustomTopology(app.CustomTopology{ID: "containers", Parent: "hosts", Key: "az"}); err == nil {
+		t.Error("expected an error replacing a built-in topology")
+	}
+	if err := registry.AddCustomTopology(app.CustomTopology{ID: "x", Parent: "containers-by-role", Key: "az"}); err == nil {
+		t.Error("expected an error grouping a sub-topology")
+	}
+	if !registry.RemoveCustomTopology("containers-by-role") {
+		t.Error("expected the custom topology to be removed")
+	}
+	if _, _, err := registry.RendererForTopology("containers-by-role", url.Values{}, fixture.Report); err == nil {
+		t.Error("expected the custom topology to be gone")
+	}
+}
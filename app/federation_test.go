@@ -0,0 +1,120 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"context"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+func TestParseDownstreams(t *testing.T) {
+	have, err := app.ParseDownstreams("eu=http://scope-eu:4040/, us=https://scope-us")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 2 || have[0] != (app.Downstream{Name: "eu", URL: "http://scope-eu:4040"}) || have[1].Name != "us" {
+		t.Errorf("unexpected downstreams %v", have)
+	}
+	if _, err := app.ParseDownstreams("http://scope-eu:4040"); err == nil {
+		t.Error("expected an error for a downstream without a name")
+	}
+}
+
+func TestLoadDownstreamTokens(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-federation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# tokens\neu eutoken\n")
+	f.Close()
+
+	downstreams := []app.Downstream{{Name: "eu"}, {Name: "us"}}
+	if err := app.LoadDownstreamTokens(f.Name(), downstreams); err != nil {
+		t.Fatal(err)
+	}
+	if downstreams[0].Token != "eutoken" || downstreams[1].Token != "" {
+		t.Errorf("unexpected downstreams %v", downstreams)
+	}
+	if err := app.LoadDownstreamTokens(f.Name(), downstreams[1:]); err == nil {
+		t.Error("expected an error for the token of an unknown downstream")
+	}
+}
+
+func TestFederation(t *testing.T) {
+	downstream := report.MakeReport()
+	downstream.Host.AddNode(report.MakeNode(report.MakeHostNodeID("eu-host")))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/report" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer eutoken" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		codec.NewEncoder(w, &codec.JsonHandle{}).Encode(downstream)
+	}))
+	defer server.Close()
+
+	collector := app.NewCollector(time.Minute)
+	federation := app.NewFederation(collector, []app.Downstream{{Name: "eu", URL: server.URL, Token: "eutoken"}}, 10*time.Millisecond)
+	defer federation.Stop()
+
+	test.Poll(t, time.Second, "eu", func() interface{} {
+		rpt, _ := collector.Report(context.Background(), time.Now())
+		cluster, _ := rpt.Host.Nodes["eu/"+report.MakeHostNodeID("eu-host")].Latest.Lookup(app.FederationCluster)
+		return cluster
+	})
+}
+
+func TestFederateReport(t *testing.T) {
+	var (
+		now        = time.Now()
+		hostNodeID = report.MakeHostNodeID("host")
+		client     = report.MakeEndpointNodeID("host", "", "10.0.0.1", "1234")
+		server     = report.MakeEndpointNodeID("host", "", "10.0.0.2", "80")
+	)
+	rpt := report.MakeReport()
+	rpt.Endpoint.AddNode(report.MakeNode(client).WithAdjacent(server))
+	rpt.Endpoint.AddNode(report.MakeNode(server))
+	rpt.Container.Controls.AddControl(report.Control{ID: "docker_stop_container"})
+	rpt.Container.AddNode(report.MakeNodeWith("container", map[string]string{report.HostNodeID: hostNodeID}).
+		WithParent(report.Host, hostNodeID).
+		WithLatestActiveControls("docker_stop_container"))
+
+	rpt = app.FederateReport(rpt, "eu", now)
+
+	if _, ok := rpt.Endpoint.Nodes["eu/"+client]; !ok {
+		t.Fatalf("expected the endpoints prefixed with the cluster, got %v", rpt.Endpoint.Nodes)
+	}
+	if have := rpt.Endpoint.Nodes["eu/"+client].Adjacency; !reflect.DeepEqual(have, report.MakeIDList("eu/"+server)) {
+		t.Errorf("expected the adjacency prefixed with the cluster, got %v", have)
+	}
+	c, ok := rpt.Container.Nodes["eu/container"]
+	if !ok || c.ID != "eu/container" {
+		t.Fatalf("expected the container prefixed with the cluster, got %v", rpt.Container.Nodes)
+	}
+	if hosts, _ := c.Parents.Lookup(report.Host); !reflect.DeepEqual(hosts, report.MakeStringSet("eu/"+hostNodeID)) {
+		t.Errorf("expected the parents prefixed with the cluster, got %v", hosts)
+	}
+	if have := report.ExtractHostID(c); have != "eu/host" {
+		t.Errorf("expected the host prefixed with the cluster, got %q", have)
+	}
+	if have := report.ClusterScope(c); have != "eu/" {
+		t.Errorf("expected the scope of the cluster, got %q", have)
+	}
+	if c.LatestControls.Size() != 0 || len(rpt.Container.Controls) != 0 {
+		t.Errorf("expected no controls, got %v and %v", c.LatestControls, rpt.Container.Controls)
+	}
+}
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// NewGRPCServer makes a gRPC server accepting streams from probes: reports
+// are added to a, and control requests for the probe are routed down the
+// stream through cr. If authorize is not nil, it must accept the
+// Authorization header of probes, and tells the tenant they belong to.
+func NewGRPCServer(a Adder, cr ControlRouter, authorize func(authorization string) (tenant string, ok bool), opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(opts, grpc.CustomCodec(xfer.FrameCodec{}))...)
+	xfer.RegisterProbeServer(server, &grpcProbeServer{
+		adder:         a,
+		controlRouter: cr,
+		authorize:     authorize,
+	})
+	return server
+}
+
+type grpcProbeServer struct {
+	adder         Adder
+	controlRouter ControlRouter
+	authorize     func(string) (string, bool)
+}
+
+func header(md metadata.MD, name string) string {
+	if values := md[strings.ToLower(name)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Connect implements xfer.ProbeServer
+func (s *grpcProbeServer) Connect(stream grpc.ServerStream) error {
+	ctx := stream.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+	if s.authorize != nil {
+		tenant, ok := s.authorize(header(md, "Authorization"))
+		if !ok {
+			return fmt.Errorf("unauthorized")
+		}
+		ctx = withTenant(ctx, tenant)
+	}
+	probeID := header(md, xfer.ScopeProbeIDHeader)
+	if probeID == "" {
+		return fmt.Errorf("missing %s header", xfer.ScopeProbeIDHeader)
+	}
+	if contentType := header(md, xfer.ScopeReportContentTypeHeader); contentType != "" {
+		schema, err := report.ParseSchemaVersion(contentType)
+		if err != nil {
+			return err
+		}
+		if schema > report.SchemaVersion {
+			return fmt.Errorf("report schema %d is newer than this app supports (%d)", schema, report.SchemaVersion)
+		}
+	}
+
+	conn := &grpcProbeConn{
+		stream:  stream,
+		pending: map[uint64]chan xfer.Response{},
+	}
+	if header(md, xfer.ScopeProbeControlsHeader) == "true" {
+		id, err := s.controlRouter.Register(ctx, probeID, conn.control)
+		if err != nil {
+			return err
+		}
+		defer s.controlRouter.Deregister(ctx, probeID, id)
+	}
+	defer conn.close()
+
+	for {
+		var frame xfer.Frame
+		if err := stream.RecvMsg(&frame); err != nil {
+			return err
+		}
+		switch frame.Type {
+		case xfer.ReportFrame:
+			var rpt report.Report
+			if err := rpt.ReadBinary(bytes.NewReader(frame.Payload), true, &codec.MsgpackHandle{}); err != nil {
+				return err
+			}
+			if rpt.IsDelta() {
+				log.Errorf("Dropping delta report from probe %s: deltas can't be published over gRPC", probeID)
+				continue
+			}
+			reportSize.Observe(float64(len(frame.Payload)))
+			if err := s.adder.Add(ctx, rpt, frame.Payload); err != nil {
+				// Keep the stream up, as for failed POSTs
+				log.Errorf("Error Adding report: %v", err)
+			}
+		case xfer.ControlResponseFrame:
+			var msg xfer.ControlMessage
+			if err := json.Unmarshal(frame.Payload, &msg); err != nil {
+				return err
+			}
+			conn.respond(msg)
+		default:
+			return fmt.Errorf("unexpected frame type %d", frame.Type)
+		}
+	}
+}
+
+// grpcProbeConn sends control requests to a probe over its stream, and
+// hands back the responses.
+type grpcProbeConn struct {
+	stream  grpc.ServerStream
+	sendMtx sync.Mutex // sends on a stream must not be concurrent
+
+	mtx     sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan xfer.Response
+	closed  bool
+}
+
+func (c *grpcProbeConn) control(req xfer.Request) xfer.Response {
+	c.mtx.Lock()
+	if c.closed {
+		c.mtx.Unlock()
+		return xfer.ResponseErrorf("probe disconnected")
+	}
+	c.nextID++
+	id := c.nextID
+	result := make(chan xfer.Response, 1)
+	c.pending[id] = result
+	c.mtx.Unlock()
+
+	payload, err := json.Marshal(xfer.ControlMessage{ID: id, Request: &req})
+	if err == nil {
+		c.sendMtx.Lock()
+		err = c.stream.SendMsg(&xfer.Frame{Type: xfer.ControlRequestFrame, Payload: payload})
+		c.sendMtx.Unlock()
+	}
+	if err != nil {
+		c.mtx.Lock()
+		delete(c.pending, id)
+		c.mtx.Unlock()
+		return xfer.ResponseError(err)
+	}
+
+	select {
+	case res := <-result:
+		return res
+	case <-c.stream.Context().Done():
+		return xfer.ResponseErrorf("probe disconnected")
+	}
+}
+
+func (c *grpcProbeConn) respond(msg xfer.ControlMessage) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	result, ok := c.pending[msg.ID]
+	if !ok || msg.Response == nil {
+		return
+	}
+	delete(c.pending, msg.ID)
+	result <- *msg.Response
+}
+
+func (c *grpcProbeConn) close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.closed = true
+}
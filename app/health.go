@@ -0,0 +1,88 @@
+package app
+
+import (
+	"net/http"
+	"os"
+	"sync"
+
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/render/detailed"
+)
+
+// HealthConfig holds the rules deciding the health of rendered nodes,
+// which each tenant can replace at runtime through the API.
+type HealthConfig struct {
+	mtx     sync.RWMutex
+	rules   detailed.HealthRules            // of the tenants which didn't replace them
+	tenants map[string]detailed.HealthRules // by tenant
+}
+
+// NewHealthConfig makes a new HealthConfig, with no rules if path is
+// empty, or else with those in the JSON file at path.
+func NewHealthConfig(path string) (*HealthConfig, error) {
+	h := &HealthConfig{tenants: map[string]detailed.HealthRules{}}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var rules detailed.HealthRules
+	if err := codec.NewDecoder(f, &codec.JsonHandle{}).Decode(&rules); err != nil {
+		return nil, err
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	h.rules = rules
+	return h, nil
+}
+
+// Rules returns the current rules of the tenant of the request in ctx.
+func (h *HealthConfig) Rules(ctx context.Context) detailed.HealthRules {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	if rules, ok := h.tenants[AuthTenant(ctx)]; ok {
+		return rules
+	}
+	return h.rules
+}
+
+// RegisterHealthRoutes registers the routes to get and replace the rules of
+// the tenant of the request. The rules can't be replaced in a read-only
+// app.
+func RegisterHealthRoutes(router *mux.Router, h *HealthConfig, readOnly bool) {
+	router.Methods("GET").Path("/api/health/rules").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			rules := h.Rules(ctx)
+			if rules == nil {
+				rules = detailed.HealthRules{}
+			}
+			respondWith(w, http.StatusOK, rules)
+		}))
+	router.Methods("PUT").Path("/api/health/rules").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			if readOnly {
+				respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+				return
+			}
+			var rules detailed.HealthRules
+			if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&rules); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+			if err := rules.Validate(); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+			h.mtx.Lock()
+			h.tenants[AuthTenant(ctx)] = rules
+			h.mtx.Unlock()
+			respondWith(w, http.StatusOK, rules)
+		}))
+}
@@ -0,0 +1,383 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys of the metadata of container images, from their registry and
+// vulnerability scanner.
+const (
+	ImageDigest                = "docker_image_digest"
+	ImagePushed                = "docker_image_pushed"
+	ImageVulnerabilities       = "docker_image_vulnerabilities"
+	ImageVulnerabilitiesPrefix = "docker_image_vulnerabilities_"
+)
+
+const (
+	imageLookupQueueSize = 1024
+	// The info of images not seen for this many refreshes is forgotten
+	imageForgetRefreshes = 3
+)
+
+// Severities of vulnerabilities, as normalized by Clair, most severe first.
+var imageSeverities = []string{"Critical", "High", "Medium", "Low", "Negligible", "Unknown"}
+
+var (
+	imageMetadataTemplates = report.MetadataTemplates{
+		ImageVulnerabilities: {ID: ImageVulnerabilities, Label: "Vulnerabilities", From: report.FromLatest, Datatype: report.Number, Priority: 3},
+		ImagePushed:          {ID: ImagePushed, Label: "Pushed", From: report.FromLatest, Datatype: report.DateTime, Priority: 4},
+		ImageDigest:          {ID: ImageDigest, Label: "Digest", From: report.FromLatest, Truncate: 19, Priority: 5},
+	}
+	imageTableTemplates = report.TableTemplates{
+		ImageVulnerabilitiesPrefix: {
+			ID:        ImageVulnerabilitiesPrefix,
+			Label:     "Vulnerabilities",
+			Type:      report.PropertyListType,
+			FixedRows: imageSeverityRows(),
+		},
+	}
+)
+
+func imageSeverityRows() map[string]string {
+	rows := map[string]string{}
+	for i, severity := range imageSeverities {
+		// Prepend spaces to keep the most severe at the top when sorted.
+		rows[ImageVulnerabilitiesPrefix+strings.ToLower(severity)] = strings.Repeat(" ", len(imageSeverities)-i) + severity
+	}
+	return rows
+}
+
+// ImageInfo is what the registry and the scanner tell of an image.
+type ImageInfo struct {
+	Digest          string
+	Pushed          time.Time      // zero if the registry doesn't tell
+	Vulnerabilities map[string]int // by severity, nil if not scanned yet
+}
+
+// ImageInfoSource looks up the info of an image, by name and tag.
+type ImageInfoSource interface {
+	ImageInfo(ctx context.Context, name, tag string) (ImageInfo, error)
+}
+
+// NewRegistryImageInfoSource makes an ImageInfoSource asking the Docker
+// registry (HTTP API v2) at registryURL for the digests of images, and the
+// Clair (v4) scanner at scannerURL, if any, for their vulnerabilities.
+// Basic auth credentials can be given in the URLs.
+func NewRegistryImageInfoSource(registryURL, scannerURL string, timeout time.Duration) (ImageInfoSource, error) {
+	registry, err := url.Parse(strings.TrimSuffix(registryURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if registry.Host == "" {
+		return nil, fmt.Errorf("invalid registry URL %q", registryURL)
+	}
+	s := &registryImageInfoSource{
+		registry: registry,
+		client:   &http.Client{Timeout: timeout},
+	}
+	if scannerURL != "" {
+		if s.scanner, err = url.Parse(strings.TrimSuffix(scannerURL, "/")); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+type registryImageInfoSource struct {
+	registry, scanner *url.URL
+	client            *http.Client
+}
+
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// clairVulnerabilityReport is the part of a Clair vulnerability report
+// the counts of vulnerabilities come from.
+type clairVulnerabilityReport struct {
+	Vulnerabilities map[string]struct {
+		NormalizedSeverity string `json:"normalized_severity"`
+	} `json:"vulnerabilities"`
+}
+
+func (s *registryImageInfoSource) ImageInfo(ctx context.Context, name, tag string) (ImageInfo, error) {
+	repository, ok := s.repository(name)
+	if !ok {
+		return ImageInfo{}, errOtherRegistry
+	}
+	req, err := http.NewRequest("HEAD", s.registry.String()+"/v2/"+repository+"/manifests/"+tag, nil)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := s.do(ctx, req, s.registry)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	resp.Body.Close()
+	info := ImageInfo{Digest: resp.Header.Get("Docker-Content-Digest")}
+	if info.Digest == "" {
+		return ImageInfo{}, fmt.Errorf("no digest for %s:%s", name, tag)
+	}
+	if pushed, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.Pushed = pushed
+	}
+	if s.scanner == nil {
+		return info, nil
+	}
+
+	req, err = http.NewRequest("GET", s.scanner.String()+"/matcher/api/v1/vulnerability_report/"+info.Digest, nil)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	resp, err = s.do(ctx, req, s.scanner)
+	if err == errImageNotFound {
+		// Not indexed by the scanner yet
+		return info, nil
+	} else if err != nil {
+		return ImageInfo{}, err
+	}
+	defer resp.Body.Close()
+	var vulnerabilities clairVulnerabilityReport
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&vulnerabilities); err != nil {
+		return ImageInfo{}, err
+	}
+	info.Vulnerabilities = map[string]int{}
+	for _, severity := range imageSeverities {
+		info.Vulnerabilities[severity] = 0
+	}
+	for _, v := range vulnerabilities.Vulnerabilities {
+		if _, ok := info.Vulnerabilities[v.NormalizedSeverity]; ok {
+			info.Vulnerabilities[v.NormalizedSeverity]++
+		} else {
+			info.Vulnerabilities["Unknown"]++
+		}
+	}
+	return info, nil
+}
+
+var (
+	errImageNotFound = fmt.Errorf("not found")
+	// Images of other registries are not looked up, nor warned about
+	errOtherRegistry = fmt.Errorf("in another registry")
+)
+
+func (s *registryImageInfoSource) do(ctx context.Context, req *http.Request, base *url.URL) (*http.Response, error) {
+	if base.User != nil {
+		password, _ := base.User.Password()
+		req.SetBasicAuth(base.User.Username(), password)
+		req.URL.User = nil
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, errImageNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+}
+
+// repository is the name of the image in the registry: without the host of
+// the registry, if the image names one. Images naming other registries
+// are not there.
+func (s *registryImageInfoSource) repository(name string) (string, bool) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[1], parts[0] == s.registry.Host
+	}
+	return name, true
+}
+
+// ImageMetadata enriches the container image nodes of reports with the info
+// of their images, looked up in the background, and kept for refresh.
+type ImageMetadata struct {
+	source  ImageInfoSource
+	refresh time.Duration
+
+	mtx     sync.Mutex
+	infos   map[string]imageInfoEntry // by image name and tag
+	pending map[string]struct{}
+	lookups chan imageRef
+	quit    chan struct{}
+}
+
+type imageRef struct {
+	name, tag string
+}
+
+func (i imageRef) String() string {
+	return i.name + ":" + i.tag
+}
+
+type imageInfoEntry struct {
+	info    ImageInfo
+	err     error
+	fetched time.Time
+	seen    time.Time // in a report, last
+}
+
+// NewImageMetadata makes an ImageMetadata looking up the info of images in
+// source, and again every refresh.
+func NewImageMetadata(source ImageInfoSource, refresh time.Duration) *ImageMetadata {
+	m := &ImageMetadata{
+		source:  source,
+		refresh: refresh,
+		infos:   map[string]imageInfoEntry{},
+		pending: map[string]struct{}{},
+		lookups: make(chan imageRef, imageLookupQueueSize),
+		quit:    make(chan struct{}),
+	}
+	go m.loop()
+	return m
+}
+
+// Stop stops looking up images.
+func (m *ImageMetadata) Stop() {
+	close(m.quit)
+}
+
+func (m *ImageMetadata) loop() {
+	ticker := time.NewTicker(m.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case ref := <-m.lookups:
+			info, err := m.source.ImageInfo(context.Background(), ref.name, ref.tag)
+			if err != nil && err != errOtherRegistry {
+				log.Warnf("Error looking up image %s: %v", ref, err)
+			}
+			now := mtime.Now()
+			m.mtx.Lock()
+			m.infos[ref.String()] = imageInfoEntry{info: info, err: err, fetched: now, seen: now}
+			delete(m.pending, ref.String())
+			m.mtx.Unlock()
+		case <-ticker.C:
+			m.forget()
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// forget forgets the info of the images which haven't been in reports for
+// imageForgetRefreshes refreshes.
+func (m *ImageMetadata) forget() {
+	oldest := mtime.Now().Add(-imageForgetRefreshes * m.refresh)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for ref, entry := range m.infos {
+		if entry.seen.Before(oldest) {
+			delete(m.infos, ref)
+		}
+	}
+}
+
+// Enrich returns rpt with the info known of its container images, after
+// queueing the lookups of those unknown, or not refreshed for too long.
+// rpt itself is not modified.
+func (m *ImageMetadata) Enrich(rpt report.Report) report.Report {
+	now := mtime.Now()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	nodes := make(report.Nodes, len(rpt.ContainerImage.Nodes))
+	for id, n := range rpt.ContainerImage.Nodes {
+		nodes[id] = n
+		name, _ := n.Latest.Lookup(docker.ImageName)
+		tag, _ := n.Latest.Lookup(docker.ImageTag)
+		if name == "" || tag == "" || tag == "<none>" {
+			continue
+		}
+		ref := imageRef{name, tag}
+		entry, ok := m.infos[ref.String()]
+		if ok {
+			entry.seen = now
+			m.infos[ref.String()] = entry
+		}
+		if !ok || now.Sub(entry.fetched) >= m.refresh {
+			m.lookup(ref)
+		}
+		if ok && entry.err == nil {
+			nodes[id] = withImageInfo(n, entry.info, entry.fetched)
+		}
+	}
+	rpt.ContainerImage.Nodes = nodes
+	rpt.ContainerImage.MetadataTemplates = rpt.ContainerImage.MetadataTemplates.Merge(imageMetadataTemplates)
+	rpt.ContainerImage.TableTemplates = rpt.ContainerImage.TableTemplates.Merge(imageTableTemplates)
+	return rpt
+}
+
+// lookup queues the lookup of ref, unless it is already queued, or the
+// queue is full, in which case the next report will. Must be called with
+// the lock held.
+func (m *ImageMetadata) lookup(ref imageRef) {
+	if _, ok := m.pending[ref.String()]; ok {
+		return
+	}
+	select {
+	case m.lookups <- ref:
+		m.pending[ref.String()] = struct{}{}
+	default:
+	}
+}
+
+func withImageInfo(n report.Node, info ImageInfo, ts time.Time) report.Node {
+	latests := map[string]string{ImageDigest: info.Digest}
+	if !info.Pushed.IsZero() {
+		latests[ImagePushed] = info.Pushed.UTC().Format(time.RFC3339Nano)
+	}
+	if info.Vulnerabilities != nil {
+		total := 0
+		for severity, count := range info.Vulnerabilities {
+			latests[ImageVulnerabilitiesPrefix+strings.ToLower(severity)] = strconv.Itoa(count)
+			total += count
+		}
+		latests[ImageVulnerabilities] = strconv.Itoa(total)
+	}
+	for k, v := range latests {
+		n = n.WithLatest(k, ts, v)
+	}
+	return n
+}
+
+// ImageMetadataCollector makes a Collector enriching the reports of c with
+// the info of their container images, from m.
+func ImageMetadataCollector(c Collector, m *ImageMetadata) Collector {
+	return imageMetadataCollector{c, m}
+}
+
+type imageMetadataCollector struct {
+	Collector
+	images *ImageMetadata
+}
+
+func (c imageMetadataCollector) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	rpt, err := c.Collector.Report(ctx, timestamp)
+	if err != nil {
+		return rpt, err
+	}
+	return c.images.Enrich(rpt), nil
+}
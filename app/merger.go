@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"hash"
 
 	"github.com/spaolacci/murmur3"
 
@@ -15,18 +16,39 @@ type Merger interface {
 
 type fastMerger struct{}
 
-// NewFastMerger makes a Merger which merges together reports, mutating the one we are building up
+// NewFastMerger makes a Merger which merges together reports, mutating the one we are building up,
+// a topology per goroutine
 func NewFastMerger() Merger {
 	return fastMerger{}
 }
 
 func (fastMerger) Merge(reports []report.Report) report.Report {
 	rpt := report.MakeReport()
+	rpt.UnsafeMergeAll(reports)
 	id := murmur3.New64()
+	topologyIDs := map[string]hash.Hash64{}
 	for _, r := range reports {
-		rpt.UnsafeMerge(r)
 		id.Write([]byte(r.ID))
+		// Only reports with nodes in a topology change it. Reports which
+		// were merged themselves say which of their reports did.
+		r.WalkNamedTopologies(func(name string, t *report.Topology) {
+			topologyID := r.ID
+			if r.TopologyIDs != nil {
+				topologyID = r.TopologyIDs[name]
+			}
+			if len(t.Nodes) == 0 || topologyID == "" {
+				return
+			}
+			if _, ok := topologyIDs[name]; !ok {
+				topologyIDs[name] = murmur3.New64()
+			}
+			topologyIDs[name].Write([]byte(topologyID))
+		})
 	}
 	rpt.ID = fmt.Sprintf("%x", id.Sum64())
+	rpt.TopologyIDs = make(map[string]string, len(topologyIDs))
+	for name, h := range topologyIDs {
+		rpt.TopologyIDs[name] = fmt.Sprintf("%x", h.Sum64())
+	}
 	return rpt
 }
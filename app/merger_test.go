@@ -44,6 +44,33 @@ func TestMerger(t *testing.T) {
 	}
 }
 
+func TestMergerTopologyIDs(t *testing.T) {
+	host := func() report.Report {
+		rpt := report.MakeReport()
+		rpt.Endpoint.AddNode(report.MakeNode(fmt.Sprintf("%x", rand.Int63())))
+		return rpt
+	}
+	cluster := report.MakeReport()
+	cluster.Pod.AddNode(report.MakeNode("pod"))
+
+	merger := app.NewFastMerger()
+	merged1 := merger.Merge([]report.Report{host(), cluster})
+	merged2 := merger.Merge([]report.Report{host(), cluster})
+	if merged1.TopologyIDs[report.Endpoint] == merged2.TopologyIDs[report.Endpoint] {
+		t.Errorf("Expected the endpoint topology to have changed")
+	}
+	if have := merged2.TopologyIDs[report.Pod]; have == "" || have != merged1.TopologyIDs[report.Pod] {
+		t.Errorf("Expected the pod topology to be the same, got %q and %q", merged1.TopologyIDs[report.Pod], have)
+	}
+
+	// Merging merged reports goes by the reports they were merged from
+	merged3 := merger.Merge([]report.Report{merged1, host()})
+	merged4 := merger.Merge([]report.Report{merged2, host()})
+	if have := merged4.TopologyIDs[report.Pod]; have != merged3.TopologyIDs[report.Pod] {
+		t.Errorf("Expected the pod topology to be the same, got %q and %q", merged3.TopologyIDs[report.Pod], have)
+	}
+}
+
 func BenchmarkFastMerger(b *testing.B) {
 	benchmarkMerger(b, app.NewFastMerger())
 }
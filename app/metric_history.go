@@ -0,0 +1,223 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"context"
+
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	// How often the history forgets the nodes, and the metrics of nodes,
+	// which are gone: those it hasn't seen for metricHistoryGoneAfter.
+	metricHistorySweepInterval = time.Minute
+	metricHistoryGoneAfter     = 5 * time.Minute
+)
+
+// MetricHistory keeps the metrics of nodes for longer than the reports
+// do, which only hold the last few seconds of them: a ring buffer of
+// samples per node and metric, at most one every resolution, going back
+// retention. Rings grow as samples come, up to their size, and are
+// forgotten once their nodes are gone.
+//
+// Endpoints and pseudo nodes have no history: they come and go too often
+// for one to be of any use.
+type MetricHistory struct {
+	retention, resolution time.Duration
+
+	mtx       sync.Mutex
+	nodes     map[string]*nodeHistory // by tenant and node ID
+	lastSweep time.Time
+}
+
+type nodeHistory struct {
+	lastSeen time.Time
+	metrics  map[string]*metricRing
+}
+
+// metricRing holds the last size samples of a metric, oldest first from
+// next once full.
+type metricRing struct {
+	samples  []report.Sample
+	size     int
+	next     int
+	max      float64
+	lastSeen time.Time
+}
+
+// NewMetricHistory makes a MetricHistory keeping a sample per resolution
+// of every metric, for retention.
+func NewMetricHistory(retention, resolution time.Duration) *MetricHistory {
+	return &MetricHistory{
+		retention:  retention,
+		resolution: resolution,
+		nodes:      map[string]*nodeHistory{},
+	}
+}
+
+// Record adds the samples of the metrics of every node of rpt which are
+// at least resolution apart to the history.
+func (h *MetricHistory) Record(ctx context.Context, rpt report.Report) {
+	now := mtime.Now()
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		if name == report.Endpoint {
+			return
+		}
+		for id, n := range t.Nodes {
+			if len(n.Metrics) == 0 || n.Topology == render.Pseudo {
+				continue
+			}
+			key := tenantID(ctx, id)
+			node, ok := h.nodes[key]
+			if !ok {
+				node = &nodeHistory{metrics: map[string]*metricRing{}}
+				h.nodes[key] = node
+			}
+			node.lastSeen = now
+			for metricID, metric := range n.Metrics {
+				ring, ok := node.metrics[metricID]
+				if !ok {
+					ring = &metricRing{size: h.size()}
+					node.metrics[metricID] = ring
+				}
+				ring.lastSeen = now
+				ring.add(metric, h.resolution)
+			}
+		}
+	})
+	if now.Sub(h.lastSweep) >= metricHistorySweepInterval {
+		for key, node := range h.nodes {
+			if now.Sub(node.lastSeen) > metricHistoryGoneAfter {
+				delete(h.nodes, key)
+				continue
+			}
+			for metricID, ring := range node.metrics {
+				if now.Sub(ring.lastSeen) > metricHistoryGoneAfter {
+					delete(node.metrics, metricID)
+				}
+			}
+		}
+		h.lastSweep = now
+	}
+}
+
+func (h *MetricHistory) size() int {
+	if h.resolution <= 0 {
+		return 1
+	}
+	if size := int(h.retention / h.resolution); size > 0 {
+		return size
+	}
+	return 1
+}
+
+func (r *metricRing) add(metric report.Metric, resolution time.Duration) {
+	if metric.Max > r.max {
+		r.max = metric.Max
+	}
+	for _, s := range metric.Samples {
+		if last, ok := r.last(); ok && s.Timestamp.Sub(last.Timestamp) < resolution {
+			continue
+		}
+		if len(r.samples) < r.size {
+			r.samples = append(r.samples, s)
+			continue
+		}
+		r.samples[r.next] = s
+		r.next = (r.next + 1) % len(r.samples)
+	}
+}
+
+func (r *metricRing) last() (report.Sample, bool) {
+	if len(r.samples) == 0 {
+		return report.Sample{}, false
+	}
+	return r.samples[(r.next+len(r.samples)-1)%len(r.samples)], true
+}
+
+func (r *metricRing) since(t time.Time) report.Metric {
+	samples := []report.Sample{}
+	for i := range r.samples {
+		if s := r.samples[(r.next+i)%len(r.samples)]; !s.Timestamp.Before(t) {
+			samples = append(samples, s)
+		}
+	}
+	metric := report.MakeMetric(samples)
+	if r.max > metric.Max {
+		metric.Max = r.max
+	}
+	return metric
+}
+
+// Metrics returns the history of the metrics of the node with the ID
+// given, since the time given, by metric ID.
+func (h *MetricHistory) Metrics(ctx context.Context, nodeID string, since time.Time) (map[string]report.Metric, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	node, ok := h.nodes[tenantID(ctx, nodeID)]
+	if !ok {
+		return nil, false
+	}
+	metrics := make(map[string]report.Metric, len(node.metrics))
+	for id, ring := range node.metrics {
+		metrics[id] = ring.since(since)
+	}
+	return metrics, true
+}
+
+// HistoryCollector makes a Collector recording the metrics of the reports
+// added to c in h.
+func HistoryCollector(c Collector, h *MetricHistory) Collector {
+	return historyCollector{c, h}
+}
+
+type historyCollector struct {
+	Collector
+	history *MetricHistory
+}
+
+func (c historyCollector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	c.history.Record(ctx, rpt)
+	return c.Collector.Add(ctx, rpt, buf)
+}
+
+// APINodeHistory is returned by the /api/history/{id} handler.
+type APINodeHistory struct {
+	ID      string                   `json:"id"`
+	Metrics map[string]report.Metric `json:"metrics"`
+}
+
+// RegisterHistoryRoutes registers the handler for the history of the
+// metrics of nodes, over the last duration (e.g. 6h; by default, all of
+// it).
+func RegisterHistoryRoutes(router *mux.Router, h *MetricHistory) {
+	router.Methods("GET").MatcherFunc(URLMatcher("/api/history/{id}")).HandlerFunc(
+		requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			since := time.Time{}
+			if d := r.URL.Query().Get("duration"); d != "" {
+				duration, err := time.ParseDuration(d)
+				if err != nil {
+					respondWith(w, http.StatusBadRequest, err)
+					return
+				}
+				since = mtime.Now().Add(-duration)
+			}
+			nodeID := mux.Vars(r)["id"]
+			metrics, ok := h.Metrics(ctx, nodeID, since)
+			if !ok {
+				respondWith(w, http.StatusNotFound, fmt.Errorf("no history of node %s", nodeID))
+				return
+			}
+			respondWith(w, http.StatusOK, APINodeHistory{ID: nodeID, Metrics: metrics})
+		}))
+}
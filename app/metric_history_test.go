@@ -0,0 +1,85 @@
+package app_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestMetricHistory(t *testing.T) {
+	start := time.Unix(1000, 0)
+	defer mtime.NowReset()
+
+	history := app.NewMetricHistory(time.Minute, 15*time.Second)
+	collector := app.HistoryCollector(app.NewCollector(time.Minute), history)
+	// A sample every 5s, for 2 minutes
+	for i := 0; i < 24; i++ {
+		now := start.Add(time.Duration(i) * 5 * time.Second)
+		mtime.NowForce(now)
+		rpt := report.MakeReport()
+		rpt.Host.AddNode(report.MakeNode("host1").WithMetric("cpu", report.MakeSingletonMetric(now, float64(i))))
+		rpt.Endpoint.AddNode(report.MakeNode("endpoint1").WithMetric("bytes", report.MakeSingletonMetric(now, float64(i))))
+		if err := collector.Add(context.Background(), rpt, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := mux.NewRouter().SkipClean(true)
+	app.RegisterHistoryRoutes(router, history)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	get := func(path string, status int) app.APINodeHistory {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("%s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+		var result app.APINodeHistory
+		if status == http.StatusOK {
+			if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return result
+	}
+
+	values := func(m report.Metric) []float64 {
+		result := []float64{}
+		for _, s := range m.Samples {
+			result = append(result, s.Value)
+		}
+		return result
+	}
+	// One sample every 15s, of the last minute
+	have := get("/api/history/host1", http.StatusOK)
+	if want, values := []float64{12, 15, 18, 21}, values(have.Metrics["cpu"]); !reflect.DeepEqual(want, values) {
+		t.Errorf("Expected samples %v, got %v", want, values)
+	}
+	have = get("/api/history/host1?duration=30s", http.StatusOK)
+	if want, values := []float64{18, 21}, values(have.Metrics["cpu"]); !reflect.DeepEqual(want, values) {
+		t.Errorf("Expected samples %v, got %v", want, values)
+	}
+	get("/api/history/host1?duration=lots", http.StatusBadRequest)
+	get("/api/history/host2", http.StatusNotFound)
+	get("/api/history/endpoint1", http.StatusNotFound)
+
+	// Nodes which are gone are forgotten
+	mtime.NowForce(start.Add(10 * time.Minute))
+	if err := collector.Add(context.Background(), report.MakeReport(), nil); err != nil {
+		t.Fatal(err)
+	}
+	get("/api/history/host1", http.StatusNotFound)
+}
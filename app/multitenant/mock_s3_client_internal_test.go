@@ -0,0 +1,79 @@
+package multitenant
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// mockS3 keeps objects of a single bucket in memory. It only implements
+// what the S3 stores use; anything else panics.
+type mockS3 struct {
+	s3iface.S3API
+	mtx     sync.Mutex
+	objects map[string][]byte
+}
+
+func newMockS3Client() *mockS3 {
+	return &mockS3{objects: map[string][]byte{}}
+}
+
+func (m *mockS3) keys() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	buf, ok := m.objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, awserr.New("NoSuchKey", "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(buf))}, nil
+}
+
+func (m *mockS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	buf, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.objects[aws.StringValue(input.Key)] = buf
+	return &s3.PutObjectOutput{}, nil
+}
+
+// ListObjectsPages lists everything in one page
+func (m *mockS3) ListObjectsPages(input *s3.ListObjectsInput, fn func(*s3.ListObjectsOutput, bool) bool) error {
+	page := &s3.ListObjectsOutput{}
+	for _, key := range m.keys() {
+		if strings.HasPrefix(key, aws.StringValue(input.Prefix)) && key > aws.StringValue(input.Marker) {
+			page.Contents = append(page.Contents, &s3.Object{Key: aws.String(key)})
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func (m *mockS3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, object := range input.Delete.Objects {
+		delete(m.objects, aws.StringValue(object.Key))
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
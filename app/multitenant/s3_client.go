@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/weaveworks/common/instrument"
@@ -24,7 +25,7 @@ var (
 
 // S3Store is an S3 client that stores and retrieves Reports.
 type S3Store struct {
-	s3         *s3.S3
+	s3         s3iface.S3API
 	bucketName string
 }
 
@@ -0,0 +1,389 @@
+package multitenant
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/instrument"
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	reportKeySuffix    = ".msgpack.gz"
+	recordingKeySuffix = ".cast"
+	recordingsPrefix   = "recordings"
+	compactedToKey     = "compacted-to"
+
+	defaultCompactAfter      = 1 * time.Hour
+	defaultCompactionPeriod  = 1 * time.Minute
+	compactionCheckInterval  = 5 * time.Minute
+	maxDeleteObjectsPerBatch = 1000
+)
+
+// S3ReportStore is an app.ReportStore keeping reports in an S3 bucket,
+// under keys made of a prefix and the nanoseconds since epoch at which
+// the report was received, e.g. "prefix/1488557088545489008.msgpack.gz".
+//
+// Reports older than CompactAfter are periodically merged together, so
+// that only one report is kept per CompactionPeriod. How far they were
+// compacted is kept under "prefix/compacted-to", for compaction to carry on
+// from there after a restart. Recordings of sessions are kept under
+// "prefix/recordings/".
+type S3ReportStore struct {
+	S3Store
+	prefix           string
+	compactAfter     time.Duration
+	compactionPeriod time.Duration
+	merger           app.Merger
+
+	mtx         sync.Mutex
+	compactedTo time.Time // all reports before this have been compacted
+	loaded      bool      // whether compactedTo was read from the bucket
+	quit        chan struct{}
+}
+
+// NewS3ReportStore makes a new S3ReportStore from a URL of the form
+// s3://[key:secret@]bucket/prefix[?region=...&endpoint=...&compact_after=1h&compaction_period=1m]
+// and starts its background compaction.
+func NewS3ReportStore(storeURL *url.URL) (*S3ReportStore, error) {
+	config := aws.NewConfig()
+	if storeURL.User != nil {
+		password, _ := storeURL.User.Password()
+		config = config.WithCredentials(credentials.NewStaticCredentials(storeURL.User.Username(), password, ""))
+	}
+	query := storeURL.Query()
+	if region := query.Get("region"); region != "" {
+		config = config.WithRegion(region)
+	}
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		// Emulated services (e.g. minio) generally need path-style addressing
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	compactAfter, compactionPeriod := defaultCompactAfter, defaultCompactionPeriod
+	for param, value := range map[string]*time.Duration{
+		"compact_after":     &compactAfter,
+		"compaction_period": &compactionPeriod,
+	} {
+		if s := query.Get(param); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s '%s': %v", param, s, err)
+			}
+			*value = d
+		}
+	}
+	if compactionPeriod <= 0 {
+		return nil, fmt.Errorf("compaction_period must be positive")
+	}
+
+	store := &S3ReportStore{
+		S3Store: S3Store{
+			s3:         s3.New(session.New(config)),
+			bucketName: storeURL.Host,
+		},
+		prefix:           strings.Trim(storeURL.Path, "/"),
+		compactAfter:     compactAfter,
+		compactionPeriod: compactionPeriod,
+		merger:           app.NewFastMerger(),
+		compactedTo:      time.Unix(0, 0),
+		quit:             make(chan struct{}),
+	}
+	go store.loop()
+	return store, nil
+}
+
+func (s *S3ReportStore) keyFor(timestamp time.Time) string {
+	return path.Join(s.prefix, fmt.Sprintf("%019d", timestamp.UnixNano())) + reportKeySuffix
+}
+
+func (s *S3ReportStore) timestampFrom(key string) (time.Time, error) {
+	name := strings.TrimSuffix(path.Base(key), reportKeySuffix)
+	nanosecondsSinceEpoch, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("key '%s' is not a report key: %v", key, err)
+	}
+	return time.Unix(0, nanosecondsSinceEpoch), nil
+}
+
+// Put implements app.ReportStore
+func (s *S3ReportStore) Put(ctx context.Context, timestamp time.Time, rpt report.Report, buf []byte) error {
+	if buf == nil {
+		w, err := rpt.WriteBinary()
+		if err != nil {
+			return err
+		}
+		buf = w.Bytes()
+	}
+	_, err := s.StoreReportBytes(ctx, s.keyFor(timestamp), buf)
+	return err
+}
+
+// Fetch implements app.ReportStore
+func (s *S3ReportStore) Fetch(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	rpt, err := s.fetchReport(ctx, s.keyFor(timestamp))
+	if err != nil {
+		return report.Report{}, err
+	}
+	return *rpt, nil
+}
+
+// Range implements app.ReportStore
+func (s *S3ReportStore) Range(ctx context.Context, start, end time.Time) ([]time.Time, error) {
+	keyPrefix := s.prefix + "/"
+	if s.prefix == "" {
+		keyPrefix = ""
+	}
+	result := []time.Time{}
+	err := instrument.TimeRequestHistogram(ctx, "S3.List", s3RequestDuration, func(_ context.Context) error {
+		var parseErr error
+		err := s.s3.ListObjectsPages(&s3.ListObjectsInput{
+			Bucket: aws.String(s.bucketName),
+			Prefix: aws.String(keyPrefix),
+			// Keys sort in time order, and the key for start sorts after this
+			Marker: aws.String(path.Join(s.prefix, fmt.Sprintf("%019d", start.UnixNano()))),
+		}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+			for _, object := range page.Contents {
+				if !strings.HasSuffix(aws.StringValue(object.Key), reportKeySuffix) {
+					continue // e.g. a recording
+				}
+				timestamp, err := s.timestampFrom(aws.StringValue(object.Key))
+				if err != nil {
+					parseErr = err
+					return false
+				}
+				if !timestamp.Before(end) {
+					return false
+				}
+				result = append(result, timestamp)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return parseErr
+	})
+	return result, err
+}
+
+func (s *S3ReportStore) recordingKeyFor(id string) string {
+	return path.Join(s.prefix, recordingsPrefix, id) + recordingKeySuffix
+}
+
+// PutRecording implements app.RecordingStore
+func (s *S3ReportStore) PutRecording(ctx context.Context, id string, buf []byte) error {
+	_, err := s.StoreReportBytes(ctx, s.recordingKeyFor(id), buf)
+	return err
+}
+
+// FetchRecording implements app.RecordingStore
+func (s *S3ReportStore) FetchRecording(ctx context.Context, id string) ([]byte, error) {
+	var buf []byte
+	err := instrument.TimeRequestHistogram(ctx, "S3.Get", s3RequestDuration, func(_ context.Context) error {
+		resp, err := s.s3.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(s.recordingKeyFor(id)),
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		buf, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	return buf, err
+}
+
+// ListRecordings implements app.RecordingStore
+func (s *S3ReportStore) ListRecordings(ctx context.Context) ([]string, error) {
+	keyPrefix := path.Join(s.prefix, recordingsPrefix) + "/"
+	result := []string{}
+	err := instrument.TimeRequestHistogram(ctx, "S3.List", s3RequestDuration, func(_ context.Context) error {
+		return s.s3.ListObjectsPages(&s3.ListObjectsInput{
+			Bucket: aws.String(s.bucketName),
+			Prefix: aws.String(keyPrefix),
+		}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+			for _, object := range page.Contents {
+				id := strings.TrimPrefix(aws.StringValue(object.Key), keyPrefix)
+				result = append(result, strings.TrimSuffix(id, recordingKeySuffix))
+			}
+			return true
+		})
+	})
+	return result, err
+}
+
+// Stop stops the background compaction.
+func (s *S3ReportStore) Stop() {
+	close(s.quit)
+}
+
+func (s *S3ReportStore) loop() {
+	ticker := time.NewTicker(compactionCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.compact(context.Background()); err != nil {
+			log.Errorf("Error compacting reports in S3: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// compact merges together the reports older than compactAfter which were
+// received within the same compactionPeriod, storing the result under the
+// time of the start of that period.
+func (s *S3ReportStore) compact(ctx context.Context) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.loaded {
+		compactedTo, err := s.loadCompactedTo(ctx)
+		if err != nil {
+			return err
+		}
+		if compactedTo.After(s.compactedTo) {
+			s.compactedTo = compactedTo
+		}
+		s.loaded = true
+	}
+	end := mtime.Now().Add(-s.compactAfter).Truncate(s.compactionPeriod)
+	if !end.After(s.compactedTo) {
+		return nil
+	}
+	// Whatever happens, save how far we got, for the next compaction
+	defer func() {
+		if err := s.saveCompactedTo(ctx, s.compactedTo); err != nil {
+			log.Errorf("Error saving how far reports were compacted: %v", err)
+		}
+	}()
+	timestamps, err := s.Range(ctx, s.compactedTo, end)
+	if err != nil {
+		return err
+	}
+
+	for len(timestamps) > 0 {
+		periodStart := timestamps[0].Truncate(s.compactionPeriod)
+		periodEnd := periodStart.Add(s.compactionPeriod)
+		i := 1
+		for i < len(timestamps) && timestamps[i].Before(periodEnd) {
+			i++
+		}
+		if err := s.compactPeriod(ctx, periodStart, timestamps[:i]); err != nil {
+			return err
+		}
+		s.compactedTo = periodEnd
+		timestamps = timestamps[i:]
+	}
+	s.compactedTo = end
+	return nil
+}
+
+// loadCompactedTo reads how far the reports were compacted, as saved by the
+// last compaction, or the zero time if none saved it.
+func (s *S3ReportStore) loadCompactedTo(ctx context.Context) (time.Time, error) {
+	var result time.Time
+	err := instrument.TimeRequestHistogram(ctx, "S3.Get", s3RequestDuration, func(_ context.Context) error {
+		resp, err := s.s3.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(s.bucketName),
+			Key:    aws.String(path.Join(s.prefix, compactedToKey)),
+		})
+		if isNoSuchKey(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		buf, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		nanosecondsSinceEpoch, err := strconv.ParseInt(strings.TrimSpace(string(buf)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", compactedToKey, err)
+		}
+		result = time.Unix(0, nanosecondsSinceEpoch)
+		return nil
+	})
+	return result, err
+}
+
+func (s *S3ReportStore) saveCompactedTo(ctx context.Context, compactedTo time.Time) error {
+	_, err := s.StoreReportBytes(ctx, path.Join(s.prefix, compactedToKey), []byte(strconv.FormatInt(compactedTo.UnixNano(), 10)))
+	return err
+}
+
+// compactPeriod merges the reports received at timestamps, within the
+// period starting at periodStart, under periodStart. Other replicas sharing
+// the bucket compact the same periods: as reports of a period were all
+// there before any of them compacted it, a report under periodStart already
+// covers the rest, and those left are only to delete.
+func (s *S3ReportStore) compactPeriod(ctx context.Context, periodStart time.Time, timestamps []time.Time) error {
+	if timestamps[0].Equal(periodStart) {
+		return s.deleteReports(ctx, timestamps[1:])
+	}
+	reports := make([]report.Report, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		rpt, err := s.Fetch(ctx, timestamp)
+		if isNoSuchKey(err) {
+			return nil // another replica compacted the period since we listed it
+		} else if err != nil {
+			return err
+		}
+		reports = append(reports, rpt)
+	}
+	if err := s.Put(ctx, periodStart, s.merger.Merge(reports), nil); err != nil {
+		return err
+	}
+	return s.deleteReports(ctx, timestamps)
+}
+
+func (s *S3ReportStore) deleteReports(ctx context.Context, timestamps []time.Time) error {
+	var objects []*s3.ObjectIdentifier
+	for _, timestamp := range timestamps {
+		objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(s.keyFor(timestamp))})
+	}
+	for len(objects) > 0 {
+		batch := objects
+		if len(batch) > maxDeleteObjectsPerBatch {
+			batch = batch[:maxDeleteObjectsPerBatch]
+		}
+		objects = objects[len(batch):]
+		err := instrument.TimeRequestHistogram(ctx, "S3.Delete", s3RequestDuration, func(_ context.Context) error {
+			_, err := s.s3.DeleteObjects(&s3.DeleteObjectsInput{
+				Bucket: aws.String(s.bucketName),
+				Delete: &s3.Delete{Objects: batch, Quiet: aws.Bool(true)},
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isNoSuchKey(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == "NoSuchKey"
+}
@@ -0,0 +1,150 @@
+package multitenant
+
+import (
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+func newTestS3ReportStore(client *mockS3) *S3ReportStore {
+	return &S3ReportStore{
+		S3Store:          S3Store{s3: client, bucketName: "bucket"},
+		prefix:           "prefix",
+		compactAfter:     time.Hour,
+		compactionPeriod: time.Minute,
+		merger:           app.NewFastMerger(),
+		compactedTo:      time.Unix(0, 0),
+		quit:             make(chan struct{}),
+	}
+}
+
+func testS3Report(hostID string) report.Report {
+	rpt := report.MakeReport()
+	rpt.Window = 15 * time.Second
+	rpt.Host.AddNode(report.MakeNode(hostID))
+	return rpt
+}
+
+var (
+	s3PeriodStart = time.Unix(1500000000, 0) // at the start of a minute
+	s3Received    = map[time.Time]string{
+		s3PeriodStart.Add(10 * time.Second): "host1",
+		s3PeriodStart.Add(20 * time.Second): "host2",
+		s3PeriodStart.Add(30 * time.Second): "host3",
+		s3PeriodStart.Add(65 * time.Second): "host4",
+		s3PeriodStart.Add(75 * time.Second): "host5",
+	}
+)
+
+func putS3Reports(t *testing.T, store *S3ReportStore) {
+	for timestamp, hostID := range s3Received {
+		if err := store.Put(context.Background(), timestamp, testS3Report(hostID), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkCompactedS3Reports(t *testing.T, store *S3ReportStore) {
+	ctx := context.Background()
+	have, err := store.Range(ctx, time.Unix(0, 0), s3PeriodStart.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{s3PeriodStart, s3PeriodStart.Add(time.Minute)}
+	if len(have) != len(want) || !have[0].Equal(want[0]) || !have[1].Equal(want[1]) {
+		t.Fatalf("Expected one report per period %v, got %v", want, have)
+	}
+	for i, hostIDs := range [][]string{{"host1", "host2", "host3"}, {"host4", "host5"}} {
+		rpt, err := store.Fetch(ctx, want[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantWindow := time.Duration(len(hostIDs)) * 15 * time.Second; rpt.Window != wantWindow {
+			t.Errorf("Expected the report of %v to be over %v, got %v", want[i], wantWindow, rpt.Window)
+		}
+		if len(rpt.Host.Nodes) != len(hostIDs) {
+			t.Errorf("Expected the report of %v to have %v, got %v", want[i], hostIDs, rpt.Host.Nodes)
+		}
+		for _, hostID := range hostIDs {
+			if _, ok := rpt.Host.Nodes[hostID]; !ok {
+				t.Errorf("Expected the report of %v to have %s", want[i], hostID)
+			}
+		}
+	}
+}
+
+func TestS3ReportStoreCompact(t *testing.T) {
+	mtime.NowForce(s3PeriodStart.Add(2*time.Minute + time.Hour))
+	defer mtime.NowReset()
+
+	client := newMockS3Client()
+	store := newTestS3ReportStore(client)
+	putS3Reports(t, store)
+	// Too recent to compact
+	recent := mtime.Now().Add(-time.Second)
+	if err := store.Put(context.Background(), recent, testS3Report("host6"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkCompactedS3Reports(t, store)
+	if _, err := store.Fetch(context.Background(), recent); err != nil {
+		t.Errorf("Expected the recent report to be kept, got %v", err)
+	}
+
+	// Another store carries on from how far this one got
+	compactedTo, err := newTestS3ReportStore(client).loadCompactedTo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := s3PeriodStart.Add(2 * time.Minute); !compactedTo.Equal(want) {
+		t.Errorf("Expected reports to be compacted to %v, got %v", want, compactedTo)
+	}
+}
+
+func TestS3ReportStoreCompactReplicas(t *testing.T) {
+	mtime.NowForce(s3PeriodStart.Add(2*time.Minute + time.Hour))
+	defer mtime.NowReset()
+	ctx := context.Background()
+
+	// One replica lists between another's put of the compacted report of
+	// the first period and its delete of the reports it merged
+	client := newMockS3Client()
+	first, second := newTestS3ReportStore(client), newTestS3ReportStore(client)
+	putS3Reports(t, first)
+	timestamps, err := first.Range(ctx, s3PeriodStart, s3PeriodStart.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := []report.Report{}
+	for _, timestamp := range timestamps {
+		rpt, err := first.Fetch(ctx, timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, rpt)
+	}
+	if err := first.Put(ctx, s3PeriodStart, first.merger.Merge(reports), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkCompactedS3Reports(t, second)
+
+	// And the first fetches what it listed before the second deleted it
+	keys := client.keys()
+	if err := first.compactPeriod(ctx, s3PeriodStart, timestamps); err != nil {
+		t.Fatalf("Expected reports compacted by another replica to be skipped, got %v", err)
+	}
+	if have := client.keys(); len(have) != len(keys) {
+		t.Errorf("Expected %v to be left alone, got %v", keys, have)
+	}
+}
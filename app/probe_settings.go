@@ -0,0 +1,107 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/common/xfer"
+	scopeprobe "github.com/weaveworks/scope/probe"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+)
+
+// probeSettings are the settings of a probe, or why they couldn't be had.
+type probeSettings struct {
+	ID       string      `json:"id"`
+	Hostname string      `json:"hostname"`
+	Settings interface{} `json:"settings,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// RegisterProbeSettingsRoutes registers the routes to get the settings of
+// all the probes, and to change them fleet-wide. Settings are changed with
+// the arguments of probe.SetSettings, of every probe or of those given by
+// the probe parameters.
+func RegisterProbeSettingsRoutes(router *mux.Router, rep Reporter, cr ControlRouter, readOnly bool) {
+	router.Methods("GET").Path("/api/probes/settings").HandlerFunc(
+		requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			handleProbeSettings(ctx, rep, cr, w, r, xfer.Request{Control: scopeprobe.GetSettings})
+		}))
+	router.Methods("POST").Path("/api/probes/settings").HandlerFunc(
+		requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			if readOnly {
+				respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+				return
+			}
+			var args map[string]string
+			if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&args); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+			handleProbeSettings(ctx, rep, cr, w, r, xfer.Request{Control: scopeprobe.SetSettings, ControlArgs: args})
+		}))
+}
+
+// handleProbeSettings sends req to the probes seen in the latest report,
+// at once, and responds with what each of them answered.
+func handleProbeSettings(ctx context.Context, rep Reporter, cr ControlRouter, w http.ResponseWriter, r *http.Request, req xfer.Request) {
+	rpt, err := rep.Report(ctx, time.Now())
+	if err != nil {
+		respondWith(w, http.StatusInternalServerError, err)
+		return
+	}
+	wanted := map[string]struct{}{}
+	for _, id := range r.URL.Query()["probe"] {
+		wanted[id] = struct{}{}
+	}
+	result := probesOf(rpt, wanted)
+
+	var wg sync.WaitGroup
+	for i := range result {
+		wg.Add(1)
+		go func(s *probeSettings) {
+			defer wg.Done()
+			res, err := cr.Handle(ctx, s.ID, req)
+			switch {
+			case err != nil:
+				s.Error = err.Error()
+			case res.Error != "":
+				s.Error = res.Error
+			default:
+				s.Settings = res.Value
+			}
+		}(&result[i])
+	}
+	wg.Wait()
+	respondWith(w, http.StatusOK, result)
+}
+
+// probesOf lists the probes which reported a host, all of them if wanted is
+// empty, sorted by ID.
+func probesOf(rpt report.Report, wanted map[string]struct{}) []probeSettings {
+	seen := map[string]struct{}{}
+	result := []probeSettings{}
+	for _, n := range rpt.Host.Nodes {
+		id, ok := n.Latest.Lookup(report.ControlProbeID)
+		if !ok {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		if _, ok := wanted[id]; len(wanted) > 0 && !ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		hostname, _ := n.Latest.Lookup(host.HostName)
+		result = append(result, probeSettings{ID: id, Hostname: hostname})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
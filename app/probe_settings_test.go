@@ -0,0 +1,78 @@
+package app_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+type probeSettingsResult struct {
+	ID       string `json:"id"`
+	Settings struct {
+		PublishInterval string          `json:"publishInterval"`
+		Reporters       map[string]bool `json:"reporters"`
+	} `json:"settings"`
+	Error string `json:"error"`
+}
+
+func TestProbeSettings(t *testing.T) {
+	ctx := context.Background()
+	collector := app.NewCollector(time.Minute)
+	rpt := report.MakeReport()
+	rpt.Host.AddNode(report.MakeNodeWith("host1", map[string]string{report.ControlProbeID: "probe1"}))
+	rpt.Host.AddNode(report.MakeNodeWith("host2", map[string]string{report.ControlProbeID: "probe2"}))
+	collector.Add(ctx, rpt, nil)
+
+	// probe1 is connected, probe2 isn't
+	p := probe.New(time.Second, 3*time.Second, nil, false)
+	p.AddReporter(probe.ReporterFunc("Endpoint", func() (report.Report, error) { return report.MakeReport(), nil }))
+	handlerRegistry := controls.NewDefaultHandlerRegistry()
+	p.RegisterControls(handlerRegistry)
+	controlRouter := app.NewLocalControlRouter()
+	controlRouter.Register(ctx, "probe1", handlerRegistry.HandleControlRequest)
+
+	router := mux.NewRouter()
+	app.RegisterProbeSettingsRoutes(router, collector, controlRouter, false)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	decode := func(body []byte) []probeSettingsResult {
+		var result []probeSettingsResult
+		if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&result); err != nil {
+			t.Fatalf("JSON parse error: %s", err)
+		}
+		return result
+	}
+
+	result := decode(is200(t, ts, "/api/probes/settings"))
+	if len(result) != 2 || result[0].ID != "probe1" || result[0].Settings.PublishInterval != "3s" || result[1].Error == "" {
+		t.Fatalf("Unexpected settings: %v", result)
+	}
+
+	res, body := checkRequest(t, ts, "POST", "/api/probes/settings?probe=probe1", []byte(`{"publish_interval": "10s", "disable": "endpoint"}`))
+	if res.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", res.StatusCode)
+	}
+	result = decode(body)
+	if len(result) != 1 || result[0].Error != "" || result[0].Settings.PublishInterval != "10s" || result[0].Settings.Reporters["endpoint"] {
+		t.Fatalf("Unexpected settings: %v", result)
+	}
+	if have := p.Settings().PublishInterval; have != "10s" {
+		t.Errorf("Expected the probe to publish every 10s, got %s", have)
+	}
+
+	// Invalid settings are refused, by each probe
+	_, body = checkRequest(t, ts, "POST", "/api/probes/settings?probe=probe1", []byte(`{"disable": "nothing"}`))
+	if result = decode(body); len(result) != 1 || result[0].Error == "" {
+		t.Errorf("Expected an error, got %v", result)
+	}
+}
@@ -0,0 +1,130 @@
+package app_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+func TestSessionRecorder(t *testing.T) {
+	ctx := context.Background()
+	recorder := app.NewSessionRecorder(app.NewMemoryReportStore(time.Minute).(app.RecordingStore))
+	controlRouter := app.RecordingControlRouter(app.NewLocalControlRouter(), recorder)
+	pipeRouter := app.RecordingPipeRouter(app.NewLocalPipeRouter(), recorder)
+	defer pipeRouter.Stop()
+	controlRouter.Register(ctx, "probe", func(req xfer.Request) xfer.Response {
+		if req.Control == "exec" {
+			return xfer.Response{Pipe: "pipe", RawTTY: true}
+		}
+		return xfer.Response{}
+	})
+
+	for _, req := range []xfer.Request{
+		{NodeID: "node", Control: "exec"},
+		{NodeID: "node", Control: "resize", ControlArgs: map[string]string{"pipeID": "pipe", "width": "120", "height": "40"}},
+	} {
+		if _, err := controlRouter.Handle(ctx, "probe", req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pipe, ui, err := pipeRouter.Get(ctx, "pipe", app.UIEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, probe := pipe.Ends()
+	go probe.Write([]byte("$ "))
+	buf := make([]byte, 2)
+	if _, err := ui.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipeRouter.Release(ctx, "pipe", app.UIEnd); err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := recorder.Recordings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 {
+		t.Fatalf("expected 1 recording, got %v", recordings)
+	}
+	recording, err := recorder.Recording(ctx, recordings[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bufio.NewScanner(bytes.NewReader(recording))
+	var header struct {
+		Version       int
+		Width, Height uint
+		Title         string
+	}
+	if !lines.Scan() || json.Unmarshal(lines.Bytes(), &header) != nil {
+		t.Fatalf("expected a header, got %q", recording)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Title != "exec on node" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	var event []interface{}
+	if !lines.Scan() || json.Unmarshal(lines.Bytes(), &event) != nil {
+		t.Fatalf("expected an event, got %q", recording)
+	}
+	if len(event) != 3 || event[1] != "o" || event[2] != "$ " {
+		t.Errorf("unexpected event %v", event)
+	}
+	if lines.Scan() {
+		t.Errorf("unexpected line %q", lines.Text())
+	}
+}
+
+func TestSessionRecorderExpiry(t *testing.T) {
+	ctx := context.Background()
+	recorder := app.NewSessionRecorder(app.NewMemoryReportStore(time.Minute).(app.RecordingStore))
+	controlRouter := app.RecordingControlRouter(app.NewLocalControlRouter(), recorder)
+	pipeRouter := app.RecordingPipeRouter(app.NewLocalPipeRouter(), recorder)
+	defer pipeRouter.Stop()
+	pipeID := "stale"
+	controlRouter.Register(ctx, "probe", func(req xfer.Request) xfer.Response {
+		return xfer.Response{Pipe: pipeID, RawTTY: true}
+	})
+
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+	if _, err := controlRouter.Handle(ctx, "probe", xfer.Request{NodeID: "node", Control: "exec"}); err != nil {
+		t.Fatal(err)
+	}
+	// The UI never connects to the first session, which is forgotten when
+	// the next one starts
+	mtime.NowForce(now.Add(2 * time.Minute))
+	pipeID = "fresh"
+	if _, err := controlRouter.Handle(ctx, "probe", xfer.Request{NodeID: "node", Control: "exec"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"stale", "fresh"} {
+		if _, _, err := pipeRouter.Get(ctx, id, app.UIEnd); err != nil {
+			t.Fatal(err)
+		}
+		if err := pipeRouter.Release(ctx, id, app.UIEnd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recordings, err := recorder.Recordings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 || !strings.HasSuffix(recordings[0].ID, "-fresh") {
+		t.Errorf("expected only the recording of the fresh session, got %v", recordings)
+	}
+}
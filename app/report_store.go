@@ -0,0 +1,227 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"context"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// ReportStore is something that can persist reports, indexed by the time
+// at which they were received.
+type ReportStore interface {
+	// Put stores a report received at the given time. buf is the
+	// serialised report (as gzip'd msgpack), if available.
+	Put(ctx context.Context, timestamp time.Time, rpt report.Report, buf []byte) error
+	// Fetch retrieves the report stored at the given time.
+	Fetch(ctx context.Context, timestamp time.Time) (report.Report, error)
+	// Range returns, in order, the times of all reports stored between
+	// start (inclusive) and end (exclusive).
+	Range(ctx context.Context, start, end time.Time) ([]time.Time, error)
+}
+
+// FetchRange retrieves all reports stored in the store between start
+// (inclusive) and end (exclusive).
+func FetchRange(ctx context.Context, store ReportStore, start, end time.Time) ([]report.Report, error) {
+	timestamps, err := store.Range(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	reports := make([]report.Report, 0, len(timestamps))
+	for _, timestamp := range timestamps {
+		rpt, err := store.Fetch(ctx, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, rpt)
+	}
+	return reports, nil
+}
+
+// memoryReportStore is a ReportStore keeping reports in memory, for as
+// long as the configured retention.
+type memoryReportStore struct {
+	mtx        sync.Mutex
+	reports    []report.Report
+	timestamps []time.Time
+	retention  time.Duration
+
+	recordings   map[string][]byte
+	recordingIDs []string // oldest first
+}
+
+// NewMemoryReportStore returns a ReportStore which keeps reports in memory
+// for the given retention.
+func NewMemoryReportStore(retention time.Duration) ReportStore {
+	return &memoryReportStore{retention: retention}
+}
+
+// Put implements ReportStore
+func (s *memoryReportStore) Put(_ context.Context, timestamp time.Time, rpt report.Report, _ []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i := sort.Search(len(s.timestamps), func(i int) bool { return !s.timestamps[i].Before(timestamp) })
+	if i < len(s.timestamps) && s.timestamps[i].Equal(timestamp) {
+		s.reports[i] = rpt
+	} else {
+		s.timestamps = append(s.timestamps, time.Time{})
+		copy(s.timestamps[i+1:], s.timestamps[i:])
+		s.timestamps[i] = timestamp
+		s.reports = append(s.reports, report.Report{})
+		copy(s.reports[i+1:], s.reports[i:])
+		s.reports[i] = rpt
+	}
+
+	s.clean()
+	return nil
+}
+
+// Fetch implements ReportStore
+func (s *memoryReportStore) Fetch(_ context.Context, timestamp time.Time) (report.Report, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	i := sort.Search(len(s.timestamps), func(i int) bool { return !s.timestamps[i].Before(timestamp) })
+	if i == len(s.timestamps) || !s.timestamps[i].Equal(timestamp) {
+		return report.Report{}, fmt.Errorf("no report stored at %s", timestamp)
+	}
+	return s.reports[i], nil
+}
+
+// Range implements ReportStore
+func (s *memoryReportStore) Range(_ context.Context, start, end time.Time) ([]time.Time, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	result := []time.Time{}
+	for _, t := range s.timestamps {
+		if !t.Before(start) && t.Before(end) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// PutRecording implements RecordingStore, keeping the latest recordings.
+func (s *memoryReportStore) PutRecording(_ context.Context, id string, buf []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.recordings == nil {
+		s.recordings = map[string][]byte{}
+	}
+	if _, ok := s.recordings[id]; !ok {
+		s.recordingIDs = append(s.recordingIDs, id)
+	}
+	s.recordings[id] = buf
+	for len(s.recordingIDs) > maxMemoryRecordings {
+		delete(s.recordings, s.recordingIDs[0])
+		s.recordingIDs = s.recordingIDs[1:]
+	}
+	return nil
+}
+
+// FetchRecording implements RecordingStore
+func (s *memoryReportStore) FetchRecording(_ context.Context, id string) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	buf, ok := s.recordings[id]
+	if !ok {
+		return nil, fmt.Errorf("no recording %s", id)
+	}
+	return buf, nil
+}
+
+// ListRecordings implements RecordingStore
+func (s *memoryReportStore) ListRecordings(_ context.Context) ([]string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]string{}, s.recordingIDs...), nil
+}
+
+// remove reports older than the retention
+func (s *memoryReportStore) clean() {
+	oldest := mtime.Now().Add(-s.retention)
+	i := sort.Search(len(s.timestamps), func(i int) bool { return s.timestamps[i].After(oldest) })
+	s.timestamps = s.timestamps[i:]
+	s.reports = s.reports[i:]
+}
+
+// storingCollector is a Collector which also persists every report it
+// receives into a ReportStore, and serves reports older than its window
+// from that store.
+type storingCollector struct {
+	Collector
+	store  ReportStore
+	window time.Duration
+	merger Merger
+}
+
+// NewStoringCollector returns a Collector which forwards reports to the
+// upstream collector, and persists them into store. Timestamps for which
+// the upstream collector no longer holds reports (older than window) are
+// rendered from the store.
+func NewStoringCollector(upstream Collector, store ReportStore, window time.Duration) Collector {
+	return &storingCollector{
+		Collector: upstream,
+		store:     store,
+		window:    window,
+		merger:    NewFastMerger(),
+	}
+}
+
+// Add implements Adder
+func (c *storingCollector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	if err := c.Collector.Add(ctx, rpt, buf); err != nil {
+		return err
+	}
+	// A failure to persist a report shouldn't stop it being rendered
+	if err := c.store.Put(ctx, ReportTimestamp(ctx), rpt, buf); err != nil {
+		log.Errorf("Error storing report: %v", err)
+	}
+	return nil
+}
+
+// historic tells whether timestamp is too old to be served by the upstream
+// collector.
+func (c *storingCollector) historic(timestamp time.Time) bool {
+	return timestamp.Before(mtime.Now().Add(-c.window))
+}
+
+// Report implements Reporter
+func (c *storingCollector) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	if !c.historic(timestamp) {
+		return c.Collector.Report(ctx, timestamp)
+	}
+	reports, err := FetchRange(ctx, c.store, timestamp.Add(-c.window), timestamp.Add(time.Nanosecond))
+	if err != nil {
+		return report.Report{}, err
+	}
+	for i := range reports {
+		reports[i] = reports[i].Upgrade()
+	}
+	return c.merger.Merge(reports), nil
+}
+
+// HasReports implements Reporter
+func (c *storingCollector) HasReports(ctx context.Context, timestamp time.Time) (bool, error) {
+	if !c.historic(timestamp) {
+		return c.Collector.HasReports(ctx, timestamp)
+	}
+	timestamps, err := c.store.Range(ctx, timestamp.Add(-c.window), timestamp.Add(time.Nanosecond))
+	if err != nil {
+		return false, err
+	}
+	return len(timestamps) > 0, nil
+}
+
+// HasHistoricReports implements Reporter
+func (c *storingCollector) HasHistoricReports() bool {
+	return true
+}
@@ -14,8 +14,10 @@ import (
 	"context"
 	"github.com/NYTimes/gziphandler"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
 
 	"github.com/weaveworks/scope/common/hostname"
 	"github.com/weaveworks/scope/common/xfer"
@@ -28,8 +30,23 @@ var (
 
 	// UniqueID - set at runtime.
 	UniqueID = "0"
+
+	// GRPCPort - set at runtime, if probes can connect over gRPC.
+	GRPCPort = 0
+
+	reportSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "report_size_bytes",
+		Help:      "Size of reports received from probes (gzipped msgpack), in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 2, 15),
+	})
 )
 
+func init() {
+	prometheus.MustRegister(reportSize)
+}
+
 // contextKey is a wrapper type for use in context.WithValue() to satisfy golint
 // https://github.com/golang/go/issues/17293
 // https://github.com/golang/lint/pull/245
@@ -103,6 +120,15 @@ func RegisterTopologyRoutes(router *mux.Router, r Reporter, capabilities map[str
 	get.Handle("/api/topology/{topology}/ws",
 		requestContextDecorator(captureReporter(r, handleWebsocket))). // NB not gzip!
 		Name("api_topology_topology_ws")
+	get.Handle("/api/topology/{topology}/export",
+		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleTopologyExport)))).
+		Name("api_topology_topology_export")
+	get.Handle("/api/topology/{topology}/diff",
+		gzipHandler(requestContextDecorator(captureReporter(r, handleTopologyDiff)))).
+		Name("api_topology_topology_diff")
+	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{id}/ws")).Handler(
+		requestContextDecorator(captureReporter(r, handleNodeWebsocket))). // NB not gzip!
+		Name("api_topology_topology_id_ws")
 	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{id}")).Handler(
 		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleNode)))).
 		Name("api_topology_topology_id")
@@ -112,8 +138,10 @@ func RegisterTopologyRoutes(router *mux.Router, r Reporter, capabilities map[str
 		gzipHandler(requestContextDecorator(makeProbeHandler(r))))
 }
 
-// RegisterReportPostHandler registers the handler for report submission
-func RegisterReportPostHandler(a Adder, router *mux.Router) {
+// RegisterReportPostHandler registers the handler for report submission,
+// keeping the bases of the delta reports of up to deltaBases probes.
+func RegisterReportPostHandler(a Adder, router *mux.Router, deltaBases int) {
+	bases := newDeltaBases(deltaBases)
 	post := router.Methods("POST").Subrouter()
 	post.HandleFunc("/api/report", requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		var (
@@ -128,6 +156,13 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 		}
 
 		contentType := r.Header.Get("Content-Type")
+		if schema, err := report.ParseSchemaVersion(contentType); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		} else if schema > report.SchemaVersion {
+			respondWith(w, http.StatusUnsupportedMediaType, fmt.Errorf("report schema %d is newer than this app supports (%d)", schema, report.SchemaVersion))
+			return
+		}
 		isMsgpack := strings.HasPrefix(contentType, "application/msgpack")
 		var handle codec.Handle
 		switch {
@@ -145,10 +180,36 @@ func RegisterReportPostHandler(a Adder, router *mux.Router) {
 			return
 		}
 
+		if r.Header.Get(xfer.ScopeReportDeltasHeader) == "true" {
+			wasDelta := rpt.IsDelta()
+			var err error
+			if rpt, err = bases.full(tenantID(ctx, r.Header.Get(xfer.ScopeProbeIDHeader)), rpt); err != nil {
+				respondWith(w, http.StatusConflict, err)
+				return
+			}
+			// Publish the whole report onwards
+			isMsgpack = isMsgpack && !wasDelta
+		} else if rpt.IsDelta() {
+			respondWith(w, http.StatusBadRequest, fmt.Errorf("delta report without the %s header", xfer.ScopeReportDeltasHeader))
+			return
+		}
+
+		if ts := r.Header.Get(xfer.ScopeReportTimestampHeader); ts != "" {
+			timestamp, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				respondWith(w, http.StatusBadRequest, fmt.Errorf("invalid %s header: %v", xfer.ScopeReportTimestampHeader, err))
+				return
+			}
+			if timestamp.Before(mtime.Now()) {
+				ctx = WithReportTimestamp(ctx, timestamp)
+			}
+		}
+
 		// a.Add(..., buf) assumes buf is gzip'd msgpack
 		if !isMsgpack {
 			buf, _ = rpt.WriteBinary()
 		}
+		reportSize.Observe(float64(buf.Len()))
 
 		if err := a.Add(ctx, rpt, buf.Bytes()); err != nil {
 			log.Errorf("Error Adding report: %v", err)
@@ -176,7 +237,7 @@ func NewVersion(version, downloadURL string) {
 
 func apiHandler(rep Reporter, capabilities map[string]bool) CtxHandlerFunc {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-		report, err := rep.Report(ctx, time.Now())
+		rpt, err := rep.Report(ctx, time.Now())
 		if err != nil {
 			respondWith(w, http.StatusInternalServerError, err)
 			return
@@ -187,8 +248,10 @@ func apiHandler(rep Reporter, capabilities map[string]bool) CtxHandlerFunc {
 			ID:           UniqueID,
 			Version:      Version,
 			Hostname:     hostname.Get(),
-			Plugins:      report.Plugins,
+			Plugins:      rpt.Plugins,
 			Capabilities: capabilities,
+			GRPCPort:     GRPCPort,
+			ReportSchema: report.SchemaVersion,
 			NewVersion:   newVersion.NewVersionInfo,
 		})
 	}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+// NewTenantCollector makes a Collector keeping the reports of each tenant
+// apart, in collectors of their own made by newCollector: reports are
+// added to, and read from, the collector of the tenant of the request
+// (see AuthTenant).
+func NewTenantCollector(newCollector func() Collector) Collector {
+	return &tenantCollector{
+		newCollector: newCollector,
+		collectors:   map[string]Collector{},
+	}
+}
+
+type tenantCollector struct {
+	newCollector func() Collector
+
+	mtx        sync.Mutex
+	collectors map[string]Collector
+}
+
+func (t *tenantCollector) collector(ctx context.Context) Collector {
+	tenant := AuthTenant(ctx)
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	c, ok := t.collectors[tenant]
+	if !ok {
+		c = t.newCollector()
+		t.collectors[tenant] = c
+	}
+	return c
+}
+
+func (t *tenantCollector) Add(ctx context.Context, rpt report.Report, buf []byte) error {
+	return t.collector(ctx).Add(ctx, rpt, buf)
+}
+
+func (t *tenantCollector) Report(ctx context.Context, timestamp time.Time) (report.Report, error) {
+	return t.collector(ctx).Report(ctx, timestamp)
+}
+
+func (t *tenantCollector) HasReports(ctx context.Context, timestamp time.Time) (bool, error) {
+	return t.collector(ctx).HasReports(ctx, timestamp)
+}
+
+func (t *tenantCollector) HasHistoricReports() bool {
+	return t.newCollector().HasHistoricReports()
+}
+
+func (t *tenantCollector) WaitOn(ctx context.Context, waiter chan struct{}) {
+	t.collector(ctx).WaitOn(ctx, waiter)
+}
+
+func (t *tenantCollector) UnWait(ctx context.Context, waiter chan struct{}) {
+	t.collector(ctx).UnWait(ctx, waiter)
+}
+
+// tenantID scopes the ID of a probe or pipe to the tenant of the request,
+// so that the same ID in another tenant is another probe or pipe.
+func tenantID(ctx context.Context, id string) string {
+	if tenant := AuthTenant(ctx); tenant != "" {
+		return tenant + "/" + id
+	}
+	return id
+}
+
+// TenantControlRouter makes a ControlRouter only routing the control
+// requests of a tenant to the probes of the same tenant.
+func TenantControlRouter(cr ControlRouter) ControlRouter {
+	return tenantControlRouter{cr}
+}
+
+type tenantControlRouter struct {
+	ControlRouter
+}
+
+func (t tenantControlRouter) Handle(ctx context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	return t.ControlRouter.Handle(ctx, tenantID(ctx, probeID), req)
+}
+
+func (t tenantControlRouter) Register(ctx context.Context, probeID string, handler xfer.ControlHandlerFunc) (int64, error) {
+	return t.ControlRouter.Register(ctx, tenantID(ctx, probeID), handler)
+}
+
+func (t tenantControlRouter) Deregister(ctx context.Context, probeID string, id int64) error {
+	return t.ControlRouter.Deregister(ctx, tenantID(ctx, probeID), id)
+}
+
+// TenantPipeRouter makes a PipeRouter only connecting the ends of pipes
+// of the same tenant.
+func TenantPipeRouter(pr PipeRouter) PipeRouter {
+	return tenantPipeRouter{pr}
+}
+
+type tenantPipeRouter struct {
+	PipeRouter
+}
+
+func (t tenantPipeRouter) Exists(ctx context.Context, id string) (bool, error) {
+	return t.PipeRouter.Exists(ctx, tenantID(ctx, id))
+}
+
+func (t tenantPipeRouter) Get(ctx context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
+	return t.PipeRouter.Get(ctx, tenantID(ctx, id), e)
+}
+
+func (t tenantPipeRouter) Release(ctx context.Context, id string, e End) error {
+	return t.PipeRouter.Release(ctx, tenantID(ctx, id), e)
+}
+
+func (t tenantPipeRouter) Delete(ctx context.Context, id string) error {
+	return t.PipeRouter.Delete(ctx, tenantID(ctx, id))
+}
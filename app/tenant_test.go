@@ -0,0 +1,184 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestTenants(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant red
+probe redprobe
+user reduser red
+tenant blue
+probe blueprobe
+user blueuser blue
+`)
+	f.Close()
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.HasTenants() {
+		t.Fatal("Expected tenants")
+	}
+	if tenant, ok := auth.ProbeTenant("Scope-Probe token=blueprobe"); !ok || tenant != "blue" {
+		t.Errorf("Expected the blue tenant, got %q", tenant)
+	}
+
+	collector := app.NewTenantCollector(func() app.Collector { return app.NewCollector(time.Minute) })
+	router := mux.NewRouter()
+	app.RegisterReportPostHandler(collector, router, app.DefaultDeltaBases)
+	router.Methods("GET").Path("/hosts").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rpt, err := collector.Report(r.Context(), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts := []string{}
+		for id := range rpt.Host.Nodes {
+			hosts = append(hosts, id)
+		}
+		sort.Strings(hosts)
+		json.NewEncoder(w).Encode(hosts)
+	})
+	server := httptest.NewServer(auth.Wrap(router))
+	defer server.Close()
+
+	do := func(method, path, authorization string, body []byte) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authorization)
+		if method == "POST" {
+			req.Header.Set("Content-Encoding", "gzip")
+			req.Header.Set("Content-Type", report.MsgpackContentType(report.SchemaVersion))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: %s", method, path, resp.Status)
+		}
+		return resp
+	}
+	publish := func(token, host string) {
+		rpt := report.MakeReport()
+		rpt.Host.AddNode(report.MakeNode(host))
+		buf, err := rpt.WriteBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		do("POST", "/api/report", "Scope-Probe token="+token, buf.Bytes()).Body.Close()
+	}
+	hosts := func(token string) []string {
+		resp := do("GET", "/hosts", "Bearer "+token, nil)
+		defer resp.Body.Close()
+		var hosts []string
+		if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
+			t.Fatal(err)
+		}
+		return hosts
+	}
+
+	publish("redprobe", "red1")
+	publish("redprobe", "red2")
+	publish("blueprobe", "blue1")
+	if want, have := []string{"red1", "red2"}, hosts("reduser"); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected red to see %v, got %v", want, have)
+	}
+	if want, have := []string{"blue1"}, hosts("blueuser"); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected blue to see %v, got %v", want, have)
+	}
+}
+
+func TestTenantSettings(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant red
+user reduser red write
+tenant blue
+user blueuser blue write
+`)
+	f.Close()
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	health, err := app.NewHealthConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	app.RegisterCustomTopologyRoutes(router, false)
+	app.RegisterHealthRoutes(router, health, false)
+	app.RegisterTopologyRoutes(router, app.NewCollector(time.Minute), nil)
+	server := httptest.NewServer(auth.Wrap(router))
+	defer server.Close()
+
+	do := func(method, path, token, body string) string {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp.Status + " " + string(bytes.TrimSpace(respBody))
+	}
+
+	// Red's custom topologies and health rules are its own
+	if have := do("PUT", "/api/custom-topology/hosts-by-kernel", "reduser", `{"parent": "hosts", "key": "kernel_version"}`); have[:3] != "200" {
+		t.Fatalf("Expected red to add a custom topology, got %s", have)
+	}
+	if have := do("PUT", "/api/health/rules", "reduser", `[{"topology": "host", "metric": "load1", "above": 8, "status": "warn"}]`); have[:3] != "200" {
+		t.Fatalf("Expected red to replace its health rules, got %s", have)
+	}
+	if have := do("GET", "/api/topology/hosts-by-kernel", "reduser", ""); have[:3] != "200" {
+		t.Errorf("Expected red to render its custom topology, got %s", have)
+	}
+
+	// Blue neither sees nor changes them
+	for _, c := range []struct{ method, path, want string }{
+		{"GET", "/api/custom-topology", "200 OK []"},
+		{"GET", "/api/health/rules", "200 OK []"},
+		{"GET", "/api/topology/hosts-by-kernel", "404 Not Found 404 page not found"},
+		{"DELETE", "/api/custom-topology/hosts-by-kernel", "404 Not Found 404 page not found"},
+	} {
+		if have := do(c.method, c.path, "blueuser", ""); have != c.want {
+			t.Errorf("%s %s: expected %q for blue, got %q", c.method, c.path, c.want, have)
+		}
+	}
+	if have := do("GET", "/api/custom-topology", "reduser", ""); !bytes.Contains([]byte(have), []byte(`"hosts-by-kernel"`)) {
+		t.Errorf("Expected red to keep its custom topology, got %s", have)
+	}
+	if have := do("GET", "/api/health/rules", "reduser", ""); !bytes.Contains([]byte(have), []byte(`"load1"`)) {
+		t.Errorf("Expected red to keep its health rules, got %s", have)
+	}
+}
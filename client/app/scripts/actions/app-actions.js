@@ -421,14 +421,14 @@ export function closeWebsocket() {
   };
 }
 
-export function doControl(nodeId, control) {
+export function doControl(nodeId, control, args) {
   return (dispatch) => {
     dispatch({
       control,
       nodeId,
       type: ActionTypes.DO_CONTROL
     });
-    doControlRequest(nodeId, control, dispatch);
+    doControlRequest(nodeId, control, dispatch, args);
   };
 }
 
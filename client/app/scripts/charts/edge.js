@@ -4,6 +4,7 @@ import classNames from 'classnames';
 
 import { enterEdge, leaveEdge } from '../actions/app-actions';
 import { encodeIdAttribute, decodeIdAttribute } from '../utils/dom-utils';
+import { formatMetricSvg } from '../utils/string-utils';
 
 function isStorageComponent(id) {
   const storageComponents = ['<persistent_volume>', '<storage_class>', '<persistent_volume_claim>', '<volume_snapshot>', '<volume_snapshot_data>'];
@@ -25,6 +26,29 @@ function getAdjacencyClass(id) {
   return 'link-none';
 }
 
+// edgeTitle describes the RTTs, retransmissions and traffic of the
+// connections along an edge, e.g. "RTT p50 1.2ms, p99 35ms, 3 retransmits,
+// 1.2 MB sent, 3.4 KB received (12 connections)"
+export function edgeTitle(metrics) {
+  if (!metrics) {
+    return null;
+  }
+  const ms = value => `${parseFloat(value.toPrecision(2))}ms`;
+  const bytes = value => formatMetricSvg(value, { format: 'filesize' });
+  const parts = [];
+  if (metrics.get('rttP50')) {
+    parts.push(`RTT p50 ${ms(metrics.get('rttP50'))}, p99 ${ms(metrics.get('rttP99'))}`);
+  }
+  if (metrics.get('retransmits') > 0) {
+    parts.push(`${metrics.get('retransmits')} retransmits`);
+  }
+  if (metrics.get('bytesSent') || metrics.get('bytesReceived')) {
+    parts.push(`${bytes(metrics.get('bytesSent') || 0)} sent, ${bytes(metrics.get('bytesReceived') || 0)} received`);
+  }
+  const connections = metrics.get('connections');
+  return `${parts.join(', ')} (${connections} connection${connections === 1 ? '' : 's'})`;
+}
+
 class Edge extends React.Component {
   constructor(props, context) {
     super(props, context);
@@ -34,10 +58,11 @@ class Edge extends React.Component {
 
   render() {
     const {
-      id, path, highlighted, focused, thickness, source, target
+      id, path, highlighted, focused, failed, denied, metrics, thickness, source, target
     } = this.props;
     const shouldRenderMarker = (focused || highlighted) && (source !== target);
-    const className = classNames('edge', { highlighted });
+    const className = classNames('edge', { denied, failed, highlighted });
+    const title = edgeTitle(metrics);
     return (
       <g
         id={encodeIdAttribute(id)}
@@ -45,6 +70,7 @@ class Edge extends React.Component {
         onMouseEnter={this.handleMouseEnter}
         onMouseLeave={this.handleMouseLeave}
       >
+        {title && <title>{title}</title>}
         <path className="shadow" d={path} style={{ strokeWidth: 10 * thickness }} />
         <path
           className={getAdjacencyClass(id)}
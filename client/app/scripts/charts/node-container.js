@@ -43,8 +43,29 @@ class NodeContainer extends React.Component {
     const matchedMetadata = this.props.matches.get('metadata', makeList());
     const matchedParents = this.props.matches.get('parents', makeList());
     const matchedDetails = matchedMetadata.concat(matchedParents);
+    const badges = (this.props.annotations || makeList()).filter(a => a.get('badge'));
+    const { health } = this.props;
+    const showHealth = health && health !== 'ok';
     return (
-      <MatchedResults matches={matchedDetails} searchTerms={this.props.searchTerms} />
+      <div>
+        {(badges.size > 0 || showHealth) && (
+          <div className="node-badges">
+            {showHealth && (
+              <span
+                className={`node-badges-badge node-health node-health-${health}`}
+                title={`Health: ${health}`}>
+                {health}
+              </span>
+            )}
+            {badges.map(a => (
+              <span className="node-badges-badge" key={a.get('key')} title={a.get('key')}>
+                {a.get('value')}
+              </span>
+            ))}
+          </div>
+        )}
+        <MatchedResults matches={matchedDetails} searchTerms={this.props.searchTerms} />
+      </div>
     );
   };
 
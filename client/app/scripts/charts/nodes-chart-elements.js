@@ -158,6 +158,8 @@ class NodesChartElements extends React.Component {
       <NodeContainer
         matches={node.get('matches')}
         networks={node.get('networks')}
+        annotations={node.get('annotations')}
+        health={node.get('health')}
         metric={node.get('metric')}
         focused={node.get('focused')}
         highlighted={node.get('highlighted')}
@@ -189,6 +191,9 @@ class NodesChartElements extends React.Component {
         waypoints={edge.get('points')}
         highlighted={edge.get('highlighted')}
         focused={edge.get('focused')}
+        failed={edge.get('failed')}
+        denied={edge.get('denied')}
+        metrics={edge.get('metrics')}
         scale={edge.get('scale')}
         isAnimated={isAnimated}
       />
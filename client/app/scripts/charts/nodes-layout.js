@@ -407,7 +407,10 @@ function copyLayoutProperties(layout, nodeCache, edgeCache) {
   result.edges = layout.edges.map((edge) => {
     if (edgeCache.has(edge.get('id'))
       && hasSameEndpoints(edgeCache.get(edge.get('id')), result.nodes)) {
-      return edge.merge(edgeCache.get(edge.get('id')));
+      // Whether the edge failed or is denied, and its metrics, are up to
+      // date, unlike its layout
+      return edge.merge(edgeCache.get(edge.get('id'))
+        .delete('failed').delete('denied').delete('metrics'));
     } else if (nodeCache.get(edge.get('source')) && nodeCache.get(edge.get('target'))) {
       return setSimpleEdgePoints(edge, nodeCache);
     }
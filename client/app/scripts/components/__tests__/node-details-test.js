@@ -48,4 +48,22 @@ describe('NodeDetails', () => {
     const title = TestUtils.findRenderedDOMComponentWithClass(c, 'node-details-header-label');
     expect(title.title).toBe('Node 1');
   });
+
+  it('shows the health of the node', () => {
+    nodes = nodes.set(nodeId, Immutable.fromJS({id: nodeId}));
+    details = {health: 'critical', label: 'Node 1'};
+    const c = TestUtils.renderIntoDocument((
+      <Provider store={configureStore()}>
+        <NodeDetails
+          nodes={nodes}
+          topologyId="containers"
+          nodeId={nodeId}
+          details={details}
+          />
+      </Provider>
+    ));
+
+    const health = TestUtils.findRenderedDOMComponentWithClass(c, 'node-health-critical');
+    expect(health.textContent).toBe('critical');
+  });
 });
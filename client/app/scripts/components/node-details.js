@@ -175,6 +175,13 @@ class NodeDetails extends React.Component {
             <h2 className="node-details-header-label truncate" title={details.label}>
               <MatchedText text={details.label} match={nodeMatches.get('label')} />
             </h2>
+            {details.health && (
+              <div className="node-details-header-health">
+                Health: <span className={`node-health node-health-${details.health}`}>
+                  {details.health}
+                </span>
+              </div>
+            )}
             <div className="node-details-header-relatives">
               {details.parents && <NodeDetailsRelatives
                 matches={nodeMatches.get('parents')}
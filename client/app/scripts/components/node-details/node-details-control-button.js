@@ -26,9 +26,17 @@ class NodeDetailsControlButton extends React.Component {
 
   handleClick(ev) {
     ev.preventDefault();
-    const { id, human, confirmation } = this.props.control;
+    const {
+      id, human, confirmation, prompt
+    } = this.props.control;
     trackAnalyticsEvent('scope.node.control.click', { id, title: human });
-    if (isEmpty(confirmation) || window.confirm(confirmation)) { // eslint-disable-line no-alert
+    if (!isEmpty(prompt)) {
+      // The answer goes in the argument the probe reads it from (report.PromptArg)
+      const value = window.prompt(prompt); // eslint-disable-line no-alert
+      if (value !== null) {
+        this.props.dispatch(doControl(this.props.nodeId, this.props.control, { value }));
+      }
+    } else if (isEmpty(confirmation) || window.confirm(confirmation)) { // eslint-disable-line no-alert
       this.props.dispatch(doControl(this.props.nodeId, this.props.control));
     }
   }
@@ -29,5 +29,52 @@ describe('LayouterUtils', () => {
         },
       });
     });
+
+    it('should mark failed edges', () => {
+      const input = fromJS({
+        a: { adjacency: ['b'], failedAdjacency: ['c', 'd'] },
+        b: {},
+        c: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1
+        },
+        [edge('a', 'c')]: {
+          id: edge('a', 'c'), source: 'a', target: 'c', value: 1, failed: true
+        },
+      });
+    });
+
+    it('should mark denied edges', () => {
+      const input = fromJS({
+        a: { adjacency: ['b'], failedAdjacency: ['c'], deniedAdjacency: ['b', 'c'] },
+        b: {},
+        c: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1, denied: true
+        },
+        [edge('a', 'c')]: {
+          id: edge('a', 'c'), source: 'a', target: 'c', value: 1, failed: true, denied: true
+        },
+      });
+    });
+
+    it('should add the metrics of edges', () => {
+      const metrics = {
+        connections: 2, rttP50: 1, rttP99: 3, retransmits: 0
+      };
+      const input = fromJS({
+        a: { adjacency: ['b'], edgeMetrics: { b: metrics, c: metrics } },
+        b: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1, metrics
+        },
+      });
+    });
   });
 });
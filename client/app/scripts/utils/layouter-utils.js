@@ -11,25 +11,45 @@ export function constructEdgeId(source, target) {
   return [source, target].join(EDGE_ID_SEPARATOR);
 }
 
-// Constructs the edges for the layout engine from the nodes' adjacency table.
+// Constructs the edges for the layout engine from the nodes' adjacency table,
+// and failed adjacency table, whose edges are marked as failed. The edges of
+// the denied adjacency table are marked as denied, and those with edge
+// metrics get the RTTs of their connections.
 // We don't collapse edge pairs (A->B, B->A) here as we want to let the layout
 // engine decide how to handle bidirectional edges.
 export function initEdgesFromNodes(nodes) {
   let edges = makeMap();
 
   nodes.forEach((node, nodeId) => {
-    (node.get('adjacency') || []).forEach((adjacentId) => {
-      const source = nodeId;
-      const target = adjacentId;
-
-      if (nodes.has(target)) {
-        // The direction source->target is important since dagre takes
-        // directionality into account when calculating the layout.
-        const edgeId = constructEdgeId(source, target);
-        const edge = makeMap({
-          id: edgeId, source, target, value: 1
-        });
-        edges = edges.set(edgeId, edge);
+    [false, true].forEach((failed) => {
+      (node.get(failed ? 'failedAdjacency' : 'adjacency') || []).forEach((adjacentId) => {
+        const source = nodeId;
+        const target = adjacentId;
+
+        if (nodes.has(target)) {
+          // The direction source->target is important since dagre takes
+          // directionality into account when calculating the layout.
+          const edgeId = constructEdgeId(source, target);
+          const edge = makeMap({
+            id: edgeId, source, target, value: 1
+          });
+          edges = edges.set(edgeId, failed ? edge.set('failed', true) : edge);
+        }
+      });
+    });
+  });
+
+  nodes.forEach((node, nodeId) => {
+    (node.get('deniedAdjacency') || []).forEach((adjacentId) => {
+      const edgeId = constructEdgeId(nodeId, adjacentId);
+      if (edges.has(edgeId)) {
+        edges = edges.setIn([edgeId, 'denied'], true);
+      }
+    });
+    (node.get('edgeMetrics') || makeMap()).forEach((metrics, adjacentId) => {
+      const edgeId = constructEdgeId(nodeId, adjacentId);
+      if (edges.has(edgeId)) {
+        edges = edges.setIn([edgeId, 'metrics'], metrics);
       }
     });
   });
@@ -387,11 +387,12 @@ export function getApiDetails(dispatch) {
   });
 }
 
-export function doControlRequest(nodeId, control, dispatch) {
+export function doControlRequest(nodeId, control, dispatch, args) {
   clearTimeout(controlErrorTimer);
   const url = `${getApiPath()}/api/control/${encodeURIComponent(control.probeId)}/`
     + `${encodeURIComponent(control.nodeId)}/${control.id}`;
   doRequest({
+    data: args && JSON.stringify(args),
     error: (err) => {
       dispatch(receiveControlError(nodeId, err.response));
       controlErrorTimer = setTimeout(() => {
@@ -12,12 +12,25 @@ const (
 
 	// ScopeProbeVersionHeader is the header we use to carry the probe's version.
 	ScopeProbeVersionHeader = "X-Scope-Probe-Version"
+
+	// ScopeReportDeltasHeader is set (to "true") by probes which publish
+	// delta reports, so that the app keeps their last report to apply the
+	// next delta to.
+	ScopeReportDeltasHeader = "X-Scope-Report-Deltas"
+
+	// ScopeReportTimestampHeader is set by probes publishing a report late,
+	// e.g. after the app was unreachable, to the time (RFC3339) they made it.
+	ScopeReportTimestampHeader = "X-Scope-Report-Timestamp"
 )
 
 // HistoricReportsCapability indicates whether reports older than the
 // current time (-app.window) can be retrieved.
 const HistoricReportsCapability = "historic_reports"
 
+// MetricHistoryCapability indicates whether the history of the metrics of
+// nodes can be retrieved from /api/history/{id}.
+const MetricHistoryCapability = "metric_history"
+
 // Details are some generic details that can be fetched from /api
 type Details struct {
 	ID           string          `json:"id"`
@@ -25,6 +38,8 @@ type Details struct {
 	Hostname     string          `json:"hostname"`
 	Plugins      PluginSpecs     `json:"plugins,omitempty"`
 	Capabilities map[string]bool `json:"capabilities,omitempty"`
+	GRPCPort     int             `json:"grpcPort,omitempty"`     // Set if the app accepts probe streams over gRPC
+	ReportSchema int             `json:"reportSchema,omitempty"` // The newest report schema the app reads
 
 	NewVersion *NewVersionInfo `json:"newVersion,omitempty"`
 }
@@ -0,0 +1,103 @@
+package xfer
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// ConnectMethod is the full name of the bidirectional gRPC stream probes
+// open to apps, to publish reports up and receive control requests down.
+const ConnectMethod = "/scope.Probe/Connect"
+
+// ScopeProbeControlsHeader is set (to "true") by probes which accept
+// control requests over their gRPC stream.
+const ScopeProbeControlsHeader = "X-Scope-Probe-Controls"
+
+// ScopeReportContentTypeHeader carries the content type, and so the schema
+// version, of the reports a probe sends over its gRPC stream.
+const ScopeReportContentTypeHeader = "X-Scope-Report-Content-Type"
+
+// FrameType says what a Frame carries.
+type FrameType byte
+
+// The types of frames exchanged over the gRPC stream
+const (
+	ReportFrame          FrameType = iota + 1 // probe -> app, a gzipped msgpack report
+	ControlRequestFrame                       // app -> probe, a JSON ControlMessage
+	ControlResponseFrame                      // probe -> app, a JSON ControlMessage
+)
+
+// Frame is a message on the gRPC stream between probes and apps.
+type Frame struct {
+	Type    FrameType
+	Payload []byte
+}
+
+// ControlMessage is the payload of control frames, correlating responses
+// with their requests.
+type ControlMessage struct {
+	ID       uint64    `json:"id"`
+	Request  *Request  `json:"request,omitempty"`
+	Response *Response `json:"response,omitempty"`
+}
+
+// FrameCodec is the gRPC codec for Frames. Reports are already serialised
+// by the time they are sent, so frames are just a type byte followed by
+// the payload.
+type FrameCodec struct{}
+
+// Marshal implements grpc.Codec
+func (FrameCodec) Marshal(v interface{}) ([]byte, error) {
+	frame, ok := v.(*Frame)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T, expected *xfer.Frame", v)
+	}
+	buf := make([]byte, 1+len(frame.Payload))
+	buf[0] = byte(frame.Type)
+	copy(buf[1:], frame.Payload)
+	return buf, nil
+}
+
+// Unmarshal implements grpc.Codec
+func (FrameCodec) Unmarshal(data []byte, v interface{}) error {
+	frame, ok := v.(*Frame)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T, expected *xfer.Frame", v)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("empty frame")
+	}
+	frame.Type = FrameType(data[0])
+	frame.Payload = data[1:]
+	return nil
+}
+
+// String implements grpc.Codec
+func (FrameCodec) String() string { return "scope-frame" }
+
+// ProbeServer is the app side of the gRPC stream.
+type ProbeServer interface {
+	Connect(grpc.ServerStream) error
+}
+
+// ConnectStreamDesc describes the stream opened at ConnectMethod.
+var ConnectStreamDesc = grpc.StreamDesc{
+	StreamName:    "Connect",
+	ServerStreams: true,
+	ClientStreams: true,
+}
+
+// RegisterProbeServer registers srv to handle probe streams on s. s must
+// use the FrameCodec.
+func RegisterProbeServer(s *grpc.Server, srv ProbeServer) {
+	desc := ConnectStreamDesc
+	desc.Handler = func(srv interface{}, stream grpc.ServerStream) error {
+		return srv.(ProbeServer).Connect(stream)
+	}
+	s.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "scope.Probe",
+		HandlerType: (*ProbeServer)(nil),
+		Streams:     []grpc.StreamDesc{desc},
+	}, srv)
+}
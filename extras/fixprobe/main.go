@@ -2,7 +2,6 @@
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -61,11 +60,8 @@ func main() {
 		log.Fatal(err)
 	}
 
-	buf, err := fixedReport.WriteBinary()
-	if err != nil {
-		log.Fatal(err)
-	}
+	published := appclient.NewPublishedReport(fixedReport)
 	for range time.Tick(*publishInterval) {
-		client.Publish(bytes.NewReader(buf.Bytes()), fixedReport.Shortcut)
+		client.Publish(published)
 	}
 }
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/render/detailed"
+)
+
+const (
+	defaultAnnotationTTL = 24 * time.Hour
+	maxAnnotationTTL     = 7 * 24 * time.Hour
+	maxAnnotations       = 1000 // per tenant
+)
+
+// AnnotationStore holds the annotations external systems, e.g. deployment
+// pipelines or incident tools, attach to nodes through the API, until they
+// expire. Each tenant has annotations of its own.
+type AnnotationStore struct {
+	mtx         sync.Mutex
+	nextID      int
+	annotations map[string]detailed.Annotation // by tenantID of the annotation
+}
+
+// NewAnnotationStore makes a new, empty, AnnotationStore.
+func NewAnnotationStore() *AnnotationStore {
+	return &AnnotationStore{annotations: map[string]detailed.Annotation{}}
+}
+
+// Add stores a for the tenant of ctx, valid for its TTL, or a day, and
+// returns it with its ID and expiry. TTLs are at most a week, and tenants
+// have at most maxAnnotations annotations.
+func (s *AnnotationStore) Add(ctx context.Context, a detailed.Annotation) (detailed.Annotation, error) {
+	if err := a.Validate(); err != nil {
+		return a, err
+	}
+	ttl := defaultAnnotationTTL
+	if a.TTL != "" {
+		ttl, _ = time.ParseDuration(a.TTL)
+	}
+	if ttl > maxAnnotationTTL {
+		return a, fmt.Errorf("ttl %q is over %v", a.TTL, maxAnnotationTTL)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.live(ctx)) >= maxAnnotations {
+		return a, fmt.Errorf("too many annotations: the most is %d", maxAnnotations)
+	}
+	s.nextID++
+	a.ID = strconv.Itoa(s.nextID)
+	a.Expires = mtime.Now().Add(ttl)
+	s.annotations[tenantID(ctx, a.ID)] = a
+	return a, nil
+}
+
+// Remove removes the annotation of the tenant of ctx with the given ID,
+// returning false if there is none.
+func (s *AnnotationStore) Remove(ctx context.Context, id string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.annotations[tenantID(ctx, id)]
+	delete(s.annotations, tenantID(ctx, id))
+	return ok
+}
+
+// Annotations returns the annotations of the tenant of ctx which haven't
+// expired, oldest first.
+func (s *AnnotationStore) Annotations(ctx context.Context) detailed.Annotations {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	result := s.live(ctx)
+	sort.Slice(result, func(i, j int) bool {
+		a, _ := strconv.Atoi(result[i].ID)
+		b, _ := strconv.Atoi(result[j].ID)
+		return a < b
+	})
+	return result
+}
+
+// live returns the annotations of the tenant of ctx which haven't expired,
+// dropping the others. It must be called with mtx held.
+func (s *AnnotationStore) live(ctx context.Context) detailed.Annotations {
+	now := mtime.Now()
+	prefix := tenantID(ctx, "")
+	result := detailed.Annotations{}
+	for key, a := range s.annotations {
+		if !a.Expires.After(now) {
+			delete(s.annotations, key)
+			continue
+		}
+		if key != prefix+a.ID {
+			continue // another tenant's
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+// RegisterAnnotationRoutes registers the routes to list, add and remove
+// annotations. Annotations can't be added or removed in a read-only app.
+func RegisterAnnotationRoutes(router *mux.Router, s *AnnotationStore, readOnly bool) {
+	router.Methods("GET").Path("/api/annotations").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			respondWith(w, http.StatusOK, s.Annotations(ctx))
+		}))
+	router.Methods("POST").Path("/api/annotations").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			if readOnly {
+				respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+				return
+			}
+			var a detailed.Annotation
+			if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&a); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+			a, err := s.Add(ctx, a)
+			if err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+			respondWith(w, http.StatusOK, a)
+		}))
+	router.Methods("DELETE").Path("/api/annotations/{id}").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			if readOnly {
+				respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+				return
+			}
+			if !s.Remove(ctx, mux.Vars(r)["id"]) {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+}
@@ -0,0 +1,656 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	opentracing "github.com/opentracing/opentracing-go"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	apiTopologyURL         = "/api/topology/"
+	processesID            = "processes"
+	processesByNameID      = "processes-by-name"
+	systemGroupID          = "system"
+	containersID           = "containers"
+	containersByHostnameID = "containers-by-hostname"
+	containersByImageID    = "containers-by-image"
+	podsID                 = "pods"
+	kubeControllersID      = "kube-controllers"
+	servicesID             = "services"
+	hostsID                = "hosts"
+	weaveID                = "weave"
+	ecsTasksID             = "ecs-tasks"
+	ecsServicesID          = "ecs-services"
+	swarmServicesID        = "swarm-services"
+)
+
+var (
+	topologyRegistry = MakeRegistry()
+	unmanagedFilter  = APITopologyOptionGroup{
+		ID:      "pseudo",
+		Default: "hide",
+		Options: []APITopologyOption{
+			{Value: "show", Label: "Show unmanaged", filter: nil, filterPseudo: false},
+			{Value: "hide", Label: "Hide unmanaged", filter: render.IsNotPseudo, filterPseudo: true},
+		},
+	}
+	storageFilter = APITopologyOptionGroup{
+		ID:      "storage",
+		Default: "hide",
+		Options: []APITopologyOption{
+			{Value: "show", Label: "Show storage", filter: nil, filterPseudo: false},
+			{Value: "hide", Label: "Hide storage", filter: render.IsPodComponent, filterPseudo: false},
+		},
+	}
+	snapshotFilter = APITopologyOptionGroup{
+		ID:      "snapshot",
+		Default: "hide",
+		Options: []APITopologyOption{
+			{Value: "show", Label: "Show snapshots", filter: nil, filterPseudo: false},
+			{Value: "hide", Label: "Hide snapshots", filter: render.IsNonSnapshotComponent, filterPseudo: false},
+		},
+	}
+)
+
+// namespaceFilters generates a namespace selector option group based on the given namespaces
+func namespaceFilters(namespaces []string, noneLabel string) APITopologyOptionGroup {
+	options := APITopologyOptionGroup{ID: "namespace", Default: "", SelectType: "union", NoneLabel: noneLabel}
+	for _, namespace := range namespaces {
+		options.Options = append(options.Options, APITopologyOption{
+			Value: namespace, Label: namespace, filter: render.IsNamespace(namespace), filterPseudo: false,
+		})
+	}
+	return options
+}
+
+// updateFilters updates the available filters based on the current report.
+func updateFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
+	topologies = updateKubeFilters(rpt, topologies)
+	topologies = updateSwarmFilters(rpt, topologies)
+	return topologies
+}
+
+func updateSwarmFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
+	namespaces := map[string]struct{}{}
+	for _, n := range rpt.SwarmService.Nodes {
+		if namespace, ok := n.Latest.Lookup(docker.StackNamespace); ok {
+			namespaces[namespace] = struct{}{}
+		}
+	}
+	if len(namespaces) == 0 {
+		// We only want to apply filters when we have swarm-related nodes,
+		// so if we don't then return early
+		return topologies
+	}
+	ns := []string{}
+	for namespace := range namespaces {
+		ns = append(ns, namespace)
+	}
+	topologies = append([]APITopologyDesc{}, topologies...) // Make a copy so we can make changes safely
+	for i, t := range topologies {
+		if t.id == containersID || t.id == swarmServicesID {
+			topologies[i] = mergeTopologyFilters(t, []APITopologyOptionGroup{
+				namespaceFilters(ns, "All Stacks"),
+			})
+		}
+	}
+	return topologies
+}
+
+func updateKubeFilters(rpt report.Report, topologies []APITopologyDesc) []APITopologyDesc {
+	ns := []string{}
+	for _, n := range rpt.Namespace.Nodes {
+		name, ok := n.Latest.Lookup(kubernetes.Name)
+		if !ok {
+			continue
+		}
+		ns = append(ns, name)
+	}
+	if len(ns) == 0 {
+		return topologies
+	}
+	sort.Strings(ns)
+	topologies = append([]APITopologyDesc{}, topologies...) // Make a copy so we can make changes safely
+	for i, t := range topologies {
+		if t.id == containersID || t.id == podsID || t.id == servicesID || t.id == kubeControllersID {
+			topologies[i] = mergeTopologyFilters(t, []APITopologyOptionGroup{
+				namespaceFilters(ns, "All Namespaces"),
+			})
+		}
+	}
+	return topologies
+}
+
+// mergeTopologyFilters recursively merges in new options on a topology description
+func mergeTopologyFilters(t APITopologyDesc, options []APITopologyOptionGroup) APITopologyDesc {
+	t.Options = append(append([]APITopologyOptionGroup{}, t.Options...), options...)
+	newSubTopologies := make([]APITopologyDesc, len(t.SubTopologies))
+	for i, sub := range t.SubTopologies {
+		newSubTopologies[i] = mergeTopologyFilters(sub, options)
+	}
+	t.SubTopologies = newSubTopologies
+	return t
+}
+
+// MakeAPITopologyOption provides an external interface to the package for creating an APITopologyOption.
+func MakeAPITopologyOption(value string, label string, filterFunc render.FilterFunc, pseudo bool) APITopologyOption {
+	return APITopologyOption{Value: value, Label: label, filter: filterFunc, filterPseudo: pseudo}
+}
+
+// Registry is a threadsafe store of the available topologies
+type Registry struct {
+	sync.RWMutex
+	items  map[string]APITopologyDesc
+	custom map[string]CustomTopology
+}
+
+// MakeRegistry returns a new Registry
+func MakeRegistry() *Registry {
+	registry := &Registry{
+		items:  map[string]APITopologyDesc{},
+		custom: map[string]CustomTopology{},
+	}
+	containerFilters := []APITopologyOptionGroup{
+		{
+			ID:      systemGroupID,
+			Default: "application",
+			Options: []APITopologyOption{
+				{Value: "all", Label: "All", filter: nil, filterPseudo: false},
+				{Value: "system", Label: "System containers", filter: render.IsSystem, filterPseudo: false},
+				{Value: "application", Label: "Application containers", filter: render.IsApplication, filterPseudo: false}},
+		},
+		{
+			ID:      "stopped",
+			Default: "running",
+			Options: []APITopologyOption{
+				{Value: "stopped", Label: "Stopped containers", filter: render.IsStopped, filterPseudo: false},
+				{Value: "running", Label: "Running containers", filter: render.IsRunning, filterPseudo: false},
+				{Value: "both", Label: "Both", filter: nil, filterPseudo: false},
+			},
+		},
+		{
+			ID:      "pseudo",
+			Default: "hide",
+			Options: []APITopologyOption{
+				{Value: "show", Label: "Show uncontained", filter: nil, filterPseudo: false},
+				{Value: "hide", Label: "Hide uncontained", filter: render.IsNotPseudo, filterPseudo: true},
+			},
+		},
+	}
+
+	proxiesFilter := APITopologyOptionGroup{
+		ID:      "proxies",
+		Default: "collapse",
+		Options: []APITopologyOption{
+			{Value: "collapse", Label: "Collapse mesh proxies", transformer: render.CollapseProxies},
+			{Value: "show", Label: "Show mesh proxies"},
+		},
+	}
+
+	unconnectedFilter := []APITopologyOptionGroup{
+		{
+			ID:      "unconnected",
+			Default: "hide",
+			Options: []APITopologyOption{
+				{Value: "show", Label: "Show unconnected", filter: nil, filterPseudo: false},
+				{Value: "hide", Label: "Hide unconnected", filter: render.IsConnected, filterPseudo: false},
+			},
+		},
+	}
+
+	// Topology option labels should tell the current state. The first item must
+	// be the verb to get to that state
+	registry.Add(
+		APITopologyDesc{
+			id:          processesID,
+			renderer:    render.ConnectedProcessRenderer,
+			Name:        "Processes",
+			Rank:        1,
+			Options:     unconnectedFilter,
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          processesByNameID,
+			parent:      processesID,
+			renderer:    render.ProcessNameRenderer,
+			Name:        "by name",
+			Options:     unconnectedFilter,
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:       containersID,
+			renderer: render.ContainerWithImageNameRenderer,
+			Name:     "Containers",
+			Rank:     2,
+			Options:  append(append([]APITopologyOptionGroup{}, containerFilters...), proxiesFilter),
+		},
+		APITopologyDesc{
+			id:       containersByHostnameID,
+			parent:   containersID,
+			renderer: render.ContainerHostnameRenderer,
+			Name:     "by DNS name",
+			Options:  containerFilters,
+		},
+		APITopologyDesc{
+			id:       containersByImageID,
+			parent:   containersID,
+			renderer: render.ContainerImageRenderer,
+			Name:     "by image",
+			Options:  containerFilters,
+		},
+		APITopologyDesc{
+			id:          podsID,
+			renderer:    render.PodNetworkPolicyRenderer,
+			Name:        "Pods",
+			Rank:        3,
+			Options:     []APITopologyOptionGroup{snapshotFilter, storageFilter, unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          kubeControllersID,
+			parent:      podsID,
+			renderer:    render.KubeControllerRenderer,
+			Name:        "Controllers",
+			Options:     []APITopologyOptionGroup{unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          servicesID,
+			parent:      podsID,
+			renderer:    render.PodServiceRenderer,
+			Name:        "Services",
+			Options:     []APITopologyOptionGroup{unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          ecsTasksID,
+			renderer:    render.ECSTaskRenderer,
+			Name:        "Tasks",
+			Rank:        3,
+			Options:     []APITopologyOptionGroup{unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          ecsServicesID,
+			parent:      ecsTasksID,
+			renderer:    render.ECSServiceRenderer,
+			Name:        "Services",
+			Options:     []APITopologyOptionGroup{unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:          swarmServicesID,
+			renderer:    render.SwarmServiceRenderer,
+			Name:        "Services",
+			Rank:        3,
+			Options:     []APITopologyOptionGroup{unmanagedFilter},
+			HideIfEmpty: true,
+		},
+		APITopologyDesc{
+			id:       hostsID,
+			renderer: render.HostRenderer,
+			Name:     "Hosts",
+			Rank:     4,
+		},
+		APITopologyDesc{
+			id:       weaveID,
+			parent:   hostsID,
+			renderer: render.WeaveRenderer,
+			Name:     "Weave Net",
+		},
+	)
+
+	return registry
+}
+
+// APITopologyDesc is returned in a list by the /api/topology handler.
+type APITopologyDesc struct {
+	id       string
+	parent   string
+	renderer render.Renderer
+
+	Name        string                   `json:"name"`
+	Rank        int                      `json:"rank"`
+	HideIfEmpty bool                     `json:"hide_if_empty"`
+	Options     []APITopologyOptionGroup `json:"options"`
+
+	URL           string            `json:"url"`
+	SubTopologies []APITopologyDesc `json:"sub_topologies,omitempty"`
+	Stats         topologyStats     `json:"stats,omitempty"`
+}
+
+type byName []APITopologyDesc
+
+func (a byName) Len() int           { return len(a) }
+func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// APITopologyOptionGroup describes a group of APITopologyOptions
+type APITopologyOptionGroup struct {
+	ID string `json:"id"`
+	// Default value for the option. Used if the value is omitted; not used if the value is ""
+	Default string              `json:"defaultValue"`
+	Options []APITopologyOption `json:"options,omitempty"`
+	// SelectType describes how options can be picked. Currently defined values:
+	//   "one": Default if empty. Exactly one option may be picked from the list.
+	//   "union": Any number of options may be picked. Nodes matching any option filter selected are displayed.
+	//           Value and Default should be a ","-separated list.
+	SelectType string `json:"selectType,omitempty"`
+	// For "union" type, this is the label the UI should use to represent the case where nothing is selected
+	NoneLabel string `json:"noneLabel,omitempty"`
+}
+
+// Get the transformer to use for this option group, if any, or nil otherwise.
+func (g APITopologyOptionGroup) transformer(value string) render.Transformer {
+	for _, opt := range g.Options {
+		if opt.Value == value {
+			return opt.transformer
+		}
+	}
+	return nil
+}
+
+// Get the render filters to use for this option group, if any, or nil otherwise.
+func (g APITopologyOptionGroup) filter(value string) render.FilterFunc {
+	var values []string
+	switch g.SelectType {
+	case "", "one":
+		values = []string{value}
+	case "union":
+		values = strings.Split(value, ",")
+	default:
+		log.Errorf("Invalid select type %s for option group %s, ignoring option", g.SelectType, g.ID)
+		return nil
+	}
+	filters := []render.FilterFunc{}
+	for _, opt := range g.Options {
+		for _, v := range values {
+			if v != opt.Value {
+				continue
+			}
+			var filter render.FilterFunc
+			if opt.filter == nil {
+				// No filter means match everything (pseudo doesn't matter)
+				filter = func(n report.Node) bool { return true }
+			} else if opt.filterPseudo {
+				// Apply filter to pseudo topologies also
+				filter = opt.filter
+			} else {
+				// Allow all pseudo topology nodes, only apply filter to non-pseudo
+				filter = render.AnyFilterFunc(render.IsPseudoTopology, opt.filter)
+			}
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return render.AnyFilterFunc(filters...)
+}
+
+// APITopologyOption describes a &param=value to a given topology.
+type APITopologyOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+
+	filter       render.FilterFunc
+	filterPseudo bool
+	transformer  render.Transformer // applied before the filters, if any
+}
+
+type topologyStats struct {
+	NodeCount          int `json:"node_count"`
+	NonpseudoNodeCount int `json:"nonpseudo_node_count"`
+	EdgeCount          int `json:"edge_count"`
+	FilteredNodes      int `json:"filtered_nodes"`
+}
+
+// deserializeTimestamp converts the ISO8601 query param into a proper timestamp.
+func deserializeTimestamp(timestamp string) (time.Time, error) {
+	if timestamp != "" {
+		result, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Error parsing timestamp '%s' - make sure the time format is RFC3339", timestamp)
+		}
+		return result, nil
+	}
+	// Default to current time if no timestamp is provided.
+	return time.Now(), nil
+}
+
+// AddContainerFilters adds to the default Registry (topologyRegistry)'s containerFilters
+func AddContainerFilters(newFilters ...APITopologyOption) {
+	topologyRegistry.AddContainerFilters(newFilters...)
+}
+
+// AddContainerFilters adds container filters to this Registry
+func (r *Registry) AddContainerFilters(newFilters ...APITopologyOption) {
+	r.Lock()
+	defer r.Unlock()
+	for _, key := range []string{containersID, containersByHostnameID, containersByImageID} {
+		for i := range r.items[key].Options {
+			if r.items[key].Options[i].ID == systemGroupID {
+				r.items[key].Options[i].Options = append(r.items[key].Options[i].Options, newFilters...)
+				break
+			}
+		}
+	}
+}
+
+// Add inserts a topologyDesc to the Registry's items map
+func (r *Registry) Add(ts ...APITopologyDesc) {
+	r.Lock()
+	defer r.Unlock()
+	r.add(ts...)
+}
+
+func (r *Registry) add(ts ...APITopologyDesc) {
+	for _, t := range ts {
+		t.URL = apiTopologyURL + t.id
+		t.renderer = render.Memoise(t.renderer)
+
+		if t.parent != "" {
+			parent := r.items[t.parent]
+			parent.SubTopologies = append(parent.SubTopologies, t)
+			r.items[t.parent] = parent
+		}
+
+		r.items[t.id] = t
+	}
+}
+
+func (r *Registry) get(name string) (APITopologyDesc, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	t, ok := r.items[name]
+	return t, ok
+}
+
+// ids returns the IDs of all the topologies, sub-topologies included, in
+// order.
+func (r *Registry) ids() []string {
+	r.RLock()
+	defer r.RUnlock()
+	ids := make([]string, 0, len(r.items))
+	for id := range r.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (r *Registry) walk(f func(APITopologyDesc)) {
+	r.RLock()
+	defer r.RUnlock()
+	descs := []APITopologyDesc{}
+	for _, desc := range r.items {
+		if desc.parent != "" {
+			continue
+		}
+		descs = append(descs, desc)
+	}
+	sort.Sort(byName(descs))
+	for _, desc := range descs {
+		f(desc)
+	}
+}
+
+// makeTopologyList returns a handler that yields an APITopologyList.
+func (r *Registry) makeTopologyList(rep Reporter) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+		timestamp, err := deserializeTimestamp(req.URL.Query().Get("timestamp"))
+		if err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		// The query filters the stats of every topology the same way
+		if query := req.URL.Query().Get("query"); query != "" {
+			if _, err := render.ParseQuery(query); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		report, err := rep.Report(ctx, timestamp)
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		respondWith(w, http.StatusOK, r.renderTopologies(ctx, report, req))
+	}
+}
+
+func (r *Registry) renderTopologies(ctx context.Context, rpt report.Report, req *http.Request) []APITopologyDesc {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "app.renderTopologies")
+	defer span.Finish()
+	topologies := []APITopologyDesc{}
+	req.ParseForm()
+	r.walk(func(desc APITopologyDesc) {
+		renderer, filter, _ := r.RendererForTopology(desc.id, req.Form, rpt)
+		desc.Stats = computeStats(ctx, rpt, renderer, filter)
+		for i, sub := range desc.SubTopologies {
+			renderer, filter, _ := r.RendererForTopology(sub.id, req.Form, rpt)
+			desc.SubTopologies[i].Stats = computeStats(ctx, rpt, renderer, filter)
+		}
+		topologies = append(topologies, desc)
+	})
+	return updateFilters(rpt, topologies)
+}
+
+func computeStats(ctx context.Context, rpt report.Report, renderer render.Renderer, transformer render.Transformer) topologyStats {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "app.computeStats")
+	defer span.Finish()
+	var (
+		nodes     int
+		realNodes int
+		edges     int
+	)
+	r := render.Render(ctx, rpt, renderer, transformer)
+	for _, n := range r.Nodes {
+		nodes++
+		if n.Topology != render.Pseudo {
+			realNodes++
+		}
+		edges += len(n.Adjacency)
+	}
+	return topologyStats{
+		NodeCount:          nodes,
+		NonpseudoNodeCount: realNodes,
+		EdgeCount:          edges,
+		FilteredNodes:      r.Filtered,
+	}
+}
+
+// RendererForTopology ..
+func (r *Registry) RendererForTopology(topologyID string, values url.Values, rpt report.Report) (render.Renderer, render.Transformer, error) {
+	topology, ok := r.get(topologyID)
+	if !ok {
+		return nil, nil, fmt.Errorf("topology not found: %s", topologyID)
+	}
+	topology = updateFilters(rpt, []APITopologyDesc{topology})[0]
+
+	if len(values) == 0 {
+		// if no options where provided, only apply base filter
+		return topology.renderer, render.FilterUnconnectedPseudo, nil
+	}
+
+	var (
+		transformers []render.Transformer
+		filters      []render.FilterFunc
+	)
+	for _, group := range topology.Options {
+		value := group.Default
+		if vs := values[group.ID]; len(vs) > 0 {
+			value = vs[0]
+		}
+		if transformer := group.transformer(value); transformer != nil {
+			transformers = append(transformers, transformer)
+		}
+		if filter := group.filter(value); filter != nil {
+			filters = append(filters, filter)
+		}
+	}
+	if query := values.Get("query"); query != "" {
+		filter, err := render.ParseQuery(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) > 0 {
+		transformers = append(transformers, render.ComposeFilterFuncs(filters...))
+	}
+	if len(transformers) > 0 {
+		return topology.renderer, render.Transformers(append(transformers, render.FilterUnconnectedPseudo)), nil
+	}
+	return topology.renderer, render.FilterUnconnectedPseudo, nil
+}
+
+type reporterHandler func(context.Context, Reporter, http.ResponseWriter, *http.Request)
+
+func captureReporter(rep Reporter, f reporterHandler) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		f(ctx, rep, w, r)
+	}
+}
+
+func (r *Registry) captureRenderer(rep Reporter, f rendererHandler) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, req *http.Request) {
+		topologyID := mux.Vars(req)["topology"]
+		if _, ok := r.get(topologyID); !ok {
+			http.NotFound(w, req)
+			return
+		}
+		timestamp, err := deserializeTimestamp(req.URL.Query().Get("timestamp"))
+		if err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		rpt, err := rep.Report(ctx, timestamp)
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		req.ParseForm()
+		renderer, filter, err := r.RendererForTopology(topologyID, req.Form, rpt)
+		if err != nil {
+			// The topology is there, so it's the query
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		f(ctx, renderer, filter, RenderContextForReporter(ctx, rep, rpt), w, req)
+	}
+}
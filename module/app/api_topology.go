@@ -0,0 +1,370 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"context"
+
+	"github.com/bluele/gcache"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	websocketLoop = 1 * time.Second
+
+	layoutsSize       = 1024
+	layoutsExpiration = 10 * time.Minute
+)
+
+var (
+	renderDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "render_duration_seconds",
+		Help:      "Time in seconds spent rendering topologies.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topology"})
+	websocketClients = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "websocket_clients",
+		Help:      "Number of clients connected to the topology websocket.",
+	})
+
+	// layouts are the last layouts of the topologies, by tenant and
+	// options, for the next ones to start from.
+	layouts = gcache.New(layoutsSize).LRU().Expiration(layoutsExpiration).Build()
+)
+
+func init() {
+	prometheus.MustRegister(renderDuration)
+	prometheus.MustRegister(websocketClients)
+}
+
+// renderTopology renders a topology, keeping track of how long it takes.
+func renderTopology(ctx context.Context, topologyID string, rpt report.Report, renderer render.Renderer, transformer render.Transformer) render.Nodes {
+	defer func(begin time.Time) {
+		renderDuration.WithLabelValues(topologyID).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return render.Render(ctx, rpt, renderer, transformer)
+}
+
+// layoutTopology lays out the nodes of a topology, starting from its last
+// layout with the same options, if any, so nodes stay where they were.
+func layoutTopology(ctx context.Context, topologyID string, r *http.Request, nodes detailed.NodeSummaries) detailed.Layout {
+	options := url.Values{}
+	for k, v := range r.URL.Query() {
+		switch k {
+		case "layout", "timestamp", "t":
+		default:
+			options[k] = v
+		}
+	}
+	key := tenantID(ctx, topologyID+"?"+options.Encode())
+	var layout detailed.Layout
+	if val, err := layouts.Get(key); err == nil {
+		layout = val.(detailed.Layout)
+	}
+	layout = layout.Update(nodes)
+	layouts.Set(key, layout)
+	return layout
+}
+
+// wantsLayout tells whether the request asks for the layout of the topology.
+func wantsLayout(r *http.Request) bool {
+	return r.URL.Query().Get("layout") == "true"
+}
+
+// APITopology is returned by the /api/topology/{name} handler.
+type APITopology struct {
+	Nodes  detailed.NodeSummaries       `json:"nodes"`
+	Layout map[string]detailed.Position `json:"layout,omitempty"`
+}
+
+// APINode is returned by the /api/topology/{name}/{id} handler.
+type APINode struct {
+	Node detailed.Node `json:"node"`
+}
+
+// RenderTopologies renders the topologies with the given IDs, or all of
+// them if there are none, out of a report, as the API would with the
+// options in values. It is for rendering saved reports offline.
+func RenderTopologies(ctx context.Context, rpt report.Report, topologyIDs []string, values url.Values) (map[string]APITopology, error) {
+	if len(topologyIDs) == 0 {
+		topologyIDs = topologyRegistry.ids()
+	}
+	rc := detailed.RenderContext{Report: rpt}
+	result := map[string]APITopology{}
+	for _, topologyID := range topologyIDs {
+		renderer, transformer, err := topologyRegistry.RendererForTopology(topologyID, values, rpt)
+		if err != nil {
+			return nil, err
+		}
+		result[topologyID] = APITopology{
+			Nodes: detailed.Summaries(ctx, rc, renderTopology(ctx, topologyID, rpt, renderer, transformer).Nodes),
+		}
+	}
+	return result, nil
+}
+
+// RenderContextForReporter creates the rendering context for the given reporter.
+func RenderContextForReporter(ctx context.Context, rep Reporter, r report.Report) detailed.RenderContext {
+	rc := detailed.RenderContext{Report: r}
+	if wrep, ok := rep.(WebReporter); ok {
+		rc.MetricsGraphURL = wrep.MetricsGraphURL
+		rc.ReadOnly = wrep.ReadOnly
+		if wrep.Health != nil {
+			rc.HealthRules = wrep.Health.Rules()
+		}
+		if wrep.Annotations != nil {
+			rc.Annotations = wrep.Annotations.Annotations(ctx)
+		}
+	}
+	return rc
+}
+
+type rendererHandler func(context.Context, render.Renderer, render.Transformer, detailed.RenderContext, http.ResponseWriter, *http.Request)
+
+// Full topology.
+func handleTopology(ctx context.Context, renderer render.Renderer, transformer render.Transformer, rc detailed.RenderContext, w http.ResponseWriter, r *http.Request) {
+	censorCfg := report.GetCensorConfigFromRequest(r)
+	topologyID := mux.Vars(r)["topology"]
+	nodeSummaries := detailed.Summaries(ctx, rc, renderTopology(ctx, topologyID, rc.Report, renderer, transformer).Nodes)
+	topology := APITopology{
+		Nodes: detailed.CensorNodeSummaries(nodeSummaries, censorCfg),
+	}
+	if wantsLayout(r) {
+		topology.Layout = layoutTopology(ctx, topologyID, r, topology.Nodes).Positions
+	}
+	respondWith(w, http.StatusOK, topology)
+}
+
+// Individual nodes.
+func handleNode(ctx context.Context, renderer render.Renderer, transformer render.Transformer, rc detailed.RenderContext, w http.ResponseWriter, r *http.Request) {
+	var (
+		censorCfg  = report.GetCensorConfigFromRequest(r)
+		vars       = mux.Vars(r)
+		topologyID = vars["topology"]
+		nodeID     = vars["id"]
+	)
+	node, ok := renderNode(ctx, topologyID, nodeID, renderer, transformer, rc)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	respondWith(w, http.StatusOK, APINode{Node: detailed.CensorNode(node, censorCfg)})
+}
+
+// renderNode renders the details of a node, or returns false if there is no
+// such node.
+func renderNode(ctx context.Context, topologyID, nodeID string, renderer render.Renderer, transformer render.Transformer, rc detailed.RenderContext) (detailed.Node, bool) {
+	// We must not lose the node during filtering. We achieve that by
+	// (1) rendering the report with the base renderer, without
+	// filtering, which gives us the node (if it exists at all), and
+	// then (2) applying the filter separately to that result.  If the
+	// node is lost in the second step, we simply put it back.
+	nodes := renderer.Render(ctx, rc.Report)
+	node, ok := nodes.Nodes[nodeID]
+	if !ok {
+		return detailed.Node{}, false
+	}
+	nodes = transformer.Transform(nodes)
+	if filteredNode, ok := nodes.Nodes[nodeID]; ok {
+		node = filteredNode
+	} else { // we've lost the node during filtering; put it back
+		nodes.Nodes[nodeID] = node
+		nodes.Filtered--
+	}
+	return detailed.MakeNode(topologyID, rc, nodes.Nodes, node), true
+}
+
+// Websocket for the full topology.
+func handleWebsocket(
+	ctx context.Context,
+	rep Reporter,
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var (
+		previousTopo   detailed.NodeSummaries
+		previousLayout detailed.Layout
+		topologyID     = mux.Vars(r)["topology"]
+		censorCfg      = report.GetCensorConfigFromRequest(r)
+	)
+	serveWebsocket(ctx, rep, w, r, func(re report.Report) (interface{}, bool, error) {
+		renderer, filter, err := topologyRegistry.RendererForTopology(topologyID, r.Form, re)
+		if err != nil {
+			return nil, false, err
+		}
+		newTopo := detailed.CensorNodeSummaries(
+			detailed.Summaries(
+				ctx,
+				RenderContextForReporter(ctx, rep, re),
+				renderTopology(ctx, topologyID, re, renderer, filter).Nodes,
+			),
+			censorCfg,
+		)
+		diff := detailed.TopoDiff(previousTopo, newTopo)
+		previousTopo = newTopo
+		if wantsLayout(r) {
+			layout := layoutTopology(ctx, topologyID, r, newTopo)
+			diff.Layout = layout.Changes(previousLayout)
+			previousLayout = layout
+		}
+		return diff, true, nil
+	})
+}
+
+// APINodeUpdate is sent by the /api/topology/{name}/{id}/ws websocket
+// whenever the node changes.
+type APINodeUpdate struct {
+	Node    *detailed.Node `json:"node,omitempty"`
+	Removed bool           `json:"removed,omitempty"`
+}
+
+// Websocket for an individual node.
+func handleNodeWebsocket(
+	ctx context.Context,
+	rep Reporter,
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var (
+		previousNode *detailed.Node
+		sentRemoved  bool
+		vars         = mux.Vars(r)
+		topologyID   = vars["topology"]
+		nodeID       = vars["id"]
+		censorCfg    = report.GetCensorConfigFromRequest(r)
+	)
+	serveWebsocket(ctx, rep, w, r, func(re report.Report) (interface{}, bool, error) {
+		renderer, filter, err := topologyRegistry.RendererForTopology(topologyID, r.Form, re)
+		if err != nil {
+			return nil, false, err
+		}
+		node, ok := renderNode(ctx, topologyID, nodeID, renderer, filter, RenderContextForReporter(ctx, rep, re))
+		if !ok {
+			// Only say so once, and from then on wait for the node to come back
+			previousNode = nil
+			if sentRemoved {
+				return nil, false, nil
+			}
+			sentRemoved = true
+			return APINodeUpdate{Removed: true}, true, nil
+		}
+		sentRemoved = false
+		node = detailed.CensorNode(node, censorCfg)
+		if previousNode != nil && reflect.DeepEqual(*previousNode, node) {
+			return nil, false, nil
+		}
+		previousNode = &node
+		return APINodeUpdate{Node: &node}, true, nil
+	})
+}
+
+// serveWebsocket upgrades the request to a websocket, and then sends it
+// the message it gets from update for every new report, if update says to
+// send one, until the client goes away.
+func serveWebsocket(
+	ctx context.Context,
+	rep Reporter,
+	w http.ResponseWriter,
+	r *http.Request,
+	update func(report.Report) (message interface{}, send bool, err error),
+) {
+	if err := r.ParseForm(); err != nil {
+		respondWith(w, http.StatusInternalServerError, err)
+		return
+	}
+	loop := websocketLoop
+	if t := r.Form.Get("t"); t != "" {
+		var err error
+		if loop, err = time.ParseDuration(t); err != nil {
+			respondWith(w, http.StatusBadRequest, t)
+			return
+		}
+	}
+	startReportingAt, err := deserializeTimestamp(r.Form.Get("timestamp"))
+	if err != nil {
+		respondWith(w, http.StatusBadRequest, err)
+		return
+	}
+
+	conn, err := xfer.Upgrade(w, r, nil)
+	if err != nil {
+		// log.Info("Upgrade:", err)
+		return
+	}
+	defer conn.Close()
+	websocketClients.Inc()
+	defer websocketClients.Dec()
+
+	quit := make(chan struct{})
+	go func(c xfer.Websocket) {
+		for { // just discard everything the browser sends
+			if _, _, err := c.ReadMessage(); err != nil {
+				if !xfer.IsExpectedWSCloseError(err) {
+					log.Error("err:", err)
+				}
+				close(quit)
+				break
+			}
+		}
+	}(conn)
+
+	var (
+		tick            = time.Tick(loop)
+		wait            = make(chan struct{}, 1)
+		channelOpenedAt = time.Now()
+	)
+
+	rep.WaitOn(ctx, wait)
+	defer rep.UnWait(ctx, wait)
+
+	for {
+		// We measure how much time has passed since the channel was opened
+		// and add it to the initial report timestamp to get the timestamp
+		// of the snapshot we want to report right now.
+		// NOTE: Multiplying `timestampDelta` by a constant factor here
+		// would have an effect of fast-forward, which is something we
+		// might be interested in implementing in the future.
+		timestampDelta := time.Since(channelOpenedAt)
+		reportTimestamp := startReportingAt.Add(timestampDelta)
+		re, err := rep.Report(ctx, reportTimestamp)
+		if err != nil {
+			log.Errorf("Error generating report: %v", err)
+			return
+		}
+		message, send, err := update(re)
+		if err != nil {
+			log.Errorf("Error generating report: %v", err)
+			return
+		}
+
+		if send {
+			if err := conn.WriteJSON(message); err != nil {
+				if !xfer.IsExpectedWSCloseError(err) {
+					log.Errorf("cannot serialize update: %s", err)
+				}
+				return
+			}
+		}
+
+		select {
+		case <-wait:
+		case <-tick:
+		case <-quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package app_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+func TestAPITopologyDiff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	// Nothing at first, then the fixture
+	store := app.NewMemoryReportStore(time.Hour)
+	collector := app.NewClusteredCollector(store, time.Second, time.Hour)
+	before, after := now.Add(-time.Minute), now
+	collector.Add(app.WithReportTimestamp(ctx, before), report.MakeReport(), nil)
+	collector.Add(app.WithReportTimestamp(ctx, after), fixture.Report, nil)
+
+	router := mux.NewRouter().SkipClean(true)
+	app.RegisterTopologyRoutes(router, collector, nil)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	is404(t, ts, "/api/topology/foobar/diff?from="+url.QueryEscape(before.Format(time.RFC3339)))
+	is400(t, ts, "/api/topology/containers/diff")
+	is400(t, ts, "/api/topology/containers/diff?from=yesterday")
+	is400(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(after.Format(time.RFC3339))+"&to="+url.QueryEscape(before.Format(time.RFC3339)))
+
+	var diff app.APITopologyDiff
+	_, body := checkGet(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(before.Format(time.RFC3339))+"&to="+url.QueryEscape(after.Format(time.RFC3339)))
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Add) == 0 || len(diff.Nodes.Update) != 0 || len(diff.Nodes.Remove) != 0 {
+		t.Errorf("Expected only added nodes, got %v", diff.Nodes)
+	}
+	edge := detailed.Edge{Source: fixture.ClientContainerNodeID, Target: fixture.ServerContainerNodeID}
+	found := false
+	for _, e := range diff.Edges.Add {
+		found = found || e == edge
+	}
+	if !found || len(diff.Edges.Remove) != 0 {
+		t.Errorf("Expected the edge %v to be added, got %v", edge, diff.Edges)
+	}
+
+	// Nothing changed since the cursor
+	_, body = checkGet(t, ts, "/api/topology/containers/diff?from="+url.QueryEscape(diff.Cursor)+"&to="+url.QueryEscape(after.Format(time.RFC3339)))
+	diff = app.APITopologyDiff{}
+	if err := codec.NewDecoderBytes(body, &codec.JsonHandle{}).Decode(&diff); err != nil {
+		t.Fatalf("JSON parse error: %s", err)
+	}
+	if len(diff.Nodes.Add)+len(diff.Nodes.Update)+len(diff.Nodes.Remove)+len(diff.Edges.Add)+len(diff.Edges.Remove) != 0 {
+		t.Errorf("Expected no changes, got %v", diff)
+	}
+}
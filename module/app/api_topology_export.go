@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// topologyExporters serialize the summaries of the nodes of a topology, by
+// the format they are asked for with.
+var topologyExporters = map[string]struct {
+	contentType string
+	export      func(topologyID string, nodes []detailed.NodeSummary) ([]byte, error)
+}{
+	"dot":     {"text/vnd.graphviz", exportDOT},
+	"graphml": {"application/graphml+xml", exportGraphML},
+	"csv":     {"text/csv", exportCSV},
+}
+
+// Export of the full topology, as a graph.
+func handleTopologyExport(ctx context.Context, renderer render.Renderer, transformer render.Transformer, rc detailed.RenderContext, w http.ResponseWriter, r *http.Request) {
+	format := r.FormValue("format")
+	exporter, ok := topologyExporters[format]
+	if !ok {
+		respondWith(w, http.StatusBadRequest, fmt.Errorf("unknown export format %q; expected dot, graphml or csv", format))
+		return
+	}
+	topologyID := mux.Vars(r)["topology"]
+	summaries := detailed.CensorNodeSummaries(
+		detailed.Summaries(ctx, rc, renderTopology(ctx, topologyID, rc.Report, renderer, transformer).Nodes),
+		report.GetCensorConfigFromRequest(r),
+	)
+	buf, err := exporter.export(topologyID, sortedSummaries(summaries))
+	if err != nil {
+		respondWith(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", exporter.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", topologyID+"."+format))
+	w.Write(buf)
+}
+
+// sortedSummaries returns the summaries by ID, with only the adjacencies
+// to the nodes among them, so that exports of the same nodes are the same.
+func sortedSummaries(summaries detailed.NodeSummaries) []detailed.NodeSummary {
+	result := make([]detailed.NodeSummary, 0, len(summaries))
+	for _, s := range summaries {
+		adjacency := report.MakeIDList()
+		for _, id := range s.Adjacency {
+			if _, ok := summaries[id]; ok {
+				adjacency = adjacency.Add(id)
+			}
+		}
+		s.Adjacency = adjacency
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func exportDOT(topologyID string, nodes []detailed.NodeSummary) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %s {\n", strconv.Quote(topologyID))
+	for _, n := range nodes {
+		fmt.Fprintf(&buf, "\t%s [label=%s", strconv.Quote(n.ID), strconv.Quote(n.Label))
+		if n.LabelMinor != "" {
+			fmt.Fprintf(&buf, ", tooltip=%s", strconv.Quote(n.LabelMinor))
+		}
+		if n.Pseudo {
+			buf.WriteString(", style=dashed")
+		}
+		buf.WriteString("];\n")
+	}
+	for _, n := range nodes {
+		for _, id := range n.Adjacency {
+			fmt.Fprintf(&buf, "\t%s -> %s;\n", strconv.Quote(n.ID), strconv.Quote(id))
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func exportGraphML(topologyID string, nodes []detailed.NodeSummary) ([]byte, error) {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "labelMinor", For: "node", Name: "labelMinor", Type: "string"},
+			{ID: "rank", For: "node", Name: "rank", Type: "string"},
+			{ID: "shape", For: "node", Name: "shape", Type: "string"},
+			{ID: "pseudo", For: "node", Name: "pseudo", Type: "boolean"},
+		},
+		Graph: graphMLGraph{ID: topologyID, EdgeDefault: "directed"},
+	}
+	for _, n := range nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: n.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: n.Label},
+				{Key: "labelMinor", Value: n.LabelMinor},
+				{Key: "rank", Value: n.Rank},
+				{Key: "shape", Value: n.Shape},
+				{Key: "pseudo", Value: strconv.FormatBool(n.Pseudo)},
+			},
+		})
+		for _, id := range n.Adjacency {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: n.ID, Target: id})
+		}
+	}
+	buf, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(buf, '\n')...), nil
+}
+
+// exportCSV writes a row per node, with the IDs of the nodes it is
+// connected to separated by spaces.
+func exportCSV(_ string, nodes []detailed.NodeSummary) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"id", "label", "label_minor", "rank", "shape", "pseudo", "adjacency"})
+	for _, n := range nodes {
+		writer.Write([]string{n.ID, n.Label, n.LabelMinor, n.Rank, n.Shape, strconv.FormatBool(n.Pseudo), strings.Join(n.Adjacency, " ")})
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
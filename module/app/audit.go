@@ -0,0 +1,228 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+const (
+	auditTimeout     = 5 * time.Second
+	auditQueueSize   = 1024
+	defaultAuditSize = 1000
+)
+
+// AuditEntry records a control invocation.
+type AuditEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+	ProbeID    string    `json:"probeId"`
+	NodeID     string    `json:"nodeId"`
+	Control    string    `json:"control"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AuditSink is where audit entries go to be kept.
+type AuditSink interface {
+	Record(AuditEntry) error
+}
+
+// NewFileAuditSink appends audit entries to a file, as lines of JSON.
+func NewFileAuditSink(path string) (AuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuditSink{f: f}, nil
+}
+
+type fileAuditSink struct {
+	sync.Mutex
+	f *os.File
+}
+
+func (s *fileAuditSink) Record(entry AuditEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err = s.f.Write(append(buf, '\n'))
+	return err
+}
+
+// NewWebhookAuditSink POSTs each audit entry, as JSON, to url. Entries are
+// posted one at a time in the background, for a slow webhook not to hold up
+// the controls; they are dropped if too many are waiting already.
+func NewWebhookAuditSink(url string) AuditSink {
+	s := &webhookAuditSink{
+		url:     url,
+		client:  &http.Client{Timeout: auditTimeout},
+		entries: make(chan AuditEntry, auditQueueSize),
+	}
+	go s.loop()
+	return s
+}
+
+type webhookAuditSink struct {
+	url     string
+	client  *http.Client
+	entries chan AuditEntry
+}
+
+func (s *webhookAuditSink) Record(entry AuditEntry) error {
+	select {
+	case s.entries <- entry:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook: too many entries waiting to be posted")
+	}
+}
+
+func (s *webhookAuditSink) loop() {
+	for entry := range s.entries {
+		if err := s.post(entry); err != nil {
+			log.Errorf("Error recording audit entry: %v", err)
+		}
+	}
+}
+
+func (s *webhookAuditSink) post(entry AuditEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("audit webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// AuditLog keeps the latest control invocations in memory, to be queried,
+// and hands them all to its sink, if any.
+type AuditLog struct {
+	sink AuditSink
+
+	mtx     sync.Mutex
+	size    int
+	entries []AuditEntry // oldest first
+}
+
+// NewAuditLog makes a new AuditLog. sink may be nil.
+func NewAuditLog(sink AuditSink) *AuditLog {
+	return &AuditLog{sink: sink, size: defaultAuditSize}
+}
+
+// Record adds an entry to the audit log.
+func (a *AuditLog) Record(entry AuditEntry) {
+	a.mtx.Lock()
+	a.entries = append(a.entries, entry)
+	if len(a.entries) > a.size {
+		a.entries = a.entries[len(a.entries)-a.size:]
+	}
+	a.mtx.Unlock()
+
+	if a.sink != nil {
+		if err := a.sink.Record(entry); err != nil {
+			log.Errorf("Error recording audit entry: %v", err)
+		}
+	}
+}
+
+// Entries returns the latest entries in the audit log matching filter,
+// newest first, and at most limit of them if limit is positive.
+func (a *AuditLog) Entries(filter func(AuditEntry) bool, limit int) []AuditEntry {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	result := []AuditEntry{}
+	for i := len(a.entries) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		if filter(a.entries[i]) {
+			result = append(result, a.entries[i])
+		}
+	}
+	return result
+}
+
+// AuditingControlRouter records every control request routed through cr
+// in the audit log.
+func AuditingControlRouter(cr ControlRouter, audit *AuditLog) ControlRouter {
+	return auditingControlRouter{cr, audit}
+}
+
+type auditingControlRouter struct {
+	ControlRouter
+	audit *AuditLog
+}
+
+func (a auditingControlRouter) Handle(ctx context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	res, err := a.ControlRouter.Handle(ctx, probeID, req)
+	entry := AuditEntry{
+		Timestamp: time.Now(),
+		User:      AuthUser(ctx),
+		Tenant:    AuthTenant(ctx),
+		ProbeID:   probeID,
+		NodeID:    req.NodeID,
+		Control:   req.Control,
+	}
+	if r, ok := ctx.Value(RequestCtxKey).(*http.Request); ok {
+		entry.RemoteAddr = r.RemoteAddr
+		if entry.User == "" {
+			entry.User, _, _ = r.BasicAuth()
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if res.Error != "" {
+		entry.Error = res.Error
+	}
+	a.audit.Record(entry)
+	return res, err
+}
+
+// RegisterAuditRoutes registers the route to query the audit log, which
+// takes the optional parameters user, node, control and limit. Only the
+// entries of the tenant of the request are returned.
+func RegisterAuditRoutes(router *mux.Router, audit *AuditLog) {
+	router.Methods("GET").Path("/api/audit").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			query   = r.URL.Query()
+			user    = query.Get("user")
+			node    = query.Get("node")
+			control = query.Get("control")
+			tenant  = AuthTenant(r.Context())
+			limit   = 0
+		)
+		if l := query.Get("limit"); l != "" {
+			var err error
+			if limit, err = strconv.Atoi(l); err != nil {
+				respondWith(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		respondWith(w, http.StatusOK, audit.Entries(func(e AuditEntry) bool {
+			return e.Tenant == tenant &&
+				(user == "" || e.User == user) &&
+				(node == "" || e.NodeID == node) &&
+				(control == "" || e.Control == control)
+		}, limit))
+	})
+}
@@ -0,0 +1,249 @@
+package app
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"context"
+)
+
+const (
+	// AuthCookie holds the bearer token of users authenticated in a
+	// browser, which cannot set headers on websockets.
+	AuthCookie = "scope_token"
+
+	// AuthQueryParam lets users hand their bearer token over in a URL,
+	// e.g. the first time they open the UI; it is then kept in AuthCookie.
+	AuthQueryParam = "access_token"
+
+	allControls = "*"
+
+	userCtxKey   contextKey = contextKey("user")
+	tenantCtxKey contextKey = contextKey("tenant")
+)
+
+// AuthUser returns the name of the user the request in ctx was
+// authenticated as, if any.
+func AuthUser(ctx context.Context) string {
+	name, _ := ctx.Value(userCtxKey).(string)
+	return name
+}
+
+// AuthTenant returns the tenant the request in ctx was authenticated for:
+// that of the probe or user token it carries, or "" if there are no
+// tenants.
+func AuthTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantCtxKey).(string)
+	return tenant
+}
+
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey, tenant)
+}
+
+// Auth authenticates the requests made to the app: probes with the probe
+// tokens, and users with bearer tokens. It also restricts which controls
+// each user may invoke, and tells which tenant each token belongs to.
+type Auth struct {
+	probeTokens map[string]string   // tenant, by token
+	users       map[string]authUser // by token
+	tenants     bool
+}
+
+type authUser struct {
+	name     string
+	tenant   string
+	controls map[string]struct{}
+}
+
+func (u authUser) mayInvoke(control string) bool {
+	if _, ok := u.controls[allControls]; ok {
+		return true
+	}
+	_, ok := u.controls[control]
+	return ok
+}
+
+// LoadAuth reads the tokens from a file, with one per line, either as
+//
+//	probe <token>
+//
+// or
+//
+//	user <token> <name> [<control>,...]
+//
+// where the controls are those the user may invoke, by ID (e.g.
+// docker_exec_container), or * for all of them. Users without controls
+// only get to look. Blank lines and lines starting with # are skipped.
+//
+// A line
+//
+//	tenant <name>
+//
+// puts the tokens after it, up to the next tenant line, in that tenant:
+// probes only publish into the reports of their tenant, and users only
+// see those, and only reach the probes of their tenant with controls and
+// pipes.
+func LoadAuth(path string) (*Auth, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	auth := &Auth{
+		probeTokens: map[string]string{},
+		users:       map[string]authUser{},
+	}
+	tenant := ""
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch {
+		case fields[0] == "tenant" && len(fields) == 2:
+			tenant = fields[1]
+			auth.tenants = true
+		case fields[0] == "probe" && len(fields) == 2:
+			auth.probeTokens[fields[1]] = tenant
+		case fields[0] == "user" && (len(fields) == 3 || len(fields) == 4):
+			user := authUser{name: fields[2], tenant: tenant, controls: map[string]struct{}{}}
+			if len(fields) == 4 {
+				for _, control := range strings.Split(fields[3], ",") {
+					user.controls[control] = struct{}{}
+				}
+			}
+			auth.users[fields[1]] = user
+		default:
+			return nil, fmt.Errorf("%s:%d: expected 'tenant <name>', 'probe <token>' or 'user <token> <name> [<controls>]'", path, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// HasTenants tells whether the tokens are split between tenants.
+func (a *Auth) HasTenants() bool {
+	return a.tenants
+}
+
+// AuthorizeProbe tells whether the Authorization header of a probe
+// carries one of the probe tokens.
+func (a *Auth) AuthorizeProbe(authorization string) bool {
+	_, ok := a.ProbeTenant(authorization)
+	return ok
+}
+
+// ProbeTenant tells the tenant of the probe token in the Authorization
+// header of a probe, and whether there is one.
+func (a *Auth) ProbeTenant(authorization string) (string, bool) {
+	token := strings.TrimPrefix(authorization, "Scope-Probe token=")
+	if token == authorization {
+		return "", false
+	}
+	var tenant string
+	ok := false
+	for candidate, t := range a.probeTokens {
+		if equalTokens(candidate, token) {
+			tenant, ok = t, true
+		}
+	}
+	return tenant, ok
+}
+
+func (a *Auth) user(r *http.Request) (authUser, bool) {
+	token := r.URL.Query().Get(AuthQueryParam)
+	if authorization := r.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		token = strings.TrimPrefix(authorization, "Bearer ")
+	} else if cookie, err := r.Cookie(AuthCookie); err == nil && token == "" {
+		token = cookie.Value
+	}
+	var user authUser
+	ok := false
+	for candidate, u := range a.users {
+		if equalTokens(candidate, token) {
+			user, ok = u, true
+		}
+	}
+	return user, ok
+}
+
+// equalTokens compares tokens in constant time, not to tell how much of a
+// token a guess got right by how long it takes to be refused.
+func equalTokens(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// isProbeRequest tells whether the request comes from a probe, and so
+// isn't meant for users at all.
+func isProbeRequest(r *http.Request) bool {
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/report":
+		return true
+	case r.Method == "GET" && r.URL.Path == "/api/control/ws":
+		return true
+	case r.Method == "GET":
+		_, ok := matchURL(r, "/api/pipe/{pipeID}/probe")
+		return ok
+	}
+	return false
+}
+
+// Wrap makes an http.Handler which only lets the requests the Auth
+// authorizes through to next.
+func (a *Auth) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, isProbe := a.ProbeTenant(r.Header.Get("Authorization"))
+		if isProbeRequest(r) {
+			if !isProbe {
+				http.Error(w, "invalid probe token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withTenant(r.Context(), tenant)))
+			return
+		}
+
+		// Probes also fetch the app's details, and close their pipes
+		if isProbe {
+			_, isPipe := matchURL(r, "/api/pipe/{pipeID}")
+			if (r.Method == "GET" && r.URL.Path == "/api") || (r.Method == "DELETE" && isPipe) {
+				next.ServeHTTP(w, r.WithContext(withTenant(r.Context(), tenant)))
+				return
+			}
+		}
+
+		user, ok := a.user(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scope"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if vars, ok := matchURL(r, "/api/control/{probeID}/{nodeID}/{control}"); ok && r.Method == "POST" {
+			if !user.mayInvoke(vars["control"]) {
+				http.Error(w, fmt.Sprintf("%s may not invoke %s", user.name, vars["control"]), http.StatusForbidden)
+				return
+			}
+		}
+		if token := r.URL.Query().Get(AuthQueryParam); token != "" {
+			http.SetCookie(w, &http.Cookie{
+				Name:     AuthCookie,
+				Value:    token,
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				Expires:  time.Now().Add(30 * 24 * time.Hour),
+			})
+		}
+		ctx := context.WithValue(r.Context(), userCtxKey, user.name)
+		next.ServeHTTP(w, r.WithContext(withTenant(ctx, user.tenant)))
+	})
+}
@@ -0,0 +1,79 @@
+package app_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/weaveworks/scope/app"
+)
+
+func TestAuth(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`# tokens
+probe probetoken
+user admintoken admin *
+user devtoken dev docker_attach_container,docker_pause_container
+user viewertoken viewer
+`)
+	f.Close()
+
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(auth.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer server.Close()
+
+	for _, c := range []struct {
+		method, path, authorization string
+		status                      int
+	}{
+		{"POST", "/api/report", "Scope-Probe token=probetoken", http.StatusOK},
+		{"POST", "/api/report", "Scope-Probe token=devtoken", http.StatusUnauthorized},
+		{"POST", "/api/report", "Bearer admintoken", http.StatusUnauthorized},
+		{"GET", "/api", "Scope-Probe token=probetoken", http.StatusOK},
+		{"DELETE", "/api/pipe/pipeid", "Scope-Probe token=probetoken", http.StatusOK},
+		{"GET", "/api/topology", "Scope-Probe token=probetoken", http.StatusUnauthorized},
+		{"GET", "/api/topology", "", http.StatusUnauthorized},
+		{"GET", "/api/topology", "Bearer viewertoken", http.StatusOK},
+		{"GET", "/api/topology?access_token=viewertoken", "", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_exec_container", "Bearer admintoken", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_pause_container", "Bearer devtoken", http.StatusOK},
+		{"POST", "/api/control/probe/node/docker_exec_container", "Bearer devtoken", http.StatusForbidden},
+		{"POST", "/api/control/probe/node/docker_pause_container", "Bearer viewertoken", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(c.method, server.URL+c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Errorf("%s %s (%q): expected %d, got %d", c.method, c.path, c.authorization, c.status, resp.StatusCode)
+		}
+	}
+
+	// The token handed over in the URL is kept in a cookie scripts can't read
+	resp, err := http.Get(server.URL + "/api/topology?access_token=viewertoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != app.AuthCookie || !cookies[0].Secure || !cookies[0].HttpOnly {
+		t.Errorf("Expected a secure, HTTP-only %s cookie, got %v", app.AuthCookie, cookies)
+	}
+}
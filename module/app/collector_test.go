@@ -0,0 +1,166 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	window := 10 * time.Second
+	c := app.NewCollector(window)
+
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("foo"))
+
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := report.MakeReport(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	c.Add(ctx, r1, nil)
+	have, err = c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := r1; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	timeBefore := mtime.Now()
+	mtime.NowForce(now.Add(time.Second))
+
+	c.Add(ctx, r2, nil)
+	merged := report.MakeReport()
+	merged = merged.Merge(r1)
+	merged = merged.Merge(r2)
+	have, err = c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := merged; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Since the timestamp given is before r2 was added,
+	// it shouldn't be included in the final report.
+	have, err = c.Report(ctx, timeBefore)
+	if err != nil {
+		t.Error(err)
+	}
+	if want := r1; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestCollectorLateReports(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	ctx := context.Background()
+	c := app.NewCollector(10 * time.Second)
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	c.Add(ctx, r1, nil)
+
+	// Too late to be rendered
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("bar"))
+	c.Add(app.WithReportTimestamp(ctx, now.Add(-20*time.Second)), r2, nil)
+
+	// Late, but still in the window, and before r1
+	r3 := report.MakeReport()
+	r3.Endpoint.AddNode(report.MakeNode("baz"))
+	c.Add(app.WithReportTimestamp(ctx, now.Add(-5*time.Second)), r3, nil)
+
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := report.MakeReport().Merge(r1).Merge(r3); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if ok, err := c.HasReports(ctx, now.Add(-5*time.Second)); !ok || err != nil {
+		t.Errorf("Expected reports as of r3, got %v: %v", ok, err)
+	}
+}
+
+func TestCollectorExpire(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	ctx := context.Background()
+	window := 10 * time.Second
+	c := app.NewCollector(window)
+
+	// 1st check the collector is empty
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := report.MakeReport(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Now check an added report is returned
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	c.Add(ctx, r1, nil)
+	have, err = c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := r1; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+
+	// Finally move time forward to expire the report
+	mtime.NowForce(now.Add(window))
+	have, err = c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Error(err)
+	}
+	if want := report.MakeReport(); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestCollectorWait(t *testing.T) {
+	ctx := context.Background()
+	window := time.Millisecond
+	c := app.NewCollector(window)
+
+	waiter := make(chan struct{}, 1)
+	c.WaitOn(ctx, waiter)
+	defer c.UnWait(ctx, waiter)
+	c.(interface {
+		Broadcast()
+	}).Broadcast()
+
+	select {
+	case <-waiter:
+	default:
+		t.Fatal("Didn't unblock")
+	}
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/render"
+)
+
+// CustomTopology is a topology defined by users, which groups the nodes of
+// another topology by the value of a key in their metadata, e.g. containers
+// by their "team" label, with the key "docker_label_team".
+type CustomTopology struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Parent string `json:"parent"` // The topology to group, e.g. "containers"
+	Key    string `json:"key"`
+}
+
+// LoadCustomTopologies reads custom topologies from a JSON file.
+func LoadCustomTopologies(path string) ([]CustomTopology, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cs []CustomTopology
+	if err := codec.NewDecoder(f, &codec.JsonHandle{}).Decode(&cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// AddCustomTopologies adds to the default Registry (topologyRegistry)'s
+// custom topologies
+func AddCustomTopologies(cs ...CustomTopology) error {
+	for _, c := range cs {
+		if err := topologyRegistry.AddCustomTopology(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddCustomTopology adds a custom topology as a sub-topology of its parent,
+// with the same options, or replaces the custom topology with the same ID.
+func (r *Registry) AddCustomTopology(c CustomTopology) error {
+	if c.ID == "" {
+		return fmt.Errorf("custom topology has no id")
+	}
+	if err := render.ValidateGroupKey(c.Key); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		c.Name = "by " + c.Key
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.items[c.ID]; ok {
+		if _, custom := r.custom[c.ID]; !custom {
+			return fmt.Errorf("topology %s already exists", c.ID)
+		}
+		r.remove(c.ID)
+	}
+	parent, ok := r.items[c.Parent]
+	if !ok || parent.parent != "" {
+		return fmt.Errorf("no top-level topology %q to group", c.Parent)
+	}
+	r.add(APITopologyDesc{
+		id:          c.ID,
+		parent:      c.Parent,
+		renderer:    render.GroupRenderer(c.Key, parent.renderer),
+		Name:        c.Name,
+		Options:     parent.Options,
+		HideIfEmpty: parent.HideIfEmpty,
+	})
+	r.custom[c.ID] = c
+	return nil
+}
+
+// RemoveCustomTopology removes a custom topology.
+func (r *Registry) RemoveCustomTopology(id string) bool {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.custom[id]; !ok {
+		return false
+	}
+	r.remove(id)
+	return true
+}
+
+func (r *Registry) remove(id string) {
+	t := r.items[id]
+	if parent, ok := r.items[t.parent]; ok {
+		subs := []APITopologyDesc{}
+		for _, sub := range parent.SubTopologies {
+			if sub.id != id {
+				subs = append(subs, sub)
+			}
+		}
+		parent.SubTopologies = subs
+		r.items[t.parent] = parent
+	}
+	delete(r.items, id)
+	delete(r.custom, id)
+}
+
+// CustomTopologies returns the custom topologies, by ID.
+func (r *Registry) CustomTopologies() []CustomTopology {
+	r.RLock()
+	defer r.RUnlock()
+	result := make([]CustomTopology, 0, len(r.custom))
+	for _, c := range r.custom {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// RegisterCustomTopologyRoutes registers the routes to list, define and
+// remove custom topologies of the default Registry. Custom topologies can't
+// be defined or removed in a read-only app.
+func RegisterCustomTopologyRoutes(router *mux.Router, readOnly bool) {
+	router.Methods("GET").Path("/api/custom-topology").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondWith(w, http.StatusOK, topologyRegistry.CustomTopologies())
+	})
+	router.Methods("PUT").Path("/api/custom-topology/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readOnly {
+			respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+			return
+		}
+		var c CustomTopology
+		if err := codec.NewDecoder(r.Body, &codec.JsonHandle{}).Decode(&c); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		c.ID = mux.Vars(r)["id"]
+		if err := topologyRegistry.AddCustomTopology(c); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+		respondWith(w, http.StatusOK, c)
+	})
+	router.Methods("DELETE").Path("/api/custom-topology/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if readOnly {
+			respondWith(w, http.StatusForbidden, "controls are disabled: the app is read-only")
+			return
+		}
+		if !topologyRegistry.RemoveCustomTopology(mux.Vars(r)["id"]) {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
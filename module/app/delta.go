@@ -0,0 +1,51 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bluele/gcache"
+
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	deltaBasesSize       = 1024
+	deltaBasesExpiration = time.Minute
+)
+
+// errNoDeltaBase is returned for delta reports whose base we don't have,
+// as after the app restarts; the probe sends a full report next.
+var errNoDeltaBase = fmt.Errorf("no base for delta report")
+
+// deltaBases holds the last report of each probe publishing delta reports,
+// to apply its next delta to.
+type deltaBases struct {
+	cache gcache.Cache
+}
+
+func newDeltaBases() *deltaBases {
+	return &deltaBases{
+		cache: gcache.New(deltaBasesSize).LRU().Expiration(deltaBasesExpiration).Build(),
+	}
+}
+
+// full returns the full report of a report from the probe: rpt itself if
+// it is a full report, or else rpt applied to its base. Full reports,
+// but not shortcut reports, become the base of the next delta.
+func (d *deltaBases) full(probeID string, rpt report.Report) (report.Report, error) {
+	if rpt.IsDelta() {
+		val, err := d.cache.Get(probeID)
+		if err != nil {
+			return rpt, errNoDeltaBase
+		}
+		if rpt, err = rpt.ApplyDelta(val.(report.Report)); err != nil {
+			d.cache.Remove(probeID)
+			return rpt, errNoDeltaBase
+		}
+	}
+	if !rpt.Shortcut {
+		d.cache.Set(probeID, rpt)
+	}
+	return rpt, nil
+}
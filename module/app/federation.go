@@ -0,0 +1,213 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"context"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// FederationCluster is the key for the cluster nodes of federated reports
+// come from.
+const FederationCluster = report.FederationCluster
+
+var federationMetadataTemplates = report.MetadataTemplates{
+	FederationCluster: {ID: FederationCluster, Label: "Cluster", From: report.FromLatest, Priority: 0.5},
+}
+
+// Downstream is another app, whose reports are merged into ours.
+type Downstream struct {
+	Name  string
+	URL   string
+	Token string // bearer token to authenticate with, if any
+}
+
+// ParseDownstreams parses a comma-separated list of name=url downstream
+// apps.
+func ParseDownstreams(s string) ([]Downstream, error) {
+	result := []Downstream{}
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		parts := strings.SplitN(d, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid downstream %q, expected name=url", d)
+		}
+		result = append(result, Downstream{Name: parts[0], URL: strings.TrimSuffix(parts[1], "/")})
+	}
+	return result, nil
+}
+
+// LoadDownstreamTokens reads the bearer tokens to authenticate to the
+// downstreams with from a file, with one per line as
+//
+//	<name> <token>
+//
+// Empty lines and lines starting with # are ignored.
+func LoadDownstreamTokens(path string, downstreams []Downstream) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	byName := map[string]*Downstream{}
+	for i := range downstreams {
+		byName[downstreams[i].Name] = &downstreams[i]
+	}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: expected '<name> <token>'", path, line)
+		}
+		d, ok := byName[fields[0]]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown downstream %q", path, line, fields[0])
+		}
+		d.Token = fields[1]
+	}
+	return scanner.Err()
+}
+
+// Federation periodically fetches the latest reports of downstream apps,
+// labels their nodes with the cluster they come from and adds them to the
+// collector, for one view over all the clusters.
+//
+// The IDs of the nodes are prefixed with their cluster, for nodes of
+// different clusters with the same ID, such as endpoints on the same
+// private addresses, to be kept apart. Controls can't be invoked on
+// federated nodes.
+type Federation struct {
+	adder       Adder
+	downstreams []Downstream
+	interval    time.Duration
+	client      *http.Client
+	quit        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewFederation makes a new Federation, fetching the reports of each of the
+// downstreams every interval.
+func NewFederation(adder Adder, downstreams []Downstream, interval time.Duration) *Federation {
+	f := &Federation{
+		adder:       adder,
+		downstreams: downstreams,
+		interval:    interval,
+		client:      &http.Client{Timeout: interval},
+		quit:        make(chan struct{}),
+	}
+	for _, d := range downstreams {
+		f.wg.Add(1)
+		go f.loop(d)
+	}
+	return f
+}
+
+// Stop stops fetching reports.
+func (f *Federation) Stop() {
+	close(f.quit)
+	f.wg.Wait()
+}
+
+func (f *Federation) loop(d Downstream) {
+	defer f.wg.Done()
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		if err := f.fetch(d); err != nil {
+			log.Errorf("Federation: error fetching report from %s: %v", d.Name, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+func (f *Federation) fetch(d Downstream) error {
+	req, err := http.NewRequest("GET", d.URL+"/api/report", nil)
+	if err != nil {
+		return err
+	}
+	if d.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	rpt := report.MakeReport()
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&rpt); err != nil {
+		return err
+	}
+	return f.adder.Add(context.Background(), FederateReport(rpt, d.Name, time.Now()), nil)
+}
+
+// FederateReport makes the nodes of rpt those of the given cluster: it
+// labels them with the cluster, prefixes their IDs and those they refer to
+// with it (see report.ClusterScope), and removes their controls, which the
+// app has no probe to invoke on.
+//
+// Overlay peers keep their IDs, as they are unique already.
+func FederateReport(rpt report.Report, cluster string, now time.Time) report.Report {
+	prefix := cluster + "/"
+	rpt.WalkNamedTopologies(func(name string, t *report.Topology) {
+		nodes := make(report.Nodes, len(t.Nodes))
+		for id, n := range t.Nodes {
+			if name != report.Overlay {
+				id = prefix + id
+				n.ID = id
+				n.Adjacency = prefixIDs(prefix, n.Adjacency)
+			}
+			parents := report.MakeSets()
+			for _, topology := range n.Parents.Keys() {
+				ids, _ := n.Parents.Lookup(topology)
+				if topology != report.Overlay {
+					ids = prefixIDs(prefix, ids)
+				}
+				parents = parents.Add(topology, ids)
+			}
+			n.Parents = parents
+			if hostNodeID, ok := n.Latest.Lookup(report.HostNodeID); ok {
+				n = n.WithLatest(report.HostNodeID, now, prefix+hostNodeID)
+			}
+			if ips, ok := n.Sets.Lookup(report.DockerContainerIPsWithScopes); ok {
+				n = n.WithSet(report.DockerContainerIPsWithScopes, prefixIDs(prefix, ips))
+			}
+			n.LatestControls = report.MakeNodeControlDataLatestMap()
+			nodes[id] = n.WithLatest(FederationCluster, now, cluster)
+		}
+		t.Nodes = nodes
+		t.Controls = report.Controls{}
+		t.MetadataTemplates = t.MetadataTemplates.Merge(federationMetadataTemplates)
+	})
+	return rpt
+}
+
+// prefixIDs prefixes all the ids, which stay sorted.
+func prefixIDs(prefix string, ids []string) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = prefix + id
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package app_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"context"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/appclient"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestGRPCTransport(t *testing.T) {
+	collector := app.NewCollector(time.Minute)
+	controlRouter := app.NewLocalControlRouter()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := app.NewGRPCServer(collector, controlRouter, nil)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		codec.NewEncoder(w, &codec.JsonHandle{}).Encode(xfer.Details{
+			ID:       "app",
+			GRPCPort: lis.Addr().(*net.TCPAddr).Port,
+		})
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	controlHandler := xfer.ControlHandlerFunc(func(req xfer.Request) xfer.Response {
+		return xfer.Response{Value: req.NodeID + "/" + req.Control}
+	})
+	client, err := appclient.NewAppClient(
+		appclient.ProbeConfig{ProbeID: "foo", UseGRPC: true},
+		host, url.URL{Scheme: "http", Host: host}, controlHandler,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	if _, err := client.Details(); err != nil {
+		t.Fatal(err)
+	}
+	client.ControlConnection()
+
+	rpt := report.MakeReport()
+	rpt.Host.AddNode(report.MakeNode("host1"))
+	if err := client.Publish(appclient.NewPublishedReport(rpt)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		have, err := collector.Report(ctx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := have.Host.Nodes["host1"]; ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("report never reached the collector")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The stream is up, so the controls must be registered by now
+	res, err := controlRouter.Handle(ctx, "foo", xfer.Request{NodeID: "nodeid", Control: "control"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Value != "nodeid/control" {
+		t.Fatalf("'%s' != 'nodeid/control'", res.Value)
+	}
+}
@@ -0,0 +1,132 @@
+package app_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+const imageDigest = "sha256:0123456789abcdef"
+
+func TestImageMetadata(t *testing.T) {
+	pushed := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "HEAD" || r.URL.Path != "/v2/team/app/manifests/v1" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", imageDigest)
+		w.Header().Set("Last-Modified", pushed.Format(http.TimeFormat))
+	}))
+	defer registry.Close()
+	scanner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/matcher/api/v1/vulnerability_report/"+imageDigest {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"manifest_hash": "`+imageDigest+`", "vulnerabilities": {
+			"1": {"name": "CVE-1", "normalized_severity": "High"},
+			"2": {"name": "CVE-2", "normalized_severity": "High"},
+			"3": {"name": "CVE-3", "normalized_severity": "Low"}
+		}}`)
+	}))
+	defer scanner.Close()
+
+	registryURL := strings.Replace(registry.URL, "http://", "http://user:secret@", 1)
+	source, err := app.NewRegistryImageInfoSource(registryURL, scanner.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := app.NewImageMetadata(source, time.Hour)
+	defer images.Stop()
+
+	ctx := context.Background()
+	c := app.ImageMetadataCollector(app.NewCollector(time.Minute), images)
+	rpt := report.MakeReport()
+	host := strings.TrimPrefix(registry.URL, "http://")
+	rpt.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("image1"), map[string]string{
+		docker.ImageName: host + "/team/app",
+		docker.ImageTag:  "v1",
+	}))
+	rpt.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("image2"), map[string]string{
+		docker.ImageName: "elsewhere.io/team/app",
+		docker.ImageTag:  "v1",
+	}))
+	c.Add(ctx, rpt, nil)
+
+	latest := func(node, key string) interface{} {
+		have, err := c.Report(ctx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, _ := have.ContainerImage.Nodes[report.MakeContainerImageNodeID(node)].Latest.Lookup(key)
+		return value
+	}
+	// Looked up in the background
+	test.Poll(t, time.Second, imageDigest, func() interface{} { return latest("image1", app.ImageDigest) })
+	for key, want := range map[string]string{
+		app.ImagePushed:                           pushed.Format(time.RFC3339Nano),
+		app.ImageVulnerabilities:                  "3",
+		app.ImageVulnerabilitiesPrefix + "high":   "2",
+		app.ImageVulnerabilitiesPrefix + "low":    "1",
+		app.ImageVulnerabilitiesPrefix + "medium": "0",
+	} {
+		if have := latest("image1", key); have != want {
+			t.Errorf("%s: want %q, have %q", key, want, have)
+		}
+	}
+	// Images of other registries are not looked up
+	if have := latest("image2", app.ImageDigest); have != "" {
+		t.Errorf("Expected no digest for an image of another registry, got %q", have)
+	}
+
+	// The reports of the collector itself are not modified
+	have, _ := c.Report(ctx, time.Now())
+	if _, ok := have.ContainerImage.MetadataTemplates[app.ImageVulnerabilities]; !ok {
+		t.Error("Expected the vulnerabilities in the metadata of images")
+	}
+	if _, ok := rpt.ContainerImage.Nodes[report.MakeContainerImageNodeID("image1")].Latest.Lookup(app.ImageDigest); ok {
+		t.Error("Expected the report added not to be modified")
+	}
+}
+
+type staticImageInfoSource struct{}
+
+func (staticImageInfoSource) ImageInfo(_ context.Context, name, tag string) (app.ImageInfo, error) {
+	return app.ImageInfo{Digest: imageDigest}, nil
+}
+
+func TestImageMetadataForgets(t *testing.T) {
+	images := app.NewImageMetadata(staticImageInfoSource{}, 50*time.Millisecond)
+	defer images.Stop()
+
+	withImage := report.MakeReport()
+	withImage.ContainerImage.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID("image"), map[string]string{
+		docker.ImageName: "team/app",
+		docker.ImageTag:  "v1",
+	}))
+	digest := func() interface{} {
+		value, _ := images.Enrich(withImage).ContainerImage.Nodes[report.MakeContainerImageNodeID("image")].Latest.Lookup(app.ImageDigest)
+		return value
+	}
+	test.Poll(t, 100*time.Millisecond, imageDigest, digest)
+
+	// Gone from the reports for a few refreshes, the image is forgotten
+	time.Sleep(500 * time.Millisecond)
+	if have := digest(); have != "" {
+		t.Errorf("Expected the image to have been forgotten, got %q", have)
+	}
+}
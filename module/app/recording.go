@@ -0,0 +1,331 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+const (
+	maxRecordingSize    = 10 * 1024 * 1024 // sessions stop being recorded past 10MB
+	maxMemoryRecordings = 100
+	// Sessions the UI doesn't connect to within this are forgotten
+	sessionConnectTimeout = time.Minute
+
+	// asciinema's default terminal size, until the UI resizes the tty
+	defaultRecordingWidth  = 80
+	defaultRecordingHeight = 24
+)
+
+// RecordingStore is something that can persist recordings of pipe
+// sessions, by ID.
+type RecordingStore interface {
+	// PutRecording stores a recording, replacing any with the same ID.
+	PutRecording(ctx context.Context, id string, buf []byte) error
+	// FetchRecording retrieves a recording.
+	FetchRecording(ctx context.Context, id string) ([]byte, error)
+	// ListRecordings returns the IDs of all the recordings stored.
+	ListRecordings(ctx context.Context) ([]string, error)
+}
+
+// RecordingInfo describes a recording, in the list of recordings.
+type RecordingInfo struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SessionRecorder records the interactive sessions (exec, attach, ...)
+// opened with controls, in the asciicast v2 format of asciinema: a header
+// line of JSON, then an event per line. Only the output of the sessions is
+// recorded, as the ttys echo what is typed, except passwords.
+//
+// A recording is stored when the UI disconnects from the pipe, under an ID
+// made of the time the session started and the ID of its pipe. Sessions the
+// UI never connects to are forgotten after sessionConnectTimeout.
+type SessionRecorder struct {
+	store RecordingStore
+
+	mtx      sync.Mutex
+	sessions map[string]*recordedSession // by tenantID of the pipe
+}
+
+// NewSessionRecorder makes a new SessionRecorder storing recordings in
+// store.
+func NewSessionRecorder(store RecordingStore) *SessionRecorder {
+	return &SessionRecorder{
+		store:    store,
+		sessions: map[string]*recordedSession{},
+	}
+}
+
+type recordingHeader struct {
+	Version   int    `json:"version"`
+	Width     uint   `json:"width"`
+	Height    uint   `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+}
+
+type recordedSession struct {
+	id string
+
+	mtx       sync.Mutex
+	header    recordingHeader
+	started   time.Time
+	connected bool
+	events    bytes.Buffer
+	full      bool
+}
+
+func (s *recordedSession) connect() {
+	s.mtx.Lock()
+	s.connected = true
+	s.mtx.Unlock()
+}
+
+// stale tells whether the UI never connected to the session, and won't.
+func (s *recordedSession) stale(now time.Time) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return !s.connected && now.Sub(s.started) > sessionConnectTimeout
+}
+
+func (s *recordedSession) event(kind, data string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.full {
+		return
+	}
+	buf, err := json.Marshal([]interface{}{mtime.Now().Sub(s.started).Seconds(), kind, data})
+	if err != nil {
+		return
+	}
+	if s.events.Len()+len(buf) > maxRecordingSize {
+		log.Warnf("Recording %s is over %d bytes, not recording the rest of the session", s.id, maxRecordingSize)
+		s.full = true
+		return
+	}
+	s.events.Write(append(buf, '\n'))
+}
+
+func (s *recordedSession) resize(width, height uint) {
+	s.mtx.Lock()
+	empty := s.events.Len() == 0
+	if empty {
+		s.header.Width, s.header.Height = width, height
+	}
+	s.mtx.Unlock()
+	if !empty {
+		s.event("r", fmt.Sprintf("%dx%d", width, height))
+	}
+}
+
+func (s *recordedSession) bytes() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	header, err := json.Marshal(s.header)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(header, '\n'), s.events.Bytes()...), nil
+}
+
+// recordingEnd records what is read from the UI end of a pipe, i.e. the
+// output of the session.
+type recordingEnd struct {
+	io.ReadWriter
+	session *recordedSession
+}
+
+func (e recordingEnd) Read(p []byte) (int, error) {
+	n, err := e.ReadWriter.Read(p)
+	if n > 0 {
+		e.session.event("o", string(p[:n]))
+	}
+	return n, err
+}
+
+func (r *SessionRecorder) start(ctx context.Context, pipeID string, req xfer.Request) {
+	now := mtime.Now()
+	title := fmt.Sprintf("%s on %s", req.Control, req.NodeID)
+	if user := AuthUser(ctx); user != "" {
+		title += " by " + user
+	}
+	session := &recordedSession{
+		id: tenantID(ctx, fmt.Sprintf("%019d-%s", now.UnixNano(), pipeID)),
+		header: recordingHeader{
+			Version:   2,
+			Width:     defaultRecordingWidth,
+			Height:    defaultRecordingHeight,
+			Timestamp: now.Unix(),
+			Title:     title,
+		},
+		started: now,
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for id, s := range r.sessions {
+		if s.stale(now) {
+			delete(r.sessions, id)
+		}
+	}
+	r.sessions[tenantID(ctx, pipeID)] = session
+}
+
+func (r *SessionRecorder) session(ctx context.Context, pipeID string) *recordedSession {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.sessions[tenantID(ctx, pipeID)]
+}
+
+// finish stores the recording of the session of the pipe, if any.
+func (r *SessionRecorder) finish(ctx context.Context, pipeID string) {
+	r.mtx.Lock()
+	session, ok := r.sessions[tenantID(ctx, pipeID)]
+	delete(r.sessions, tenantID(ctx, pipeID))
+	r.mtx.Unlock()
+	if !ok {
+		return
+	}
+	buf, err := session.bytes()
+	if err == nil {
+		err = r.store.PutRecording(ctx, session.id, buf)
+	}
+	if err != nil {
+		log.Errorf("Error storing recording %s: %v", session.id, err)
+	}
+}
+
+// Recordings returns the recordings of the tenant of the request, oldest
+// first.
+func (r *SessionRecorder) Recordings(ctx context.Context) ([]RecordingInfo, error) {
+	ids, err := r.store.ListRecordings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := tenantID(ctx, "")
+	result := []RecordingInfo{}
+	for _, id := range ids {
+		name := strings.TrimPrefix(id, prefix)
+		if !strings.HasPrefix(id, prefix) || strings.Contains(name, "/") {
+			continue // another tenant's
+		}
+		nanos, err := strconv.ParseInt(strings.SplitN(name, "-", 2)[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		result = append(result, RecordingInfo{ID: name, Timestamp: time.Unix(0, nanos).UTC()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// Recording returns a recording of the tenant of the request.
+func (r *SessionRecorder) Recording(ctx context.Context, id string) ([]byte, error) {
+	return r.store.FetchRecording(ctx, tenantID(ctx, id))
+}
+
+// RecordingControlRouter starts recording the sessions opened by the
+// control requests routed through cr, and the resizes of their ttys.
+func RecordingControlRouter(cr ControlRouter, recorder *SessionRecorder) ControlRouter {
+	return recordingControlRouter{cr, recorder}
+}
+
+type recordingControlRouter struct {
+	ControlRouter
+	recorder *SessionRecorder
+}
+
+func (rc recordingControlRouter) Handle(ctx context.Context, probeID string, req xfer.Request) (xfer.Response, error) {
+	res, err := rc.ControlRouter.Handle(ctx, probeID, req)
+	if err != nil || res.Error != "" {
+		return res, err
+	}
+	if res.Pipe != "" && res.RawTTY {
+		rc.recorder.start(ctx, res.Pipe, req)
+	} else if pipeID, ok := req.ControlArgs["pipeID"]; ok {
+		if session := rc.recorder.session(ctx, pipeID); session != nil {
+			width, werr := strconv.ParseUint(req.ControlArgs["width"], 10, 32)
+			height, herr := strconv.ParseUint(req.ControlArgs["height"], 10, 32)
+			if werr == nil && herr == nil {
+				session.resize(uint(width), uint(height))
+			}
+		}
+	}
+	return res, err
+}
+
+// RecordingPipeRouter records what goes through the pipes of the sessions
+// the recorder has started, and stores the recordings once the UI is done
+// with them.
+func RecordingPipeRouter(pr PipeRouter, recorder *SessionRecorder) PipeRouter {
+	return recordingPipeRouter{pr, recorder}
+}
+
+type recordingPipeRouter struct {
+	PipeRouter
+	recorder *SessionRecorder
+}
+
+func (rp recordingPipeRouter) Get(ctx context.Context, id string, e End) (xfer.Pipe, io.ReadWriter, error) {
+	pipe, endIO, err := rp.PipeRouter.Get(ctx, id, e)
+	if err != nil || e != UIEnd {
+		return pipe, endIO, err
+	}
+	if session := rp.recorder.session(ctx, id); session != nil {
+		session.connect()
+		endIO = recordingEnd{endIO, session}
+	}
+	return pipe, endIO, nil
+}
+
+func (rp recordingPipeRouter) Release(ctx context.Context, id string, e End) error {
+	if e == UIEnd {
+		rp.recorder.finish(ctx, id)
+	}
+	return rp.PipeRouter.Release(ctx, id, e)
+}
+
+func (rp recordingPipeRouter) Delete(ctx context.Context, id string) error {
+	rp.recorder.finish(ctx, id)
+	return rp.PipeRouter.Delete(ctx, id)
+}
+
+// RegisterRecordingRoutes registers the routes to list the recordings of
+// sessions, and to get one, as an asciicast.
+func RegisterRecordingRoutes(router *mux.Router, recorder *SessionRecorder) {
+	router.Methods("GET").Path("/api/recordings").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			recordings, err := recorder.Recordings(ctx)
+			if err != nil {
+				respondWith(w, http.StatusInternalServerError, err)
+				return
+			}
+			respondWith(w, http.StatusOK, recordings)
+		}))
+
+	router.Methods("GET").Path("/api/recordings/{id}").HandlerFunc(requestContextDecorator(
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			buf, err := recorder.Recording(ctx, mux.Vars(r)["id"])
+			if err != nil {
+				respondWith(w, http.StatusNotFound, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-asciicast")
+			w.Write(buf)
+		}))
+}
@@ -0,0 +1,150 @@
+package app_test
+
+import (
+	"testing"
+	"time"
+
+	"context"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestMemoryReportStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	store := app.NewMemoryReportStore(time.Minute)
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("bar"))
+
+	t1, t2 := now.Add(-30*time.Second), now.Add(-10*time.Second)
+	// Insert out of order, the store keeps them sorted
+	store.Put(ctx, t2, r2, nil)
+	store.Put(ctx, t1, r1, nil)
+
+	have, err := store.Range(ctx, now.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Time{t1, t2}; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+	if have, _ := store.Range(ctx, t1.Add(time.Second), t2); len(have) != 0 {
+		t.Errorf("expected no reports, got %v", have)
+	}
+
+	rpt, err := store.Fetch(ctx, t1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r1, rpt) {
+		t.Error(test.Diff(r1, rpt))
+	}
+	if _, err := store.Fetch(ctx, now); err == nil {
+		t.Error("expected an error fetching a missing report")
+	}
+
+	reports, err := app.FetchRange(ctx, store, t1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []report.Report{r1, r2}; !reflect.DeepEqual(want, reports) {
+		t.Error(test.Diff(want, reports))
+	}
+
+	// Reports older than the retention get dropped
+	mtime.NowForce(now.Add(45 * time.Second))
+	store.Put(ctx, mtime.Now(), report.MakeReport(), nil)
+	have, _ = store.Range(ctx, time.Time{}, mtime.Now().Add(time.Second))
+	if want := []time.Time{t2, mtime.Now()}; !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestStoringCollector(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	store := app.NewMemoryReportStore(time.Minute)
+	c := app.NewStoringCollector(app.NewCollector(10*time.Second), store, 10*time.Second)
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	if err := c.Add(ctx, r1, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r1, have) {
+		t.Error(test.Diff(r1, have))
+	}
+
+	stored, err := store.Fetch(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r1, stored) {
+		t.Error(test.Diff(r1, stored))
+	}
+}
+
+func TestStoringCollectorHistoricReports(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	window := 10 * time.Second
+	store := app.NewMemoryReportStore(time.Hour)
+	c := app.NewStoringCollector(app.NewCollector(window), store, window)
+	if !c.HasHistoricReports() {
+		t.Error("expected historic reports")
+	}
+
+	r1 := report.MakeReport()
+	r1.Endpoint.AddNode(report.MakeNode("foo"))
+	c.Add(ctx, r1, nil)
+
+	// Time passes, the collector no longer holds r1
+	mtime.NowForce(now.Add(time.Minute))
+	r2 := report.MakeReport()
+	r2.Endpoint.AddNode(report.MakeNode("bar"))
+	c.Add(ctx, r2, nil)
+
+	have, err := c.Report(ctx, mtime.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r2, have) {
+		t.Error(test.Diff(r2, have))
+	}
+
+	// But r1 is still available from the store
+	have, err = c.Report(ctx, now.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r1, have) {
+		t.Error(test.Diff(r1, have))
+	}
+	if ok, _ := c.HasReports(ctx, now.Add(time.Second)); !ok {
+		t.Error("expected reports one second after r1")
+	}
+	if ok, _ := c.HasReports(ctx, now.Add(30*time.Second)); ok {
+		t.Error("expected no reports between r1 and r2")
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"context"
+	"github.com/NYTimes/gziphandler"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/common/hostname"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+var (
+	// Version - set at buildtime.
+	Version = "dev"
+
+	// UniqueID - set at runtime.
+	UniqueID = "0"
+
+	// GRPCPort - set at runtime, if probes can connect over gRPC.
+	GRPCPort = 0
+
+	reportSize = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "scope",
+		Subsystem: "app",
+		Name:      "report_size_bytes",
+		Help:      "Size of reports received from probes (gzipped msgpack), in bytes.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 2, 15),
+	})
+)
+
+func init() {
+	prometheus.MustRegister(reportSize)
+}
+
+// contextKey is a wrapper type for use in context.WithValue() to satisfy golint
+// https://github.com/golang/go/issues/17293
+// https://github.com/golang/lint/pull/245
+type contextKey string
+
+// RequestCtxKey is key used for request entry in context
+const RequestCtxKey contextKey = contextKey("request")
+
+// CtxHandlerFunc is a http.HandlerFunc, with added contexts
+type CtxHandlerFunc func(context.Context, http.ResponseWriter, *http.Request)
+
+func requestContextDecorator(f CtxHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), RequestCtxKey, r)
+		f(ctx, w, r)
+	}
+}
+
+// URLMatcher uses request.RequestURI (the raw, unparsed request) to attempt
+// to match pattern.  It does this as go's URL.Parse method is broken, and
+// mistakenly unescapes the Path before parsing it.  This breaks %2F (encoded
+// forward slashes) in the paths.
+func URLMatcher(pattern string) mux.MatcherFunc {
+	return func(r *http.Request, rm *mux.RouteMatch) bool {
+		vars, match := matchURL(r, pattern)
+		if match {
+			rm.Vars = vars
+		}
+		return match
+	}
+}
+
+func matchURL(r *http.Request, pattern string) (map[string]string, bool) {
+	matchParts := strings.Split(pattern, "/")
+	path := strings.SplitN(r.RequestURI, "?", 2)[0]
+	parts := strings.Split(path, "/")
+	if len(parts) != len(matchParts) {
+		return nil, false
+	}
+
+	vars := map[string]string{}
+	for i, part := range parts {
+		unescaped, err := url.QueryUnescape(part)
+		if err != nil {
+			return nil, false
+		}
+		match := matchParts[i]
+		if strings.HasPrefix(match, "{") && strings.HasSuffix(match, "}") {
+			vars[strings.Trim(match, "{}")] = unescaped
+		} else if matchParts[i] != unescaped {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+func gzipHandler(h http.HandlerFunc) http.Handler {
+	return gziphandler.GzipHandler(h)
+}
+
+// RegisterTopologyRoutes registers the various topology routes with a http mux.
+func RegisterTopologyRoutes(router *mux.Router, r Reporter, capabilities map[string]bool) {
+	get := router.Methods("GET").Subrouter()
+	get.Handle("/api",
+		gzipHandler(requestContextDecorator(apiHandler(r, capabilities))))
+	get.Handle("/api/topology",
+		gzipHandler(requestContextDecorator(topologyRegistry.makeTopologyList(r))))
+	get.Handle("/api/topology/{topology}",
+		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleTopology)))).
+		Name("api_topology_topology")
+	get.Handle("/api/topology/{topology}/ws",
+		requestContextDecorator(captureReporter(r, handleWebsocket))). // NB not gzip!
+		Name("api_topology_topology_ws")
+	get.Handle("/api/topology/{topology}/export",
+		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleTopologyExport)))).
+		Name("api_topology_topology_export")
+	get.Handle("/api/topology/{topology}/diff",
+		gzipHandler(requestContextDecorator(captureReporter(r, handleTopologyDiff)))).
+		Name("api_topology_topology_diff")
+	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{id}/ws")).Handler(
+		requestContextDecorator(captureReporter(r, handleNodeWebsocket))). // NB not gzip!
+		Name("api_topology_topology_id_ws")
+	get.MatcherFunc(URLMatcher("/api/topology/{topology}/{id}")).Handler(
+		gzipHandler(requestContextDecorator(topologyRegistry.captureRenderer(r, handleNode)))).
+		Name("api_topology_topology_id")
+	get.Handle("/api/report",
+		gzipHandler(requestContextDecorator(makeRawReportHandler(r))))
+	get.Handle("/api/probes",
+		gzipHandler(requestContextDecorator(makeProbeHandler(r))))
+}
+
+// RegisterReportPostHandler registers the handler for report submission
+func RegisterReportPostHandler(a Adder, router *mux.Router) {
+	bases := newDeltaBases()
+	post := router.Methods("POST").Subrouter()
+	post.HandleFunc("/api/report", requestContextDecorator(func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		var (
+			rpt    report.Report
+			buf    = &bytes.Buffer{}
+			reader = io.TeeReader(r.Body, buf)
+		)
+
+		gzipped := strings.Contains(r.Header.Get("Content-Encoding"), "gzip")
+		if !gzipped {
+			reader = io.TeeReader(r.Body, gzip.NewWriter(buf))
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		if schema, err := report.ParseSchemaVersion(contentType); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		} else if schema > report.SchemaVersion {
+			respondWith(w, http.StatusUnsupportedMediaType, fmt.Errorf("report schema %d is newer than this app supports (%d)", schema, report.SchemaVersion))
+			return
+		}
+		isMsgpack := strings.HasPrefix(contentType, "application/msgpack")
+		var handle codec.Handle
+		switch {
+		case strings.HasPrefix(contentType, "application/json"):
+			handle = &codec.JsonHandle{}
+		case isMsgpack:
+			handle = &codec.MsgpackHandle{}
+		default:
+			respondWith(w, http.StatusBadRequest, fmt.Errorf("Unsupported Content-Type: %v", contentType))
+			return
+		}
+
+		if err := rpt.ReadBinary(reader, gzipped, handle); err != nil {
+			respondWith(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if r.Header.Get(xfer.ScopeReportDeltasHeader) == "true" {
+			wasDelta := rpt.IsDelta()
+			var err error
+			if rpt, err = bases.full(tenantID(ctx, r.Header.Get(xfer.ScopeProbeIDHeader)), rpt); err != nil {
+				respondWith(w, http.StatusConflict, err)
+				return
+			}
+			// Publish the whole report onwards
+			isMsgpack = isMsgpack && !wasDelta
+		} else if rpt.IsDelta() {
+			respondWith(w, http.StatusBadRequest, fmt.Errorf("delta report without the %s header", xfer.ScopeReportDeltasHeader))
+			return
+		}
+
+		if ts := r.Header.Get(xfer.ScopeReportTimestampHeader); ts != "" {
+			timestamp, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				respondWith(w, http.StatusBadRequest, fmt.Errorf("invalid %s header: %v", xfer.ScopeReportTimestampHeader, err))
+				return
+			}
+			if timestamp.Before(mtime.Now()) {
+				ctx = WithReportTimestamp(ctx, timestamp)
+			}
+		}
+
+		// a.Add(..., buf) assumes buf is gzip'd msgpack
+		if !isMsgpack {
+			buf, _ = rpt.WriteBinary()
+		}
+		reportSize.Observe(float64(buf.Len()))
+
+		if err := a.Add(ctx, rpt, buf.Bytes()); err != nil {
+			log.Errorf("Error Adding report: %v", err)
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+var newVersion = struct {
+	sync.Mutex
+	*xfer.NewVersionInfo
+}{}
+
+// NewVersion is called to expose new version information to /api
+func NewVersion(version, downloadURL string) {
+	newVersion.Lock()
+	defer newVersion.Unlock()
+	newVersion.NewVersionInfo = &xfer.NewVersionInfo{
+		Version:     version,
+		DownloadURL: downloadURL,
+	}
+}
+
+func apiHandler(rep Reporter, capabilities map[string]bool) CtxHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+		rpt, err := rep.Report(ctx, time.Now())
+		if err != nil {
+			respondWith(w, http.StatusInternalServerError, err)
+			return
+		}
+		newVersion.Lock()
+		defer newVersion.Unlock()
+		respondWith(w, http.StatusOK, xfer.Details{
+			ID:           UniqueID,
+			Version:      Version,
+			Hostname:     hostname.Get(),
+			Plugins:      rpt.Plugins,
+			Capabilities: capabilities,
+			GRPCPort:     GRPCPort,
+			ReportSchema: report.SchemaVersion,
+			NewVersion:   newVersion.NewVersionInfo,
+		})
+	}
+}
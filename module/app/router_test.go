@@ -0,0 +1,197 @@
+package app_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+)
+
+type v map[string]string
+
+func TestURLMatcher(t *testing.T) {
+	test := func(pattern, path string, match bool, vars v) {
+		routeMatch := &mux.RouteMatch{}
+		if app.URLMatcher(pattern)(&http.Request{RequestURI: path}, routeMatch) != match {
+			t.Fatalf("'%s' '%s'", pattern, path)
+		}
+		if match && !reflect.DeepEqual(v(routeMatch.Vars), vars) {
+			t.Fatalf("%v != %v", v(routeMatch.Vars), vars)
+		}
+	}
+
+	test("/a/b/c", "/a/b/c", true, v{})
+	test("/a/b/c", "/c/b/a", false, v{})
+	test("/{a}/b/c", "/b/b/c", true, v{"a": "b"})
+	test("/{a}/b/c", "/b/b/b", false, v{})
+	test("/a/b/{c}", "/a/b/b", true, v{"c": "b"})
+	test("/a/b/{c}", "/a/b/b%2Fb", true, v{"c": "b/b"})
+}
+
+func TestReportPostHandler(t *testing.T) {
+	test := func(contentType string, encoder func(interface{}) ([]byte, error)) {
+		router := mux.NewRouter()
+		c := app.NewCollector(1 * time.Minute)
+		app.RegisterReportPostHandler(c, router)
+		ts := httptest.NewServer(router)
+		defer ts.Close()
+
+		b, err := encoder(fixture.Report)
+		if err != nil {
+			t.Fatalf("Content-Type %s: %s", contentType, err)
+		}
+
+		req, err := http.NewRequest("POST", ts.URL+"/api/report", bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("Error posting report: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error posting report %v", err)
+		}
+
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Error posting report: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Error posting report: %d", resp.StatusCode)
+		}
+
+		ctx := context.Background()
+		report, err := c.Report(ctx, time.Now())
+		if err != nil {
+			t.Error(err)
+		}
+		if want, have := fixture.Report.Endpoint.Nodes, report.Endpoint.Nodes; len(have) == 0 || len(want) != len(have) {
+			t.Fatalf("Content-Type %s: %v", contentType, test.Diff(have, want))
+		}
+	}
+
+	test("application/json", func(v interface{}) ([]byte, error) {
+		buf := &bytes.Buffer{}
+		err := codec.NewEncoder(buf, &codec.JsonHandle{}).Encode(v)
+		return buf.Bytes(), err
+	})
+	test("application/msgpack", func(v interface{}) ([]byte, error) {
+		buf := &bytes.Buffer{}
+		err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(v)
+		return buf.Bytes(), err
+	})
+}
+
+func TestReportPostHandlerSchema(t *testing.T) {
+	router := mux.NewRouter()
+	app.RegisterReportPostHandler(app.NewCollector(1*time.Minute), router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(fixture.Report); err != nil {
+		t.Fatal(err)
+	}
+	for schema, status := range map[int]int{
+		0:                        http.StatusOK,
+		report.SchemaVersion:     http.StatusOK,
+		report.SchemaVersion + 1: http.StatusUnsupportedMediaType,
+	} {
+		req, err := http.NewRequest("POST", ts.URL+"/api/report", bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", report.MsgpackContentType(schema))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("schema %d: expected status %d, got %d", schema, status, resp.StatusCode)
+		}
+	}
+}
+
+func TestReportPostHandlerDeltas(t *testing.T) {
+	router := mux.NewRouter()
+	collector := app.NewCollector(1 * time.Minute)
+	app.RegisterReportPostHandler(collector, router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	post := func(rpt report.Report, deltas bool) int {
+		buf := &bytes.Buffer{}
+		if err := codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(rpt); err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("POST", ts.URL+"/api/report", buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", report.MsgpackContentType(report.SchemaVersion))
+		req.Header.Set(xfer.ScopeProbeIDHeader, "probe1")
+		if deltas {
+			req.Header.Set(xfer.ScopeReportDeltasHeader, "true")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	now := time.Now()
+	base := report.MakeReport()
+	base.Host.AddNode(report.MakeNode("host1").WithLatest("k", now, "v"))
+	next := base.Copy()
+	next.Host.Nodes = report.Nodes{}
+	next.Host.AddNode(report.MakeNode("host1").WithLatest("k", now.Add(time.Second), "w"))
+	next.Host.AddNode(report.MakeNode("host2").WithLatest("k", now.Add(time.Second), "v"))
+	delta := next.Delta(base)
+
+	// Deltas need the header, and a base
+	if status := post(delta, false); status != http.StatusBadRequest {
+		t.Errorf("Expected %d without the header, got %d", http.StatusBadRequest, status)
+	}
+	if status := post(delta, true); status != http.StatusConflict {
+		t.Errorf("Expected %d without a base, got %d", http.StatusConflict, status)
+	}
+
+	if status := post(base, true); status != http.StatusOK {
+		t.Fatalf("Expected %d for the base, got %d", http.StatusOK, status)
+	}
+	if status := post(delta, true); status != http.StatusOK {
+		t.Fatalf("Expected %d for the delta, got %d", http.StatusOK, status)
+	}
+	have, err := collector.Report(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"host1": "w", "host2": "v"} {
+		if value, _ := have.Host.Nodes[id].Latest.Lookup("k"); value != want {
+			t.Errorf("%s: expected %q, got %q", id, want, value)
+		}
+	}
+
+	// The delta was applied, so it is the base now
+	if status := post(delta, true); status != http.StatusConflict {
+		t.Errorf("Expected %d for a stale delta, got %d", http.StatusConflict, status)
+	}
+}
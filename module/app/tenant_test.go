@@ -0,0 +1,111 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestTenants(t *testing.T) {
+	f, err := ioutil.TempFile("", "scope-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`tenant red
+probe redprobe
+user reduser red
+tenant blue
+probe blueprobe
+user blueuser blue
+`)
+	f.Close()
+	auth, err := app.LoadAuth(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !auth.HasTenants() {
+		t.Fatal("Expected tenants")
+	}
+	if tenant, ok := auth.ProbeTenant("Scope-Probe token=blueprobe"); !ok || tenant != "blue" {
+		t.Errorf("Expected the blue tenant, got %q", tenant)
+	}
+
+	collector := app.NewTenantCollector(func() app.Collector { return app.NewCollector(time.Minute) })
+	router := mux.NewRouter()
+	app.RegisterReportPostHandler(collector, router)
+	router.Methods("GET").Path("/hosts").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rpt, err := collector.Report(r.Context(), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts := []string{}
+		for id := range rpt.Host.Nodes {
+			hosts = append(hosts, id)
+		}
+		sort.Strings(hosts)
+		json.NewEncoder(w).Encode(hosts)
+	})
+	server := httptest.NewServer(auth.Wrap(router))
+	defer server.Close()
+
+	do := func(method, path, authorization string, body []byte) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authorization)
+		if method == "POST" {
+			req.Header.Set("Content-Encoding", "gzip")
+			req.Header.Set("Content-Type", report.MsgpackContentType(report.SchemaVersion))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: %s", method, path, resp.Status)
+		}
+		return resp
+	}
+	publish := func(token, host string) {
+		rpt := report.MakeReport()
+		rpt.Host.AddNode(report.MakeNode(host))
+		buf, err := rpt.WriteBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		do("POST", "/api/report", "Scope-Probe token="+token, buf.Bytes()).Body.Close()
+	}
+	hosts := func(token string) []string {
+		resp := do("GET", "/hosts", "Bearer "+token, nil)
+		defer resp.Body.Close()
+		var hosts []string
+		if err := json.NewDecoder(resp.Body).Decode(&hosts); err != nil {
+			t.Fatal(err)
+		}
+		return hosts
+	}
+
+	publish("redprobe", "red1")
+	publish("redprobe", "red2")
+	publish("blueprobe", "blue1")
+	if want, have := []string{"red1", "red2"}, hosts("reduser"); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected red to see %v, got %v", want, have)
+	}
+	if want, have := []string{"blue1"}, hosts("blueuser"); !reflect.DeepEqual(want, have) {
+		t.Errorf("Expected blue to see %v, got %v", want, have)
+	}
+}
@@ -0,0 +1,108 @@
+import React from 'react';
+import { connect } from 'react-redux';
+import classNames from 'classnames';
+
+import { enterEdge, leaveEdge } from '../actions/app-actions';
+import { encodeIdAttribute, decodeIdAttribute } from '../utils/dom-utils';
+import { formatMetricSvg } from '../utils/string-utils';
+
+function isStorageComponent(id) {
+  const storageComponents = ['<persistent_volume>', '<storage_class>', '<persistent_volume_claim>', '<volume_snapshot>', '<volume_snapshot_data>'];
+  return storageComponents.includes(id);
+}
+
+// getAdjacencyClass takes id which contains information about edge as a topology
+// of parent and child node.
+// For example: id is of form "nodeA;<storage_class>---nodeB;<persistent_volume_claim>"
+function getAdjacencyClass(id) {
+  const topologyId = id.split('---');
+  const fromNode = topologyId[0].split(';');
+  const toNode = topologyId[1].split(';');
+  if (fromNode[1] !== undefined && toNode[1] !== undefined) {
+    if (isStorageComponent(fromNode[1]) || isStorageComponent(toNode[1])) {
+      return 'link-storage';
+    }
+  }
+  return 'link-none';
+}
+
+// edgeTitle describes the RTTs, retransmissions and traffic of the
+// connections along an edge, e.g. "RTT p50 1.2ms, p99 35ms, 3 retransmits,
+// 1.2 MB sent, 3.4 KB received (12 connections)"
+export function edgeTitle(metrics) {
+  if (!metrics) {
+    return null;
+  }
+  const ms = value => `${parseFloat(value.toPrecision(2))}ms`;
+  const bytes = value => formatMetricSvg(value, { format: 'filesize' });
+  const parts = [];
+  if (metrics.get('rttP50')) {
+    parts.push(`RTT p50 ${ms(metrics.get('rttP50'))}, p99 ${ms(metrics.get('rttP99'))}`);
+  }
+  if (metrics.get('retransmits') > 0) {
+    parts.push(`${metrics.get('retransmits')} retransmits`);
+  }
+  if (metrics.get('bytesSent') || metrics.get('bytesReceived')) {
+    parts.push(`${bytes(metrics.get('bytesSent') || 0)} sent, ${bytes(metrics.get('bytesReceived') || 0)} received`);
+  }
+  const connections = metrics.get('connections');
+  return `${parts.join(', ')} (${connections} connection${connections === 1 ? '' : 's'})`;
+}
+
+class Edge extends React.Component {
+  constructor(props, context) {
+    super(props, context);
+    this.handleMouseEnter = this.handleMouseEnter.bind(this);
+    this.handleMouseLeave = this.handleMouseLeave.bind(this);
+  }
+
+  render() {
+    const {
+      id, path, highlighted, focused, failed, denied, metrics, thickness, source, target
+    } = this.props;
+    const shouldRenderMarker = (focused || highlighted) && (source !== target);
+    const className = classNames('edge', { denied, failed, highlighted });
+    const title = edgeTitle(metrics);
+    return (
+      <g
+        id={encodeIdAttribute(id)}
+        className={className}
+        onMouseEnter={this.handleMouseEnter}
+        onMouseLeave={this.handleMouseLeave}
+      >
+        {title && <title>{title}</title>}
+        <path className="shadow" d={path} style={{ strokeWidth: 10 * thickness }} />
+        <path
+          className={getAdjacencyClass(id)}
+          d={path}
+          style={{ strokeWidth: 5 }}
+        />
+        <path
+          className="link"
+          d={path}
+          markerEnd={shouldRenderMarker ? 'url(#end-arrow)' : null}
+          style={{ strokeWidth: thickness }}
+        />
+      </g>
+    );
+  }
+
+  handleMouseEnter(ev) {
+    this.props.enterEdge(decodeIdAttribute(ev.currentTarget.id));
+  }
+
+  handleMouseLeave(ev) {
+    this.props.leaveEdge(decodeIdAttribute(ev.currentTarget.id));
+  }
+}
+
+function mapStateToProps(state) {
+  return {
+    contrastMode: state.get('contrastMode')
+  };
+}
+
+export default connect(
+  mapStateToProps,
+  { enterEdge, leaveEdge }
+)(Edge);
@@ -0,0 +1 @@
+module.exports = require('./components/app').default;
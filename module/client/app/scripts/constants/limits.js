@@ -0,0 +1,2 @@
+
+export const NODE_DETAILS_DATA_ROWS_DEFAULT_LIMIT = 5;
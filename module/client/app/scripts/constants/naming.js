@@ -0,0 +1,21 @@
+
+export const EDGE_ID_SEPARATOR = '---';
+
+// NOTE: Inconsistent naming is a consequence of
+// keeping it backwards-compatible with the old URLs.
+export const GRAPH_VIEW_MODE = 'topo';
+export const TABLE_VIEW_MODE = 'grid';
+export const RESOURCE_VIEW_MODE = 'resource';
+
+// Named constants to avoid typos that would result in hard-to-detect bugs.
+export const BLURRED_EDGES_LAYER = 'blurred-edges';
+export const BLURRED_NODES_LAYER = 'blurred-nodes';
+export const NORMAL_EDGES_LAYER = 'normal-edges';
+export const NORMAL_NODES_LAYER = 'normal-nodes';
+export const HIGHLIGHTED_EDGES_LAYER = 'highlighted-edges';
+export const HIGHLIGHTED_NODES_LAYER = 'highlighted-nodes';
+export const HOVERED_EDGES_LAYER = 'hovered-edges';
+export const HOVERED_NODES_LAYER = 'hovered-nodes';
+
+export const CONTENT_INCLUDED = 'content-included';
+export const CONTENT_COVERING = 'content-covering';
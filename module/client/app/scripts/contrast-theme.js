@@ -0,0 +1 @@
+import '../styles/contrast.scss';
@@ -0,0 +1,4 @@
+exports.reducer = require('./reducers/root').default;
+exports.Scope = require('./components/app').default;
+exports.actions = require('./actions/app-actions');
+exports.TerminalApp = require('./components/terminal-app').default;
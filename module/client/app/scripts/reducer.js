@@ -0,0 +1 @@
+module.exports = require('./reducers/root').default;
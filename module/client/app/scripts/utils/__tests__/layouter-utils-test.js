@@ -0,0 +1,80 @@
+import { fromJS } from 'immutable';
+
+import {
+  initEdgesFromNodes,
+  constructEdgeId as edge
+} from '../layouter-utils';
+
+
+describe('LayouterUtils', () => {
+  describe('initEdgesFromNodes', () => {
+    it('should return map of edges', () => {
+      const input = fromJS({
+        a: { adjacency: ['b', 'c'] },
+        b: { adjacency: ['a', 'b'] },
+        c: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1
+        },
+        [edge('a', 'c')]: {
+          id: edge('a', 'c'), source: 'a', target: 'c', value: 1
+        },
+        [edge('b', 'a')]: {
+          id: edge('b', 'a'), source: 'b', target: 'a', value: 1
+        },
+        [edge('b', 'b')]: {
+          id: edge('b', 'b'), source: 'b', target: 'b', value: 1
+        },
+      });
+    });
+
+    it('should mark failed edges', () => {
+      const input = fromJS({
+        a: { adjacency: ['b'], failedAdjacency: ['c', 'd'] },
+        b: {},
+        c: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1
+        },
+        [edge('a', 'c')]: {
+          id: edge('a', 'c'), source: 'a', target: 'c', value: 1, failed: true
+        },
+      });
+    });
+
+    it('should mark denied edges', () => {
+      const input = fromJS({
+        a: { adjacency: ['b'], failedAdjacency: ['c'], deniedAdjacency: ['b', 'c'] },
+        b: {},
+        c: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1, denied: true
+        },
+        [edge('a', 'c')]: {
+          id: edge('a', 'c'), source: 'a', target: 'c', value: 1, failed: true, denied: true
+        },
+      });
+    });
+
+    it('should add the metrics of edges', () => {
+      const metrics = {
+        connections: 2, rttP50: 1, rttP99: 3, retransmits: 0
+      };
+      const input = fromJS({
+        a: { adjacency: ['b'], edgeMetrics: { b: metrics, c: metrics } },
+        b: {}
+      });
+      expect(initEdgesFromNodes(input).toJS()).toEqual({
+        [edge('a', 'b')]: {
+          id: edge('a', 'b'), source: 'a', target: 'b', value: 1, metrics
+        },
+      });
+    });
+  });
+});
@@ -0,0 +1,15 @@
+package hostname
+
+import "os"
+
+// Get returns the hostname of this host.
+func Get() string {
+	if hostname := os.Getenv("SCOPE_HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "(unknown)"
+	}
+	return hostname
+}
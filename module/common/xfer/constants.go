@@ -0,0 +1,52 @@
+package xfer
+
+const (
+	// AppPort is the default port that the app will use for its HTTP server.
+	// The app publishes the API and user interface, and receives reports from
+	// probes, on this port.
+	AppPort = 4040
+
+	// ScopeProbeIDHeader is the header we use to carry the probe's unique ID. The
+	// ID is currently set to the a random string on probe startup.
+	ScopeProbeIDHeader = "X-Scope-Probe-ID"
+
+	// ScopeProbeVersionHeader is the header we use to carry the probe's version.
+	ScopeProbeVersionHeader = "X-Scope-Probe-Version"
+
+	// ScopeReportDeltasHeader is set (to "true") by probes which publish
+	// delta reports, so that the app keeps their last report to apply the
+	// next delta to.
+	ScopeReportDeltasHeader = "X-Scope-Report-Deltas"
+
+	// ScopeReportTimestampHeader is set by probes publishing a report late,
+	// e.g. after the app was unreachable, to the time (RFC3339) they made it.
+	ScopeReportTimestampHeader = "X-Scope-Report-Timestamp"
+)
+
+// HistoricReportsCapability indicates whether reports older than the
+// current time (-app.window) can be retrieved.
+const HistoricReportsCapability = "historic_reports"
+
+// MetricHistoryCapability indicates whether the history of the metrics of
+// nodes can be retrieved from /api/history/{id}.
+const MetricHistoryCapability = "metric_history"
+
+// Details are some generic details that can be fetched from /api
+type Details struct {
+	ID           string          `json:"id"`
+	Version      string          `json:"version"`
+	Hostname     string          `json:"hostname"`
+	Plugins      PluginSpecs     `json:"plugins,omitempty"`
+	Capabilities map[string]bool `json:"capabilities,omitempty"`
+	GRPCPort     int             `json:"grpcPort,omitempty"`     // Set if the app accepts probe streams over gRPC
+	ReportSchema int             `json:"reportSchema,omitempty"` // The newest report schema the app reads
+
+	NewVersion *NewVersionInfo `json:"newVersion,omitempty"`
+}
+
+// NewVersionInfo is the struct exposed in /api when there is a new
+// version of Scope available.
+type NewVersionInfo struct {
+	Version     string `json:"version"`
+	DownloadURL string `json:"downloadUrl"`
+}
@@ -0,0 +1,620 @@
+package appclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/rpc"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	httpClientTimeout = 12 * time.Second // a bit less than default app.window
+	initialBackoff    = 1 * time.Second
+	maxBackoff        = 60 * time.Second
+
+	// Publish a full report after this many deltas, so nodes which haven't
+	// changed don't keep old timestamps for too long
+	deltasPerFullReport = 20
+)
+
+var publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "scope",
+	Subsystem: "probe",
+	Name:      "publish_duration_seconds",
+	Help:      "Time in seconds spent publishing reports to apps.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"success"})
+
+func init() {
+	prometheus.MustRegister(publishDuration)
+}
+
+// AppClient is a client to an app, dealing with report publishing, controls and pipes.
+type AppClient interface {
+	Details() (xfer.Details, error)
+	ControlConnection()
+	PipeConnection(string, xfer.Pipe)
+	PipeClose(string) error
+	Publish(*PublishedReport) error
+	Target() url.URL
+	ReTarget(url.URL)
+	Stop()
+}
+
+// appClient is a client to an app, dealing with report publishing, controls and pipes.
+type appClient struct {
+	ProbeConfig
+
+	quit     chan struct{}
+	mtx      sync.Mutex
+	client   *http.Client
+	wsDialer websocket.Dialer
+	appID    string
+	hostname string
+	target   url.URL
+
+	// Track all the background goroutines, ensure they all stop
+	backgroundWait sync.WaitGroup
+
+	// Track ongoing websocket connections
+	conns map[string]xfer.Websocket
+
+	// For publish
+	publishLoop sync.Once
+	reports     chan *PublishedReport
+
+	// The last report the app acknowledged, to publish the next as a delta
+	// of, and how many deltas of it were published in a row. Only used by
+	// the publish loop.
+	deltaBase *report.Report
+	deltas    int
+
+	// For controls
+	control xfer.ControlHandler
+
+	// For the gRPC transport: the app's gRPC port, if it has one, and
+	// whether controls are to be served over the stream.
+	grpcPort     int
+	grpcControls bool
+
+	// The newest report schema the app reads
+	reportSchema int
+}
+
+// PublishedReport is a report published to the apps, and when it was made.
+// It is encoded once, for all the apps it is published to in full rather
+// than as a delta.
+type PublishedReport struct {
+	report.Report
+	timestamp time.Time
+
+	once sync.Once
+	buf  []byte
+	err  error
+}
+
+// NewPublishedReport makes a PublishedReport of r, made now.
+func NewPublishedReport(r report.Report) *PublishedReport {
+	return &PublishedReport{Report: r, timestamp: mtime.Now()}
+}
+
+// encoded returns the report as gzipped msgpack, encoding it the first
+// time.
+func (r *PublishedReport) encoded() ([]byte, error) {
+	r.once.Do(func() {
+		buf, err := r.Report.WriteBinary()
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.buf = buf.Bytes()
+	})
+	return r.buf, r.err
+}
+
+// NewAppClient makes a new appClient.
+func NewAppClient(pc ProbeConfig, hostname string, target url.URL, control xfer.ControlHandler) (AppClient, error) {
+	httpTransport := pc.getHTTPTransport(hostname)
+	httpClient := cleanhttp.DefaultClient()
+	httpClient.Transport = httpTransport
+	httpClient.Timeout = httpClientTimeout
+
+	return &appClient{
+		ProbeConfig: pc,
+		quit:        make(chan struct{}),
+		hostname:    hostname,
+		target:      target,
+		client:      httpClient,
+		wsDialer: websocket.Dialer{
+			TLSClientConfig:  httpTransport.TLSClientConfig,
+			HandshakeTimeout: httpClientTimeout,
+		},
+		conns:   map[string]xfer.Websocket{},
+		reports: make(chan *PublishedReport, 2),
+		control: control,
+	}, nil
+}
+
+func (c *appClient) url(path string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.target.String() + path
+}
+
+func (c *appClient) getAppID() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.appID
+}
+
+func (c *appClient) wsURL(path string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	output := c.target //copy the url
+	if output.Scheme == "https" {
+		output.Scheme = "wss"
+	} else {
+		output.Scheme = "ws"
+	}
+	return output.String() + path
+}
+
+func (c *appClient) hasQuit() bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *appClient) registerConn(id string, conn xfer.Websocket) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.hasQuit() {
+		conn.Close()
+		return false
+	}
+	c.conns[id] = conn
+	return true
+}
+
+func (c *appClient) closeConn(id string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if conn, ok := c.conns[id]; ok {
+		conn.Close()
+		delete(c.conns, id)
+	}
+}
+
+func (c *appClient) retainGoroutine() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.hasQuit() {
+		return false
+	}
+	c.backgroundWait.Add(1)
+	return true
+}
+
+func (c *appClient) releaseGoroutine() {
+	c.backgroundWait.Done()
+}
+
+func (c *appClient) Target() url.URL {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.target
+}
+
+// Re-target the appClient, publishing to a new URL. Note that control
+// and pipe websocket connections are left untouched since we don't
+// want to disrupt them just because there's some load-balancing going
+// on. They *will* however pick up the new URL when terminating
+// (e.g. due to errors or when the connection drops).
+func (c *appClient) ReTarget(target url.URL) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.target = target
+}
+
+// Stop stops the appClient.
+func (c *appClient) Stop() {
+	c.mtx.Lock()
+	close(c.reports)
+	close(c.quit)
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	c.conns = map[string]xfer.Websocket{}
+	c.mtx.Unlock()
+
+	c.backgroundWait.Wait()
+	c.client.Transport.(*http.Transport).CloseIdleConnections()
+	return
+}
+
+// Details fetches the details (version, id) of the app.
+func (c *appClient) Details() (xfer.Details, error) {
+	result := xfer.Details{}
+	req, err := c.ProbeConfig.authorizedRequest("GET", c.url("/api"), nil)
+	if err != nil {
+		return result, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	if err := codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(&result); err != nil {
+		return result, err
+	}
+	c.mtx.Lock()
+	c.appID = result.ID
+	c.grpcPort = result.GRPCPort
+	c.reportSchema = result.ReportSchema
+	c.mtx.Unlock()
+	return result, nil
+}
+
+func (c *appClient) doWithBackoff(msg string, f func() (bool, error)) {
+	if !c.retainGoroutine() {
+		return
+	}
+	defer c.releaseGoroutine()
+
+	backoff := initialBackoff
+
+	for {
+		done, err := f()
+		if done {
+			return
+		}
+		if err == nil {
+			backoff = initialBackoff
+			continue
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			// The timeout period itself serves as a backoff that
+			// prevents thrashing. Hence there is no need to introduce
+			// further delays. Moreover, any delays between publishing
+			// reports that exceed the app.window (defaults to 15s)
+			// cause the UI to display no data, which is debilitating.
+			log.Errorf("Error doing %s for %s: %v", msg, c.hostname, err)
+			backoff = initialBackoff
+			continue
+		}
+		log.Errorf("Error doing %s for %s, backing off %s: %v", msg, c.hostname, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-c.quit:
+			return
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (c *appClient) controlConnection() (bool, error) {
+	headers := http.Header{}
+	c.ProbeConfig.authorizeHeaders(headers)
+	url := c.wsURL("/api/control/ws")
+	conn, _, err := xfer.DialWS(&c.wsDialer, url, headers)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	doControl := func(req xfer.Request) xfer.Response {
+		req.AppID = c.getAppID()
+		var res xfer.Response
+		c.control.Handle(req, &res)
+		return res
+	}
+
+	codec := xfer.NewJSONWebsocketCodec(conn)
+	server := rpc.NewServer()
+	if err := server.RegisterName("control", xfer.ControlHandlerFunc(doControl)); err != nil {
+		return false, err
+	}
+
+	// Will return false if we are exiting
+	if !c.registerConn("control", conn) {
+		return true, nil
+	}
+	defer c.closeConn("control")
+
+	server.ServeCodec(codec)
+	return false, nil
+}
+
+func (c *appClient) ControlConnection() {
+	if _, ok := c.grpcTarget(); ok {
+		// Served over the publishing stream instead
+		c.mtx.Lock()
+		c.grpcControls = true
+		c.mtx.Unlock()
+		return
+	}
+	go func() {
+		log.Infof("Control connection to %s starting", c.hostname)
+		defer log.Infof("Control connection to %s exiting", c.hostname)
+		c.doWithBackoff("controls", c.controlConnection)
+	}()
+}
+
+func (c *appClient) publish(r *PublishedReport) (err error) {
+	defer func(begin time.Time) {
+		publishDuration.WithLabelValues(strconv.FormatBool(err == nil)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	// Shortcut reports only hold some topologies, so are neither published
+	// as deltas nor become the base of one
+	rpt := r.Report
+	deltas := c.PublishDeltas && c.schema() >= report.DeltaSchemaVersion
+	published := rpt
+	var buf []byte
+	if deltas && !rpt.Shortcut && c.deltaBase != nil && c.deltas < deltasPerFullReport {
+		published = rpt.Delta(*c.deltaBase)
+		encoded, err := published.WriteBinary()
+		if err != nil {
+			return err
+		}
+		buf = encoded.Bytes()
+	} else if buf, err = r.encoded(); err != nil {
+		return err
+	}
+	reportSize.Observe(float64(len(buf)))
+
+	req, err := c.reportRequest(bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	if deltas {
+		req.Header.Set(xfer.ScopeReportDeltasHeader, "true")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict && published.IsDelta() {
+		// The app doesn't have the base of the delta: send it all
+		log.Infof("App %s doesn't have the base of our delta report, publishing it in full", c.hostname)
+		c.deltaBase = nil
+		return c.publish(r)
+	}
+	if resp.StatusCode != http.StatusOK {
+		text, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf(resp.Status + ": " + string(text))
+	}
+	if deltas && !rpt.Shortcut {
+		c.deltaBase = &rpt
+		if published.IsDelta() {
+			c.deltas++
+		} else {
+			c.deltas = 0
+		}
+	}
+	return nil
+}
+
+// reportRequest makes the request to publish buf, a gzipped msgpack report.
+func (c *appClient) reportRequest(buf io.Reader) (*http.Request, error) {
+	req, err := c.ProbeConfig.authorizedRequest("POST", c.url("/api/report"), buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", c.reportContentType())
+	// req.Header.Set("Content-Type", "application/binary") // TODO: we should use http.DetectContentType(..) on the gob'ed
+
+	// Make sure this request is cancelled when we stop the client
+	req.Cancel = c.quit
+	return req, nil
+}
+
+// spool keeps r to publish later, if the probe has a spool: it couldn't
+// be published, or had to make way for a newer report.
+func (c *appClient) spool(r *PublishedReport) {
+	if c.Spool == nil || r.Shortcut {
+		return
+	}
+	buf, err := r.encoded()
+	if err == nil {
+		err = c.Spool.Put(r.timestamp, buf)
+	}
+	if err != nil {
+		log.Errorf("Error spooling report to %s: %v", c.hostname, err)
+	}
+}
+
+// replaySpool publishes the spooled reports, oldest first, as of the time
+// they were made. They are published in full, and don't become the base
+// of deltas, as the app has newer reports.
+func (c *appClient) replaySpool() error {
+	if c.Spool == nil {
+		return nil
+	}
+	replayed := 0
+	defer func() {
+		if replayed > 0 {
+			log.Infof("Published %d spooled reports to %s", replayed, c.hostname)
+		}
+	}()
+	for !c.hasQuit() {
+		timestamp, buf, ok, err := c.Spool.Oldest()
+		if !ok {
+			return nil
+		}
+		if err != nil {
+			log.Errorf("Error reading spooled report: %v", err)
+			c.Spool.Remove(timestamp)
+			continue
+		}
+		req, err := c.reportRequest(bytes.NewReader(buf))
+		if err != nil {
+			return err
+		}
+		req.Header.Set(xfer.ScopeReportTimestampHeader, timestamp.Format(time.RFC3339Nano))
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		text, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			// Another try won't do any better
+			log.Warnf("App %s rejected spooled report: %s: %s", c.hostname, resp.Status, text)
+		} else {
+			replayed++
+		}
+		c.Spool.Remove(timestamp)
+	}
+	return nil
+}
+
+// schema is the newest report schema the app reads.
+func (c *appClient) schema() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.reportSchema
+}
+
+// reportContentType is the content type to publish reports as, in the
+// newest schema both we and the app know about. Apps which don't say
+// predate schema versioning.
+func (c *appClient) reportContentType() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	schema := report.SchemaVersion
+	if c.reportSchema < schema {
+		schema = c.reportSchema
+	}
+	return report.MsgpackContentType(schema)
+}
+
+func (c *appClient) startPublishing() {
+	go func() {
+		log.Infof("Publish loop for %s starting", c.hostname)
+		defer log.Infof("Publish loop for %s exiting", c.hostname)
+		c.doWithBackoff("publish", func() (bool, error) {
+			if target, ok := c.grpcTarget(); ok {
+				return c.grpcConnection(target)
+			}
+			r, ok := <-c.reports
+			if !ok {
+				return true, nil
+			}
+			if err := c.publish(r); err != nil {
+				c.spool(r)
+				return false, err
+			}
+			return false, c.replaySpool()
+		})
+	}()
+}
+
+// Publish implements Publisher
+func (c *appClient) Publish(r *PublishedReport) error {
+	// Lazily start the background publishing loop.
+	c.publishLoop.Do(c.startPublishing)
+	// enqueue report
+	select {
+	case c.reports <- r:
+	default:
+		if r.Shortcut {
+			log.Warnf("Dropping report to %s", c.hostname)
+			return nil
+		}
+		// drop an old report to make way for new one
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		select {
+		case old := <-c.reports:
+			if c.Spool != nil && !old.Shortcut {
+				log.Warnf("Spooling report to %s", c.hostname)
+				c.spool(old)
+			} else {
+				log.Warnf("Dropping report to %s", c.hostname)
+			}
+		default:
+		}
+		c.reports <- r
+	}
+	return nil
+}
+
+func (c *appClient) pipeConnection(id string, pipe xfer.Pipe) (bool, error) {
+	headers := http.Header{}
+	c.ProbeConfig.authorizeHeaders(headers)
+	url := c.wsURL(fmt.Sprintf("/api/pipe/%s/probe", id))
+	conn, resp, err := xfer.DialWS(&c.wsDialer, url, headers)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// Special handling - 404 means the app/user has closed the pipe
+		pipe.Close()
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Will return false if we are exiting
+	if !c.registerConn(id, conn) {
+		return true, nil
+	}
+	defer c.closeConn(id)
+
+	_, remote := pipe.Ends()
+	if err := pipe.CopyToWebsocket(remote, conn); err != nil && !xfer.IsExpectedWSCloseError(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+func (c *appClient) PipeConnection(id string, pipe xfer.Pipe) {
+	go func() {
+		log.Infof("Pipe %s connection to %s starting", id, c.hostname)
+		defer log.Infof("Pipe %s connection to %s exiting", id, c.hostname)
+		c.doWithBackoff(id, func() (bool, error) {
+			return c.pipeConnection(id, pipe)
+		})
+	}()
+}
+
+// PipeClose closes the given pipe id on the app.
+func (c *appClient) PipeClose(id string) error {
+	url := c.url(fmt.Sprintf("/api/pipe/%s", id))
+	req, err := c.ProbeConfig.authorizedRequest("DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,406 @@
+package appclient
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/handlers"
+	"github.com/ugorji/go/codec"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/report"
+)
+
+func dummyServer(t *testing.T, expectedToken, expectedID string, expectedVersion string, expectedReport report.Report, done chan struct{}) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if have := r.Header.Get("Authorization"); fmt.Sprintf("Scope-Probe token=%s", expectedToken) != have {
+			t.Errorf("want %q, have %q", expectedToken, have)
+		}
+
+		if have := r.Header.Get(xfer.ScopeProbeIDHeader); expectedID != have {
+			t.Errorf("want %q, have %q", expectedID, have)
+		}
+
+		if have := r.Header.Get(xfer.ScopeProbeVersionHeader); expectedVersion != have {
+			t.Errorf("want %q, have %q", expectedID, have)
+		}
+
+		var have report.Report
+
+		reader := r.Body
+		var err error
+		if strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
+			reader, err = gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer reader.Close()
+		}
+
+		decoder := codec.NewDecoder(reader, &codec.MsgpackHandle{})
+		if err := decoder.Decode(&have); err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(expectedReport, have) {
+			t.Error(test.Diff(expectedReport, have))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		done <- struct{}{}
+	})
+
+	return httptest.NewServer(handlers.CompressHandler(handler))
+}
+
+func TestAppClientPublish(t *testing.T) {
+	var (
+		token   = "abcdefg"
+		id      = "1234567"
+		version = "0.18"
+		rpt     = report.MakeReport()
+		done    = make(chan struct{}, 10)
+	)
+
+	// marshalling->unmarshaling is not idempotent due to `json:"omitempty"`
+	// tags, transforming empty slices into nils. So, we make DeepEqual
+	// happy by setting empty `json:"omitempty"` entries to nil
+	rpt.WalkTopologies(func(to *report.Topology) {
+		*to = report.MakeTopology()
+		to.Controls = nil
+	})
+
+	s := dummyServer(t, token, id, version, rpt, done)
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := ProbeConfig{
+		Token:        token,
+		ProbeVersion: version,
+		ProbeID:      id,
+		Insecure:     false,
+	}
+
+	url, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(pc, u.Host, *url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	// First few reports might be dropped as the client is spinning up.
+	for i := 0; i < 10; i++ {
+		if err := p.Publish(NewPublishedReport(rpt)); err != nil {
+			t.Error(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Error("timeout")
+	}
+}
+
+func TestAppClientDetails(t *testing.T) {
+	var (
+		id      = "foobarbaz"
+		version = "imalittleteapot"
+		want    = xfer.Details{ID: id, Version: version}
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoder := codec.NewEncoder(w, &codec.JsonHandle{})
+		if err := encoder.Encode(want); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	s := httptest.NewServer(handlers.CompressHandler(handler))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := ProbeConfig{
+		Token:    "",
+		ProbeID:  "",
+		Insecure: false,
+	}
+	url, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(pc, u.Host, *url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	have, err := p.Details()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+		return
+	}
+}
+
+// Make sure Stopping a client works even if the connection or the remote app
+// gets stuck for whatever reason.
+// See https://github.com/weaveworks/scope/issues/1576
+func TestStop(t *testing.T) {
+	var (
+		rpt            = report.MakeReport()
+		stopHanging    = make(chan struct{})
+		receivedReport = make(chan struct{})
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(receivedReport)
+		<-stopHanging
+	})
+
+	s := httptest.NewServer(handlers.CompressHandler(handler))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := ProbeConfig{
+		Token:    "",
+		ProbeID:  "",
+		Insecure: false,
+	}
+
+	url, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewAppClient(pc, u.Host, *url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Make sure the app received our report and is stuck
+	for done := false; !done; {
+		select {
+		case <-receivedReport:
+			done = true
+		default:
+			if err := p.Publish(NewPublishedReport(rpt)); err != nil {
+				t.Error(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Close the client while the app is stuck
+	p.Stop()
+
+	// Let the server go so that the test can end
+	close(stopHanging)
+}
+
+func TestAppClientPublishDeltas(t *testing.T) {
+	var (
+		published []report.Report
+		conflict  = true
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api" {
+			codec.NewEncoder(w, &codec.JsonHandle{}).Encode(xfer.Details{ReportSchema: report.SchemaVersion})
+			return
+		}
+		if have := r.Header.Get(xfer.ScopeReportDeltasHeader); have != "true" {
+			t.Errorf("Expected the %s header, got %q", xfer.ScopeReportDeltasHeader, have)
+		}
+		var rpt report.Report
+		if err := rpt.ReadBinary(r.Body, true, &codec.MsgpackHandle{}); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, rpt)
+		// Lose the base of the first delta, as if the app had restarted
+		if rpt.IsDelta() && conflict {
+			conflict = false
+			w.WriteHeader(http.StatusConflict)
+		}
+	}))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewAppClient(ProbeConfig{PublishDeltas: true}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	if _, err := client.Details(); err != nil {
+		t.Fatal(err)
+	}
+	c := client.(*appClient)
+
+	rpt1 := report.MakeReport()
+	rpt1.Host.AddNode(report.MakeNodeWith("host1", map[string]string{"k": "v"}))
+	rpt2 := rpt1.Copy()
+	rpt2.Host.AddNode(report.MakeNodeWith("host2", map[string]string{"k": "v"}))
+	rpt3 := rpt2.Copy()
+	rpt3.Host.AddNode(report.MakeNodeWith("host3", map[string]string{"k": "v"}))
+	for _, rpt := range []report.Report{rpt1, rpt2, rpt3} {
+		if err := c.publish(NewPublishedReport(rpt)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rpt1 in full; rpt2 as a delta, refused, then in full; rpt3 as a delta.
+	var deltaBases []string
+	for _, rpt := range published {
+		deltaBases = append(deltaBases, rpt.DeltaBase)
+	}
+	if want := []string{"", rpt1.ID, "", rpt2.ID}; !reflect.DeepEqual(want, deltaBases) {
+		t.Errorf("Expected reports with delta bases %q, got %q", want, deltaBases)
+	}
+	if last := published[len(published)-1]; len(last.Host.Nodes) != 1 || last.Host.Nodes["host3"].ID == "" {
+		t.Errorf("Expected only host3 in the last delta, got %v", last.Host.Nodes)
+	}
+}
+
+func TestAppClientSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spool, err := NewSpool(dir, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		down       = true
+		published  []string
+		timestamps []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var rpt report.Report
+		if err := rpt.ReadBinary(r.Body, true, &codec.MsgpackHandle{}); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, rpt.ID)
+		timestamps = append(timestamps, r.Header.Get(xfer.ScopeReportTimestampHeader))
+	}))
+	defer s.Close()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewAppClient(ProbeConfig{Spool: spool}, u.Host, *u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Stop()
+	c := client.(*appClient)
+
+	// The app is down: the report is spooled
+	made := time.Unix(1500000000, 0)
+	rpt1 := report.MakeReport()
+	if err := c.publish(NewPublishedReport(rpt1)); err == nil {
+		t.Fatal("Expected publishing to fail")
+	}
+	spooled := &PublishedReport{Report: rpt1, timestamp: made}
+	c.spool(spooled)
+	// and only once, when the clients of other apps of the target spool it
+	c.spool(spooled)
+	if spool.Len() != 1 {
+		t.Fatalf("Expected a spooled report, got %d", spool.Len())
+	}
+
+	// Spooled reports survive probe restarts
+	if spool, err = NewSpool(dir, 1024*1024); err != nil || spool.Len() != 1 {
+		t.Fatalf("Expected to find the spooled report again, got %d: %v", spool.Len(), err)
+	}
+	c.Spool = spool
+
+	// The app is back: the report is published after a newer one, as of
+	// the time it was made
+	down = false
+	rpt2 := report.MakeReport()
+	if err := c.publish(NewPublishedReport(rpt2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.replaySpool(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{rpt2.ID, rpt1.ID}; !reflect.DeepEqual(want, published) {
+		t.Errorf("Expected reports %v, got %v", want, published)
+	}
+	if want := []string{"", made.Format(time.RFC3339Nano)}; !reflect.DeepEqual(want, timestamps) {
+		t.Errorf("Expected timestamps %q, got %q", want, timestamps)
+	}
+	if spool.Len() != 0 {
+		t.Errorf("Expected the spool to be empty, got %d", spool.Len())
+	}
+}
+
+func TestSpoolSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rpt := report.MakeReport()
+	buf, err := rpt.WriteBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Room for two reports
+	spool, err := NewSpool(dir, int64(2*buf.Len()+buf.Len()/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := spool.Put(time.Unix(int64(i), 0), buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spool.Len() != 2 {
+		t.Fatalf("Expected 2 reports in the spool, got %d", spool.Len())
+	}
+	if timestamp, _, _, err := spool.Oldest(); err != nil || !timestamp.Equal(time.Unix(2, 0)) {
+		t.Errorf("Expected the oldest report to have been dropped, got %v: %v", timestamp, err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("Expected 2 files in the spool, got %d", len(files))
+	}
+}
@@ -0,0 +1,154 @@
+package appclient
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"context"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/weaveworks/scope/common/xfer"
+)
+
+// grpcTarget returns the address of the app's gRPC listener, if it has
+// one and we were asked to use it.
+func (c *appClient) grpcTarget() (string, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.UseGRPC || c.grpcPort == 0 {
+		return "", false
+	}
+	return net.JoinHostPort(c.target.Hostname(), strconv.Itoa(c.grpcPort)), true
+}
+
+// grpcConnection opens a stream to the app at target, publishes reports
+// over it and serves the control requests coming down it, until it breaks.
+func (c *appClient) grpcConnection(target string) (bool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	creds := grpc.WithInsecure()
+	if c.Target().Scheme == "https" {
+		creds = grpc.WithTransportCredentials(credentials.NewTLS(c.wsDialer.TLSClientConfig))
+	}
+	dialCtx, dialCancel := context.WithTimeout(ctx, dialTimeout)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, target, creds, grpc.WithBlock(), grpc.WithCodec(xfer.FrameCodec{}))
+	if err != nil {
+		return c.hasQuit(), err
+	}
+	defer conn.Close()
+
+	headers := http.Header{}
+	c.ProbeConfig.authorizeHeaders(headers)
+	headers.Set(xfer.ScopeReportContentTypeHeader, c.reportContentType())
+	c.mtx.Lock()
+	if c.grpcControls {
+		headers.Set(xfer.ScopeProbeControlsHeader, "true")
+	}
+	c.mtx.Unlock()
+	md := metadata.MD{}
+	for name, values := range headers {
+		md[strings.ToLower(name)] = values
+	}
+	stream, err := grpc.NewClientStream(metadata.NewOutgoingContext(ctx, md), &xfer.ConnectStreamDesc, conn, xfer.ConnectMethod)
+	if err != nil {
+		return c.hasQuit(), err
+	}
+	log.Infof("gRPC stream to %s (%s) established", c.hostname, target)
+
+	var sendMtx sync.Mutex // sends on a stream must not be concurrent
+	send := func(frame *xfer.Frame) error {
+		sendMtx.Lock()
+		defer sendMtx.Unlock()
+		return stream.SendMsg(frame)
+	}
+
+	recvErrs := make(chan error, 1)
+	go func() {
+		for {
+			var frame xfer.Frame
+			if err := stream.RecvMsg(&frame); err != nil {
+				recvErrs <- err
+				return
+			}
+			if frame.Type != xfer.ControlRequestFrame {
+				continue
+			}
+			var msg xfer.ControlMessage
+			if err := json.Unmarshal(frame.Payload, &msg); err != nil || msg.Request == nil {
+				log.Errorf("Invalid control request from %s: %v", c.hostname, err)
+				continue
+			}
+			go c.grpcControl(msg, send)
+		}
+	}()
+
+	for {
+		select {
+		case r, ok := <-c.reports:
+			if !ok {
+				stream.CloseSend()
+				return true, nil
+			}
+			if err := c.grpcPublish(r, send); err != nil {
+				c.spool(r)
+				return false, err
+			}
+			if err := c.replaySpool(); err != nil {
+				log.Errorf("Error publishing spooled reports to %s: %v", c.hostname, err)
+			}
+		case err := <-recvErrs:
+			if err == io.EOF {
+				err = nil
+			}
+			return c.hasQuit(), err
+		}
+	}
+}
+
+func (c *appClient) grpcPublish(r *PublishedReport, send func(*xfer.Frame) error) (err error) {
+	defer func(begin time.Time) {
+		publishDuration.WithLabelValues(strconv.FormatBool(err == nil)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	// Always in full: frames aren't acknowledged, so there's no telling
+	// which report the app could apply a delta to
+	buf, err := r.encoded()
+	if err != nil {
+		return err
+	}
+	reportSize.Observe(float64(len(buf)))
+	// Blocks while the app is not keeping up, which backs up Publish
+	return send(&xfer.Frame{Type: xfer.ReportFrame, Payload: buf})
+}
+
+func (c *appClient) grpcControl(msg xfer.ControlMessage, send func(*xfer.Frame) error) {
+	req := *msg.Request
+	req.AppID = c.getAppID()
+	var res xfer.Response
+	c.control.Handle(req, &res)
+	payload, err := json.Marshal(xfer.ControlMessage{ID: msg.ID, Response: &res})
+	if err == nil {
+		err = send(&xfer.Frame{Type: xfer.ControlResponseFrame, Payload: payload})
+	}
+	if err != nil {
+		log.Errorf("Error responding to control request from %s: %v", c.hostname, err)
+	}
+}
@@ -0,0 +1,177 @@
+package appclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Spooled reports are gzipped msgpack, named after the time they were made,
+// so a spool can also be read with --app.collector=file://
+const spoolSuffix = ".msgpack.gz"
+
+// Spools are the spools of the targets of a probe, each in a directory of
+// its own, for the apps of every target to get the reports they missed,
+// whichever comes back first.
+type Spools struct {
+	dir     string
+	maxSize int64
+
+	mtx    sync.Mutex
+	spools map[string]*Spool // by target
+}
+
+// NewSpools makes the Spools in dir, of up to maxSize bytes of reports
+// each.
+func NewSpools(dir string, maxSize int64) (*Spools, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Spools{dir: dir, maxSize: maxSize, spools: map[string]*Spool{}}, nil
+}
+
+// For returns the spool of target, opening it the first time, with the
+// reports left there by a previous probe.
+func (s *Spools) For(target string) (*Spool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if spool, ok := s.spools[target]; ok {
+		return spool, nil
+	}
+	spool, err := NewSpool(filepath.Join(s.dir, url.QueryEscape(target)), s.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	s.spools[target] = spool
+	return spool, nil
+}
+
+// Spool is a bounded, on-disk queue of the reports which couldn't be
+// published while the apps of a target were unreachable, to publish them
+// once one is back. The oldest reports are dropped when it is full.
+type Spool struct {
+	dir     string
+	maxSize int64
+
+	mtx   sync.Mutex
+	files []spooledFile // oldest first
+	size  int64
+}
+
+type spooledFile struct {
+	timestamp time.Time
+	size      int64
+}
+
+// NewSpool makes a Spool of up to maxSize bytes of reports in dir, with
+// the reports left there by a previous probe.
+func NewSpool(dir string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Spool{dir: dir, maxSize: maxSize}
+	for _, info := range infos {
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(info.Name(), spoolSuffix), 10, 64)
+		if err != nil || !strings.HasSuffix(info.Name(), spoolSuffix) {
+			continue
+		}
+		s.files = append(s.files, spooledFile{time.Unix(0, nanos), info.Size()})
+		s.size += info.Size()
+	}
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].timestamp.Before(s.files[j].timestamp) })
+	s.mtx.Lock()
+	s.trim()
+	s.mtx.Unlock()
+	if len(s.files) > 0 {
+		log.Infof("Spool %s holds %d reports to publish", dir, len(s.files))
+	}
+	return s, nil
+}
+
+func (s *Spool) path(timestamp time.Time) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%d%s", timestamp.UnixNano(), spoolSuffix))
+}
+
+// trim drops the oldest reports until the spool is within its size. It
+// must be called with mtx held.
+func (s *Spool) trim() {
+	for len(s.files) > 0 && s.size > s.maxSize {
+		oldest := s.files[0]
+		if err := os.Remove(s.path(oldest.timestamp)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Error dropping spooled report: %v", err)
+		}
+		s.files = s.files[1:]
+		s.size -= oldest.size
+	}
+}
+
+// Put spools buf, a report made at timestamp as gzipped msgpack.
+func (s *Spool) Put(timestamp time.Time, buf []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, f := range s.files {
+		if f.timestamp.Equal(timestamp) {
+			return nil // already spooled, e.g. by the client of another app of the target
+		}
+	}
+	if err := ioutil.WriteFile(s.path(timestamp), buf, 0600); err != nil {
+		return err
+	}
+	i := sort.Search(len(s.files), func(i int) bool { return s.files[i].timestamp.After(timestamp) })
+	s.files = append(s.files, spooledFile{})
+	copy(s.files[i+1:], s.files[i:])
+	s.files[i] = spooledFile{timestamp, int64(len(buf))}
+	s.size += int64(len(buf))
+	s.trim()
+	return nil
+}
+
+// Oldest returns the oldest report in the spool, gzipped msgpack, and the
+// time it was made, if there are any.
+func (s *Spool) Oldest() (time.Time, []byte, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.files) == 0 {
+		return time.Time{}, nil, false, nil
+	}
+	timestamp := s.files[0].timestamp
+	buf, err := ioutil.ReadFile(s.path(timestamp))
+	return timestamp, buf, true, err
+}
+
+// Remove removes the report made at timestamp from the spool, once it is
+// published.
+func (s *Spool) Remove(timestamp time.Time) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i, f := range s.files {
+		if f.timestamp.Equal(timestamp) {
+			if err := os.Remove(s.path(timestamp)); err != nil && !os.IsNotExist(err) {
+				log.Errorf("Error removing spooled report: %v", err)
+			}
+			s.files = append(s.files[:i], s.files[i+1:]...)
+			s.size -= f.size
+			return
+		}
+	}
+}
+
+// Len is the number of reports in the spool.
+func (s *Spool) Len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.files)
+}
@@ -0,0 +1,119 @@
+package awsecs
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+)
+
+// How often the tasks are read from the ECS agent.
+const agentPollInterval = 5 * time.Second
+
+// AgentTask is a task running on this container instance, as the ECS agent
+// knows it. Exported for test.
+type AgentTask struct {
+	Arn           string
+	Family        string
+	Version       string
+	KnownStatus   string
+	DesiredStatus string
+	Containers    []AgentContainer
+}
+
+// AgentContainer is a container of an AgentTask. Exported for test.
+type AgentContainer struct {
+	DockerID string `json:"DockerId"`
+	Name     string
+}
+
+// agentClient reads the tasks on this container instance from the
+// introspection API of the ECS agent. Unlike the container labels, this
+// also knows the status of the tasks, and needs no AWS credentials.
+//
+// The tasks are read in the background, every agentPollInterval, for
+// reports not to wait for the agent.
+type agentClient struct {
+	url    string
+	client *http.Client
+	quit   chan struct{}
+
+	mtx     sync.Mutex
+	cluster string
+	tasks   []AgentTask
+	err     error
+}
+
+func newAgentClient(url string) *agentClient {
+	a := &agentClient{
+		url:    url,
+		client: &http.Client{Timeout: time.Second},
+		quit:   make(chan struct{}),
+		err:    fmt.Errorf("no tasks read from the ECS agent yet"),
+	}
+	go a.loop()
+	return a
+}
+
+func (a *agentClient) loop() {
+	ticker := time.NewTicker(agentPollInterval)
+	defer ticker.Stop()
+	for {
+		cluster, tasks, err := a.readTasks()
+		if err != nil {
+			log.Debugf("Error reading tasks from the ECS agent: %v", err)
+		}
+		a.mtx.Lock()
+		a.cluster, a.tasks, a.err = cluster, tasks, err
+		a.mtx.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+func (a *agentClient) stop() {
+	close(a.quit)
+}
+
+func (a *agentClient) get(path string, v interface{}) error {
+	resp, err := a.client.Get(a.url + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s%s: %s", a.url, path, resp.Status)
+	}
+	return codec.NewDecoder(resp.Body, &codec.JsonHandle{}).Decode(v)
+}
+
+// getTasks returns the cluster of this container instance, and its tasks,
+// as last read from the agent.
+func (a *agentClient) getTasks() (string, []AgentTask, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.cluster, a.tasks, a.err
+}
+
+func (a *agentClient) readTasks() (string, []AgentTask, error) {
+	var metadata struct {
+		Cluster string
+	}
+	if err := a.get("/v1/metadata", &metadata); err != nil {
+		return "", nil, err
+	}
+	var tasks struct {
+		Tasks []AgentTask
+	}
+	if err := a.get("/v1/tasks", &tasks); err != nil {
+		return "", nil, err
+	}
+	return metadata.Cluster, tasks.Tasks, nil
+}
@@ -0,0 +1,166 @@
+package cloud
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys for use in Node.Latest.
+const (
+	Provider     = "cloud_provider"
+	InstanceID   = "cloud_instance_id"
+	InstanceType = "cloud_instance_type"
+	Zone         = "cloud_zone"
+	TagPrefix    = "cloud_tag_"
+)
+
+// Exposed for testing.
+var (
+	MetadataTemplates = report.MetadataTemplates{
+		Provider:     {ID: Provider, Label: "Cloud provider", From: report.FromLatest, Priority: 3},
+		InstanceID:   {ID: InstanceID, Label: "Instance ID", From: report.FromLatest, Priority: 4},
+		InstanceType: {ID: InstanceType, Label: "Instance type", From: report.FromLatest, Priority: 5},
+		Zone:         {ID: Zone, Label: "Zone", From: report.FromLatest, Priority: 6},
+	}
+
+	TableTemplates = report.TableTemplates{
+		TagPrefix: {
+			ID:     TagPrefix,
+			Label:  "Instance tags",
+			Type:   report.PropertyListType,
+			Prefix: TagPrefix,
+		},
+	}
+)
+
+// Metadata describes the cloud instance the probe runs on.
+type Metadata struct {
+	Provider     string
+	InstanceID   string
+	InstanceType string
+	Zone         string
+	Tags         map[string]string
+}
+
+// A metadataService fetches the metadata of this instance from the
+// metadata service of a cloud provider.
+type metadataService interface {
+	name() string
+	fetch(client *http.Client) (Metadata, error)
+}
+
+// Reporter tags the host node with the metadata of the cloud instance it
+// runs on, from the EC2 or GCE metadata service. The metadata is fetched in
+// the background, and refreshed every interval to pick up changes to tags.
+type Reporter struct {
+	hostID   string
+	services []metadataService
+	client   *http.Client
+	interval time.Duration
+	quit     chan struct{}
+
+	mtx      sync.Mutex
+	metadata *Metadata // nil until found
+}
+
+// NewReporter makes a new Reporter, which looks for the metadata services
+// of every supported cloud provider.
+func NewReporter(hostID string, interval time.Duration) *Reporter {
+	r := &Reporter{
+		hostID:   hostID,
+		services: []metadataService{ec2{baseURL: EC2MetadataURL}, gce{baseURL: GCEMetadataURL}},
+		client:   &http.Client{Timeout: 2 * time.Second},
+		interval: interval,
+		quit:     make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// Name of this reporter, for metrics gathering
+func (*Reporter) Name() string { return "Cloud" }
+
+// Stop stops the reporter.
+func (r *Reporter) Stop() {
+	close(r.quit)
+}
+
+func (r *Reporter) loop() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.refresh()
+		select {
+		case <-ticker.C:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// refresh fetches the metadata from the first service which answers,
+// keeping the previous metadata if none do.
+func (r *Reporter) refresh() {
+	for _, s := range r.services {
+		metadata, err := s.fetch(r.client)
+		if err != nil {
+			log.Debugf("Cloud: no %s metadata: %v", s.name(), err)
+			continue
+		}
+		r.mtx.Lock()
+		r.metadata = &metadata
+		r.mtx.Unlock()
+		return
+	}
+}
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	rpt := report.MakeReport()
+	r.mtx.Lock()
+	metadata := r.metadata
+	r.mtx.Unlock()
+	if metadata == nil {
+		return rpt, nil
+	}
+
+	latests := map[string]string{
+		Provider:     metadata.Provider,
+		InstanceID:   metadata.InstanceID,
+		InstanceType: metadata.InstanceType,
+		Zone:         metadata.Zone,
+	}
+	for k, v := range latests {
+		if v == "" {
+			delete(latests, k)
+		}
+	}
+	rpt.Host.AddNode(
+		report.MakeNodeWith(report.MakeHostNodeID(r.hostID), latests).
+			AddPrefixPropertyList(TagPrefix, metadata.Tags),
+	)
+	rpt.Host = rpt.Host.
+		WithMetadataTemplates(MetadataTemplates).
+		WithTableTemplates(TableTemplates)
+	return rpt, nil
+}
+
+func get(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
@@ -0,0 +1,116 @@
+package cloud_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/probe/cloud"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+)
+
+const hostID = "hostid"
+
+func latests(t *testing.T, r *cloud.Reporter) map[string]string {
+	rpt, err := r.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]string{}
+	if node, ok := rpt.Host.Nodes[report.MakeHostNodeID(hostID)]; ok {
+		node.Latest.ForEach(func(k string, _ time.Time, v string) {
+			result[k] = v
+		})
+	}
+	return result
+}
+
+func withMetadata(t *testing.T, ec2, gce http.HandlerFunc) func() {
+	oldEC2, oldGCE := cloud.EC2MetadataURL, cloud.GCEMetadataURL
+	ec2Server, gceServer := httptest.NewServer(ec2), httptest.NewServer(gce)
+	cloud.EC2MetadataURL, cloud.GCEMetadataURL = ec2Server.URL, gceServer.URL
+	return func() {
+		ec2Server.Close()
+		gceServer.Close()
+		cloud.EC2MetadataURL, cloud.GCEMetadataURL = oldEC2, oldGCE
+	}
+}
+
+func TestEC2(t *testing.T) {
+	metadata := map[string]string{
+		"/latest/meta-data/instance-id":                 "i-0123456789",
+		"/latest/meta-data/instance-type":               "m5.large",
+		"/latest/meta-data/placement/availability-zone": "eu-west-1a",
+		"/latest/meta-data/tags/instance":               "team\nName",
+		"/latest/meta-data/tags/instance/team":          "payments",
+		"/latest/meta-data/tags/instance/Name":          "worker-1",
+	}
+	defer withMetadata(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			w.Write([]byte("token"))
+			return
+		}
+		value, ok := metadata[r.URL.Path]
+		if !ok || r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	}, http.NotFound)()
+
+	r := cloud.NewReporter(hostID, time.Minute)
+	defer r.Stop()
+	test.Poll(t, time.Second, map[string]string{
+		cloud.Provider:           "aws",
+		cloud.InstanceID:         "i-0123456789",
+		cloud.InstanceType:       "m5.large",
+		cloud.Zone:               "eu-west-1a",
+		cloud.TagPrefix + "team": "payments",
+		cloud.TagPrefix + "Name": "worker-1",
+	}, func() interface{} {
+		return latests(t, r)
+	})
+}
+
+func TestGCE(t *testing.T) {
+	metadata := map[string]string{
+		"/computeMetadata/v1/instance/id":           "4520031799277581759",
+		"/computeMetadata/v1/instance/machine-type": "projects/123/machineTypes/n1-standard-2",
+		"/computeMetadata/v1/instance/zone":         "projects/123/zones/us-central1-b",
+		"/computeMetadata/v1/instance/tags":         "http-server\nweb",
+	}
+	defer withMetadata(t, http.NotFound, func(w http.ResponseWriter, r *http.Request) {
+		value, ok := metadata[r.URL.Path]
+		if !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	})()
+
+	r := cloud.NewReporter(hostID, time.Minute)
+	defer r.Stop()
+	test.Poll(t, time.Second, map[string]string{
+		cloud.Provider:                  "gcp",
+		cloud.InstanceID:                "4520031799277581759",
+		cloud.InstanceType:              "n1-standard-2",
+		cloud.Zone:                      "us-central1-b",
+		cloud.TagPrefix + "http-server": "",
+		cloud.TagPrefix + "web":         "",
+	}, func() interface{} {
+		return latests(t, r)
+	})
+}
+
+func TestNoCloud(t *testing.T) {
+	defer withMetadata(t, http.NotFound, http.NotFound)()
+
+	r := cloud.NewReporter(hostID, time.Minute)
+	defer r.Stop()
+	time.Sleep(50 * time.Millisecond)
+	if have := latests(t, r); len(have) != 0 {
+		t.Errorf("Expected no host node, got %v", have)
+	}
+}
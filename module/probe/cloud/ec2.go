@@ -0,0 +1,61 @@
+package cloud
+
+import (
+	"net/http"
+	"strings"
+)
+
+// EC2MetadataURL is the base URL of the EC2 instance metadata service.
+// Exposed for testing.
+var EC2MetadataURL = "http://169.254.169.254"
+
+// ec2 reads the instance metadata with IMDSv2, getting a session token
+// first. Tags are only there if the instance allows access to tags in its
+// metadata.
+type ec2 struct {
+	baseURL string
+}
+
+func (ec2) name() string { return "EC2" }
+
+func (e ec2) fetch(client *http.Client) (Metadata, error) {
+	req, err := http.NewRequest("PUT", e.baseURL+"/latest/api/token", nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := get(client, req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	metadata := func(path string) (string, error) {
+		req, err := http.NewRequest("GET", e.baseURL+"/latest/meta-data/"+path, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		return get(client, req)
+	}
+
+	result := Metadata{Provider: "aws", Tags: map[string]string{}}
+	for path, field := range map[string]*string{
+		"instance-id":                 &result.InstanceID,
+		"instance-type":               &result.InstanceType,
+		"placement/availability-zone": &result.Zone,
+	} {
+		if *field, err = metadata(path); err != nil {
+			return Metadata{}, err
+		}
+	}
+	keys, err := metadata("tags/instance")
+	if err != nil {
+		// Access to tags isn't enabled on the instance
+		return result, nil
+	}
+	for _, key := range strings.Fields(keys) {
+		if value, err := metadata("tags/instance/" + key); err == nil {
+			result.Tags[key] = value
+		}
+	}
+	return result, nil
+}
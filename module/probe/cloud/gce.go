@@ -0,0 +1,53 @@
+package cloud
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// GCEMetadataURL is the base URL of the GCE metadata server. Exposed for
+// testing.
+var GCEMetadataURL = "http://metadata.google.internal"
+
+// gce reads the instance metadata from the GCE metadata server. Its network
+// tags are reported as tags; labels aren't in the metadata.
+type gce struct {
+	baseURL string
+}
+
+func (gce) name() string { return "GCE" }
+
+func (g gce) fetch(client *http.Client) (Metadata, error) {
+	metadata := func(p string) (string, error) {
+		req, err := http.NewRequest("GET", g.baseURL+"/computeMetadata/v1/instance/"+p, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return get(client, req)
+	}
+
+	result := Metadata{Provider: "gcp", Tags: map[string]string{}}
+	var machineType, zone string
+	var err error
+	for p, field := range map[string]*string{
+		"id":           &result.InstanceID,
+		"machine-type": &machineType,
+		"zone":         &zone,
+	} {
+		if *field, err = metadata(p); err != nil {
+			return Metadata{}, err
+		}
+	}
+	// These are of the form projects/<project>/machineTypes/<type>, and
+	// projects/<project>/zones/<zone>
+	result.InstanceType, result.Zone = path.Base(machineType), path.Base(zone)
+
+	if tags, err := metadata("tags?alt=text"); err == nil {
+		for _, tag := range strings.Fields(tags) {
+			result.Tags[tag] = ""
+		}
+	}
+	return result, nil
+}
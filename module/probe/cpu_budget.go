@@ -0,0 +1,144 @@
+package probe
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	// The first levels of degradation slow down spying and publishing,
+	// by doubling their intervals at each level. The further levels give
+	// up on the degradations of the budget, in order.
+	slowdownLevels = 2
+
+	// How long the CPU usage of the probe is measured over, before
+	// changing the level of degradation.
+	budgetWindow = 10 * time.Second
+)
+
+// Degradation is something expensive the probe gives up on, while it uses
+// more CPU than its budget.
+type Degradation struct {
+	Name    string // What is given up, e.g. "process metrics"
+	Degrade func(degraded bool)
+}
+
+// CPUBudget keeps the CPU usage of the probe under a share of one CPU. The
+// probe degrades one level further at every window it is over budget, and
+// recovers one level at every window it uses less than half of it.
+type CPUBudget struct {
+	max          float64
+	cpuTime      func() (time.Duration, error)
+	degradations []Degradation
+
+	mtx         sync.Mutex
+	level       int
+	lastCPUTime time.Duration
+	lastCheck   time.Time
+}
+
+// NewCPUBudget makes a new CPUBudget of max, e.g. 0.05 for 5% of one CPU,
+// with what to give up on, least useful first. cpuTime tells how much CPU
+// time the probe has used in all.
+func NewCPUBudget(max float64, cpuTime func() (time.Duration, error), degradations ...Degradation) *CPUBudget {
+	return &CPUBudget{
+		max:          max,
+		cpuTime:      cpuTime,
+		degradations: degradations,
+	}
+}
+
+// Check measures the CPU usage of the probe since the last time it was
+// checked, once the window has passed, and changes the level of
+// degradation accordingly.
+func (b *CPUBudget) Check() {
+	now := mtime.Now()
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if !b.lastCheck.IsZero() && now.Sub(b.lastCheck) < budgetWindow {
+		return
+	}
+	cpuTime, err := b.cpuTime()
+	if err != nil {
+		log.Warnf("Cannot measure the CPU usage of the probe: %v", err)
+		return
+	}
+	if !b.lastCheck.IsZero() {
+		usage := float64(cpuTime-b.lastCPUTime) / float64(now.Sub(b.lastCheck))
+		switch {
+		case usage > b.max && b.level < slowdownLevels+len(b.degradations):
+			b.setLevel(b.level + 1)
+			log.Warnf("Probe used %.1f%% CPU, over its budget of %.1f%%; degrading to %s", usage*100, b.max*100, b.describe())
+		case usage < b.max/2 && b.level > 0:
+			b.setLevel(b.level - 1)
+			log.Infof("Probe used %.1f%% CPU, under its budget of %.1f%%; recovering to %s", usage*100, b.max*100, b.describe())
+		}
+	}
+	b.lastCPUTime, b.lastCheck = cpuTime, now
+}
+
+func (b *CPUBudget) setLevel(level int) {
+	for i, d := range b.degradations {
+		if degraded := level > slowdownLevels+i; degraded != (b.level > slowdownLevels+i) {
+			d.Degrade(degraded)
+		}
+	}
+	b.level = level
+}
+
+// Level is the current level of degradation, 0 for none.
+func (b *CPUBudget) Level() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.level
+}
+
+// Slowdown is by how much the spy and publish intervals are multiplied.
+func (b *CPUBudget) Slowdown() time.Duration {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.slowdown()
+}
+
+func (b *CPUBudget) slowdown() time.Duration {
+	level := b.level
+	if level > slowdownLevels {
+		level = slowdownLevels
+	}
+	return 1 << uint(level)
+}
+
+func (b *CPUBudget) describe() string {
+	if b.level == 0 {
+		return "none"
+	}
+	parts := []string{fmt.Sprintf("%dx slower reports", b.slowdown())}
+	for i, d := range b.degradations {
+		if b.level > slowdownLevels+i {
+			parts = append(parts, "no "+d.Name)
+		}
+	}
+	return fmt.Sprintf("level %d (%s)", b.level, strings.Join(parts, ", "))
+}
+
+// Name of this tagger, for metrics gathering
+func (*CPUBudget) Name() string { return "CPUBudget" }
+
+// Tag implements Tagger, by telling the level of degradation in the host
+// node.
+func (b *CPUBudget) Tag(r report.Report) (report.Report, error) {
+	b.mtx.Lock()
+	degradation := b.describe()
+	b.mtx.Unlock()
+	for id, n := range r.Host.Nodes {
+		r.Host.Nodes[id] = n.WithLatests(map[string]string{report.ProbeDegradation: degradation})
+	}
+	return r, nil
+}
@@ -0,0 +1,17 @@
+// +build !windows
+
+package probe
+
+import (
+	"syscall"
+	"time"
+)
+
+// ProcessCPUTime is the user and system CPU time used by this process.
+func ProcessCPUTime() (time.Duration, error) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, err
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), nil
+}
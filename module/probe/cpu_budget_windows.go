@@ -0,0 +1,25 @@
+package probe
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// ProcessCPUTime is the user and system CPU time used by this process.
+func ProcessCPUTime() (time.Duration, error) {
+	process, err := windows.GetCurrentProcess()
+	if err != nil {
+		return 0, err
+	}
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0, err
+	}
+	return filetimeDuration(kernel) + filetimeDuration(user), nil
+}
+
+// The times of processes are in 100-nanosecond intervals
+func filetimeDuration(ft windows.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
@@ -0,0 +1,80 @@
+package cri
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/weaveworks/scope/common/xfer"
+	client "github.com/weaveworks/scope/cri/runtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// Control IDs used by the CRI integration. They differ from those of the
+// docker integration, so both can run in the same probe.
+const (
+	StopContainer   = "cri_stop_container"
+	RemoveContainer = "cri_remove_container"
+
+	waitTime = 10
+)
+
+// ContainerControls are the controls of containers the CRI supports.
+var ContainerControls = []report.Control{
+	{
+		ID:           StopContainer,
+		Human:        "Stop",
+		Icon:         "fa fa-stop",
+		Confirmation: "Are you sure you want to stop this container?",
+		Rank:         7,
+	},
+	{
+		ID:           RemoveContainer,
+		Human:        "Remove",
+		Icon:         "far fa-trash-alt",
+		Confirmation: "Are you sure you want to remove this container?",
+		Rank:         8,
+	},
+}
+
+func (r *Reporter) stopContainer(containerID string, _ xfer.Request) xfer.Response {
+	log.Infof("Stopping container %s", containerID)
+	_, err := r.cri.StopContainer(context.Background(), &client.StopContainerRequest{
+		ContainerId: containerID,
+		Timeout:     waitTime,
+	})
+	return xfer.ResponseError(err)
+}
+
+func (r *Reporter) removeContainer(containerID string, req xfer.Request) xfer.Response {
+	log.Infof("Removing container %s", containerID)
+	if _, err := r.cri.RemoveContainer(context.Background(), &client.RemoveContainerRequest{
+		ContainerId: containerID,
+	}); err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{
+		RemovedNode: req.NodeID,
+	}
+}
+
+func captureContainerID(f func(string, xfer.Request) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		containerID, ok := report.ParseContainerNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		return f(containerID, req)
+	}
+}
+
+func (r *Reporter) registerControls() {
+	r.handlerRegistry.Batch(nil, map[string]xfer.ControlHandlerFunc{
+		StopContainer:   captureContainerID(r.stopContainer),
+		RemoveContainer: captureContainerID(r.removeContainer),
+	})
+}
+
+func (r *Reporter) deregisterControls() {
+	r.handlerRegistry.Batch([]string{StopContainer, RemoveContainer}, nil)
+}
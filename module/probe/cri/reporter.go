@@ -0,0 +1,206 @@
+package cri
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	log "github.com/sirupsen/logrus"
+
+	client "github.com/weaveworks/scope/cri/runtime"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+// Reporter generate Reports containing Container and ContainerImage topologies
+type Reporter struct {
+	cri             client.RuntimeServiceClient
+	images          client.ImageServiceClient
+	probeID         string
+	handlerRegistry *controls.HandlerRegistry
+
+	// The CPU usage of containers at the last report, to work out how
+	// much of the CPU they used since.
+	cpuUsage map[string]*client.CpuUsage
+}
+
+// NewReporter makes a new Reporter. images may be nil, to leave out the
+// ContainerImage topology.
+func NewReporter(cri client.RuntimeServiceClient, images client.ImageServiceClient, probeID string, handlerRegistry *controls.HandlerRegistry) *Reporter {
+	reporter := &Reporter{
+		cri:             cri,
+		images:          images,
+		probeID:         probeID,
+		handlerRegistry: handlerRegistry,
+		cpuUsage:        map[string]*client.CpuUsage{},
+	}
+	reporter.registerControls()
+
+	return reporter
+}
+
+// Stop deregisters the controls of the reporter.
+func (r *Reporter) Stop() {
+	r.deregisterControls()
+}
+
+// Name of this reporter, for metrics gathering
+func (Reporter) Name() string { return "CRI" }
+
+// Report generates a Report containing Container and ContainerImage topologies
+func (r *Reporter) Report() (report.Report, error) {
+	result := report.MakeReport()
+	containerTopol, err := r.containerTopology()
+	if err != nil {
+		return report.MakeReport(), err
+	}
+	imageTopol, err := r.containerImageTopology()
+	if err != nil {
+		// The containers are worth reporting without their images
+		log.Warnf("CRI: error listing images, reporting no images: %v", err)
+	}
+
+	result.Container = result.Container.Merge(containerTopol)
+	result.ContainerImage = result.ContainerImage.Merge(imageTopol)
+	return result, nil
+}
+
+func (r *Reporter) containerTopology() (report.Topology, error) {
+	result := report.MakeTopology().
+		WithMetadataTemplates(docker.ContainerMetadataTemplates).
+		WithMetricTemplates(docker.ContainerMetricTemplates).
+		WithTableTemplates(docker.ContainerTableTemplates)
+	result.Controls.AddControls(ContainerControls)
+
+	ctx := context.Background()
+	resp, err := r.cri.ListContainers(ctx, &client.ListContainersRequest{})
+	if err != nil {
+		return result, err
+	}
+	metrics, err := r.containerMetrics(ctx)
+	if err != nil {
+		log.Warnf("CRI: error listing the stats of containers, reporting them without: %v", err)
+	}
+
+	for _, c := range resp.Containers {
+		node := getNode(c).
+			WithLatests(map[string]string{report.ControlProbeID: r.probeID}).
+			WithMetrics(metrics[c.Id])
+		result.AddNode(node)
+	}
+
+	return result, nil
+}
+
+// containerMetrics returns the memory and CPU usage of the containers, by
+// ID. The CRI only gives the CPU time containers used in all, so their CPU
+// usage is only known from the second report on.
+func (r *Reporter) containerMetrics(ctx context.Context) (map[string]report.Metrics, error) {
+	resp, err := r.cri.ListContainerStats(ctx, &client.ListContainerStatsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]report.Metrics{}
+	cpuUsage := map[string]*client.CpuUsage{}
+	for _, s := range resp.Stats {
+		if s.Attributes == nil {
+			continue
+		}
+		id := s.Attributes.Id
+		metrics := report.Metrics{}
+		if m := s.Memory; m != nil && m.WorkingSetBytes != nil {
+			metrics[docker.MemoryUsage] = report.MakeSingletonMetric(time.Unix(0, m.Timestamp), float64(m.WorkingSetBytes.Value))
+		}
+		if c := s.Cpu; c != nil && c.UsageCoreNanoSeconds != nil {
+			if previous, ok := r.cpuUsage[id]; ok && c.Timestamp > previous.Timestamp {
+				used := float64(c.UsageCoreNanoSeconds.Value - previous.UsageCoreNanoSeconds.Value)
+				elapsed := float64(c.Timestamp-previous.Timestamp) * float64(runtime.NumCPU())
+				metrics[docker.CPUTotalUsage] = report.MakeSingletonMetric(time.Unix(0, c.Timestamp), used/elapsed*100.0).WithMax(100.0)
+			}
+			cpuUsage[id] = c
+		}
+		result[id] = metrics
+	}
+	r.cpuUsage = cpuUsage
+
+	return result, nil
+}
+
+func (r *Reporter) containerImageTopology() (report.Topology, error) {
+	result := report.MakeTopology().
+		WithMetadataTemplates(docker.ContainerImageMetadataTemplates).
+		WithTableTemplates(docker.ContainerImageTableTemplates)
+	if r.images == nil {
+		return result, nil
+	}
+
+	resp, err := r.images.ListImages(context.Background(), &client.ListImagesRequest{})
+	if err != nil {
+		return result, err
+	}
+
+	for _, image := range resp.Images {
+		imageID := trimImageID(image.Id)
+		latests := map[string]string{
+			docker.ImageID:   imageID,
+			docker.ImageSize: humanize.Bytes(image.Size_),
+		}
+		if len(image.RepoTags) > 0 {
+			imageFullName := image.RepoTags[0]
+			latests[docker.ImageName] = docker.ImageNameWithoutTag(imageFullName)
+			latests[docker.ImageTag] = docker.ImageNameTag(imageFullName)
+		}
+		result.AddNode(report.MakeNodeWith(report.MakeContainerImageNodeID(imageID), latests))
+	}
+
+	return result, nil
+}
+
+func getNode(c *client.Container) report.Node {
+	state, stateHuman := containerState(c.State)
+	imageID := trimImageID(c.ImageRef)
+	result := report.MakeNodeWith(report.MakeContainerNodeID(c.Id), map[string]string{
+		docker.ContainerName:         c.Metadata.Name,
+		docker.ContainerID:           c.Id,
+		docker.ContainerState:        state,
+		docker.ContainerStateHuman:   stateHuman,
+		docker.ContainerRestartCount: fmt.Sprintf("%v", c.Metadata.Attempt),
+		docker.ContainerCreated:      time.Unix(0, c.CreatedAt).Format(time.RFC3339Nano),
+		docker.ImageID:               imageID,
+		docker.ImageName:             c.Image.Image,
+	}).WithParents(report.MakeSets().
+		Add(report.ContainerImage, report.MakeStringSet(report.MakeContainerImageNodeID(imageID))),
+	)
+	result = result.AddPrefixPropertyList(docker.LabelPrefix, c.Labels)
+
+	running := state == docker.StateRunning
+	result = result.WithLatestControls(map[string]report.NodeControlData{
+		StopContainer:   {Dead: !running},
+		RemoveContainer: {Dead: running},
+	})
+
+	return result
+}
+
+// containerState maps the state of a CRI container to that of a docker
+// one, and how to show it.
+func containerState(state client.ContainerState) (string, string) {
+	switch state {
+	case client.ContainerState_CONTAINER_CREATED:
+		return docker.StateCreated, "Created"
+	case client.ContainerState_CONTAINER_RUNNING:
+		return docker.StateRunning, "Running"
+	case client.ContainerState_CONTAINER_EXITED:
+		return docker.StateExited, "Exited"
+	}
+	return "unknown", "Unknown"
+}
+
+func trimImageID(id string) string {
+	return strings.TrimPrefix(id, "sha256:")
+}
@@ -0,0 +1,166 @@
+package cri_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/weaveworks/scope/common/xfer"
+	client "github.com/weaveworks/scope/cri/runtime"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/cri"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+type mockRuntimeClient struct {
+	client.RuntimeServiceClient
+	cpu      uint64
+	at       int64
+	stopped  []string
+	statsErr error
+}
+
+func (m *mockRuntimeClient) ListContainers(context.Context, *client.ListContainersRequest, ...grpc.CallOption) (*client.ListContainersResponse, error) {
+	return &client.ListContainersResponse{Containers: []*client.Container{
+		{
+			Id:       "ping",
+			Metadata: &client.ContainerMetadata{Name: "pinger", Attempt: 1},
+			Image:    &client.ImageSpec{Image: "weaveworks/ping:latest"},
+			ImageRef: "sha256:abc",
+			State:    client.ContainerState_CONTAINER_RUNNING,
+			Labels:   map[string]string{"app": "ping"},
+		},
+		{
+			Id:       "pong",
+			Metadata: &client.ContainerMetadata{Name: "ponger"},
+			Image:    &client.ImageSpec{Image: "weaveworks/pong:latest"},
+			ImageRef: "sha256:def",
+			State:    client.ContainerState_CONTAINER_EXITED,
+		},
+	}}, nil
+}
+
+func (m *mockRuntimeClient) ListContainerStats(context.Context, *client.ListContainerStatsRequest, ...grpc.CallOption) (*client.ListContainerStatsResponse, error) {
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	return &client.ListContainerStatsResponse{Stats: []*client.ContainerStats{
+		{
+			Attributes: &client.ContainerAttributes{Id: "ping"},
+			Cpu:        &client.CpuUsage{Timestamp: m.at, UsageCoreNanoSeconds: &client.UInt64Value{Value: m.cpu}},
+			Memory:     &client.MemoryUsage{Timestamp: m.at, WorkingSetBytes: &client.UInt64Value{Value: 1024}},
+		},
+	}}, nil
+}
+
+func (m *mockRuntimeClient) StopContainer(_ context.Context, req *client.StopContainerRequest, _ ...grpc.CallOption) (*client.StopContainerResponse, error) {
+	m.stopped = append(m.stopped, req.ContainerId)
+	return &client.StopContainerResponse{}, nil
+}
+
+type mockImageClient struct {
+	client.ImageServiceClient
+	err error
+}
+
+func (m mockImageClient) ListImages(context.Context, *client.ListImagesRequest, ...grpc.CallOption) (*client.ListImagesResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &client.ListImagesResponse{Images: []*client.Image{
+		{Id: "sha256:abc", RepoTags: []string{"weaveworks/ping:latest"}, Size_: 2048},
+	}}, nil
+}
+
+func TestReporter(t *testing.T) {
+	runtimeClient := &mockRuntimeClient{at: time.Unix(1000, 0).UnixNano()}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := cri.NewReporter(runtimeClient, mockImageClient{}, "probe", hr)
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ping, ok := rpt.Container.Nodes[report.MakeContainerNodeID("ping")]
+	if !ok {
+		t.Fatalf("Expected a node for container ping, got %v", rpt.Container.Nodes)
+	}
+	for key, want := range map[string]string{
+		docker.ContainerName:         "pinger",
+		docker.ContainerState:        docker.StateRunning,
+		docker.ContainerRestartCount: "1",
+		docker.ImageID:               "abc",
+		docker.LabelPrefix + "app":   "ping",
+		report.ControlProbeID:        "probe",
+	} {
+		if have, _ := ping.Latest.Lookup(key); have != want {
+			t.Errorf("Expected %s %q, got %q", key, want, have)
+		}
+	}
+	if parents, _ := ping.Parents.Lookup(report.ContainerImage); !parents.Contains(report.MakeContainerImageNodeID("abc")) {
+		t.Errorf("Expected image abc as parent, got %v", parents)
+	}
+	if _, ok := ping.Metrics[docker.MemoryUsage]; !ok {
+		t.Errorf("Expected a memory metric, got %v", ping.Metrics)
+	}
+	if _, ok := ping.Metrics[docker.CPUTotalUsage]; ok {
+		t.Errorf("Expected no CPU metric from the first report, got %v", ping.Metrics)
+	}
+	if data, ok := ping.LatestControls.Lookup(cri.StopContainer); !ok || data.Dead {
+		t.Errorf("Expected stop to be live on a running container")
+	}
+	if data, ok := rpt.Container.Nodes[report.MakeContainerNodeID("pong")].LatestControls.Lookup(cri.StopContainer); !ok || !data.Dead {
+		t.Errorf("Expected stop to be dead on an exited container")
+	}
+	if have, _ := rpt.ContainerImage.Nodes[report.MakeContainerImageNodeID("abc")].Latest.Lookup(docker.ImageName); have != "weaveworks/ping" {
+		t.Errorf("Expected image name weaveworks/ping, got %q", have)
+	}
+
+	// A second later, with a second of CPU time used.
+	runtimeClient.at += int64(time.Second)
+	runtimeClient.cpu += uint64(time.Second)
+	rpt, err = reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpu, ok := rpt.Container.Nodes[report.MakeContainerNodeID("ping")].Metrics[docker.CPUTotalUsage]
+	if sample, _ := cpu.LastSample(); !ok || sample.Value <= 0 {
+		t.Errorf("Expected some CPU usage, got %v", cpu)
+	}
+
+	resp := hr.HandleControlRequest(xfer.Request{
+		Control: cri.StopContainer,
+		NodeID:  report.MakeContainerNodeID("ping"),
+	})
+	if resp.Error != "" || len(runtimeClient.stopped) != 1 || runtimeClient.stopped[0] != "ping" {
+		t.Errorf("Expected container ping to be stopped, got %v, %v", resp, runtimeClient.stopped)
+	}
+}
+
+func TestReporterErrors(t *testing.T) {
+	// The containers are reported without the stats or images which fail
+	runtimeClient := &mockRuntimeClient{statsErr: fmt.Errorf("no stats")}
+	reporter := cri.NewReporter(runtimeClient, mockImageClient{err: fmt.Errorf("no images")}, "probe", controls.NewDefaultHandlerRegistry())
+	defer reporter.Stop()
+
+	rpt, err := reporter.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ping, ok := rpt.Container.Nodes[report.MakeContainerNodeID("ping")]
+	if !ok {
+		t.Fatalf("Expected a node for container ping, got %v", rpt.Container.Nodes)
+	}
+	if len(ping.Metrics) != 0 {
+		t.Errorf("Expected no metrics, got %v", ping.Metrics)
+	}
+	if len(rpt.ContainerImage.Nodes) != 0 {
+		t.Errorf("Expected no images, got %v", rpt.ContainerImage.Nodes)
+	}
+}
@@ -0,0 +1,226 @@
+package docker_test
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	client "github.com/fsouza/go-dockerclient"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+type mockStatsGatherer struct {
+	opts  client.StatsOptions
+	ready chan bool
+}
+
+func newMockStatsGatherer() *mockStatsGatherer {
+	return &mockStatsGatherer{ready: make(chan bool)}
+}
+
+func (s *mockStatsGatherer) Stats(opts client.StatsOptions) error {
+	s.opts = opts
+	close(s.ready)
+	return nil
+}
+
+func (s *mockStatsGatherer) Send(stats *client.Stats) {
+	<-s.ready
+	s.opts.Stats <- stats
+}
+
+func TestContainer(t *testing.T) {
+	now := time.Unix(12345, 67890).UTC()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	const hostID = "scope"
+	c := docker.NewContainer(container1, hostID, false, false)
+	s := newMockStatsGatherer()
+	err := c.StartGatheringStats(s)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	defer c.StopGatheringStats()
+
+	// Send some stats to the docker container
+	stats := &client.Stats{}
+	stats.Read = now
+	stats.MemoryStats.Usage = 12345
+	stats.MemoryStats.Limit = 45678
+	s.Send(stats)
+
+	// Now see if we go them
+	{
+		uptimeSeconds := int(now.Sub(startTime) / time.Second)
+		controls := map[string]report.NodeControlData{
+			docker.UnpauseContainer: {Dead: true},
+			docker.RestartContainer: {Dead: false},
+			docker.StopContainer:    {Dead: false},
+			docker.PauseContainer:   {Dead: false},
+			docker.AttachContainer:  {Dead: false},
+			docker.ExecContainer:    {Dead: false},
+			docker.StartContainer:   {Dead: true},
+			docker.RemoveContainer:  {Dead: true},
+		}
+		want := report.MakeNodeWith("ping;<container>", map[string]string{
+			"docker_container_command":     "ping foo.bar.local",
+			"docker_container_created":     "0001-01-01T00:00:00Z",
+			"docker_container_id":          "ping",
+			"docker_container_name":        "pong",
+			"docker_image_id":              "baz",
+			"docker_label_foo1":            "bar1",
+			"docker_label_foo2":            "bar2",
+			"docker_container_state":       "running",
+			"docker_container_state_human": c.Container().State.String(),
+			"docker_container_uptime":      strconv.Itoa(uptimeSeconds),
+			"docker_env_FOO":               "secret-bar",
+		}).WithLatestControls(
+			controls,
+		).WithMetrics(report.Metrics{
+			"docker_cpu_total_usage": report.MakeMetric(nil),
+			"docker_memory_usage":    report.MakeSingletonMetric(now, 12345).WithMax(45678),
+		}).WithParents(report.MakeSets().
+			Add(report.ContainerImage, report.MakeStringSet(report.MakeContainerImageNodeID("baz"))),
+		)
+
+		test.Poll(t, 100*time.Millisecond, want, func() interface{} {
+			node := c.GetNode()
+			latest := report.MakeStringLatestMap()
+			node.Latest.ForEach(func(k string, t time.Time, v string) {
+				if v != "0" && v != "" {
+					latest = latest.Set(k, t, v)
+				}
+			})
+			node.Latest = latest
+			return node
+		})
+	}
+
+	{
+		want := report.MakeSets().
+			Add("docker_container_ports", report.MakeStringSet("1.2.3.4:80->80/tcp", "81/tcp")).
+			Add("docker_container_networks", nil).
+			Add("docker_container_ips", report.MakeStringSet("1.2.3.4")).
+			Add("docker_container_ips", report.MakeStringSet("5.6.7.8")).
+			Add("docker_container_ips_with_scopes", report.MakeStringSet(";1.2.3.4")).
+			Add("docker_container_ips_with_scopes", report.MakeStringSet(";5.6.7.8")).
+			Add("docker_container_networks", report.MakeStringSet("network1"))
+
+		test.Poll(t, 100*time.Millisecond, want, func() interface{} {
+			return c.NetworkInfo([]net.IP{})
+		})
+	}
+
+	if c.Image() != "baz" {
+		t.Errorf("%s != baz", c.Image())
+	}
+	if c.PID() != 2 {
+		t.Errorf("%d != 2", c.PID())
+	}
+	node := c.GetNode().WithSets(c.NetworkInfo([]net.IP{}))
+	if have := docker.ExtractContainerIPs(node); !reflect.DeepEqual(have, []string{"1.2.3.4", "5.6.7.8"}) {
+		t.Errorf("%v != %v", have, []string{"1.2.3.4", "5.6.7.8"})
+	}
+}
+
+func TestContainerCgroupV2Stats(t *testing.T) {
+	c := docker.NewContainer(container1, "scope", false, false)
+	s := newMockStatsGatherer()
+	if err := c.StartGatheringStats(s); err != nil {
+		t.Fatal(err)
+	}
+	defer c.StopGatheringStats()
+
+	// cgroup v2 has no page cache in the stats, but inactive files, and
+	// some setups don't report the system usage
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		stats := &client.Stats{}
+		stats.Read = now.Add(time.Duration(i) * time.Second)
+		stats.MemoryStats.Usage = 1000
+		stats.MemoryStats.Stats.InactiveFile = 300
+		stats.CPUStats.CPUUsage.TotalUsage = uint64(i) * uint64(time.Second/2)
+		stats.CPUStats.OnlineCPUs = 2
+		s.Send(stats)
+	}
+	// The last stats are only there to know the others were received
+
+	metrics := c.GetNode().Metrics
+	if sample, ok := metrics[docker.MemoryUsage].LastSample(); !ok || sample.Value != 700 {
+		t.Errorf("Expected a memory usage of 700, got %v", metrics[docker.MemoryUsage])
+	}
+	if sample, ok := metrics[docker.CPUTotalUsage].LastSample(); !ok || sample.Value != 25 {
+		t.Errorf("Expected a CPU usage of 25%%, got %v", metrics[docker.CPUTotalUsage])
+	}
+}
+
+func TestContainerHidingArgs(t *testing.T) {
+	const hostID = "scope"
+	c := docker.NewContainer(container1, hostID, true, false)
+	node := c.GetNode()
+	node.Latest.ForEach(func(k string, _ time.Time, v string) {
+		if strings.Contains(v, "foo.bar.local") {
+			t.Errorf("Found command line argument in node")
+		}
+	})
+}
+
+func TestContainerHidingEnv(t *testing.T) {
+	const hostID = "scope"
+	c := docker.NewContainer(container1, hostID, false, true)
+	node := c.GetNode()
+	node.Latest.ForEach(func(k string, _ time.Time, v string) {
+		if strings.Contains(v, "secret-bar") {
+			t.Errorf("Found environment variable in node")
+		}
+	})
+}
+
+func TestContainerHidingBoth(t *testing.T) {
+	const hostID = "scope"
+	c := docker.NewContainer(container1, hostID, true, true)
+	node := c.GetNode()
+	node.Latest.ForEach(func(k string, _ time.Time, v string) {
+		if strings.Contains(v, "foo.bar.local") {
+			t.Errorf("Found command line argument in node")
+		}
+		if strings.Contains(v, "secret-bar") {
+			t.Errorf("Found environment variable in node")
+		}
+	})
+}
+
+func TestContainerEvents(t *testing.T) {
+	const hostID = "scope"
+	c := docker.NewContainer(container1, hostID, false, false)
+	start := time.Unix(1500000000, 0).UTC()
+	for i := 0; i < 12; i++ {
+		c.RecordEvent(start.Add(time.Duration(i)*time.Second), fmt.Sprintf("die (exit code %d)", i))
+	}
+
+	events := map[string]string{}
+	c.GetNode().Latest.ForEach(func(k string, _ time.Time, v string) {
+		if strings.HasPrefix(k, docker.EventPrefix) {
+			events[strings.TrimPrefix(k, docker.EventPrefix)] = v
+		}
+	})
+	// Only the most recent events are kept
+	if len(events) != 10 {
+		t.Errorf("Expected 10 events, got %v", events)
+	}
+	if have := events["2017-07-14T02:40:11.000Z"]; have != "die (exit code 11)" {
+		t.Errorf("Expected the last event to be kept, got %q", have)
+	}
+	if _, ok := events["2017-07-14T02:40:00.000Z"]; ok {
+		t.Errorf("Expected the first event to be dropped")
+	}
+}
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Metrics keys of the usage of NVIDIA GPUs by containers.
+const (
+	GPUTotalUsage  = "docker_gpu_total_usage"
+	GPUMemoryUsage = "docker_gpu_memory_usage"
+)
+
+const nvidiaSMIBinary = "nvidia-smi"
+
+// GPUUsage is the usage of the GPUs of the host, by PID.
+type GPUUsage struct {
+	GPUs      int
+	Processes map[int]GPUProcessUsage
+}
+
+// GPUProcessUsage is the usage of the GPUs by a process.
+type GPUProcessUsage struct {
+	Percent     float64 // Of the streaming multiprocessors of a GPU, summed over the GPUs
+	MemoryBytes float64
+}
+
+// GPUUsageReader reads the usage of the GPUs of the host.
+type GPUUsageReader func() (GPUUsage, error)
+
+// NvidiaSMIUsage reads the usage of the NVIDIA GPUs of the host, which
+// nvidia-smi takes from NVML. The probe doesn't call NVML itself, as that
+// would take Go bindings which aren't vendored, and loading the driver's
+// libnvidia-ml into the probe; nvidia-smi comes with the driver. It takes
+// a second or so to sample, so it is meant to be run by a GPUSampler.
+func NvidiaSMIUsage() (GPUUsage, error) {
+	output, err := exec.Command(
+		nvidiaSMIBinary, "pmon",
+		"-c", "1", // one sample
+		"-s", "um", // utilization and memory
+	).Output()
+	if err != nil {
+		return GPUUsage{}, err
+	}
+	return parsePmon(output), nil
+}
+
+// GPUSampler reads the usage of the GPUs in the background, every
+// interval, for Usage to return the latest sample without waiting. It
+// stops at the first error, e.g. there is no nvidia-smi, warning once.
+type GPUSampler struct {
+	read GPUUsageReader
+	quit chan struct{}
+
+	mtx   sync.Mutex
+	usage GPUUsage
+	err   error
+}
+
+// NewGPUSampler starts sampling the usage of the GPUs with read.
+func NewGPUSampler(read GPUUsageReader, interval time.Duration) *GPUSampler {
+	s := &GPUSampler{read: read, quit: make(chan struct{})}
+	go s.loop(interval)
+	return s
+}
+
+func (s *GPUSampler) loop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		usage, err := s.read()
+		s.mtx.Lock()
+		s.usage, s.err = usage, err
+		s.mtx.Unlock()
+		if err != nil {
+			log.Warnf("Docker: not reporting the usage of GPUs: %v", err)
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Usage is a GPUUsageReader returning the latest sample, which is empty
+// until the first one is taken.
+func (s *GPUSampler) Usage() (GPUUsage, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.usage, s.err
+}
+
+// Stop stops sampling.
+func (s *GPUSampler) Stop() {
+	close(s.quit)
+}
+
+// parsePmon parses the output of nvidia-smi pmon, e.g.
+//
+//	# gpu        pid  type    sm   mem   enc   dec    fb   command
+//	# Idx          #   C/G     %     %     %     %    MB   name
+//	    0      12345     C    45    10     -     -  1024   python
+//	    1          -     -     -     -     -     -     -   -
+//
+// by the names of its columns, which vary with the version of the driver.
+func parsePmon(output []byte) GPUUsage {
+	var (
+		usage   = GPUUsage{Processes: map[int]GPUProcessUsage{}}
+		columns = map[string]int{}
+		gpus    = map[string]struct{}{}
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasPrefix(fields[0], "#") {
+			// The first header line names the columns, the second their units
+			if len(columns) == 0 {
+				fields[0] = strings.TrimPrefix(fields[0], "#")
+				if fields[0] == "" {
+					fields = fields[1:]
+				}
+				for i, name := range fields {
+					columns[name] = i
+				}
+			}
+			continue
+		}
+		value := func(column string) (string, bool) {
+			i, ok := columns[column]
+			if !ok || i >= len(fields) || fields[i] == "-" {
+				return "", false
+			}
+			return fields[i], true
+		}
+		if gpu, ok := value("gpu"); ok {
+			gpus[gpu] = struct{}{}
+		}
+		pidStr, ok := value("pid")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+		process := usage.Processes[pid]
+		if sm, ok := value("sm"); ok {
+			if percent, err := strconv.ParseFloat(sm, 64); err == nil {
+				process.Percent += percent
+			}
+		}
+		if fb, ok := value("fb"); ok {
+			if mb, err := strconv.ParseFloat(fb, 64); err == nil {
+				process.MemoryBytes += mb * 1024 * 1024
+			}
+		}
+		usage.Processes[pid] = process
+	}
+	usage.GPUs = len(gpus)
+	return usage
+}
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	scopetest "github.com/weaveworks/scope/test"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestParsePmon(t *testing.T) {
+	output := []byte(`# gpu        pid  type    sm   mem   enc   dec    fb   command
+# Idx          #   C/G     %     %     %     %    MB   name
+    0      12345     C    45    10     -     -  1024   python
+    0      23456     C     -     -     -     -   512   python
+    1      12345     C    20     5     -     -   256   python
+    2          -     -     -     -     -     -     -   -
+`)
+	want := GPUUsage{
+		GPUs: 3,
+		Processes: map[int]GPUProcessUsage{
+			12345: {Percent: 65, MemoryBytes: 1280 * 1024 * 1024},
+			23456: {MemoryBytes: 512 * 1024 * 1024},
+		},
+	}
+	if have := parsePmon(output); !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestGPUSampler(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		reads int
+	)
+	read := func() (GPUUsage, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reads++
+		if reads > 2 {
+			return GPUUsage{}, fmt.Errorf("nvidia-smi is gone")
+		}
+		return GPUUsage{GPUs: reads}, nil
+	}
+	sampler := NewGPUSampler(read, 10*time.Millisecond)
+	defer sampler.Stop()
+
+	// The samples are read in the background, until the first error
+	scopetest.Poll(t, time.Second, true, func() interface{} {
+		_, err := sampler.Usage()
+		return err != nil
+	})
+	time.Sleep(50 * time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	if reads != 3 {
+		t.Errorf("Expected sampling to stop at the error, got %d reads", reads)
+	}
+}
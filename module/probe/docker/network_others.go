@@ -0,0 +1,16 @@
+// +build !linux
+
+package docker
+
+import (
+	"errors"
+	"net"
+)
+
+func namespaceIPAddresses(processID int) ([]*net.IPNet, error) {
+	return nil, errors.New("namespaceIPAddresses not implemented on this platform")
+}
+
+func netNamespaceID(processID int) (uint64, error) {
+	return 0, errors.New("netNamespaceID not implemented on this platform")
+}
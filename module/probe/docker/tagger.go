@@ -0,0 +1,178 @@
+package docker
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Node metadata keys.
+const (
+	ContainerID = report.DockerContainerID
+	Name        = report.Name
+)
+
+// These vars are exported for testing.
+var (
+	NewProcessTreeStub = process.NewTree
+)
+
+// Tagger is a tagger that tags Docker container information to process
+// nodes that have a PID.
+// It also populates the SwarmService topology if any of the associated docker labels are present.
+// With a GPUUsageReader, it also attributes the usage of the GPUs by the
+// processes to their containers. Tag calls it with every report, so it
+// should return quickly, as GPUSampler.Usage does.
+type Tagger struct {
+	registry   Registry
+	procWalker process.Walker
+	gpuUsage   GPUUsageReader // nil not to report the usage of GPUs
+}
+
+// NewTagger returns a usable Tagger.
+func NewTagger(registry Registry, procWalker process.Walker, gpuUsage GPUUsageReader) *Tagger {
+	return &Tagger{
+		registry:   registry,
+		procWalker: procWalker,
+		gpuUsage:   gpuUsage,
+	}
+}
+
+// Name of this tagger, for metrics gathering
+func (Tagger) Name() string { return "Docker" }
+
+// Tag implements Tagger.
+func (t *Tagger) Tag(r report.Report) (report.Report, error) {
+	tree, err := NewProcessTreeStub(t.procWalker)
+	if err != nil {
+		return report.MakeReport(), err
+	}
+	t.tag(tree, &r.Process)
+	if t.gpuUsage != nil {
+		t.tagGPUUsage(&r)
+	}
+
+	// Scan for Swarm service info
+	for containerID, container := range r.Container.Nodes {
+		serviceID, ok := container.Latest.Lookup(LabelPrefix + "com.docker.swarm.service.id")
+		if !ok {
+			continue
+		}
+		serviceName, ok := container.Latest.Lookup(LabelPrefix + "com.docker.swarm.service.name")
+		if !ok {
+			continue
+		}
+		stackNamespace, ok := container.Latest.Lookup(LabelPrefix + "com.docker.stack.namespace")
+		if !ok {
+			stackNamespace = DefaultNamespace
+		} else {
+			prefix := stackNamespace + "_"
+			if strings.HasPrefix(serviceName, prefix) {
+				serviceName = serviceName[len(prefix):]
+			}
+		}
+
+		nodeID := report.MakeSwarmServiceNodeID(serviceID)
+		node := report.MakeNodeWith(nodeID, map[string]string{
+			ServiceName:    serviceName,
+			StackNamespace: stackNamespace,
+		})
+		r.SwarmService.AddNode(node)
+
+		r.Container.Nodes[containerID] = container.WithParent(report.SwarmService, nodeID)
+	}
+
+	return r, nil
+}
+
+func (t *Tagger) tag(tree process.Tree, topology *report.Topology) {
+	for _, node := range topology.Nodes {
+		pidStr, ok := node.Latest.Lookup(process.PID)
+		if !ok {
+			continue
+		}
+
+		pid, err := strconv.ParseUint(pidStr, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		var (
+			c         Container
+			candidate = int(pid)
+		)
+
+		t.registry.LockedPIDLookup(func(lookup func(int) Container) {
+			for {
+				c = lookup(candidate)
+				if c != nil {
+					break
+				}
+
+				candidate, err = tree.GetParent(candidate)
+				if err != nil {
+					break
+				}
+			}
+		})
+
+		if c == nil || ContainerIsStopped(c) || c.PID() == 1 {
+			continue
+		}
+
+		node = node.WithLatest(ContainerID, mtime.Now(), c.ID())
+		node = node.WithParent(report.Container, report.MakeContainerNodeID(c.ID()))
+
+		// If we can work out the image name, add a parent tag for it
+		image, ok := t.registry.GetContainerImage(c.Image())
+		if ok && len(image.RepoTags) > 0 {
+			imageName := ImageNameWithoutTag(image.RepoTags[0])
+			node = node.WithParent(report.ContainerImage, report.MakeContainerImageNodeID(imageName))
+		}
+
+		topology.ReplaceNode(node)
+	}
+}
+
+// tagGPUUsage adds up the usage of the GPUs by the processes of each
+// container, as the metrics of the container.
+func (t *Tagger) tagGPUUsage(r *report.Report) {
+	usage, err := t.gpuUsage()
+	if err != nil {
+		// e.g. there is no nvidia-smi, which the reader warned about
+		return
+	}
+	containers := map[string]GPUProcessUsage{}
+	for _, node := range r.Process.Nodes {
+		containerID, ok := node.Latest.Lookup(ContainerID)
+		if !ok {
+			continue
+		}
+		pidStr, _ := node.Latest.Lookup(process.PID)
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			continue
+		}
+		if p, ok := usage.Processes[pid]; ok {
+			c := containers[containerID]
+			c.Percent += p.Percent
+			c.MemoryBytes += p.MemoryBytes
+			containers[containerID] = c
+		}
+	}
+	now := mtime.Now()
+	for containerID, c := range containers {
+		nodeID := report.MakeContainerNodeID(containerID)
+		node, ok := r.Container.Nodes[nodeID]
+		if !ok {
+			continue
+		}
+		r.Container.Nodes[nodeID] = node.WithMetrics(report.Metrics{
+			GPUTotalUsage:  report.MakeSingletonMetric(now, c.Percent).WithMax(100 * float64(usage.GPUs)),
+			GPUMemoryUsage: report.MakeSingletonMetric(now, c.MemoryBytes),
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// +build linux
+
+package endpoint
+
+import (
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/typetypetype/conntrack"
+	"github.com/weaveworks/common/mtime"
+
+	"github.com/weaveworks/scope/probe/endpoint/procspy"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+type connectionTracker struct {
+	conf            ReporterConfig
+	flowWalker      flowWalker // Interface
+	ebpfTracker     *EbpfTracker
+	reverseResolver *reverseResolver
+	hostsFile       *hostsFile // nil without a hosts file
+	noMetrics       bool       // leave out the accounting metrics of connections
+
+	// time of the previous ebpf failure, or zero if it didn't fail
+	ebpfLastFailureTime time.Time
+}
+
+func newConnectionTracker(conf ReporterConfig) connectionTracker {
+	ct := connectionTracker{
+		conf:            conf,
+		reverseResolver: newReverseResolver(),
+	}
+	if conf.HostsFile != "" {
+		ct.hostsFile = newHostsFile(conf.HostsFile)
+	}
+	if conf.UseEbpfConn {
+		et, err := newEbpfTracker()
+		if err == nil {
+			ct.ebpfTracker = et
+			go ct.getInitialState()
+			return ct
+		}
+		log.Warnf("Error setting up the eBPF tracker, falling back to proc scanning: %v", err)
+	}
+	ct.useProcfs()
+	return ct
+}
+
+func flowToTuple(f conntrack.Conn) (ft fourTuple) {
+	ft = fourTuple{
+		f.Orig.Src.String(),
+		f.Orig.Dst.String(),
+		uint16(f.Orig.SrcPort),
+		uint16(f.Orig.DstPort),
+	}
+	// Handle DNAT-ed connections in the initial state
+	if !f.Orig.Dst.Equal(f.Reply.Src) {
+		ft = fourTuple{
+			f.Reply.Dst.String(),
+			f.Reply.Src.String(),
+			uint16(f.Reply.DstPort),
+			uint16(f.Reply.SrcPort),
+		}
+	}
+	return ft
+}
+
+// flowToMetrics extracts the conntrack accounting counters of a flow,
+// from the point of view of the originating endpoint. It returns nil
+// when accounting is disabled (nf_conntrack_acct=0), in which case
+// the kernel reports no counters.
+func flowToMetrics(f conntrack.Conn, now time.Time) report.Metrics {
+	if f.OrigPktCount == 0 && f.ReplyPktCount == 0 {
+		return nil
+	}
+	return report.Metrics{
+		EgressBytes:    report.MakeSingletonMetric(now, float64(f.OrigPktLen)),
+		EgressPackets:  report.MakeSingletonMetric(now, float64(f.OrigPktCount)),
+		IngressBytes:   report.MakeSingletonMetric(now, float64(f.ReplyPktLen)),
+		IngressPackets: report.MakeSingletonMetric(now, float64(f.ReplyPktCount)),
+	}
+}
+
+func (t *connectionTracker) useProcfs() {
+	t.ebpfTracker = nil
+	if t.conf.WalkProc && t.conf.Scanner == nil {
+		t.conf.Scanner = procspy.NewConnectionScanner(t.conf.ProcessCache, t.conf.SpyProcs)
+	}
+	if t.flowWalker == nil {
+		t.flowWalker = newConntrackFlowWalker(t.conf.UseConntrack, t.conf.ProcRoot, t.conf.BufferSize, false /* natOnly */)
+	}
+}
+
+// ReportConnections calls trackers according to the configuration.
+func (t *connectionTracker) ReportConnections(rpt *report.Report) {
+	hostNodeID := report.MakeHostNodeID(t.conf.HostID)
+	if t.hostsFile != nil {
+		t.hostsFile.refresh()
+	}
+
+	if t.ebpfTracker != nil {
+		if !t.ebpfTracker.isDead() {
+			t.performEbpfTrack(rpt, hostNodeID)
+			return
+		}
+
+		// We only restart the EbpfTracker if the failures are not too frequent to
+		// avoid repeatitive restarts.
+
+		ebpfLastFailureTime := t.ebpfLastFailureTime
+		t.ebpfLastFailureTime = time.Now()
+
+		if ebpfLastFailureTime.After(time.Now().Add(-5 * time.Minute)) {
+			// Multiple failures in the last 5 minutes, fall back to proc parsing
+			log.Warnf("ebpf tracker died again, gently falling back to proc scanning")
+			t.useProcfs()
+		} else {
+			// Tolerable failure rate, restart the tracker
+			log.Warnf("ebpf tracker died, restarting it")
+			err := t.ebpfTracker.restart()
+			if err == nil {
+				go t.getInitialState()
+				t.performEbpfTrack(rpt, hostNodeID)
+				return
+			}
+			log.Warnf("could not restart ebpf tracker, falling back to proc scanning: %v", err)
+			t.useProcfs()
+		}
+	}
+
+	// consult the flowWalker for short-lived (conntracked) connections
+	seenTuples := map[string]fourTuple{}
+	now := mtime.Now()
+	t.flowWalker.walkFlows(func(f conntrack.Conn, alive bool) {
+		tuple := flowToTuple(f)
+		if flowFailed(f, alive) {
+			t.addFailedConnection(rpt, tuple)
+			return
+		}
+		seenTuples[tuple.key()] = tuple
+		t.addConnection(rpt, false, tuple, "", nil, nil)
+		if t.noMetrics {
+			return
+		}
+		if metrics := flowToMetrics(f, now); metrics != nil {
+			t.addConnectionMetrics(rpt, tuple, metrics)
+		}
+	})
+
+	if t.conf.WalkProc && t.conf.Scanner != nil {
+		t.performWalkProc(rpt, hostNodeID, seenTuples)
+	}
+}
+
+func (t *connectionTracker) existingFlows() map[string]fourTuple {
+	seenTuples := map[string]fourTuple{}
+	if !t.conf.UseConntrack {
+		// log.Warnf("Not using conntrack: disabled")
+	} else if err := IsConntrackSupported(t.conf.ProcRoot); err != nil {
+		log.Warnf("Not using conntrack: not supported by the kernel: %s", err)
+	} else if existingFlows, err := conntrack.ConnectionsSize(t.conf.BufferSize); err != nil {
+		log.Errorf("conntrack existingConnections error: %v", err)
+	} else {
+		for _, f := range existingFlows {
+			if (f.Status & conntrack.IPS_NAT_MASK) == 0 {
+				continue
+			}
+			tuple := flowToTuple(f)
+			seenTuples[tuple.key()] = tuple
+		}
+	}
+	return seenTuples
+}
+
+// getInitialState runs conntrack and proc parsing synchronously only
+// once to initialize ebpfTracker
+func (t *connectionTracker) getInitialState() {
+	var processCache *process.CachingWalker
+	walker := process.NewWalker(t.conf.ProcRoot, true)
+	processCache = process.NewCachingWalker(walker)
+	processCache.Tick()
+
+	scanner := procspy.NewSyncConnectionScanner(processCache, t.conf.SpyProcs)
+
+	// Consult conntrack to get the initial state
+	seenTuples := t.existingFlows()
+
+	conns, err := scanner.Connections()
+	if err != nil {
+		log.Errorf("Error initializing ebpfTracker while scanning /proc, continuing without initial connections: %s", err)
+	}
+	scanner.Stop()
+
+	processesWaitingInAccept := []int{}
+	processCache.Walk(func(p, prev process.Process) {
+		if p.IsWaitingInAccept {
+			processesWaitingInAccept = append(processesWaitingInAccept, p.PID)
+		}
+	})
+
+	t.ebpfTracker.feedInitialConnections(conns, seenTuples, processesWaitingInAccept, report.MakeHostNodeID(t.conf.HostID))
+}
+
+func (t *connectionTracker) performEbpfTrack(rpt *report.Report, hostNodeID string) error {
+	t.ebpfTracker.walkConnections(func(e ebpfConnection) {
+		var toNodeInfo, fromNodeInfo map[string]string
+		if e.pid > 0 {
+			fromNodeInfo = map[string]string{
+				process.PID:       strconv.Itoa(e.pid),
+				report.HostNodeID: hostNodeID,
+			}
+			if e.networkNamespace != "" {
+				fromNodeInfo[NetNamespace] = e.networkNamespace
+			}
+		}
+		t.addConnection(rpt, e.incoming, e.tuple, e.networkNamespace, fromNodeInfo, toNodeInfo)
+	})
+	return nil
+}
+
+// addFailedConnection records a connection which failed to be
+// established, as the ID of the endpoint it was to in the failed
+// adjacencies of the originating endpoint, rather than as an adjacency.
+func (t *connectionTracker) addFailedConnection(rpt *report.Report, ft fourTuple) {
+	var (
+		fromNode = t.makeEndpointNode("", ft.fromAddr, ft.fromPort, nil)
+		toNode   = t.makeEndpointNode("", ft.toAddr, ft.toPort, nil)
+	)
+	rpt.Endpoint.AddNode(fromNode.WithSet(report.FailedAdjacency, report.MakeStringSet(toNode.ID)))
+	rpt.Endpoint.AddNode(toNode)
+	t.addDNS(rpt, ft.fromAddr)
+	t.addDNS(rpt, ft.toAddr)
+}
+
+// addConnectionMetrics attaches the accounting metrics of a connection
+// to its originating endpoint node.
+func (t *connectionTracker) addConnectionMetrics(rpt *report.Report, ft fourTuple, metrics report.Metrics) {
+	fromNode := t.makeEndpointNode("", ft.fromAddr, ft.fromPort, nil)
+	rpt.Endpoint.AddNode(fromNode.WithMetrics(metrics))
+}
+
+func (t *connectionTracker) Stop() error {
+	if t.ebpfTracker != nil {
+		t.ebpfTracker.stop()
+	}
+	if t.flowWalker != nil {
+		t.flowWalker.stop()
+	}
+	t.reverseResolver.stop()
+	return nil
+}
@@ -0,0 +1,131 @@
+// +build linux
+
+package endpoint
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/typetypetype/conntrack"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestConnectionMetrics(t *testing.T) {
+	now := mtime.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	f := conntrack.Conn{
+		MsgType: conntrack.NfctMsgUpdate,
+		Orig: conntrack.Tuple{
+			Src:     net.ParseIP("10.0.0.1"),
+			Dst:     net.ParseIP("10.0.0.2"),
+			SrcPort: 45678,
+			DstPort: 80,
+			Proto:   syscall.IPPROTO_TCP,
+		},
+		Reply: conntrack.Tuple{
+			Src:     net.ParseIP("10.0.0.2"),
+			Dst:     net.ParseIP("10.0.0.1"),
+			SrcPort: 80,
+			DstPort: 45678,
+			Proto:   syscall.IPPROTO_TCP,
+		},
+		CtId:          1,
+		OrigPktLen:    1500,
+		OrigPktCount:  10,
+		ReplyPktLen:   64000,
+		ReplyPktCount: 50,
+	}
+
+	tracker := connectionTracker{
+		conf:            ReporterConfig{HostID: "host1"},
+		flowWalker:      &mockFlowWalker{flows: []conntrack.Conn{f}},
+		reverseResolver: newReverseResolver(),
+	}
+	defer tracker.reverseResolver.stop()
+
+	rpt := report.MakeReport()
+	tracker.ReportConnections(&rpt)
+
+	fromID := report.MakeEndpointNodeID("host1", "", "10.0.0.1", "45678")
+	want := report.Metrics{
+		EgressBytes:    report.MakeSingletonMetric(now, 1500),
+		EgressPackets:  report.MakeSingletonMetric(now, 10),
+		IngressBytes:   report.MakeSingletonMetric(now, 64000),
+		IngressPackets: report.MakeSingletonMetric(now, 50),
+	}
+	if have := rpt.Endpoint.Nodes[fromID].Metrics; !reflect.DeepEqual(want, have) {
+		t.Fatal(test.Diff(want, have))
+	}
+
+	toID := report.MakeEndpointNodeID("host1", "", "10.0.0.2", "80")
+	if have := rpt.Endpoint.Nodes[toID].Metrics; len(have) != 0 {
+		t.Fatalf("expected no metrics on the destination endpoint, got %v", have)
+	}
+
+	// Without accounting, conntrack reports no counters
+	if metrics := flowToMetrics(conntrack.Conn{}, now); metrics != nil {
+		t.Fatalf("expected no metrics, got %v", metrics)
+	}
+
+	// Nor are they reported when disabled, e.g. over the CPU budget
+	tracker.noMetrics = true
+	rpt = report.MakeReport()
+	tracker.ReportConnections(&rpt)
+	if have := rpt.Endpoint.Nodes[fromID]; len(have.Metrics) != 0 || len(have.Adjacency) != 1 {
+		t.Fatalf("expected the connection without metrics, got %v", have)
+	}
+}
+
+func TestFailedConnection(t *testing.T) {
+	f := conntrack.Conn{
+		MsgType: conntrack.NfctMsgUpdate,
+		Orig: conntrack.Tuple{
+			Src:     net.ParseIP("10.0.0.1"),
+			Dst:     net.ParseIP("10.0.0.2"),
+			SrcPort: 45678,
+			DstPort: 80,
+			Proto:   syscall.IPPROTO_TCP,
+		},
+		Reply: conntrack.Tuple{
+			Src:     net.ParseIP("10.0.0.2"),
+			Dst:     net.ParseIP("10.0.0.1"),
+			SrcPort: 80,
+			DstPort: 45678,
+			Proto:   syscall.IPPROTO_TCP,
+		},
+		// Refused: reset in reply to the SYN
+		Status:   conntrack.IPS_SEEN_REPLY,
+		TCPState: tcpClose,
+		CtId:     1,
+	}
+
+	tracker := connectionTracker{
+		conf:            ReporterConfig{HostID: "host1"},
+		flowWalker:      &mockFlowWalker{flows: []conntrack.Conn{f}},
+		reverseResolver: newReverseResolver(),
+	}
+	defer tracker.reverseResolver.stop()
+
+	rpt := report.MakeReport()
+	tracker.ReportConnections(&rpt)
+
+	fromID := report.MakeEndpointNodeID("host1", "", "10.0.0.1", "45678")
+	toID := report.MakeEndpointNodeID("host1", "", "10.0.0.2", "80")
+	from := rpt.Endpoint.Nodes[fromID]
+	if failed, _ := from.Sets.Lookup(report.FailedAdjacency); !failed.Contains(toID) {
+		t.Errorf("expected a failed connection to %s, got %v", toID, failed)
+	}
+	if len(from.Adjacency) != 0 {
+		t.Errorf("expected no adjacency, got %v", from.Adjacency)
+	}
+	if _, ok := rpt.Endpoint.Nodes[toID]; !ok {
+		t.Errorf("expected the destination endpoint")
+	}
+}
@@ -0,0 +1,45 @@
+package endpoint
+
+import (
+	"github.com/weaveworks/scope/probe/endpoint/procspy"
+	"github.com/weaveworks/scope/report"
+)
+
+// connectionTracker reports the connections in the TCP tables of Windows,
+// which has neither conntrack nor eBPF.
+type connectionTracker struct {
+	conf            ReporterConfig
+	reverseResolver *reverseResolver
+	hostsFile       *hostsFile // nil without a hosts file
+	noMetrics       bool       // no metrics are reported anyway
+}
+
+func newConnectionTracker(conf ReporterConfig) connectionTracker {
+	ct := connectionTracker{
+		conf:            conf,
+		reverseResolver: newReverseResolver(),
+	}
+	if conf.HostsFile != "" {
+		ct.hostsFile = newHostsFile(conf.HostsFile)
+	}
+	if conf.WalkProc && conf.Scanner == nil {
+		ct.conf.Scanner = procspy.NewConnectionScanner(conf.ProcessCache, conf.SpyProcs)
+	}
+	return ct
+}
+
+// ReportConnections reports the connections of the TCP tables, guessing
+// their direction from their ports.
+func (t *connectionTracker) ReportConnections(rpt *report.Report) {
+	if t.hostsFile != nil {
+		t.hostsFile.refresh()
+	}
+	if t.conf.WalkProc && t.conf.Scanner != nil {
+		t.performWalkProc(rpt, report.MakeHostNodeID(t.conf.HostID), map[string]fourTuple{})
+	}
+}
+
+func (t *connectionTracker) Stop() error {
+	t.reverseResolver.stop()
+	return nil
+}
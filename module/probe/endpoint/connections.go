@@ -0,0 +1,96 @@
+// +build linux windows
+
+package endpoint
+
+import (
+	"strconv"
+
+	"github.com/weaveworks/scope/probe/endpoint/procspy"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// performWalkProc reports the connections of the scanner, with their
+// processes when it knows them.
+func (t *connectionTracker) performWalkProc(rpt *report.Report, hostNodeID string, seenTuples map[string]fourTuple) error {
+	conns, err := t.conf.Scanner.Connections()
+	if err != nil {
+		return err
+	}
+	for conn := conns.Next(); conn != nil; conn = conns.Next() {
+		tuple, namespaceID, incoming := connectionTuple(conn, seenTuples)
+		var toNodeInfo, fromNodeInfo map[string]string
+		if conn.Proc.PID > 0 {
+			fromNodeInfo = map[string]string{
+				process.PID:       strconv.FormatUint(uint64(conn.Proc.PID), 10),
+				report.HostNodeID: hostNodeID,
+			}
+			if namespaceID != "" {
+				fromNodeInfo[NetNamespace] = namespaceID
+			}
+		}
+		t.addConnection(rpt, incoming, tuple, namespaceID, fromNodeInfo, toNodeInfo)
+	}
+	return nil
+}
+
+func (t *connectionTracker) addConnection(rpt *report.Report, incoming bool, ft fourTuple, namespaceID string, extraFromNode, extraToNode map[string]string) {
+	if incoming {
+		ft = reverse(ft)
+		extraFromNode, extraToNode = extraToNode, extraFromNode
+	}
+	var (
+		fromNode = t.makeEndpointNode(namespaceID, ft.fromAddr, ft.fromPort, extraFromNode)
+		toNode   = t.makeEndpointNode(namespaceID, ft.toAddr, ft.toPort, extraToNode)
+	)
+	rpt.Endpoint.AddNode(fromNode.WithAdjacent(toNode.ID))
+	rpt.Endpoint.AddNode(toNode)
+	t.addDNS(rpt, ft.fromAddr)
+	t.addDNS(rpt, ft.toAddr)
+}
+
+func (t *connectionTracker) makeEndpointNode(namespaceID string, addr string, port uint16, extra map[string]string) report.Node {
+	portStr := strconv.Itoa(int(port))
+	node := report.MakeNodeWith(report.MakeEndpointNodeID(t.conf.HostID, namespaceID, addr, portStr), nil)
+	if extra != nil {
+		node = node.WithLatests(extra)
+	}
+	return node
+}
+
+// Add DNS record for address to report, if not already there
+func (t *connectionTracker) addDNS(rpt *report.Report, addr string) {
+	if _, found := rpt.DNS[addr]; !found {
+		forward := t.conf.DNSSnooper.CachedNamesForIP(addr)
+		if t.hostsFile != nil {
+			forward = append(forward, t.hostsFile.namesForIP(addr)...)
+		}
+		record := report.DNSRecord{
+			Forward: report.MakeStringSet(forward...),
+		}
+		if names, err := t.reverseResolver.get(addr); err == nil && len(names) > 0 {
+			record.Reverse = report.MakeStringSet(names...)
+		}
+		rpt.DNS[addr] = record
+	}
+}
+
+func connectionTuple(conn *procspy.Connection, seenTuples map[string]fourTuple) (fourTuple, string, bool) {
+	namespaceID := ""
+	tuple := fourTuple{
+		conn.LocalAddress.String(),
+		conn.RemoteAddress.String(),
+		conn.LocalPort,
+		conn.RemotePort,
+	}
+	if conn.Proc.NetNamespaceID > 0 {
+		namespaceID = strconv.FormatUint(conn.Proc.NetNamespaceID, 10)
+	}
+
+	// If we've already seen this connection, we should know the direction
+	// (or have already figured it out), so we normalize and use the
+	// canonical direction. Otherwise, we can use a port-heuristic to guess
+	// the direction.
+	canonical, ok := seenTuples[tuple.key()]
+	return tuple, namespaceID, (ok && canonical != tuple) || (!ok && tuple.fromPort < tuple.toPort)
+}
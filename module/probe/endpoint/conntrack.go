@@ -0,0 +1,245 @@
+// +build linux
+
+package endpoint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/typetypetype/conntrack"
+)
+
+const (
+	// From https://www.kernel.org/doc/Documentation/networking/nf_conntrack-sysctl.txt
+	eventsPath = "sys/net/netfilter/nf_conntrack_events"
+	timeWait   = "TIME_WAIT"
+	tcpClose   = "CLOSE"
+	tcpProto   = 6
+)
+
+var conntrackResyncs = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "scope",
+	Subsystem: "probe",
+	Name:      "conntrack_resyncs_total",
+	Help:      "Number of times the conntrack table had to be re-read, e.g. after the netlink buffer overflowed.",
+})
+
+func init() {
+	prometheus.MustRegister(conntrackResyncs)
+}
+
+// flowWalker is something that maintains flows, and provides an accessor
+// method to walk them.
+type flowWalker interface {
+	walkFlows(f func(conntrack.Conn, bool))
+	stop()
+}
+
+type nilFlowWalker struct{}
+
+func (n nilFlowWalker) stop()                                  {}
+func (n nilFlowWalker) walkFlows(f func(conntrack.Conn, bool)) {}
+
+// conntrackWalker uses conntrack (via netlink) to track network connections and
+// implement flowWalker.
+type conntrackWalker struct {
+	sync.Mutex
+	activeFlows   map[uint32]conntrack.Conn // active flows in state != TIME_WAIT
+	bufferedFlows []conntrack.Conn          // flows coming out of activeFlows spend 1 walk cycle here
+	bufferSize    int
+	natOnly       bool
+	synced        bool // whether the flows were read from the table before
+	quit          chan struct{}
+}
+
+// newConntracker creates and starts a new conntracker.
+func newConntrackFlowWalker(useConntrack bool, procRoot string, bufferSize int, natOnly bool) flowWalker {
+	if !useConntrack {
+		return nilFlowWalker{}
+	} else if err := IsConntrackSupported(procRoot); err != nil {
+		log.Warnf("Not using conntrack: not supported by the kernel: %s", err)
+		return nilFlowWalker{}
+	}
+	result := &conntrackWalker{
+		activeFlows: map[uint32]conntrack.Conn{},
+		bufferSize:  bufferSize,
+		natOnly:     natOnly,
+		quit:        make(chan struct{}),
+	}
+	go result.loop()
+	return result
+}
+
+// IsConntrackSupported returns true if conntrack is suppported by the kernel
+var IsConntrackSupported = func(procRoot string) error {
+	// Make sure events are enabled, the conntrack CLI doesn't verify it
+	f := filepath.Join(procRoot, eventsPath)
+	contents, err := ioutil.ReadFile(f)
+	if err != nil {
+		return err
+	}
+	if string(contents) == "0" {
+		return fmt.Errorf("conntrack events (%s) are disabled", f)
+	}
+	return nil
+}
+
+func (c *conntrackWalker) loop() {
+	// conntrack can sometimes fail with ENOBUFS, when there is a particularly
+	// high connection rate.  In these cases just retry in a loop, so we can
+	// survive the spike.  For sustained loads this degrades nicely, as we
+	// read the table before starting to handle events - basically degrading to
+	// polling.
+	for {
+		c.run()
+
+		select {
+		case <-time.After(time.Second):
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *conntrackWalker) clearFlows() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, f := range c.activeFlows {
+		c.bufferedFlows = append(c.bufferedFlows, f)
+	}
+
+	c.activeFlows = map[uint32]conntrack.Conn{}
+}
+
+func (c *conntrackWalker) relevant(f conntrack.Conn) bool {
+	// For now, we're only interested in tcp connections - there is too much udp
+	// traffic going on (every container talking to dns, for example) to
+	// render nicely. TODO: revisit this.
+	if f.Orig.Proto != tcpProto {
+		return false
+	}
+	return !(c.natOnly && (f.Status&conntrack.IPS_NAT_MASK) == 0)
+}
+
+func (c *conntrackWalker) run() {
+	existingFlows, err := conntrack.ConnectionsSize(c.bufferSize)
+	if err != nil {
+		log.Errorf("conntrack Connections error: %v", err)
+		c.clearFlows()
+		return
+	}
+	c.resync(existingFlows)
+
+	events, stop, err := conntrack.FollowSize(c.bufferSize, conntrack.NF_NETLINK_CONNTRACK_UPDATE|conntrack.NF_NETLINK_CONNTRACK_DESTROY)
+	if err != nil {
+		log.Errorf("conntrack Follow error: %v", err)
+		return
+	}
+
+	defer log.Infof("conntrack exiting")
+
+	// Handle conntrack events from netlink socket
+	for {
+		select {
+		case <-c.quit:
+			stop()
+			return
+		case f, ok := <-events:
+			if !ok {
+				return
+			}
+			if c.relevant(f) {
+				c.handleFlow(f)
+			}
+		}
+	}
+}
+
+// resync replaces the active flows with the flows currently in the
+// conntrack table. Flows which went missing (e.g. whose events were
+// dropped when the netlink buffer overflowed) are buffered as finished.
+func (c *conntrackWalker) resync(existingFlows []conntrack.Conn) {
+	c.Lock()
+	defer c.Unlock()
+
+	// The first read of the table is the initial sync, not a resync
+	if c.synced {
+		conntrackResyncs.Inc()
+	}
+	c.synced = true
+
+	activeFlows := map[uint32]conntrack.Conn{}
+	for _, flow := range existingFlows {
+		if c.relevant(flow) && flow.TCPState != tcpClose && flow.TCPState != timeWait {
+			activeFlows[flow.CtId] = flow
+		}
+	}
+	for id, flow := range c.activeFlows {
+		if _, ok := activeFlows[id]; !ok {
+			c.bufferedFlows = append(c.bufferedFlows, flow)
+		}
+	}
+	c.activeFlows = activeFlows
+}
+
+func (c *conntrackWalker) stop() {
+	c.Lock()
+	defer c.Unlock()
+	close(c.quit)
+}
+
+func (c *conntrackWalker) handleFlow(f conntrack.Conn) {
+	c.Lock()
+	defer c.Unlock()
+
+	// Ignore flows for which we never saw an update; they are likely
+	// incomplete or wrong.  See #1462.
+	switch {
+	case f.MsgType == conntrack.NfctMsgUpdate:
+		if f.TCPState != timeWait {
+			c.activeFlows[f.CtId] = f
+		} else if _, ok := c.activeFlows[f.CtId]; ok {
+			delete(c.activeFlows, f.CtId)
+			c.bufferedFlows = append(c.bufferedFlows, f)
+		}
+	case f.MsgType == conntrack.NfctMsgDestroy:
+		if active, ok := c.activeFlows[f.CtId]; ok {
+			delete(c.activeFlows, f.CtId)
+			c.bufferedFlows = append(c.bufferedFlows, active)
+		} else if !c.natOnly && f.Status&conntrack.IPS_SEEN_REPLY == 0 {
+			// A SYN nothing ever replied to gets no update, only
+			// destroyed once it times out; we want it as failed.
+			c.bufferedFlows = append(c.bufferedFlows, f)
+		}
+	}
+}
+
+// flowFailed tells whether a flow is that of a connection which never got
+// established: refused, with a reset in reply to its SYN, or timed out,
+// with no reply at all.
+func flowFailed(f conntrack.Conn, alive bool) bool {
+	if f.Status&conntrack.IPS_ASSURED != 0 {
+		return false
+	}
+	return !alive || f.TCPState == tcpClose
+}
+
+// walkFlows calls f with all active flows and flows that have come and gone
+// since the last call to walkFlows
+func (c *conntrackWalker) walkFlows(f func(conntrack.Conn, bool)) {
+	c.Lock()
+	defer c.Unlock()
+	for _, flow := range c.activeFlows {
+		f(flow, true)
+	}
+	for _, flow := range c.bufferedFlows {
+		f(flow, false)
+	}
+	c.bufferedFlows = c.bufferedFlows[:0]
+}
@@ -0,0 +1,83 @@
+// +build linux
+
+package endpoint
+
+import (
+	"testing"
+
+	"github.com/typetypetype/conntrack"
+)
+
+func TestConntrackResync(t *testing.T) {
+	flow := func(id uint32) conntrack.Conn {
+		return conntrack.Conn{
+			MsgType:  conntrack.NfctMsgUpdate,
+			Orig:     conntrack.Tuple{Proto: tcpProto},
+			TCPState: "ESTABLISHED",
+			CtId:     id,
+		}
+	}
+	c := &conntrackWalker{activeFlows: map[uint32]conntrack.Conn{}}
+	c.handleFlow(flow(1))
+	c.handleFlow(flow(2))
+
+	// The destroy event for flow 1 got lost, and flow 3 began meanwhile
+	c.resync([]conntrack.Conn{flow(2), flow(3)})
+
+	active, finished := map[uint32]bool{}, map[uint32]bool{}
+	c.walkFlows(func(f conntrack.Conn, alive bool) {
+		if alive {
+			active[f.CtId] = true
+		} else {
+			finished[f.CtId] = true
+		}
+	})
+	if len(active) != 2 || !active[2] || !active[3] {
+		t.Errorf("expected flows 2 and 3 to be active, got %v", active)
+	}
+	if len(finished) != 1 || !finished[1] {
+		t.Errorf("expected flow 1 to be finished, got %v", finished)
+	}
+}
+
+func TestConntrackFailedFlows(t *testing.T) {
+	c := &conntrackWalker{activeFlows: map[uint32]conntrack.Conn{}}
+	// A SYN nobody replied to, destroyed once it timed out
+	c.handleFlow(conntrack.Conn{
+		MsgType:  conntrack.NfctMsgDestroy,
+		Orig:     conntrack.Tuple{Proto: tcpProto},
+		TCPState: "SYN_SENT",
+		CtId:     1,
+	})
+	// A connection which got established, and closed
+	c.handleFlow(conntrack.Conn{
+		MsgType: conntrack.NfctMsgDestroy,
+		Orig:    conntrack.Tuple{Proto: tcpProto},
+		Status:  conntrack.IPS_SEEN_REPLY | conntrack.IPS_ASSURED,
+		CtId:    2,
+	})
+
+	failed := map[uint32]bool{}
+	c.walkFlows(func(f conntrack.Conn, alive bool) {
+		failed[f.CtId] = flowFailed(f, alive)
+	})
+	if len(failed) != 1 || !failed[1] {
+		t.Errorf("expected flow 1 to have failed, got %v", failed)
+	}
+
+	for _, tc := range []struct {
+		status   conntrack.CtStatus
+		state    string
+		alive    bool
+		expected bool
+	}{
+		{conntrack.IPS_SEEN_REPLY, tcpClose, true, true},                          // refused
+		{conntrack.IPS_SEEN_REPLY | conntrack.IPS_ASSURED, tcpClose, true, false}, // closed
+		{0, "SYN_SENT", true, false},                                              // still trying
+		{conntrack.IPS_SEEN_REPLY | conntrack.IPS_ASSURED, "ESTABLISHED", false, false},
+	} {
+		if have := flowFailed(conntrack.Conn{Status: tc.status, TCPState: tc.state}, tc.alive); have != tc.expected {
+			t.Errorf("%v %s alive=%v: expected %v, got %v", tc.status, tc.state, tc.alive, tc.expected, have)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package endpoint
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// hostsFile holds the names of addresses in a hosts file, such as
+// /etc/hosts, re-reading it whenever it changes. These names are often the
+// only ones there are for internal services which aren't in DNS.
+type hostsFile struct {
+	path    string
+	modTime time.Time
+	names   map[string][]string
+}
+
+func newHostsFile(path string) *hostsFile {
+	h := &hostsFile{path: path}
+	h.refresh()
+	return h
+}
+
+// refresh re-reads the file if it has changed since it was last read.
+func (h *hostsFile) refresh() {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		h.modTime, h.names = time.Time{}, nil
+		return
+	}
+	if info.ModTime().Equal(h.modTime) {
+		return
+	}
+	names, err := readHostsFile(h.path)
+	if err != nil {
+		log.Warnf("Error reading hosts file %s: %v", h.path, err)
+		return
+	}
+	h.modTime, h.names = info.ModTime(), names
+}
+
+func (h *hostsFile) namesForIP(addr string) []string {
+	return h.names[addr]
+}
+
+func readHostsFile(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := map[string][]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		ip := net.ParseIP(fields[0])
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		addr := ip.String()
+		names[addr] = append(names[addr], fields[1:]...)
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,139 @@
+package procspy
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/weaveworks/scope/probe/process"
+)
+
+// From iprtrmib.h and tcpmib.h
+const (
+	tcpTableOwnerPIDAll = 5
+
+	mibTCPStateEstab     = 5
+	mibTCPStateFinWait1  = 6
+	mibTCPStateFinWait2  = 7
+	mibTCPStateCloseWait = 8
+)
+
+var procGetExtendedTCPTable = windows.NewLazySystemDLL("iphlpapi.dll").NewProc("GetExtendedTcpTable")
+
+// The sizes of MIB_TCPROW_OWNER_PID and MIB_TCP6ROW_OWNER_PID, the rows of
+// the tables, which follow the number of rows. The addresses and ports in
+// the rows are in network byte order, the rest in host (little endian) order.
+const (
+	tcpRowSize  = 24
+	tcp6RowSize = 56
+)
+
+// NewConnectionScanner creates a new Windows ConnectionScanner
+func NewConnectionScanner(_ process.Walker, processes bool) ConnectionScanner {
+	return &windowsScanner{processes}
+}
+
+// NewSyncConnectionScanner creates a new synchronous Windows ConnectionScanner
+func NewSyncConnectionScanner(_ process.Walker, processes bool) ConnectionScanner {
+	return &windowsScanner{processes}
+}
+
+// windowsScanner reads the TCP tables of the host, which hold the owning
+// process of each connection, with GetExtendedTcpTable.
+type windowsScanner struct {
+	processes bool
+}
+
+// Connections returns all established (TCP) connections.
+func (s *windowsScanner) Connections() (ConnIter, error) {
+	var connections []Connection
+	table, err := getExtendedTCPTable(windows.AF_INET)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows(table, tcpRowSize) {
+		// state, local addr, local port, remote addr, remote port, pid
+		if tracked(binary.LittleEndian.Uint32(row)) {
+			connections = append(connections, s.connection(row[4:8], row[8:10], row[12:16], row[16:18], row[20:24]))
+		}
+	}
+	table, err = getExtendedTCPTable(windows.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows(table, tcp6RowSize) {
+		// local addr, scope, local port, remote addr, scope, remote port, state, pid
+		if tracked(binary.LittleEndian.Uint32(row[48:52])) {
+			connections = append(connections, s.connection(row[0:16], row[20:22], row[24:40], row[44:46], row[52:56]))
+		}
+	}
+	f := fixedConnIter(connections)
+	return &f, nil
+}
+
+func (s *windowsScanner) connection(localAddr, localPort, remoteAddr, remotePort, pid []byte) Connection {
+	c := Connection{
+		Transport:     "tcp",
+		LocalAddress:  net.IP(append([]byte(nil), localAddr...)),
+		LocalPort:     binary.BigEndian.Uint16(localPort),
+		RemoteAddress: net.IP(append([]byte(nil), remoteAddr...)),
+		RemotePort:    binary.BigEndian.Uint16(remotePort),
+	}
+	if s.processes {
+		c.Proc = Proc{PID: uint(binary.LittleEndian.Uint32(pid))}
+	}
+	return c
+}
+
+// rows splits a table into its rows
+func rows(table []byte, size int) [][]byte {
+	if len(table) < 4 {
+		return nil
+	}
+	n := int(binary.LittleEndian.Uint32(table))
+	result := make([][]byte, 0, n)
+	for i, b := 0, table[4:]; i < n && len(b) >= size; i, b = i+1, b[size:] {
+		result = append(result, b[:size])
+	}
+	return result
+}
+
+// Only process established or half-closed connections
+func tracked(state uint32) bool {
+	switch state {
+	case mibTCPStateEstab, mibTCPStateFinWait1, mibTCPStateFinWait2, mibTCPStateCloseWait:
+		return true
+	}
+	return false
+}
+
+// getExtendedTCPTable returns the table of the TCP connections of the
+// family, growing the buffer until it fits, as connections come and go.
+func getExtendedTCPTable(family uint32) ([]byte, error) {
+	size := uint32(4096)
+	for {
+		buf := make([]byte, size)
+		r, _, _ := procGetExtendedTCPTable.Call(
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(unsafe.Pointer(&size)),
+			0, // unsorted
+			uintptr(family),
+			tcpTableOwnerPIDAll,
+			0,
+		)
+		switch syscall.Errno(r) {
+		case 0:
+			return buf[:size], nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			continue
+		default:
+			return nil, syscall.Errno(r)
+		}
+	}
+}
+
+// Nothing to stop since there's nothing running in the background
+func (s *windowsScanner) Stop() {}
@@ -0,0 +1,80 @@
+package endpoint
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weaveworks/scope/probe/endpoint/procspy"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Node metadata keys.
+const (
+	ReverseDNSNames = report.ReverseDNSNames
+	SnoopedDNSNames = report.SnoopedDNSNames
+	CopyOf          = report.CopyOf
+	SampledWeight   = report.SampledWeight
+	NetNamespace    = report.NetNamespace // The inode of the network namespace of the process
+)
+
+// Node metrics keys, set on the originating endpoint of a connection
+// when conntrack accounting, or TCP_INFO, is available.
+const (
+	EgressBytes    = "egress_bytes"
+	EgressPackets  = "egress_packets"
+	IngressBytes   = "ingress_bytes"
+	IngressPackets = "ingress_packets"
+
+	// From the TCP_INFO of either end of established TCP connections
+	TCPRTT         = "tcp_rtt" // in milliseconds
+	TCPRetransmits = "tcp_retransmits"
+)
+
+// MetricTemplates for the connection accounting metrics. Exposed for
+// testing.
+var MetricTemplates = report.MetricTemplates{
+	EgressBytes:    {ID: EgressBytes, Label: "Bytes sent", Format: report.FilesizeFormat, Priority: 1},
+	IngressBytes:   {ID: IngressBytes, Label: "Bytes received", Format: report.FilesizeFormat, Priority: 2},
+	EgressPackets:  {ID: EgressPackets, Label: "Packets sent", Format: report.IntegerFormat, Priority: 3},
+	IngressPackets: {ID: IngressPackets, Label: "Packets received", Format: report.IntegerFormat, Priority: 4},
+	TCPRTT:         {ID: TCPRTT, Label: "RTT (ms)", Priority: 5},
+	TCPRetransmits: {ID: TCPRetransmits, Label: "Retransmits", Format: report.IntegerFormat, Priority: 6},
+}
+
+// ReporterConfig are the config options for the endpoint reporter.
+type ReporterConfig struct {
+	HostID       string
+	HostName     string
+	SpyProcs     bool
+	UseConntrack bool
+	WalkProc     bool
+	UseEbpfConn  bool
+	TCPInfo      bool // Report the RTT of TCP connections, from TCP_INFO
+	ProcRoot     string
+	BufferSize   int
+	ProcessCache *process.CachingWalker
+	Scanner      procspy.ConnectionScanner
+	DNSSnooper   *DNSSnooper
+	HostsFile    string // Path of a hosts file naming addresses, empty for none
+	MaxNodes     int    // Sample connections above this many endpoints, 0 for no limit
+}
+
+// SpyDuration is an exported prometheus metric
+var SpyDuration = prometheus.NewSummaryVec(
+	prometheus.SummaryOpts{
+		Namespace: "scope",
+		Subsystem: "probe",
+		Name:      "spy_duration_seconds",
+		Help:      "Time in seconds spent spying on active connections.",
+		MaxAge:    10 * time.Second, // like statsd
+	},
+	[]string{},
+)
+
+func init() {
+	prometheus.MustRegister(SpyDuration)
+}
+
+// Name of this reporter, for metrics gathering
+func (Reporter) Name() string { return "Endpoint" }
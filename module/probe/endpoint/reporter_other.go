@@ -0,0 +1,24 @@
+// +build !linux,!windows
+
+package endpoint
+
+import "github.com/weaveworks/scope/report"
+
+// Reporter dummy
+type Reporter struct{}
+
+// NewReporter makes a dummy
+func NewReporter(conf ReporterConfig) *Reporter {
+	return &Reporter{}
+}
+
+// Stop dummy
+func (r *Reporter) Stop() {}
+
+// DisableConnectionMetrics dummy
+func (r *Reporter) DisableConnectionMetrics(disabled bool) {}
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	return report.MakeReport(), nil
+}
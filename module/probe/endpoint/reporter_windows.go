@@ -0,0 +1,52 @@
+package endpoint
+
+import (
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Reporter generates Reports containing the Endpoint topology.
+type Reporter struct {
+	conf              ReporterConfig
+	connectionTracker connectionTracker
+}
+
+// NewReporter creates a new Reporter that reads the TCP tables of the
+// host, which hold the owning processes (PIDs) of the connections, to
+// generate a report.Report of every connection on the host machine, at the
+// granularity of host and port.
+func NewReporter(conf ReporterConfig) *Reporter {
+	return &Reporter{
+		conf:              conf,
+		connectionTracker: newConnectionTracker(conf),
+	}
+}
+
+// Stop stop stop
+func (r *Reporter) Stop() {
+	r.connectionTracker.Stop()
+	if r.conf.Scanner != nil {
+		r.conf.Scanner.Stop()
+	}
+}
+
+// DisableConnectionMetrics is a no-op: there is no accounting of the
+// connections on Windows.
+func (r *Reporter) DisableConnectionMetrics(disabled bool) {
+	r.connectionTracker.noMetrics = disabled
+}
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	defer func(begin time.Time) {
+		SpyDuration.WithLabelValues().Observe(time.Since(begin).Seconds())
+	}(time.Now())
+
+	rpt := report.MakeReport()
+	r.connectionTracker.ReportConnections(&rpt)
+	if r.conf.MaxNodes > 0 {
+		rpt.Endpoint = sampleEndpoints(rpt.Endpoint, r.conf.MaxNodes)
+	}
+	return rpt, nil
+}
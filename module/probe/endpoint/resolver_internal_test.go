@@ -0,0 +1,71 @@
+package endpoint
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/test"
+)
+
+func TestReverseResolver(t *testing.T) {
+	tests := map[string][]string{
+		"1.2.3.4": {"test.domain.name"},
+		"4.3.2.1": {"im.a.little.tea.pot"},
+	}
+
+	revRes := newReverseResolver()
+	defer revRes.stop()
+
+	// Use a mocked resolver function.
+	revRes.Resolver = func(addr string) (names []string, err error) {
+		if names, ok := tests[addr]; ok {
+			return names, nil
+		}
+		return []string{}, errors.New("invalid IP")
+	}
+
+	// Up the rate limit so the test runs faster.
+	revRes.Throttle = time.Tick(time.Millisecond)
+
+	for ip, names := range tests {
+		test.Poll(t, 100*time.Millisecond, names, func() interface{} {
+			ns, _ := revRes.get(ip)
+			return ns
+		})
+	}
+}
+
+func TestHostsFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("127.0.0.1 localhost\n# 10.0.0.9 commented\n10.0.0.1 db db.internal  # the database\n10.0.0.2\tcache\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	h := newHostsFile(f.Name())
+	for addr, want := range map[string][]string{
+		"10.0.0.1":  {"db", "db.internal"},
+		"10.0.0.2":  {"cache"},
+		"10.0.0.9":  nil,
+		"127.0.0.1": nil,
+	} {
+		if have := h.namesForIP(addr); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: expected %v, got %v", addr, want, have)
+		}
+	}
+
+	// Should forget the names once the file goes away
+	os.Remove(f.Name())
+	h.refresh()
+	if have := h.namesForIP("10.0.0.1"); have != nil {
+		t.Errorf("Expected no names, got %v", have)
+	}
+}
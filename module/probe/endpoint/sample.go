@@ -0,0 +1,71 @@
+package endpoint
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// sampleEndpoints reduces the endpoint topology to roughly maxNodes
+// nodes, by keeping only a sample of the connections, i.e. of the
+// endpoints with adjacencies, and the endpoints they connect to.
+//
+// The sampled source endpoints are tagged with the number of
+// connections each stands for, so that renderers can scale their
+// counts and metrics back up.
+func sampleEndpoints(t report.Topology, maxNodes int) report.Topology {
+	if len(t.Nodes) <= maxNodes {
+		return t
+	}
+	sources := 0
+	for _, n := range t.Nodes {
+		if len(n.Adjacency) > 0 {
+			sources++
+		}
+	}
+	// Every sampled source brings (usually) one destination along
+	weight := (2*sources + maxNodes - 1) / maxNodes
+	if weight <= 1 {
+		return t
+	}
+
+	now := mtime.Now()
+	value := strconv.Itoa(weight)
+	nodes := report.Nodes{}
+	for id, n := range t.Nodes {
+		if len(n.Adjacency) == 0 || !sampled(n, weight) {
+			continue
+		}
+		nodes[id] = n.WithLatest(SampledWeight, now, value)
+		for _, dstID := range n.Adjacency {
+			if _, ok := nodes[dstID]; ok {
+				continue
+			}
+			if dst, ok := t.Nodes[dstID]; ok {
+				// If the destination also is the source of a connection
+				// which isn't sampled, drop that connection; it gets
+				// replaced if the connection is sampled after all.
+				dst.Adjacency = report.MakeIDList()
+				nodes[dstID] = dst
+			}
+		}
+	}
+	result := t
+	result.Nodes = nodes
+	return result
+}
+
+// sampled tells whether to keep the connection originating from n, when
+// keeping 1 in every weight connections. NATed copies of an endpoint are
+// sampled along with the original.
+func sampled(n report.Node, weight int) bool {
+	id := n.ID
+	if copyID, ok := n.Latest.Lookup(CopyOf); ok {
+		id = copyID
+	}
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return h.Sum32()%uint32(weight) == 0
+}
@@ -0,0 +1,45 @@
+package endpoint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestSampleEndpoints(t *testing.T) {
+	topology := report.MakeTopology()
+	for i := 0; i < 100; i++ {
+		srcID := report.MakeEndpointNodeID("host1", "", "10.0.0.1", fmt.Sprint(10000+i))
+		dstID := report.MakeEndpointNodeID("host1", "", "10.0.0.2", fmt.Sprint(i))
+		topology.AddNode(report.MakeNode(srcID).WithAdjacent(dstID))
+		topology.AddNode(report.MakeNode(dstID))
+	}
+
+	// Small enough topologies are left alone
+	if have := sampleEndpoints(topology, 200); !reflect.DeepEqual(topology, have) {
+		t.Fatal("expected the topology to be unchanged")
+	}
+
+	have := sampleEndpoints(topology, 50)
+	if len(have.Nodes) == 0 || len(have.Nodes) >= len(topology.Nodes) {
+		t.Fatalf("expected a sample of the endpoints, got %d", len(have.Nodes))
+	}
+	for id, n := range have.Nodes {
+		if len(n.Adjacency) == 0 {
+			if _, ok := n.Latest.Lookup(SampledWeight); ok {
+				t.Errorf("expected destination %s not to be weighted", id)
+			}
+			continue
+		}
+		if weight, ok := n.Latest.Lookup(SampledWeight); !ok || weight != "4" {
+			t.Errorf("expected source %s to have weight 4, got %q", id, weight)
+		}
+		for _, dstID := range n.Adjacency {
+			if _, ok := have.Nodes[dstID]; !ok {
+				t.Errorf("expected destination %s of %s to be kept", dstID, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// +build linux
+
+package endpoint
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink/nl"
+	"github.com/vishvananda/netns"
+	"github.com/weaveworks/common/mtime"
+	"golang.org/x/sys/unix"
+
+	"github.com/weaveworks/scope/probe/endpoint/procspy"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// From linux/inet_diag.h and linux/tcp.h
+const (
+	inetDiagInfo          = 2 // INET_DIAG_INFO, the attribute carrying the struct tcp_info
+	sizeofInetDiagRequest = 56
+	sizeofInetDiagMsg     = 72
+
+	tcpStateEstablished = 1
+	tcpStateListen      = 10
+
+	// Offsets of the fields of struct tcp_info we read
+	tcpInfoRTT          = 68  // tcpi_rtt, smoothed RTT in microseconds
+	tcpInfoTotalRetrans = 100 // tcpi_total_retrans
+	sizeofTCPInfo       = tcpInfoTotalRetrans + 4
+)
+
+// tcpSocket is a TCP socket, as sock_diag tells of it, with its TCP_INFO
+// if it is established.
+type tcpSocket struct {
+	listening             bool
+	localAddr, remoteAddr string
+	localPort, remotePort uint16
+	rtt                   time.Duration // zero without TCP_INFO
+	retransmits           uint32
+}
+
+// inetDiagRequest asks sock_diag for all the TCP sockets of a family in
+// the given states, with their TCP_INFO.
+type inetDiagRequest struct {
+	family uint8
+	states uint32
+}
+
+func (r *inetDiagRequest) Len() int { return sizeofInetDiagRequest }
+
+func (r *inetDiagRequest) Serialize() []byte {
+	b := make([]byte, sizeofInetDiagRequest)
+	b[0] = r.family
+	b[1] = unix.IPPROTO_TCP
+	b[2] = 1 << (inetDiagInfo - 1)
+	nl.NativeEndian().PutUint32(b[4:8], r.states)
+	// The socket ID is left zero, to dump them all
+	return b
+}
+
+// parseInetDiagMsg parses a struct inet_diag_msg, followed by its
+// attributes.
+func parseInetDiagMsg(b []byte) (tcpSocket, bool) {
+	if len(b) < sizeofInetDiagMsg {
+		return tcpSocket{}, false
+	}
+	ipLen := net.IPv4len
+	if b[0] == unix.AF_INET6 {
+		ipLen = net.IPv6len
+	}
+	s := tcpSocket{
+		listening:  b[1] == tcpStateListen,
+		localPort:  uint16(b[4])<<8 | uint16(b[5]),
+		remotePort: uint16(b[6])<<8 | uint16(b[7]),
+		localAddr:  net.IP(b[8 : 8+ipLen]).String(),
+		remoteAddr: net.IP(b[24 : 24+ipLen]).String(),
+	}
+	attrs, err := nl.ParseRouteAttr(b[sizeofInetDiagMsg:])
+	if err != nil {
+		return s, true
+	}
+	native := nl.NativeEndian()
+	for _, attr := range attrs {
+		if attr.Attr.Type != inetDiagInfo || len(attr.Value) < sizeofTCPInfo {
+			continue
+		}
+		s.rtt = time.Duration(native.Uint32(attr.Value[tcpInfoRTT:])) * time.Microsecond
+		s.retransmits = native.Uint32(attr.Value[tcpInfoTotalRetrans:])
+	}
+	return s, true
+}
+
+// readTCPSockets dumps the established and listening TCP sockets of the
+// network namespace at nsPath, or of the probe's if empty, with sock_diag,
+// as `ss -ti` does.
+func readTCPSockets(nsPath string) ([]tcpSocket, error) {
+	ns := netns.None()
+	if nsPath != "" {
+		var err error
+		if ns, err = netns.GetFromPath(nsPath); err != nil {
+			return nil, err
+		}
+		defer ns.Close()
+	}
+	s, err := nl.GetNetlinkSocketAt(ns, netns.None(), unix.NETLINK_INET_DIAG)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	sockets := []tcpSocket{}
+	for _, family := range []uint8{unix.AF_INET, unix.AF_INET6} {
+		req := nl.NewNetlinkRequest(nl.SOCK_DIAG_BY_FAMILY, unix.NLM_F_DUMP)
+		req.Sockets = map[int]*nl.SocketHandle{unix.NETLINK_INET_DIAG: {Socket: s}}
+		req.AddData(&inetDiagRequest{family: family, states: 1<<tcpStateEstablished | 1<<tcpStateListen})
+		msgs, err := req.Execute(unix.NETLINK_INET_DIAG, nl.SOCK_DIAG_BY_FAMILY)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if socket, ok := parseInetDiagMsg(msg); ok {
+				sockets = append(sockets, socket)
+			}
+		}
+	}
+	return sockets, nil
+}
+
+// tcpInfoNamespaces returns the paths of the network namespaces to read the
+// sockets of besides the probe's: that of a process in each of them.
+func tcpInfoNamespaces(procRoot string, processes *process.CachingWalker) []string {
+	paths := []string{}
+	if processes == nil {
+		return paths
+	}
+	self, err := procspy.ReadNetnsFromPID(os.Getpid())
+	if err != nil {
+		return paths
+	}
+	seen := map[uint64]struct{}{self: {}}
+	processes.Walk(func(p, _ process.Process) {
+		ns, err := procspy.ReadNetnsFromPID(p.PID)
+		if err != nil {
+			return
+		}
+		if _, ok := seen[ns]; !ok {
+			seen[ns] = struct{}{}
+			paths = append(paths, filepath.Join(procRoot, strconv.Itoa(p.PID), "ns", "net"))
+		}
+	})
+	return paths
+}
+
+// originTCPInfo attributes the TCP_INFO of the established sockets (of one
+// network namespace) to the originating endpoints of their connections:
+// the local one, unless the socket was accepted on a listening port. The
+// RTTs measured at the originating end are kept over those measured at the
+// other.
+func originTCPInfo(sockets []tcpSocket, origins map[fourTuple]tcpSocket) {
+	listening := map[uint16]struct{}{}
+	for _, s := range sockets {
+		if s.listening {
+			listening[s.localPort] = struct{}{}
+		}
+	}
+	for _, s := range sockets {
+		if s.listening || s.rtt == 0 || report.IsLoopback(s.localAddr) {
+			continue
+		}
+		ft := fourTuple{s.localAddr, s.remoteAddr, s.localPort, s.remotePort}
+		if _, ok := listening[s.localPort]; ok {
+			if _, ok := origins[reverse(ft)]; !ok {
+				origins[reverse(ft)] = s
+			}
+			continue
+		}
+		origins[ft] = s
+	}
+}
+
+// ReportTCPInfo attaches the RTT and retransmissions of the established TCP
+// connections, from TCP_INFO, to the originating endpoints of those of
+// them in rpt.
+func (t *connectionTracker) ReportTCPInfo(rpt *report.Report) {
+	if !t.conf.TCPInfo || t.noMetrics {
+		return
+	}
+	origins := map[fourTuple]tcpSocket{}
+	for _, path := range append([]string{""}, tcpInfoNamespaces(t.conf.ProcRoot, t.conf.ProcessCache)...) {
+		sockets, err := readTCPSockets(path)
+		if err != nil && path == "" {
+			log.Warnf("Not reporting the RTT of connections: can't read the TCP_INFO of sockets: %v", err)
+			t.conf.TCPInfo = false
+			return
+		} else if err != nil {
+			continue // the namespace went away
+		}
+		originTCPInfo(sockets, origins)
+	}
+	t.addTCPInfo(rpt, origins)
+}
+
+// addTCPInfo attaches the TCP_INFO of connections, by their four-tuples
+// from the originating end, to the originating endpoints in rpt.
+func (t *connectionTracker) addTCPInfo(rpt *report.Report, origins map[fourTuple]tcpSocket) {
+	now := mtime.Now()
+	for ft, s := range origins {
+		id := report.MakeEndpointNodeID(t.conf.HostID, "", ft.fromAddr, strconv.Itoa(int(ft.fromPort)))
+		if node, ok := rpt.Endpoint.Nodes[id]; !ok || len(node.Adjacency) == 0 {
+			continue // not a connection we reported
+		}
+		rpt.Endpoint.AddNode(report.MakeNode(id).WithMetrics(report.Metrics{
+			TCPRTT:         report.MakeSingletonMetric(now, float64(s.rtt)/float64(time.Millisecond)),
+			TCPRetransmits: report.MakeSingletonMetric(now, float64(s.retransmits)),
+		}))
+	}
+}
@@ -0,0 +1,93 @@
+// +build linux
+
+package endpoint
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+func makeInetDiagMsg(state uint8, local, remote string, localPort, remotePort uint16, rtt time.Duration, retransmits uint32) []byte {
+	b := make([]byte, sizeofInetDiagMsg)
+	b[0] = unix.AF_INET
+	b[1] = state
+	b[4], b[5] = byte(localPort>>8), byte(localPort)
+	b[6], b[7] = byte(remotePort>>8), byte(remotePort)
+	copy(b[8:], net.ParseIP(local).To4())
+	copy(b[24:], net.ParseIP(remote).To4())
+	if state == tcpStateEstablished {
+		info := make([]byte, sizeofTCPInfo)
+		nl.NativeEndian().PutUint32(info[tcpInfoRTT:], uint32(rtt/time.Microsecond))
+		nl.NativeEndian().PutUint32(info[tcpInfoTotalRetrans:], retransmits)
+		b = append(b, nl.NewRtAttr(inetDiagInfo, info).Serialize()...)
+	}
+	return b
+}
+
+func TestParseInetDiagMsg(t *testing.T) {
+	s, ok := parseInetDiagMsg(makeInetDiagMsg(tcpStateEstablished, "10.0.0.1", "10.0.0.2", 45678, 80, 1500*time.Microsecond, 3))
+	if !ok {
+		t.Fatal("Expected a socket")
+	}
+	if want := (tcpSocket{
+		localAddr: "10.0.0.1", remoteAddr: "10.0.0.2",
+		localPort: 45678, remotePort: 80,
+		rtt: 1500 * time.Microsecond, retransmits: 3,
+	}); s != want {
+		t.Errorf("Expected %+v, got %+v", want, s)
+	}
+
+	if s, ok := parseInetDiagMsg(makeInetDiagMsg(tcpStateListen, "0.0.0.0", "0.0.0.0", 80, 0, 0, 0)); !ok || !s.listening {
+		t.Errorf("Expected a listening socket, got %+v", s)
+	}
+	if _, ok := parseInetDiagMsg([]byte{unix.AF_INET}); ok {
+		t.Error("Expected a short message not to parse")
+	}
+}
+
+func TestReportTCPInfo(t *testing.T) {
+	now := mtime.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	// The client end, and the server end on a listening port: the RTT of the
+	// client end is kept
+	origins := map[fourTuple]tcpSocket{}
+	originTCPInfo([]tcpSocket{
+		{listening: true, localAddr: "0.0.0.0", remoteAddr: "0.0.0.0", localPort: 80},
+		{localAddr: "10.0.0.2", remoteAddr: "10.0.0.1", localPort: 80, remotePort: 45678, rtt: 2 * time.Millisecond, retransmits: 1},
+		{localAddr: "127.0.0.1", remoteAddr: "127.0.0.1", localPort: 80, remotePort: 45679, rtt: time.Microsecond},
+	}, origins)
+	originTCPInfo([]tcpSocket{
+		{localAddr: "10.0.0.1", remoteAddr: "10.0.0.2", localPort: 45678, remotePort: 80, rtt: time.Millisecond},
+	}, origins)
+	ft := fourTuple{"10.0.0.1", "10.0.0.2", 45678, 80}
+	if len(origins) != 1 || origins[ft].rtt != time.Millisecond {
+		t.Fatalf("Expected the RTT of the client end, got %+v", origins)
+	}
+
+	tracker := connectionTracker{
+		conf:            ReporterConfig{HostID: "host", TCPInfo: true},
+		reverseResolver: newReverseResolver(),
+	}
+	defer tracker.reverseResolver.stop()
+	rpt := report.MakeReport()
+	tracker.addConnection(&rpt, false, ft, "", nil, nil)
+	origins[fourTuple{"10.0.0.3", "10.0.0.2", 45680, 80}] = tcpSocket{rtt: time.Millisecond}
+	tracker.addTCPInfo(&rpt, origins)
+
+	node := rpt.Endpoint.Nodes[report.MakeEndpointNodeID("host", "", "10.0.0.1", "45678")]
+	if sample, ok := node.Metrics[TCPRTT].LastSample(); !ok || sample.Value != 1 || !sample.Timestamp.Equal(now) {
+		t.Errorf("Expected an RTT of 1ms, got %v", node.Metrics)
+	}
+	if _, ok := rpt.Endpoint.Nodes[report.MakeEndpointNodeID("host", "", "10.0.0.3", "45680")]; ok {
+		t.Error("Expected no endpoint for a connection which wasn't reported")
+	}
+}
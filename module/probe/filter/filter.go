@@ -0,0 +1,122 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+// Tagger drops containers and processes matching its rules from reports,
+// along with the processes in those containers and the endpoints of all
+// those processes, so that they never reach the app. It must come after the
+// docker tagger, which says which container each process is in.
+type Tagger struct {
+	containerLabels map[string]string // value "" matches any value
+	processName     *regexp.Regexp
+}
+
+// NewTagger makes a new Tagger. containerLabels is a comma-separated list of
+// key=value labels, or just keys for any value, of the containers to drop;
+// processName is a regular expression matching the names of the processes to
+// drop. Either may be empty.
+func NewTagger(containerLabels, processName string) (*Tagger, error) {
+	t := &Tagger{containerLabels: map[string]string{}}
+	for _, label := range strings.Split(containerLabels, ",") {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+		kv := strings.SplitN(label, "=", 2)
+		if kv[0] == "" {
+			return nil, fmt.Errorf("invalid container label %q", label)
+		}
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		t.containerLabels[kv[0]] = kv[1]
+	}
+	if processName != "" {
+		re, err := regexp.Compile(processName)
+		if err != nil {
+			return nil, err
+		}
+		t.processName = re
+	}
+	return t, nil
+}
+
+// Name of this tagger, for metrics gathering
+func (*Tagger) Name() string { return "Filter" }
+
+// Tag implements Tagger.
+func (t *Tagger) Tag(r report.Report) (report.Report, error) {
+	containers := map[string]struct{}{}
+	for id, n := range r.Container.Nodes {
+		if t.excludeContainer(n) {
+			containers[id] = struct{}{}
+			delete(r.Container.Nodes, id)
+		}
+	}
+
+	pids := map[string]struct{}{}
+	for id, n := range r.Process.Nodes {
+		if t.excludeProcess(n, containers) {
+			if pid, ok := n.Latest.Lookup(process.PID); ok {
+				pids[pid] = struct{}{}
+			}
+			delete(r.Process.Nodes, id)
+		}
+	}
+
+	if len(pids) == 0 {
+		return r, nil
+	}
+	endpoints := map[string]struct{}{}
+	for id, n := range r.Endpoint.Nodes {
+		if pid, ok := n.Latest.Lookup(process.PID); ok {
+			if _, ok := pids[pid]; ok {
+				endpoints[id] = struct{}{}
+				delete(r.Endpoint.Nodes, id)
+			}
+		}
+	}
+	// Don't leave connections to the endpoints we dropped
+	for id, n := range r.Endpoint.Nodes {
+		adjacency := report.MakeIDList()
+		for _, dst := range n.Adjacency {
+			if _, ok := endpoints[dst]; !ok {
+				adjacency = adjacency.Add(dst)
+			}
+		}
+		if len(adjacency) != len(n.Adjacency) {
+			n.Adjacency = adjacency
+			r.Endpoint.Nodes[id] = n
+		}
+	}
+	return r, nil
+}
+
+func (t *Tagger) excludeContainer(n report.Node) bool {
+	for key, value := range t.containerLabels {
+		if v, ok := n.Latest.Lookup(docker.LabelPrefix + key); ok && (value == "" || v == value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tagger) excludeProcess(n report.Node, containers map[string]struct{}) bool {
+	if t.processName != nil {
+		if name, ok := n.Latest.Lookup(process.Name); ok && t.processName.MatchString(name) {
+			return true
+		}
+	}
+	if containerID, ok := n.Latest.Lookup(docker.ContainerID); ok {
+		_, excluded := containers[report.MakeContainerNodeID(containerID)]
+		return excluded
+	}
+	return false
+}
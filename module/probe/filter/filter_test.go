@@ -0,0 +1,67 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/filter"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestTagger(t *testing.T) {
+	var (
+		pauseID    = report.MakeContainerNodeID("pause")
+		appID      = report.MakeContainerNodeID("app")
+		pauseProc  = report.MakeProcessNodeID("host", "1")
+		agentProc  = report.MakeProcessNodeID("host", "2")
+		appProc    = report.MakeProcessNodeID("host", "3")
+		pauseEP    = report.MakeEndpointNodeID("host", "", "10.0.0.1", "80")
+		agentEP    = report.MakeEndpointNodeID("host", "", "10.0.0.2", "8125")
+		appEP      = report.MakeEndpointNodeID("host", "", "10.0.0.3", "8080")
+		remoteEP   = report.MakeEndpointNodeID("host", "", "10.0.0.4", "54321")
+		rpt        = report.MakeReport()
+		pauseLabel = docker.LabelPrefix + "io.kubernetes.docker.type"
+	)
+	rpt.Container.AddNode(report.MakeNodeWith(pauseID, map[string]string{pauseLabel: "podsandbox"}))
+	rpt.Container.AddNode(report.MakeNodeWith(appID, map[string]string{pauseLabel: "container"}))
+	rpt.Process.AddNode(report.MakeNodeWith(pauseProc, map[string]string{process.PID: "1", process.Name: "pause", docker.ContainerID: "pause"}))
+	rpt.Process.AddNode(report.MakeNodeWith(agentProc, map[string]string{process.PID: "2", process.Name: "statsd-agent"}))
+	rpt.Process.AddNode(report.MakeNodeWith(appProc, map[string]string{process.PID: "3", process.Name: "app", docker.ContainerID: "app"}))
+	rpt.Endpoint.AddNode(report.MakeNodeWith(pauseEP, map[string]string{process.PID: "1"}))
+	rpt.Endpoint.AddNode(report.MakeNodeWith(agentEP, map[string]string{process.PID: "2"}))
+	rpt.Endpoint.AddNode(report.MakeNodeWith(appEP, map[string]string{process.PID: "3"}).WithAdjacent(agentEP))
+	rpt.Endpoint.AddNode(report.MakeNode(remoteEP).WithAdjacent(appEP))
+
+	tagger, err := filter.NewTagger("io.kubernetes.docker.type=podsandbox,scope.ignore", "-agent$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := tagger.Tag(rpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for topology, want := range map[string][]string{
+		report.Container: {appID},
+		report.Process:   {appProc},
+		report.Endpoint:  {appEP, remoteEP},
+	} {
+		nodes, _ := have.Topology(topology)
+		if len(nodes.Nodes) != len(want) {
+			t.Errorf("%s: expected %v, got %v", topology, want, nodes.Nodes)
+		}
+		for _, id := range want {
+			if _, ok := nodes.Nodes[id]; !ok {
+				t.Errorf("%s: expected %s to be kept", topology, id)
+			}
+		}
+	}
+	if adjacency := have.Endpoint.Nodes[appEP].Adjacency; len(adjacency) != 0 {
+		t.Errorf("expected the connection to the dropped endpoint to be removed, got %v", adjacency)
+	}
+
+	if _, err := filter.NewTagger("", "("); err == nil {
+		t.Error("expected an error for an invalid regular expression")
+	}
+}
@@ -0,0 +1,5 @@
+package host
+
+func getHostShellCmd() []string {
+	return []string{"/bin/bash", "-l"}
+}
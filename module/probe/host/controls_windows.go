@@ -0,0 +1,12 @@
+package host
+
+// The host shell needs a pty, which Windows doesn't have, so there are no
+// host controls there.
+
+func getHostShellCmd() []string {
+	return nil
+}
+
+func (r *Reporter) registerControls() {}
+
+func (r *Reporter) deregisterControls() {}
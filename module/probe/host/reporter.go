@@ -0,0 +1,232 @@
+package host
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys for use in Node.Latest.
+const (
+	Timestamp     = "ts"
+	HostName      = "host_name"
+	LocalNetworks = "local_networks"
+	OS            = "os"
+	KernelVersion = "kernel_version"
+	Uptime        = "uptime"
+	Load1         = "load1"
+	CPUUsage      = "host_cpu_usage_percent"
+	MemoryUsage   = "host_mem_usage_bytes"
+	ScopeVersion  = "host_scope_version"
+
+	ProbeDegradation = report.ProbeDegradation
+)
+
+// Control IDs used by the host integration.
+const (
+	ExecHost      = "host_exec"
+	ResizeExecTTY = "host_resize_exec_tty"
+)
+
+// Prefixes of the keys of per-filesystem and per-disk metrics
+const (
+	DiskUsagePrefix  = "host_disk_usage_bytes_"
+	DiskInodesPrefix = "host_disk_inodes_"
+	DiskReadPrefix   = "host_disk_read_bytes_per_second_"
+	DiskWritePrefix  = "host_disk_write_bytes_per_second_"
+)
+
+// Exposed for testing.
+const (
+	ProcUptime  = "/proc/uptime"
+	ProcLoad    = "/proc/loadavg"
+	ProcStat    = "/proc/stat"
+	ProcMemInfo = "/proc/meminfo"
+
+	ProcMounts    = "/proc/1/mounts"
+	ProcDiskStats = "/proc/diskstats"
+	HostRoot      = "/proc/1/root"
+	SysBlock      = "/sys/block"
+)
+
+// Exposed for testing.
+var (
+	MetadataTemplates = report.MetadataTemplates{
+		KernelVersion: {ID: KernelVersion, Label: "Kernel version", From: report.FromLatest, Priority: 1},
+		Uptime:        {ID: Uptime, Label: "Uptime", From: report.FromLatest, Priority: 2, Datatype: report.Duration},
+		HostName:      {ID: HostName, Label: "Hostname", From: report.FromLatest, Priority: 11},
+		OS:            {ID: OS, Label: "OS", From: report.FromLatest, Priority: 12},
+		LocalNetworks: {ID: LocalNetworks, Label: "Local networks", From: report.FromSets, Priority: 13},
+		ScopeVersion:  {ID: ScopeVersion, Label: "Scope version", From: report.FromLatest, Priority: 14},
+
+		ProbeDegradation: {ID: ProbeDegradation, Label: "Probe degradation", From: report.FromLatest, Priority: 15},
+	}
+
+	MetricTemplates = report.MetricTemplates{
+		CPUUsage:    {ID: CPUUsage, Label: "CPU", Format: report.PercentFormat, Priority: 1},
+		MemoryUsage: {ID: MemoryUsage, Label: "Memory", Format: report.FilesizeFormat, Priority: 2},
+		Load1:       {ID: Load1, Label: "Load (1m)", Format: report.DefaultFormat, Group: "load", Priority: 11},
+	}
+)
+
+// Filesystem is the usage of a mounted filesystem.
+type Filesystem struct {
+	MountPoint              string
+	UsedBytes, TotalBytes   uint64
+	UsedInodes, TotalInodes uint64
+}
+
+// DiskIO is the throughput of a disk.
+type DiskIO struct {
+	Device                                  string
+	ReadBytesPerSecond, WriteBytesPerSecond float64
+}
+
+// Reporter generates Reports containing the host topology.
+type Reporter struct {
+	sync.RWMutex
+	hostID          string
+	hostName        string
+	probeID         string
+	version         string
+	pipes           controls.PipeClient
+	hostShellCmd    []string // nil if the shell control is disabled
+	handlerRegistry *controls.HandlerRegistry
+	pipeIDToTTY     map[string]uintptr
+}
+
+// NewReporter returns a Reporter which produces a report containing host
+// topology for this host. With hostShell, it offers a control which opens a
+// login shell on the host.
+func NewReporter(hostID, hostName, probeID, version string, pipes controls.PipeClient, handlerRegistry *controls.HandlerRegistry, hostShell bool) *Reporter {
+	r := &Reporter{
+		hostID:          hostID,
+		hostName:        hostName,
+		probeID:         probeID,
+		pipes:           pipes,
+		version:         version,
+		handlerRegistry: handlerRegistry,
+		pipeIDToTTY:     map[string]uintptr{},
+	}
+	if hostShell {
+		r.hostShellCmd = getHostShellCmd()
+		r.registerControls()
+	}
+	return r
+}
+
+// Name of this reporter, for metrics gathering
+func (*Reporter) Name() string { return "Host" }
+
+// GetLocalNetworks is exported for mocking
+var GetLocalNetworks = report.GetLocalNetworks
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	var (
+		rep        = report.MakeReport()
+		localCIDRs []string
+	)
+
+	localNets, err := GetLocalNetworks()
+	if err != nil {
+		return rep, nil
+	}
+	for _, localNet := range localNets {
+		localCIDRs = append(localCIDRs, localNet.String())
+	}
+
+	uptime, err := GetUptime()
+	if err != nil {
+		return rep, err
+	}
+
+	kernelRelease, kernelVersion, err := GetKernelReleaseAndVersion()
+	if err != nil {
+		return rep, err
+	}
+	kernel := fmt.Sprintf("%s %s", kernelRelease, kernelVersion)
+
+	rep.Host = rep.Host.WithMetadataTemplates(MetadataTemplates)
+	rep.Host = rep.Host.WithMetricTemplates(MetricTemplates)
+
+	now := mtime.Now()
+	metrics := GetLoad(now)
+	if metrics == nil {
+		metrics = report.Metrics{}
+	}
+	cpuUsage, max := GetCPUUsagePercent()
+	metrics[CPUUsage] = report.MakeSingletonMetric(now, cpuUsage).WithMax(max)
+	memoryUsage, max := GetMemoryUsageBytes()
+	metrics[MemoryUsage] = report.MakeSingletonMetric(now, memoryUsage).WithMax(max)
+	rep.Host = rep.Host.WithMetricTemplates(diskMetrics(now, metrics))
+
+	node := report.MakeNodeWith(report.MakeHostNodeID(r.hostID), map[string]string{
+		report.ControlProbeID: r.probeID,
+		Timestamp:             mtime.Now().UTC().Format(time.RFC3339Nano),
+		HostName:              r.hostName,
+		OS:                    runtime.GOOS,
+		KernelVersion:         kernel,
+		Uptime:                strconv.Itoa(int(uptime / time.Second)), // uptime in seconds
+		ScopeVersion:          r.version,
+	}).
+		WithSets(report.MakeSets().
+			Add(LocalNetworks, report.MakeStringSet(localCIDRs...)),
+		).
+		WithMetrics(metrics)
+
+	if r.hostShellCmd != nil {
+		node = node.WithLatestActiveControls(ExecHost)
+		rep.Host.Controls.AddControl(report.Control{
+			ID:    ExecHost,
+			Human: "Exec shell",
+			Icon:  "fa fa-terminal",
+		})
+	}
+	rep.Host.AddNode(node)
+
+	return rep, nil
+}
+
+// diskMetrics adds the metrics of the host's filesystems and disks to
+// metrics, and returns their templates.
+func diskMetrics(now time.Time, metrics report.Metrics) report.MetricTemplates {
+	templates := report.MetricTemplates{}
+	add := func(id, label, format, group string, priority float64, metric report.Metric) {
+		metrics[id] = metric
+		templates[id] = report.MetricTemplate{ID: id, Label: label, Format: format, Group: group, Priority: priority}
+	}
+	filesystems := GetFilesystems()
+	sort.Slice(filesystems, func(i, j int) bool { return filesystems[i].MountPoint < filesystems[j].MountPoint })
+	for i, fs := range filesystems {
+		add(DiskUsagePrefix+fs.MountPoint, "Disk "+fs.MountPoint, report.FilesizeFormat, "disk", float64(20+i),
+			report.MakeSingletonMetric(now, float64(fs.UsedBytes)).WithMax(float64(fs.TotalBytes)))
+		if fs.TotalInodes > 0 { // some filesystems, e.g. btrfs, don't have a fixed number
+			add(DiskInodesPrefix+fs.MountPoint, "Inodes "+fs.MountPoint, report.IntegerFormat, "inodes", float64(40+i),
+				report.MakeSingletonMetric(now, float64(fs.UsedInodes)).WithMax(float64(fs.TotalInodes)))
+		}
+	}
+	disks := GetDiskIO(now)
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Device < disks[j].Device })
+	for i, disk := range disks {
+		add(DiskReadPrefix+disk.Device, disk.Device+" reads/s", report.FilesizeFormat, "disk_io", float64(60+2*i),
+			report.MakeSingletonMetric(now, disk.ReadBytesPerSecond))
+		add(DiskWritePrefix+disk.Device, disk.Device+" writes/s", report.FilesizeFormat, "disk_io", float64(61+2*i),
+			report.MakeSingletonMetric(now, disk.WriteBytesPerSecond))
+	}
+	return templates
+}
+
+// Stop stops the reporter.
+func (r *Reporter) Stop() {
+	if r.hostShellCmd != nil {
+		r.deregisterControls()
+	}
+}
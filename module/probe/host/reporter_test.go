@@ -0,0 +1,148 @@
+package host_test
+
+import (
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestReporter(t *testing.T) {
+	var (
+		release   = "release"
+		version   = "version"
+		network   = "192.168.0.0/16"
+		hostID    = "hostid"
+		hostname  = "hostname"
+		timestamp = time.Now()
+		metrics   = report.Metrics{
+			host.Load1:       report.MakeSingletonMetric(timestamp, 1.0),
+			host.CPUUsage:    report.MakeSingletonMetric(timestamp, 30.0).WithMax(100.0),
+			host.MemoryUsage: report.MakeSingletonMetric(timestamp, 60.0).WithMax(100.0),
+		}
+		uptime      = "3600" // one hour
+		kernel      = "release version"
+		_, ipnet, _ = net.ParseCIDR(network)
+	)
+
+	mtime.NowForce(timestamp)
+	defer mtime.NowReset()
+
+	var (
+		oldGetKernelReleaseAndVersion = host.GetKernelReleaseAndVersion
+		oldGetLoad                    = host.GetLoad
+		oldGetUptime                  = host.GetUptime
+		oldGetCPUUsagePercent         = host.GetCPUUsagePercent
+		oldGetMemoryUsageBytes        = host.GetMemoryUsageBytes
+		oldGetLocalNetworks           = host.GetLocalNetworks
+		oldGetFilesystems             = host.GetFilesystems
+		oldGetDiskIO                  = host.GetDiskIO
+	)
+	defer func() {
+		host.GetKernelReleaseAndVersion = oldGetKernelReleaseAndVersion
+		host.GetLoad = oldGetLoad
+		host.GetUptime = oldGetUptime
+		host.GetCPUUsagePercent = oldGetCPUUsagePercent
+		host.GetMemoryUsageBytes = oldGetMemoryUsageBytes
+		host.GetLocalNetworks = oldGetLocalNetworks
+		host.GetFilesystems = oldGetFilesystems
+		host.GetDiskIO = oldGetDiskIO
+	}()
+	host.GetKernelReleaseAndVersion = func() (string, string, error) { return release, version, nil }
+	host.GetLoad = func(time.Time) report.Metrics { return metrics }
+	host.GetUptime = func() (time.Duration, error) { return time.Hour, nil }
+	host.GetCPUUsagePercent = func() (float64, float64) { return 30.0, 100.0 }
+	host.GetMemoryUsageBytes = func() (float64, float64) { return 60.0, 100.0 }
+	host.GetLocalNetworks = func() ([]*net.IPNet, error) { return []*net.IPNet{ipnet}, nil }
+	host.GetFilesystems = func() []host.Filesystem {
+		return []host.Filesystem{{MountPoint: "/", UsedBytes: 10, TotalBytes: 40, UsedInodes: 1, TotalInodes: 4}}
+	}
+	host.GetDiskIO = func(time.Time) []host.DiskIO {
+		return []host.DiskIO{{Device: "sda", ReadBytesPerSecond: 100, WriteBytesPerSecond: 200}}
+	}
+
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, err := host.NewReporter(hostID, hostname, "probe-id", "", nil, hr, true).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodeID := report.MakeHostNodeID(hostID)
+	node, ok := rpt.Host.Nodes[nodeID]
+	if !ok {
+		t.Errorf("Expected host node %q, but not found", nodeID)
+	}
+
+	// Should offer the shell control
+	if have, ok := node.LatestControls.Lookup(host.ExecHost); !ok || have.Dead {
+		t.Errorf("Expected the %s control, got %v", host.ExecHost, have)
+	}
+
+	// Should have a bunch of expected latest keys
+	for _, tuple := range []struct {
+		key, want string
+	}{
+		{host.Timestamp, timestamp.UTC().Format(time.RFC3339Nano)},
+		{host.HostName, hostname},
+		{host.OS, runtime.GOOS},
+		{host.Uptime, uptime},
+		{host.KernelVersion, kernel},
+		{report.ControlProbeID, "probe-id"},
+	} {
+		if have, ok := node.Latest.Lookup(tuple.key); !ok || have != tuple.want {
+			t.Errorf("Expected %s %q, got %q", tuple.key, tuple.want, have)
+		}
+	}
+
+	// Should have the local network
+	if have, ok := node.Sets.Lookup(host.LocalNetworks); !ok || !have.Contains(network) {
+		t.Errorf("Expected host.LocalNetworks to include %q, got %q", network, have)
+	}
+
+	// Should have metrics
+	for key, want := range metrics {
+		wantSample, _ := want.LastSample()
+		if metric, ok := node.Metrics[key]; !ok {
+			t.Errorf("Expected %s metric, but not found", key)
+		} else if sample, ok := metric.LastSample(); !ok {
+			t.Errorf("Expected %s metric to have a sample, but there were none", key)
+		} else if sample.Value != wantSample.Value {
+			t.Errorf("Expected %s metric sample %f, got %f", key, wantSample.Value, sample.Value)
+		}
+	}
+
+	// Should have disk metrics, with their templates
+	for key, want := range map[string]float64{
+		host.DiskUsagePrefix + "/":   10,
+		host.DiskInodesPrefix + "/":  1,
+		host.DiskReadPrefix + "sda":  100,
+		host.DiskWritePrefix + "sda": 200,
+	} {
+		if sample, ok := node.Metrics[key].LastSample(); !ok || sample.Value != want {
+			t.Errorf("Expected %s metric sample %f, got %v", key, want, sample)
+		}
+		if _, ok := rpt.Host.MetricTemplates[key]; !ok {
+			t.Errorf("Expected a template for %s", key)
+		}
+	}
+}
+
+func TestReporterWithoutShell(t *testing.T) {
+	hostID := "hostid"
+	rpt, err := host.NewReporter(hostID, "hostname", "probe-id", "", nil, controls.NewDefaultHandlerRegistry(), false).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := rpt.Host.Nodes[report.MakeHostNodeID(hostID)]
+	if _, ok := node.LatestControls.Lookup(host.ExecHost); ok {
+		t.Errorf("Expected no %s control", host.ExecHost)
+	}
+	if _, ok := rpt.Host.Controls[host.ExecHost]; ok {
+		t.Errorf("Expected no %s control", host.ExecHost)
+	}
+}
@@ -0,0 +1,209 @@
+package host
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	linuxproc "github.com/c9s/goprocinfo/linux"
+
+	"github.com/weaveworks/scope/report"
+
+	"golang.org/x/sys/unix"
+)
+
+const kb = 1024
+
+// Uname is swappable for mocking in tests.
+var Uname = unix.Uname
+
+// GetKernelReleaseAndVersion returns the kernel version as reported by uname.
+var GetKernelReleaseAndVersion = func() (string, string, error) {
+	var utsname unix.Utsname
+	if err := Uname(&utsname); err != nil {
+		return "unknown", "unknown", err
+	}
+	release := utsname.Release[:bytes.IndexByte(utsname.Release[:], 0)]
+	version := utsname.Version[:bytes.IndexByte(utsname.Version[:], 0)]
+	return string(release), string(version), nil
+}
+
+// GetLoad returns the current load averages as metrics.
+var GetLoad = func(now time.Time) report.Metrics {
+	buf, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil
+	}
+	toks := strings.Fields(string(buf))
+	if len(toks) < 3 {
+		return nil
+	}
+	one, err := strconv.ParseFloat(toks[0], 64)
+	if err != nil {
+		return nil
+	}
+	return report.Metrics{
+		Load1: report.MakeSingletonMetric(now, one),
+	}
+}
+
+// GetUptime returns the uptime of the host.
+var GetUptime = func() (time.Duration, error) {
+	buf, err := ioutil.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(buf))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid format: %s", string(buf))
+	}
+
+	uptime, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(uptime) * time.Second, nil
+}
+
+var previousStat = linuxproc.CPUStat{}
+
+// GetCPUUsagePercent returns the percent cpu usage and max (i.e. 100% or 0 if unavailable)
+var GetCPUUsagePercent = func() (float64, float64) {
+	stat, err := linuxproc.ReadStat(ProcStat)
+	if err != nil {
+		return 0.0, 0.0
+	}
+
+	// From http://stackoverflow.com/questions/23367857/accurate-calculation-of-cpu-usage-given-in-percentage-in-linux
+	var (
+		currentStat = stat.CPUStatAll
+		prevIdle    = previousStat.Idle + previousStat.IOWait
+		idle        = currentStat.Idle + currentStat.IOWait
+		prevNonIdle = (previousStat.User + previousStat.Nice + previousStat.System +
+			previousStat.IRQ + previousStat.SoftIRQ + previousStat.Steal)
+		nonIdle = (currentStat.User + currentStat.Nice + currentStat.System +
+			currentStat.IRQ + currentStat.SoftIRQ + currentStat.Steal)
+		prevTotal = prevIdle + prevNonIdle
+		total     = idle + nonIdle
+		// differentiate: actual value minus the previous one
+		totald = total - prevTotal
+		idled  = idle - prevIdle
+	)
+	previousStat = currentStat
+	return float64(totald-idled) * 100. / float64(totald), 100.
+}
+
+// GetMemoryUsageBytes returns the bytes memory usage and max
+var GetMemoryUsageBytes = func() (float64, float64) {
+	meminfo, err := linuxproc.ReadMemInfo(ProcMemInfo)
+	if err != nil {
+		return 0.0, 0.0
+	}
+
+	used := meminfo.MemTotal - meminfo.MemFree - meminfo.Buffers - meminfo.Cached
+	return float64(used * kb), float64(meminfo.MemTotal * kb)
+}
+
+// GetFilesystems returns the usage of the filesystems mounted on the host
+// from block devices, as seen by its init process.
+var GetFilesystems = func() []Filesystem {
+	mounts, err := linuxproc.ReadMounts(ProcMounts)
+	if err != nil {
+		return nil
+	}
+	var (
+		result  []Filesystem
+		devices = map[string]struct{}{}
+	)
+	for _, mount := range mounts.Mounts {
+		// Skip pseudo-filesystems, loop devices (e.g. snaps), and
+		// filesystems mounted more than once
+		if !strings.HasPrefix(mount.Device, "/dev/") || strings.HasPrefix(mount.Device, "/dev/loop") {
+			continue
+		}
+		if _, ok := devices[mount.Device]; ok {
+			continue
+		}
+		var fs unix.Statfs_t
+		if err := unix.Statfs(filepath.Join(HostRoot, mount.MountPoint), &fs); err != nil {
+			// Without access to init's root (e.g. not running as root), we
+			// can only hope to share its mount namespace
+			if err := unix.Statfs(mount.MountPoint, &fs); err != nil {
+				continue
+			}
+		}
+		devices[mount.Device] = struct{}{}
+		result = append(result, Filesystem{
+			MountPoint:  mount.MountPoint,
+			UsedBytes:   (fs.Blocks - fs.Bfree) * uint64(fs.Bsize),
+			TotalBytes:  fs.Blocks * uint64(fs.Bsize),
+			UsedInodes:  fs.Files - fs.Ffree,
+			TotalInodes: fs.Files,
+		})
+	}
+	return result
+}
+
+var (
+	previousDiskStats     = map[string]linuxproc.DiskStat{}
+	previousDiskStatsTime time.Time
+)
+
+// GetDiskIO returns the throughput of the host's disks since the previous
+// call; there's none on the first one.
+var GetDiskIO = func(now time.Time) []DiskIO {
+	stats, err := readDiskStats(ProcDiskStats)
+	if err != nil {
+		return nil
+	}
+	var (
+		result  []DiskIO
+		current = make(map[string]linuxproc.DiskStat, len(stats))
+		elapsed = now.Sub(previousDiskStatsTime).Seconds()
+	)
+	for _, stat := range stats {
+		// Partitions are accounted for in their disk
+		if _, err := ioutil.ReadFile(filepath.Join(SysBlock, stat.Name, "dev")); err != nil {
+			continue
+		}
+		current[stat.Name] = stat
+		prev, ok := previousDiskStats[stat.Name]
+		if !ok || elapsed <= 0 || stat.ReadSectors < prev.ReadSectors || stat.WriteSectors < prev.WriteSectors {
+			continue
+		}
+		result = append(result, DiskIO{
+			Device:              stat.Name,
+			ReadBytesPerSecond:  float64(stat.GetReadBytes()-prev.GetReadBytes()) / elapsed,
+			WriteBytesPerSecond: float64(stat.GetWriteBytes()-prev.GetWriteBytes()) / elapsed,
+		})
+	}
+	previousDiskStats, previousDiskStatsTime = current, now
+	return result
+}
+
+// readDiskStats is linuxproc.ReadDiskStats, minus the panics on lines it
+// doesn't expect.
+func readDiskStats(path string) ([]linuxproc.DiskStat, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []linuxproc.DiskStat
+	for _, line := range strings.Split(string(buf), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 14 || strings.HasPrefix(fields[2], "loop") || strings.HasPrefix(fields[2], "ram") {
+			continue
+		}
+		stat := linuxproc.DiskStat{Name: fields[2]}
+		stat.ReadSectors, _ = strconv.ParseUint(fields[5], 10, 64)
+		stat.WriteSectors, _ = strconv.ParseUint(fields[9], 10, 64)
+		result = append(result, stat)
+	}
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package host
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/weaveworks/scope/report"
+)
+
+var (
+	kernel32                 = windows.NewLazySystemDLL("kernel32.dll")
+	procGetTickCount64       = kernel32.NewProc("GetTickCount64")
+	procGetSystemTimes       = kernel32.NewProc("GetSystemTimes")
+	procGlobalMemoryStatusEx = kernel32.NewProc("GlobalMemoryStatusEx")
+	procGetDiskFreeSpaceExW  = kernel32.NewProc("GetDiskFreeSpaceExW")
+)
+
+// memoryStatusEx is the MEMORYSTATUSEX of GlobalMemoryStatusEx
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// GetKernelReleaseAndVersion returns the version of Windows, and its build.
+var GetKernelReleaseAndVersion = func() (string, string, error) {
+	version, err := windows.GetVersion()
+	if err != nil {
+		return "unknown", "unknown", err
+	}
+	major, minor, build := byte(version), byte(version>>8), uint16(version>>16)
+	return fmt.Sprintf("%d.%d", major, minor), fmt.Sprintf("Windows %d.%d build %d", major, minor, build), nil
+}
+
+// GetLoad returns the current load averages as metrics. Windows doesn't
+// have load averages.
+var GetLoad = func(now time.Time) report.Metrics {
+	return nil
+}
+
+// GetUptime returns the uptime of the host.
+var GetUptime = func() (time.Duration, error) {
+	ms, _, _ := procGetTickCount64.Call()
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+var previousIdle, previousTotal uint64
+
+// GetCPUUsagePercent returns the percent cpu usage and max (i.e. 100% or 0 if unavailable)
+var GetCPUUsagePercent = func() (float64, float64) {
+	var idle, kernel, user windows.Filetime
+	if ok, _, _ := procGetSystemTimes.Call(
+		uintptr(unsafe.Pointer(&idle)),
+		uintptr(unsafe.Pointer(&kernel)),
+		uintptr(unsafe.Pointer(&user)),
+	); ok == 0 {
+		return 0.0, 0.0
+	}
+	// The kernel time includes the idle time
+	var (
+		currentIdle  = filetimeTicks(idle)
+		currentTotal = filetimeTicks(kernel) + filetimeTicks(user)
+		totald       = currentTotal - previousTotal
+		idled        = currentIdle - previousIdle
+	)
+	previousIdle, previousTotal = currentIdle, currentTotal
+	if totald == 0 {
+		return 0.0, 100.
+	}
+	return float64(totald-idled) * 100. / float64(totald), 100.
+}
+
+func filetimeTicks(ft windows.Filetime) uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
+
+// GetMemoryUsageBytes returns the bytes memory usage and max
+var GetMemoryUsageBytes = func() (float64, float64) {
+	status := memoryStatusEx{}
+	status.Length = uint32(unsafe.Sizeof(status))
+	if ok, _, _ := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return 0.0, 0.0
+	}
+	return float64(status.TotalPhys - status.AvailPhys), float64(status.TotalPhys)
+}
+
+// GetFilesystems returns the usage of the fixed drives of the host.
+var GetFilesystems = func() []Filesystem {
+	buf := make([]uint16, 254)
+	n, err := windows.GetLogicalDriveStrings(uint32(len(buf)), &buf[0])
+	if err != nil || int(n) > len(buf) {
+		return nil
+	}
+	var result []Filesystem
+	// The drives are NUL-terminated strings, e.g. C:\
+	for start, i := 0, 0; i < int(n); i++ {
+		if buf[i] != 0 {
+			continue
+		}
+		root, drive := &buf[start], windows.UTF16ToString(buf[start:i])
+		start = i + 1
+		if drive == "" || windows.GetDriveType(root) != windows.DRIVE_FIXED {
+			continue
+		}
+		var free, total, totalFree uint64
+		if ok, _, _ := procGetDiskFreeSpaceExW.Call(
+			uintptr(unsafe.Pointer(root)),
+			uintptr(unsafe.Pointer(&free)),
+			uintptr(unsafe.Pointer(&total)),
+			uintptr(unsafe.Pointer(&totalFree)),
+		); ok == 0 {
+			continue
+		}
+		result = append(result, Filesystem{
+			MountPoint: drive,
+			UsedBytes:  total - totalFree,
+			TotalBytes: total,
+		})
+	}
+	return result
+}
+
+// GetDiskIO returns the throughput of the host's disks
+var GetDiskIO = func(time.Time) []DiskIO {
+	return nil
+}
@@ -0,0 +1,270 @@
+package kubernetes
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/report"
+)
+
+// Control IDs used by the kubernetes integration.
+const (
+	CloneVolumeSnapshot  = report.KubernetesCloneVolumeSnapshot
+	CreateVolumeSnapshot = report.KubernetesCreateVolumeSnapshot
+	GetLogs              = report.KubernetesGetLogs
+	DeletePod            = report.KubernetesDeletePod
+	DeleteVolumeSnapshot = report.KubernetesDeleteVolumeSnapshot
+	ScaleUp              = report.KubernetesScaleUp
+	ScaleDown            = report.KubernetesScaleDown
+	SetReplicas          = report.KubernetesSetReplicas
+)
+
+// GetLogs is the control to get the logs for a kubernetes pod. The logs of
+// all its containers are interleaved, unless the request picks one.
+func (r *Reporter) GetLogs(req xfer.Request, namespaceID, podID string, containerNames []string) xfer.Response {
+	opts, err := ParseLogOptions(req.ControlArgs)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	if opts.Container != "" {
+		found := false
+		for _, name := range containerNames {
+			found = found || name == opts.Container
+		}
+		if !found {
+			return xfer.ResponseErrorf("Container not found in pod %s: %s", podID, opts.Container)
+		}
+		containerNames = []string{opts.Container}
+	}
+	readCloser, err := r.client.GetLogs(namespaceID, podID, containerNames, opts)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	readCloser = newRateLimitedReadCloser(readCloser, logsRateLimit)
+
+	readWriter := struct {
+		io.Reader
+		io.Writer
+	}{
+		readCloser,
+		ioutil.Discard,
+	}
+	id, pipe, err := controls.NewPipeFromEnds(nil, readWriter, r.pipes, req.AppID)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	pipe.OnClose(func() {
+		readCloser.Close()
+	})
+	return xfer.Response{
+		Pipe: id,
+	}
+}
+
+func (r *Reporter) cloneVolumeSnapshot(req xfer.Request, namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity string) xfer.Response {
+	err := r.client.CloneVolumeSnapshot(namespaceID, volumeSnapshotID, persistentVolumeClaimID, capacity)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{}
+}
+
+func (r *Reporter) createVolumeSnapshot(req xfer.Request, namespaceID, persistentVolumeClaimID, capacity string) xfer.Response {
+	err := r.client.CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity)
+	if err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{}
+}
+
+func (r *Reporter) deletePod(req xfer.Request, namespaceID, podID string, _ []string) xfer.Response {
+	if err := r.client.DeletePod(namespaceID, podID); err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{
+		RemovedNode: req.NodeID,
+	}
+}
+
+func (r *Reporter) deleteVolumeSnapshot(req xfer.Request, namespaceID, volumeSnapshotID, _, _ string) xfer.Response {
+	if err := r.client.DeleteVolumeSnapshot(namespaceID, volumeSnapshotID); err != nil {
+		return xfer.ResponseError(err)
+	}
+	return xfer.Response{
+		RemovedNode: req.NodeID,
+	}
+}
+
+// CapturePod is exported for testing
+func (r *Reporter) CapturePod(f func(xfer.Request, string, string, []string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParsePodNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		// find pod by UID
+		var pod Pod
+		r.client.WalkPods(func(p Pod) error {
+			if p.UID() == uid {
+				pod = p
+			}
+			return nil
+		})
+		if pod == nil {
+			return xfer.ResponseErrorf("Pod not found: %s", uid)
+		}
+		return f(req, pod.Namespace(), pod.Name(), pod.ContainerNames())
+	}
+}
+
+// CaptureDeployment is exported for testing
+func (r *Reporter) CaptureDeployment(f func(xfer.Request, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParseDeploymentNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		var deployment Deployment
+		r.client.WalkDeployments(func(d Deployment) error {
+			if d.UID() == uid {
+				deployment = d
+			}
+			return nil
+		})
+		if deployment == nil {
+			return xfer.ResponseErrorf("Deployment not found: %s", uid)
+		}
+		return f(req, deployment.Namespace(), deployment.Name())
+	}
+}
+
+// CaptureReplicaSet is exported for testing
+func (r *Reporter) CaptureReplicaSet(f func(xfer.Request, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParseReplicaSetNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		var replicaSet ReplicaSet
+		r.client.WalkReplicaSets(func(rs ReplicaSet) error {
+			if rs.UID() == uid {
+				replicaSet = rs
+			}
+			return nil
+		})
+		if replicaSet == nil {
+			return xfer.ResponseErrorf("Replica set not found: %s", uid)
+		}
+		return f(req, replicaSet.Namespace(), replicaSet.Name())
+	}
+}
+
+// CaptureScalable captures deployments and replica sets, passing on the
+// kind of resource to scale.
+func (r *Reporter) CaptureScalable(f func(xfer.Request, string, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		if _, ok := report.ParseDeploymentNodeID(req.NodeID); ok {
+			return r.CaptureDeployment(func(req xfer.Request, namespace, id string) xfer.Response {
+				return f(req, "Deployment", namespace, id)
+			})(req)
+		}
+		return r.CaptureReplicaSet(func(req xfer.Request, namespace, id string) xfer.Response {
+			return f(req, "ReplicaSet", namespace, id)
+		})(req)
+	}
+}
+
+// CapturePersistentVolumeClaim will return name, namespace and capacity of PVC
+func (r *Reporter) CapturePersistentVolumeClaim(f func(xfer.Request, string, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParsePersistentVolumeClaimNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		// find persistentVolumeClaim by UID
+		var persistentVolumeClaim PersistentVolumeClaim
+		r.client.WalkPersistentVolumeClaims(func(p PersistentVolumeClaim) error {
+			if p.UID() == uid {
+				persistentVolumeClaim = p
+			}
+			return nil
+		})
+		if persistentVolumeClaim == nil {
+			return xfer.ResponseErrorf("Persistent volume claim not found: %s", uid)
+		}
+		return f(req, persistentVolumeClaim.Namespace(), persistentVolumeClaim.Name(), persistentVolumeClaim.GetCapacity())
+	}
+}
+
+// CaptureVolumeSnapshot will return name, pvc name, namespace and capacity of volume snapshot
+func (r *Reporter) CaptureVolumeSnapshot(f func(xfer.Request, string, string, string, string) xfer.Response) func(xfer.Request) xfer.Response {
+	return func(req xfer.Request) xfer.Response {
+		uid, ok := report.ParseVolumeSnapshotNodeID(req.NodeID)
+		if !ok {
+			return xfer.ResponseErrorf("Invalid ID: %s", req.NodeID)
+		}
+		// find volume snapshot by UID
+		var volumeSnapshot VolumeSnapshot
+		r.client.WalkVolumeSnapshots(func(p VolumeSnapshot) error {
+			if p.UID() == uid {
+				volumeSnapshot = p
+			}
+			return nil
+		})
+		if volumeSnapshot == nil {
+			return xfer.ResponseErrorf("Volume snapshot not found: %s", uid)
+		}
+		return f(req, volumeSnapshot.Namespace(), volumeSnapshot.Name(), volumeSnapshot.GetVolumeName(), volumeSnapshot.GetCapacity())
+	}
+}
+
+// ScaleUp is the control to scale up a deployment or replica set
+func (r *Reporter) ScaleUp(req xfer.Request, resource, namespace, id string) xfer.Response {
+	return xfer.ResponseError(r.client.ScaleUp(resource, namespace, id))
+}
+
+// ScaleDown is the control to scale down a deployment or replica set
+func (r *Reporter) ScaleDown(req xfer.Request, resource, namespace, id string) xfer.Response {
+	return xfer.ResponseError(r.client.ScaleDown(resource, namespace, id))
+}
+
+// SetReplicas is the control to set the number of replicas of a deployment
+// or replica set, which comes in the prompt argument.
+func (r *Reporter) SetReplicas(req xfer.Request, resource, namespace, id string) xfer.Response {
+	replicas, err := strconv.Atoi(req.ControlArgs[report.PromptArg])
+	if err != nil || replicas < 0 {
+		return xfer.ResponseErrorf("Invalid number of replicas: %q", req.ControlArgs[report.PromptArg])
+	}
+	return xfer.ResponseError(r.client.SetReplicas(resource, namespace, id, replicas))
+}
+
+func (r *Reporter) registerControls() {
+	controls := map[string]xfer.ControlHandlerFunc{
+		CloneVolumeSnapshot:  r.CaptureVolumeSnapshot(r.cloneVolumeSnapshot),
+		CreateVolumeSnapshot: r.CapturePersistentVolumeClaim(r.createVolumeSnapshot),
+		GetLogs:              r.CapturePod(r.GetLogs),
+		DeletePod:            r.CapturePod(r.deletePod),
+		DeleteVolumeSnapshot: r.CaptureVolumeSnapshot(r.deleteVolumeSnapshot),
+		ScaleUp:              r.CaptureScalable(r.ScaleUp),
+		ScaleDown:            r.CaptureScalable(r.ScaleDown),
+		SetReplicas:          r.CaptureScalable(r.SetReplicas),
+	}
+	r.handlerRegistry.Batch(nil, controls)
+}
+
+func (r *Reporter) deregisterControls() {
+	controls := []string{
+		CloneVolumeSnapshot,
+		CreateVolumeSnapshot,
+		GetLogs,
+		DeletePod,
+		DeleteVolumeSnapshot,
+		ScaleUp,
+		ScaleDown,
+		SetReplicas,
+	}
+	r.handlerRegistry.Batch(controls, nil)
+}
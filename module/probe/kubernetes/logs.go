@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"context"
+	"golang.org/x/time/rate"
+)
+
+// Control args understood by the GetLogs control.
+const (
+	LogsTail      = "tail"
+	LogsFollow    = "follow"
+	LogsContainer = "container"
+)
+
+// logsRateLimit is the most bytes per second streamed from a pod's logs,
+// so a chatty pod can't swamp the pipe to the app.
+const logsRateLimit = 256 * 1024
+
+// LogOptions are the options for streaming the logs of a pod.
+type LogOptions struct {
+	Follow    bool
+	TailLines *int64 // nil for all the lines
+	Container string // empty for all the containers, interleaved
+}
+
+// ParseLogOptions parses the args of a GetLogs control request. Without
+// any args, the logs of all the containers are followed from the start.
+func ParseLogOptions(args map[string]string) (LogOptions, error) {
+	opts := LogOptions{Follow: true, Container: args[LogsContainer]}
+	if s, ok := args[LogsTail]; ok && s != "" {
+		lines, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || lines < 0 {
+			return opts, fmt.Errorf("invalid %s: %q", LogsTail, s)
+		}
+		opts.TailLines = &lines
+	}
+	if s, ok := args[LogsFollow]; ok && s != "" {
+		follow, err := strconv.ParseBool(s)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %q", LogsFollow, s)
+		}
+		opts.Follow = follow
+	}
+	return opts, nil
+}
+
+type rateLimitedReadCloser struct {
+	io.ReadCloser
+	limiter *rate.Limiter
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newRateLimitedReadCloser(rc io.ReadCloser, bytesPerSecond int) io.ReadCloser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &rateLimitedReadCloser{
+		ReadCloser: rc,
+		limiter:    rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+func (r *rateLimitedReadCloser) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *rateLimitedReadCloser) Close() error {
+	r.cancel()
+	return r.ReadCloser.Close()
+}
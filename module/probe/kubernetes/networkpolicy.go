@@ -0,0 +1,211 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// These constants are keys used in node metadata
+const (
+	NetworkPolicySpec  = report.KubernetesNetworkPolicySpec
+	NetworkPolicyNames = report.KubernetesNetworkPolicies
+	DeniedAdjacency    = report.KubernetesDeniedAdjacency
+)
+
+// NetworkPolicy represents a Kubernetes network policy
+type NetworkPolicy interface {
+	Meta
+	GetNode() report.Node
+}
+
+type networkPolicy struct {
+	*networkingv1.NetworkPolicy
+	Meta
+}
+
+// NewNetworkPolicy creates a new NetworkPolicy
+func NewNetworkPolicy(p *networkingv1.NetworkPolicy) NetworkPolicy {
+	return &networkPolicy{NetworkPolicy: p, Meta: meta{p.ObjectMeta}}
+}
+
+// GetNode returns the network policy as a Node, with its spec, so the
+// app can work out which traffic between pods the policy allows.
+func (p *networkPolicy) GetNode() report.Node {
+	latests := map[string]string{
+		NodeType: "Network Policy",
+	}
+	if spec, err := json.Marshal(p.Spec); err != nil {
+		log.Warnf("Cannot encode the spec of network policy %s/%s: %v", p.Namespace(), p.Name(), err)
+	} else {
+		latests[NetworkPolicySpec] = string(spec)
+	}
+	return p.MetaNode(report.MakeNetworkPolicyNodeID(p.UID())).WithLatests(latests)
+}
+
+// PolicyPeer is a pod, with what network policies select it by.
+type PolicyPeer struct {
+	Namespace       string
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+	IP              net.IP
+}
+
+type namedPolicy struct {
+	name      string
+	namespace string
+	spec      networkingv1.NetworkPolicySpec
+}
+
+// NetworkPolicies are the network policies of a cluster.
+type NetworkPolicies []namedPolicy
+
+// MakeNetworkPolicies returns the network policies of a network policy
+// topology, leaving out the ones whose spec cannot be decoded.
+func MakeNetworkPolicies(t report.Topology) NetworkPolicies {
+	result := NetworkPolicies{}
+	for _, n := range t.Nodes {
+		spec, ok := n.Latest.Lookup(NetworkPolicySpec)
+		if !ok {
+			continue
+		}
+		p := namedPolicy{}
+		if err := json.Unmarshal([]byte(spec), &p.spec); err != nil {
+			log.Warnf("Cannot decode the spec of network policy %s: %v", n.ID, err)
+			continue
+		}
+		p.name, _ = n.Latest.Lookup(Name)
+		p.namespace, _ = n.Latest.Lookup(Namespace)
+		result = append(result, p)
+	}
+	return result
+}
+
+// Selecting returns the names of the policies which select the pod.
+func (ps NetworkPolicies) Selecting(pod PolicyPeer) []string {
+	result := []string{}
+	for _, p := range ps {
+		if p.selects(pod) {
+			result = append(result, p.name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Allows tells whether the policies let from open connections to to: both
+// the egress of from and the ingress of to have to allow it. Ports are
+// not taken into account, so traffic to some ports only counts as allowed.
+func (ps NetworkPolicies) Allows(from, to PolicyPeer) bool {
+	return ps.allows(from, to, networkingv1.PolicyTypeEgress) &&
+		ps.allows(to, from, networkingv1.PolicyTypeIngress)
+}
+
+// allows tells whether the traffic of pod with peer, in the direction of
+// policyType, is allowed. Pods no policy isolates allow all traffic.
+func (ps NetworkPolicies) allows(pod, peer PolicyPeer, policyType networkingv1.PolicyType) bool {
+	isolated := false
+	for _, p := range ps {
+		if !p.hasType(policyType) || !p.selects(pod) {
+			continue
+		}
+		isolated = true
+		if p.admits(peer, policyType) {
+			return true
+		}
+	}
+	return !isolated
+}
+
+func (p namedPolicy) selects(pod PolicyPeer) bool {
+	return p.namespace == pod.Namespace && selectorMatches(&p.spec.PodSelector, pod.Labels)
+}
+
+// hasType tells whether the policy is about the traffic in the direction
+// of policyType. Policies without types are about ingress, and egress too
+// if they have egress rules.
+func (p namedPolicy) hasType(policyType networkingv1.PolicyType) bool {
+	if len(p.spec.PolicyTypes) == 0 {
+		return policyType == networkingv1.PolicyTypeIngress || len(p.spec.Egress) > 0
+	}
+	for _, t := range p.spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+// admits tells whether any rule of the policy, in the direction of
+// policyType, lets the traffic of peer through.
+func (p namedPolicy) admits(peer PolicyPeer, policyType networkingv1.PolicyType) bool {
+	rules := [][]networkingv1.NetworkPolicyPeer{}
+	if policyType == networkingv1.PolicyTypeIngress {
+		for _, rule := range p.spec.Ingress {
+			rules = append(rules, rule.From)
+		}
+	} else {
+		for _, rule := range p.spec.Egress {
+			rules = append(rules, rule.To)
+		}
+	}
+	for _, peers := range rules {
+		// A rule without peers matches all of them
+		if len(peers) == 0 {
+			return true
+		}
+		for _, policyPeer := range peers {
+			if p.peerMatches(policyPeer, peer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p namedPolicy) peerMatches(policyPeer networkingv1.NetworkPolicyPeer, peer PolicyPeer) bool {
+	if policyPeer.IPBlock != nil {
+		return ipBlockContains(policyPeer.IPBlock, peer.IP)
+	}
+	if policyPeer.PodSelector == nil && policyPeer.NamespaceSelector == nil {
+		return false
+	}
+	if policyPeer.NamespaceSelector != nil {
+		if !selectorMatches(policyPeer.NamespaceSelector, peer.NamespaceLabels) {
+			return false
+		}
+	} else if peer.Namespace != p.namespace {
+		return false
+	}
+	return policyPeer.PodSelector == nil || selectorMatches(policyPeer.PodSelector, peer.Labels)
+}
+
+func selectorMatches(selector *metav1.LabelSelector, set map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(set))
+}
+
+func ipBlockContains(block *networkingv1.IPBlock, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if _, cidr, err := net.ParseCIDR(block.CIDR); err != nil || !cidr.Contains(ip) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, cidr, err := net.ParseCIDR(except); err == nil && cidr.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
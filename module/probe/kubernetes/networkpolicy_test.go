@@ -0,0 +1,101 @@
+package kubernetes_test
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+func makeNetworkPolicies(policies ...networkingv1.NetworkPolicy) kubernetes.NetworkPolicies {
+	topology := report.MakeTopology()
+	for i := range policies {
+		topology.AddNode(kubernetes.NewNetworkPolicy(&policies[i]).GetNode())
+	}
+	return kubernetes.MakeNetworkPolicies(topology)
+}
+
+func TestNetworkPolicies(t *testing.T) {
+	var (
+		frontend = kubernetes.PolicyPeer{
+			Namespace:       "shop",
+			Labels:          map[string]string{"app": "frontend"},
+			NamespaceLabels: map[string]string{"team": "shop"},
+			IP:              net.ParseIP("10.32.0.1"),
+		}
+		db = kubernetes.PolicyPeer{
+			Namespace:       "shop",
+			Labels:          map[string]string{"app": "db"},
+			NamespaceLabels: map[string]string{"team": "shop"},
+			IP:              net.ParseIP("10.32.0.2"),
+		}
+		monitoring = kubernetes.PolicyPeer{
+			Namespace:       "monitoring",
+			Labels:          map[string]string{"app": "prometheus"},
+			NamespaceLabels: map[string]string{"team": "ops"},
+			IP:              net.ParseIP("10.40.0.1"),
+		}
+		dbIngress = networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-ingress", Namespace: "shop", UID: types.UID("np1")},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				Ingress: []networkingv1.NetworkPolicyIngressRule{{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}},
+						{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ops"}}},
+					},
+				}},
+			},
+		}
+		denyEgress = networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "deny-egress", Namespace: "monitoring", UID: types.UID("np2")},
+			Spec: networkingv1.NetworkPolicySpec{
+				PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+			},
+		}
+		allowShopCIDR = networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "allow-shop", Namespace: "monitoring", UID: types.UID("np3")},
+			Spec: networkingv1.NetworkPolicySpec{
+				Egress: []networkingv1.NetworkPolicyEgressRule{{
+					To: []networkingv1.NetworkPolicyPeer{{
+						IPBlock: &networkingv1.IPBlock{CIDR: "10.32.0.0/16", Except: []string{"10.32.0.2/32"}},
+					}},
+				}},
+			},
+		}
+	)
+
+	for _, c := range []struct {
+		name     string
+		policies kubernetes.NetworkPolicies
+		from, to kubernetes.PolicyPeer
+		want     bool
+	}{
+		{"no policies", makeNetworkPolicies(), db, frontend, true},
+		{"pod selector", makeNetworkPolicies(dbIngress), frontend, db, true},
+		{"not selected", makeNetworkPolicies(dbIngress), db, frontend, true},
+		{"isolated", makeNetworkPolicies(dbIngress), db, db, false},
+		{"namespace selector", makeNetworkPolicies(dbIngress), monitoring, db, true},
+		{"egress denied", makeNetworkPolicies(denyEgress), monitoring, frontend, false},
+		{"ip block", makeNetworkPolicies(denyEgress, allowShopCIDR), monitoring, frontend, true},
+		{"ip block except", makeNetworkPolicies(denyEgress, allowShopCIDR), monitoring, db, false},
+	} {
+		if have := c.policies.Allows(c.from, c.to); have != c.want {
+			t.Errorf("%s: expected allowed to be %v, got %v", c.name, c.want, have)
+		}
+	}
+
+	policies := makeNetworkPolicies(dbIngress, denyEgress, allowShopCIDR)
+	if have, want := policies.Selecting(monitoring), []string{"allow-shop", "deny-egress"}; !reflect.DeepEqual(have, want) {
+		t.Errorf("Expected monitoring to be selected by %v, got %v", want, have)
+	}
+	if have := policies.Selecting(frontend); len(have) != 0 {
+		t.Errorf("Expected frontend not to be selected, got %v", have)
+	}
+}
@@ -0,0 +1,243 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// PermissionPrefix is the prefix of the keys of the permissions of the
+// probe, by resource, in its host node.
+const PermissionPrefix = "kubernetes_permission_"
+
+// Permissions of resources.
+const (
+	PermissionAllowed   = "allowed"
+	PermissionForbidden = "forbidden"
+)
+
+const (
+	// How long to wait before checking again whether the probe may list
+	// and watch a resource, when it may not.
+	permissionRecheckInterval = 5 * time.Minute
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// clusterResources are the resources which are not in namespaces.
+var clusterResources = map[string]struct{}{
+	"nodes":               {},
+	"namespaces":          {},
+	"persistentvolumes":   {},
+	"storageclasses":      {},
+	"volumesnapshotdatas": {},
+}
+
+// permissions keep track of what the probe may list and watch, by resource
+// and namespace ("" for all of them).
+type permissions struct {
+	mtx     sync.Mutex
+	allowed map[string]map[string]bool
+}
+
+func newPermissions() *permissions {
+	return &permissions{allowed: map[string]map[string]bool{}}
+}
+
+// set records whether the probe may list and watch resource in namespace,
+// telling if that changed. It starts out allowed.
+func (p *permissions) set(resource, namespace string, allowed bool) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	namespaces, ok := p.allowed[resource]
+	if !ok {
+		namespaces = map[string]bool{}
+		p.allowed[resource] = namespaces
+	}
+	was, ok := namespaces[namespace]
+	if !ok {
+		was = true
+	}
+	namespaces[namespace] = allowed
+	return allowed != was
+}
+
+// describe tells, by resource, whether the probe may list and watch it, or
+// in which namespaces.
+func (p *permissions) describe() map[string]string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	result := map[string]string{}
+	for resource, namespaces := range p.allowed {
+		if allowed, ok := namespaces[""]; ok {
+			if allowed {
+				result[resource] = PermissionAllowed
+			} else {
+				result[resource] = PermissionForbidden
+			}
+			continue
+		}
+		allowed := []string{}
+		for namespace, ok := range namespaces {
+			if ok {
+				allowed = append(allowed, namespace)
+			}
+		}
+		if len(allowed) == 0 {
+			result[resource] = PermissionForbidden
+			continue
+		}
+		sort.Strings(allowed)
+		result[resource] = "in " + strings.Join(allowed, ", ")
+	}
+	return result
+}
+
+// Permissions tells, by resource, whether the probe may list and watch it,
+// or in which namespaces.
+func (c *client) Permissions() map[string]string {
+	return c.permissions.describe()
+}
+
+// namespacesOf are the namespaces to list and watch resource in, "" for
+// all of them.
+func (c *client) namespacesOf(resource string) []string {
+	if _, ok := clusterResources[resource]; ok || len(c.namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return c.namespaces
+}
+
+// canListAndWatch asks the API server whether the probe may list and
+// watch resource in namespace. It assumes it may, if it can't tell.
+func (c *client) canListAndWatch(group, resource, namespace string) bool {
+	for _, verb := range []string{"list", "watch"} {
+		review, err := c.client.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     group,
+					Resource:  resource,
+				},
+			},
+		})
+		if err != nil {
+			log.Debugf("kubernetes: can't review access to %s: %v", resource, err)
+			return true
+		}
+		if !review.Status.Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// setPermission records whether the probe may list and watch resource in
+// namespace, saying so when that changes.
+func (c *client) setPermission(resource, namespace string, allowed bool) {
+	if !c.permissions.set(resource, namespace, allowed) {
+		return
+	}
+	where := "in namespace " + namespace
+	if namespace == metav1.NamespaceAll {
+		where = "cluster-wide"
+	}
+	if allowed {
+		log.Infof("kubernetes: now allowed to list and watch %s %s", resource, where)
+	} else {
+		log.Infof("kubernetes: not allowed to list and watch %s %s, leaving them out; checking again every %v", resource, where, permissionRecheckInterval)
+	}
+}
+
+// discoverNamespaces finds the namespaces the probe may list and watch pods
+// in: all of them if it may do so cluster-wide, or else those it may list,
+// or else the namespace of its service account.
+func (c *client) discoverNamespaces() ([]string, error) {
+	if c.canListAndWatch("", "pods", metav1.NamespaceAll) {
+		return nil, nil
+	}
+	var candidates []string
+	list, err := c.client.CoreV1().Namespaces().List(metav1.ListOptions{})
+	switch {
+	case err == nil:
+		for _, namespace := range list.Items {
+			candidates = append(candidates, namespace.Name)
+		}
+	case apierrors.IsForbidden(err):
+		own, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("not allowed to list namespaces, and no service account namespace: %v", err)
+		}
+		candidates = []string{strings.TrimSpace(string(own))}
+	default:
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, namespace := range candidates {
+		if c.canListAndWatch("", "pods", namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("not allowed to list and watch pods in any namespace")
+	}
+	return namespaces, nil
+}
+
+// namespaceStore is the part of a store shared by the reflectors of
+// several namespaces which one of them replaces, lest each of them
+// replaces the objects of the others.
+type namespaceStore struct {
+	cache.Store
+	namespace string
+}
+
+func (s namespaceStore) Replace(objects []interface{}, _ string) error {
+	keep := map[string]struct{}{}
+	for _, o := range objects {
+		key, err := cache.MetaNamespaceKeyFunc(o)
+		if err != nil {
+			return err
+		}
+		keep[key] = struct{}{}
+		_, exists, err := s.Store.GetByKey(key)
+		if err != nil {
+			return err
+		}
+		if exists {
+			err = s.Store.Update(o)
+		} else {
+			err = s.Store.Add(o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, o := range s.Store.List() {
+		object, err := apimeta.Accessor(o)
+		if err != nil || object.GetNamespace() != s.namespace {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(o)
+		if err != nil {
+			return err
+		}
+		if _, ok := keep[key]; !ok {
+			if err := s.Store.Delete(o); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func makePod(namespace, name string) *apiv1.Pod {
+	return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+}
+
+func TestNamespaceStore(t *testing.T) {
+	store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	a, b := namespaceStore{store, "a"}, namespaceStore{store, "b"}
+	a.Replace([]interface{}{makePod("a", "1"), makePod("a", "2")}, "")
+	b.Replace([]interface{}{makePod("b", "1")}, "")
+
+	// Relisting a namespace leaves the others alone
+	a.Replace([]interface{}{makePod("a", "2"), makePod("a", "3")}, "")
+	keys := store.ListKeys()
+	sort.Strings(keys)
+	if want := []string{"a/2", "a/3", "b/1"}; !reflect.DeepEqual(want, keys) {
+		t.Errorf("want %v, have %v", want, keys)
+	}
+
+	// and so does forgetting one, when the probe is forbidden to watch it
+	b.Replace(nil, "")
+	keys = store.ListKeys()
+	sort.Strings(keys)
+	if want := []string{"a/2", "a/3"}; !reflect.DeepEqual(want, keys) {
+		t.Errorf("want %v, have %v", want, keys)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	p := newPermissions()
+	if p.set("pods", "a", true) {
+		t.Error("Expected being allowed not to be a change")
+	}
+	if !p.set("pods", "b", false) {
+		t.Error("Expected being forbidden to be a change")
+	}
+	if p.set("pods", "b", false) {
+		t.Error("Expected being forbidden again not to be a change")
+	}
+	p.set("nodes", metav1.NamespaceAll, false)
+	p.set("services", metav1.NamespaceAll, true)
+	p.set("deployments", "a", false)
+
+	want := map[string]string{
+		"pods":        "in a",
+		"nodes":       PermissionForbidden,
+		"services":    PermissionAllowed,
+		"deployments": PermissionForbidden,
+	}
+	if have := p.describe(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
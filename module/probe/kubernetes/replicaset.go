@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	apiv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// These constants are keys used in node metadata
+const (
+	FullyLabeledReplicas = report.KubernetesFullyLabeledReplicas
+)
+
+// ReplicaSet represents a Kubernetes replica set
+type ReplicaSet interface {
+	Meta
+	Selector() (labels.Selector, error)
+	IsManaged() bool
+	GetNode(probeID string) report.Node
+}
+
+type replicaSet struct {
+	*apiv1beta1.ReplicaSet
+	Meta
+}
+
+// NewReplicaSet creates a new replica set
+func NewReplicaSet(r *apiv1beta1.ReplicaSet) ReplicaSet {
+	return &replicaSet{
+		ReplicaSet: r,
+		Meta:       meta{r.ObjectMeta},
+	}
+}
+
+func (r *replicaSet) Selector() (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(r.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	return selector, nil
+}
+
+// IsManaged tells whether the replica set belongs to a deployment, which
+// scales it and owns its pods as far as we are concerned.
+func (r *replicaSet) IsManaged() bool {
+	for _, owner := range r.OwnerReferences {
+		if owner.Kind == "Deployment" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *replicaSet) GetNode(probeID string) report.Node {
+	// Spec.Replicas can be omitted, and the pointer will be nil. It defaults to 1.
+	desiredReplicas := 1
+	if r.Spec.Replicas != nil {
+		desiredReplicas = int(*r.Spec.Replicas)
+	}
+	return r.MetaNode(report.MakeReplicaSetNodeID(r.UID())).WithLatests(map[string]string{
+		ObservedGeneration:    fmt.Sprint(r.Status.ObservedGeneration),
+		DesiredReplicas:       fmt.Sprint(desiredReplicas),
+		Replicas:              fmt.Sprint(r.Status.Replicas),
+		FullyLabeledReplicas:  fmt.Sprint(r.Status.FullyLabeledReplicas),
+		AvailableReplicas:     fmt.Sprint(r.Status.AvailableReplicas),
+		report.ControlProbeID: probeID,
+		NodeType:              "ReplicaSet",
+	}).WithLatestActiveControls(ScaleUp, ScaleDown, SetReplicas)
+}
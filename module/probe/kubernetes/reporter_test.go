@@ -0,0 +1,700 @@
+package kubernetes_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apiv1 "k8s.io/api/core/v1"
+	apiv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+var (
+	nodeName    = "nodename"
+	pod1UID     = "a1b2c3d4e5"
+	pod2UID     = "f6g7h8i9j0"
+	serviceUID  = "service1234"
+	podTypeMeta = metav1.TypeMeta{
+		Kind:       "Pod",
+		APIVersion: "v1",
+	}
+	apiPod1 = apiv1.Pod{
+		TypeMeta: podTypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pong-a",
+			UID:               types.UID(pod1UID),
+			Namespace:         "ping",
+			CreationTimestamp: metav1.Now(),
+			Labels:            map[string]string{"ponger": "true"},
+		},
+		Status: apiv1.PodStatus{
+			HostIP: "1.2.3.4",
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{ContainerID: "container1"},
+				{ContainerID: "container2"},
+			},
+		},
+		Spec: apiv1.PodSpec{
+			NodeName:    nodeName,
+			HostNetwork: true,
+			Containers:  []apiv1.Container{{Name: "ponger"}, {Name: "sidecar"}},
+		},
+	}
+	apiPod2 = apiv1.Pod{
+		TypeMeta: podTypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pong-b",
+			UID:               types.UID(pod2UID),
+			Namespace:         "ping",
+			CreationTimestamp: metav1.Now(),
+			Labels:            map[string]string{"ponger": "true"},
+		},
+		Status: apiv1.PodStatus{
+			HostIP: "1.2.3.4",
+			ContainerStatuses: []apiv1.ContainerStatus{
+				{ContainerID: "container3"},
+				{ContainerID: "container4"},
+			},
+		},
+		Spec: apiv1.PodSpec{
+			NodeName: nodeName,
+		},
+	}
+	apiService1 = apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "pongservice",
+			UID:               types.UID(serviceUID),
+			Namespace:         "ping",
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:           apiv1.ServiceTypeLoadBalancer,
+			ClusterIP:      "10.0.1.1",
+			LoadBalancerIP: "10.0.1.2",
+			Ports: []apiv1.ServicePort{
+				{Protocol: "TCP", Port: 6379},
+			},
+			Selector: map[string]string{"ponger": "true"},
+		},
+		Status: apiv1.ServiceStatus{
+			LoadBalancer: apiv1.LoadBalancerStatus{
+				Ingress: []apiv1.LoadBalancerIngress{
+					{IP: "10.0.1.2"},
+				},
+			},
+		},
+	}
+	pod1     = kubernetes.NewPod(&apiPod1)
+	pod2     = kubernetes.NewPod(&apiPod2)
+	service1 = kubernetes.NewService(&apiService1)
+)
+
+func newMockClient() *mockClient {
+	return &mockClient{
+		pods:     []kubernetes.Pod{pod1, pod2},
+		services: []kubernetes.Service{service1},
+		logs:     map[string]io.ReadCloser{},
+	}
+}
+
+type mockClient struct {
+	pods         []kubernetes.Pod
+	services     []kubernetes.Service
+	deployments  []kubernetes.Deployment
+	replicaSets  []kubernetes.ReplicaSet
+	daemonSets   []kubernetes.DaemonSet
+	statefulSets []kubernetes.StatefulSet
+	cronJobs     []kubernetes.CronJob
+	logs         map[string]io.ReadCloser
+
+	logContainers []string
+	logOptions    kubernetes.LogOptions
+	scaled        []string
+	permissions   map[string]string
+}
+
+func (c *mockClient) Stop() {}
+func (c *mockClient) WalkPods(f func(kubernetes.Pod) error) error {
+	for _, pod := range c.pods {
+		if err := f(pod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkServices(f func(kubernetes.Service) error) error {
+	for _, service := range c.services {
+		if err := f(service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkReplicaSets(f func(kubernetes.ReplicaSet) error) error {
+	for _, replicaSet := range c.replicaSets {
+		if err := f(replicaSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkDaemonSets(f func(kubernetes.DaemonSet) error) error {
+	for _, daemonSet := range c.daemonSets {
+		if err := f(daemonSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkStatefulSets(f func(kubernetes.StatefulSet) error) error {
+	for _, statefulSet := range c.statefulSets {
+		if err := f(statefulSet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkCronJobs(f func(kubernetes.CronJob) error) error {
+	for _, cronJob := range c.cronJobs {
+		if err := f(cronJob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkDeployments(f func(kubernetes.Deployment) error) error {
+	for _, deployment := range c.deployments {
+		if err := f(deployment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (c *mockClient) WalkNamespaces(f func(kubernetes.NamespaceResource) error) error {
+	return nil
+}
+func (c *mockClient) WalkPersistentVolumes(f func(kubernetes.PersistentVolume) error) error {
+	return nil
+}
+func (c *mockClient) WalkPersistentVolumeClaims(f func(kubernetes.PersistentVolumeClaim) error) error {
+	return nil
+}
+func (c *mockClient) WalkStorageClasses(f func(kubernetes.StorageClass) error) error {
+	return nil
+}
+func (c *mockClient) WalkVolumeSnapshots(f func(kubernetes.VolumeSnapshot) error) error {
+	return nil
+}
+func (c *mockClient) WalkVolumeSnapshotData(f func(kubernetes.VolumeSnapshotData) error) error {
+	return nil
+}
+func (c *mockClient) WalkNetworkPolicies(f func(kubernetes.NetworkPolicy) error) error {
+	return nil
+}
+func (*mockClient) WatchPods(func(kubernetes.Event, kubernetes.Pod)) {}
+func (c *mockClient) Permissions() map[string]string {
+	return c.permissions
+}
+func (c *mockClient) GetLogs(namespaceID, podName string, containerNames []string, opts kubernetes.LogOptions) (io.ReadCloser, error) {
+	c.logContainers, c.logOptions = containerNames, opts
+	r, ok := c.logs[namespaceID+";"+podName]
+	if !ok {
+		return nil, fmt.Errorf("Not found")
+	}
+	return r, nil
+}
+func (c *mockClient) DeletePod(namespaceID, podID string) error {
+	return nil
+}
+func (c *mockClient) ScaleUp(resource, namespaceID, id string) error {
+	c.scaled = append(c.scaled, fmt.Sprintf("%s %s/%s up", resource, namespaceID, id))
+	return nil
+}
+func (c *mockClient) ScaleDown(resource, namespaceID, id string) error {
+	c.scaled = append(c.scaled, fmt.Sprintf("%s %s/%s down", resource, namespaceID, id))
+	return nil
+}
+func (c *mockClient) SetReplicas(resource, namespaceID, id string, replicas int) error {
+	c.scaled = append(c.scaled, fmt.Sprintf("%s %s/%s to %d", resource, namespaceID, id, replicas))
+	return nil
+}
+func (c *mockClient) CloneVolumeSnapshot(namespaceID, VolumeSnapshotID, persistentVolumeClaimID, capacity string) error {
+	return nil
+}
+func (c *mockClient) CreateVolumeSnapshot(namespaceID, persistentVolumeClaimID, capacity string) error {
+	return nil
+}
+func (c *mockClient) DeleteVolumeSnapshot(namespaceID, VolumeSnapshotID string) error {
+	return nil
+}
+
+type mockPipeClient map[string]xfer.Pipe
+
+func (c mockPipeClient) PipeConnection(appID, id string, pipe xfer.Pipe) error {
+	c[id] = pipe
+	return nil
+}
+
+func (c mockPipeClient) PipeClose(appID, id string) error {
+	err := c[id].Close()
+	delete(c, id)
+	return err
+}
+
+func TestReporter(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		uids := map[string]struct{}{
+			pod1UID: {},
+			pod2UID: {},
+		}
+		return uids, nil
+	}
+
+	pod1ID := report.MakePodNodeID(pod1UID)
+	pod2ID := report.MakePodNodeID(pod2UID)
+	serviceID := report.MakeServiceNodeID(serviceUID)
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, _ := kubernetes.NewReporter(newMockClient(), nil, "probe-id", "foo", nil, hr, "", 0).Report()
+
+	// Reporter should have added the following pods
+	for _, pod := range []struct {
+		id            string
+		parentService string
+		latest        map[string]string
+	}{
+		{pod1ID, serviceID, map[string]string{
+			kubernetes.Name:      "pong-a",
+			kubernetes.Namespace: "ping",
+			kubernetes.Created:   pod1.Created(),
+		}},
+		{pod2ID, serviceID, map[string]string{
+			kubernetes.Name:      "pong-b",
+			kubernetes.Namespace: "ping",
+			kubernetes.Created:   pod2.Created(),
+		}},
+	} {
+		node, ok := rpt.Pod.Nodes[pod.id]
+		if !ok {
+			t.Errorf("Expected report to have pod %q, but not found", pod.id)
+		}
+
+		if parents, ok := node.Parents.Lookup(report.Service); !ok || !parents.Contains(pod.parentService) {
+			t.Errorf("Expected pod %s to have parent service %q, got %q", pod.id, pod.parentService, parents)
+		}
+
+		for k, want := range pod.latest {
+			if have, ok := node.Latest.Lookup(k); !ok || have != want {
+				t.Errorf("Expected pod %s latest %q: %q, got %q", pod.id, k, want, have)
+			}
+		}
+	}
+
+	// Reporter should have added a service
+	{
+		node, ok := rpt.Service.Nodes[serviceID]
+		if !ok {
+			t.Errorf("Expected report to have service %q, but not found", serviceID)
+		}
+
+		for k, want := range map[string]string{
+			kubernetes.Name:      "pongservice",
+			kubernetes.Namespace: "ping",
+			kubernetes.Created:   service1.Created(),
+		} {
+			if have, ok := node.Latest.Lookup(k); !ok || have != want {
+				t.Errorf("Expected service %s latest %q: %q, got %q", serviceID, k, want, have)
+			}
+		}
+	}
+
+	// Reporter should allow controls for k8s topologies by providing a probe ID
+	{
+		for _, topologyName := range []string{
+			report.Container,
+			report.CronJob,
+			report.DaemonSet,
+			report.Deployment,
+			report.Pod,
+			report.Service,
+			report.StatefulSet,
+		} {
+			topology, ok := rpt.Topology(topologyName)
+			if !ok {
+				// TODO: this mock report doesn't have nodes for all the topologies yet, so don't fail for now.
+				// t.Errorf("Expected report to have nodes in topology %q, but none found", topology)
+			}
+			for _, n := range topology.Nodes {
+				if probeID, ok := n.Latest.Lookup(report.ControlProbeID); !ok || probeID != "probe-id" {
+					t.Errorf("Expected node %q to have probeID, but not found", n.ID)
+				}
+			}
+		}
+	}
+
+}
+
+func TestReporterControllerParents(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"ponger": "true"}}
+	mockK8s := newMockClient()
+	mockK8s.daemonSets = []kubernetes.DaemonSet{kubernetes.NewDaemonSet(&apiv1beta1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong-ds", UID: "daemonset1234", Namespace: "ping"},
+		Spec:       apiv1beta1.DaemonSetSpec{Selector: selector},
+	})}
+	mockK8s.statefulSets = []kubernetes.StatefulSet{kubernetes.NewStatefulSet(&appsv1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong-ss", UID: "statefulset1234", Namespace: "ping"},
+		Spec:       appsv1beta1.StatefulSetSpec{Selector: selector},
+	})}
+	// Cron jobs select their pods through their active jobs
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong-job", UID: "job1234", Namespace: "ping"},
+		Spec:       batchv1.JobSpec{Selector: selector},
+	}
+	mockK8s.cronJobs = []kubernetes.CronJob{kubernetes.NewCronJob(&batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong-cj", UID: "cronjob1234", Namespace: "ping"},
+		Spec:       batchv1beta1.CronJobSpec{Schedule: "*/5 * * * *"},
+		Status:     batchv1beta1.CronJobStatus{Active: []apiv1.ObjectReference{{UID: job.UID}}},
+	}, map[types.UID]*batchv1.Job{job.UID: job})}
+	hr := controls.NewDefaultHandlerRegistry()
+	rpt, _ := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, hr, nodeName, 0).Report()
+
+	daemonSetID := report.MakeDaemonSetNodeID("daemonset1234")
+	statefulSetID := report.MakeStatefulSetNodeID("statefulset1234")
+	cronJobID := report.MakeCronJobNodeID("cronjob1234")
+	if node, ok := rpt.CronJob.Nodes[cronJobID]; !ok {
+		t.Errorf("Expected report to have cronjob %q, but not found", cronJobID)
+	} else if schedule, _ := node.Latest.Lookup(kubernetes.Schedule); schedule != "*/5 * * * *" {
+		t.Errorf("Expected the schedule of the cronjob, got %q", schedule)
+	}
+	if _, ok := rpt.DaemonSet.Nodes[daemonSetID]; !ok {
+		t.Errorf("Expected report to have daemonset %q, but not found", daemonSetID)
+	}
+	if _, ok := rpt.StatefulSet.Nodes[statefulSetID]; !ok {
+		t.Errorf("Expected report to have statefulset %q, but not found", statefulSetID)
+	}
+	for _, podID := range []string{report.MakePodNodeID(pod1UID), report.MakePodNodeID(pod2UID)} {
+		node := rpt.Pod.Nodes[podID]
+		if parents, ok := node.Parents.Lookup(report.DaemonSet); !ok || !parents.Contains(daemonSetID) {
+			t.Errorf("Expected pod %s to have parent daemonset %q, got %q", podID, daemonSetID, parents)
+		}
+		if parents, ok := node.Parents.Lookup(report.StatefulSet); !ok || !parents.Contains(statefulSetID) {
+			t.Errorf("Expected pod %s to have parent statefulset %q, got %q", podID, statefulSetID, parents)
+		}
+		if parents, ok := node.Parents.Lookup(report.CronJob); !ok || !parents.Contains(cronJobID) {
+			t.Errorf("Expected pod %s to have parent cronjob %q, got %q", podID, cronJobID, parents)
+		}
+	}
+}
+
+func TestReporterScale(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"ponger": "true"}}
+	mockK8s := newMockClient()
+	mockK8s.deployments = []kubernetes.Deployment{kubernetes.NewDeployment(&apiv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "pong-d", UID: "deployment1234", Namespace: "ping"},
+		Spec:       apiv1beta1.DeploymentSpec{Selector: selector},
+	})}
+	mockK8s.replicaSets = []kubernetes.ReplicaSet{
+		kubernetes.NewReplicaSet(&apiv1beta1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "pong-rs", UID: "replicaset1234", Namespace: "ping"},
+			Spec:       apiv1beta1.ReplicaSetSpec{Selector: selector},
+		}),
+		kubernetes.NewReplicaSet(&apiv1beta1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "pong-d-123", UID: "replicaset5678", Namespace: "ping",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "pong-d", UID: "deployment1234"}},
+			},
+			Spec: apiv1beta1.ReplicaSetSpec{Selector: selector},
+		}),
+	}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, hr, nodeName, 0)
+	rpt, _ := reporter.Report()
+
+	// Replica sets of deployments are left out
+	replicaSetID := report.MakeReplicaSetNodeID("replicaset1234")
+	if _, ok := rpt.ReplicaSet.Nodes[replicaSetID]; !ok || len(rpt.ReplicaSet.Nodes) != 1 {
+		t.Errorf("Expected report to have only replica set %q, got %v", replicaSetID, rpt.ReplicaSet.Nodes)
+	}
+	if parents, ok := rpt.Pod.Nodes[report.MakePodNodeID(pod1UID)].Parents.Lookup(report.ReplicaSet); !ok || !parents.Equal(report.MakeStringSet(replicaSetID)) {
+		t.Errorf("Expected pod to have parent replica set %q, got %q", replicaSetID, parents)
+	}
+
+	deploymentID := report.MakeDeploymentNodeID("deployment1234")
+	for _, req := range []xfer.Request{
+		{NodeID: deploymentID, Control: kubernetes.ScaleUp},
+		{NodeID: replicaSetID, Control: kubernetes.ScaleDown},
+		{NodeID: replicaSetID, Control: kubernetes.SetReplicas, ControlArgs: map[string]string{report.PromptArg: "3"}},
+	} {
+		if resp := hr.HandleControlRequest(req); resp.Error != "" {
+			t.Fatal(resp.Error)
+		}
+	}
+	want := []string{"Deployment ping/pong-d up", "ReplicaSet ping/pong-rs down", "ReplicaSet ping/pong-rs to 3"}
+	if !reflect.DeepEqual(mockK8s.scaled, want) {
+		t.Errorf("Expected scaling %v, got %v", want, mockK8s.scaled)
+	}
+
+	// Should reject bad numbers of replicas
+	for _, replicas := range []string{"", "-1", "many"} {
+		req := xfer.Request{NodeID: deploymentID, Control: kubernetes.SetReplicas, ControlArgs: map[string]string{report.PromptArg: replicas}}
+		if resp := hr.HandleControlRequest(req); resp.Error == "" {
+			t.Errorf("Expected an error for %q replicas", replicas)
+		}
+	}
+}
+
+func BenchmarkReporter(b *testing.B) {
+	hr := controls.NewDefaultHandlerRegistry()
+	mockK8s := newMockClient()
+	// Add more dummy data
+	for i := 0; i < 50; i++ {
+		service := apiService1
+		service.ObjectMeta.UID = types.UID(fmt.Sprintf("service%d", i))
+		mockK8s.services = append(mockK8s.services, kubernetes.NewService(&service))
+		pod := apiPod1
+		pod.ObjectMeta.UID = types.UID(fmt.Sprintf("pod%d", i))
+		mockK8s.pods = append(mockK8s.pods, kubernetes.NewPod(&pod))
+		deployment := apiv1beta1.Deployment{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "Deployment",
+				APIVersion: "v1beta1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              fmt.Sprintf("deployment%d", i),
+				UID:               types.UID(fmt.Sprintf("deployment%d", i)),
+				Namespace:         "ping",
+				CreationTimestamp: metav1.Now(),
+			},
+		}
+		mockK8s.deployments = append(mockK8s.deployments, kubernetes.NewDeployment(&deployment))
+	}
+	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, hr, nodeName, 0)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reporter.Report()
+	}
+}
+
+func TestTagger(t *testing.T) {
+	rpt := report.MakeReport()
+	rpt.Container.AddNode(report.MakeNodeWith("container1", map[string]string{
+		docker.LabelPrefix + "io.kubernetes.pod.uid": "123456",
+	}))
+
+	rpt, err := (&kubernetes.Tagger{}).Tag(rpt)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	have, ok := rpt.Container.Nodes["container1"].Parents.Lookup(report.Pod)
+	want := report.MakeStringSet(report.MakePodNodeID("123456"))
+	if !ok || !reflect.DeepEqual(have, want) {
+		t.Errorf("Expected container to have pod parent %v %v", have, want)
+	}
+}
+
+func TestReporterPermissions(t *testing.T) {
+	mockK8s := newMockClient()
+	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, controls.NewDefaultHandlerRegistry(), nodeName, 0)
+	rpt := report.MakeReport()
+	hostID := report.MakeHostNodeID("foo")
+	rpt.Host.AddNode(report.MakeNode(hostID))
+
+	// Nothing to tell until the client has tried
+	rpt, _ = reporter.Tag(rpt)
+	if _, ok := rpt.Host.TableTemplates[kubernetes.PermissionPrefix]; ok {
+		t.Error("Expected no permissions")
+	}
+
+	mockK8s.permissions = map[string]string{
+		"pods":        "in default, team",
+		"deployments": kubernetes.PermissionForbidden,
+	}
+	rpt, _ = reporter.Tag(rpt)
+	if have, _ := rpt.Host.Nodes[hostID].Latest.Lookup(kubernetes.PermissionPrefix + "deployments"); have != kubernetes.PermissionForbidden {
+		t.Errorf("Expected deployments to be forbidden, got %q", have)
+	}
+	if have, _ := rpt.Host.Nodes[hostID].Latest.Lookup(kubernetes.PermissionPrefix + "pods"); have != "in default, team" {
+		t.Errorf("Expected pods in two namespaces, got %q", have)
+	}
+	if _, ok := rpt.Host.TableTemplates[kubernetes.PermissionPrefix]; !ok {
+		t.Error("Expected the permissions table")
+	}
+}
+
+func TestReporterPermissionsWithoutHost(t *testing.T) {
+	mockK8s := newMockClient()
+	mockK8s.permissions = map[string]string{"deployments": kubernetes.PermissionForbidden}
+	reporter := kubernetes.NewReporter(mockK8s, nil, "probe-id", "foo", nil, controls.NewDefaultHandlerRegistry(), "", 0)
+
+	// The cluster agent reports no host, so it gets one for its permissions
+	rpt, _ := reporter.Tag(report.MakeReport())
+	if have, _ := rpt.Host.Nodes[report.MakeHostNodeID("foo")].Latest.Lookup(kubernetes.PermissionPrefix + "deployments"); have != kubernetes.PermissionForbidden {
+		t.Errorf("Expected deployments to be forbidden, got %q", have)
+	}
+}
+
+type callbackReadCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (c *callbackReadCloser) Close() error { return c.close() }
+
+func TestReporterGetLogs(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		return map[string]struct{}{}, nil
+	}
+
+	client := newMockClient()
+	pipes := mockPipeClient{}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, pipes, "", "", nil, hr, "", 0)
+
+	// Should error on invalid IDs
+	{
+		resp := reporter.CapturePod(reporter.GetLogs)(xfer.Request{
+			NodeID:  "invalidID",
+			Control: kubernetes.GetLogs,
+		})
+		if want := "Invalid ID: invalidID"; resp.Error != want {
+			t.Errorf("Expected error on invalid ID: %q, got %q", want, resp.Error)
+		}
+	}
+
+	// Should pass through errors from k8s (e.g if pod does not exist)
+	{
+		resp := reporter.CapturePod(reporter.GetLogs)(xfer.Request{
+			AppID:   "appID",
+			NodeID:  report.MakePodNodeID("notfound"),
+			Control: kubernetes.GetLogs,
+		})
+		if want := "Pod not found: notfound"; resp.Error != want {
+			t.Errorf("Expected error on invalid ID: %q, got %q", want, resp.Error)
+		}
+	}
+
+	podNamespaceAndID := "ping;pong-a"
+	pod1Request := xfer.Request{
+		AppID:   "appID",
+		NodeID:  report.MakePodNodeID(pod1UID),
+		Control: kubernetes.GetLogs,
+	}
+
+	// Inject our logs content, and watch for it to be closed
+	closed := false
+	wantContents := "logs: ping/pong-a"
+	client.logs[podNamespaceAndID] = &callbackReadCloser{Reader: strings.NewReader(wantContents), close: func() error {
+		closed = true
+		return nil
+	}}
+
+	// Should create a new pipe for the stream
+	resp := reporter.CapturePod(reporter.GetLogs)(pod1Request)
+	if resp.Pipe == "" {
+		t.Errorf("Expected pipe id to be returned, but got %#v", resp)
+	}
+	pipe, ok := pipes[resp.Pipe]
+	if !ok {
+		t.Fatalf("Expected pipe %q to have been created, but wasn't", resp.Pipe)
+	}
+
+	// Should push logs from k8s client into the pipe
+	_, readWriter := pipe.Ends()
+	contents, err := ioutil.ReadAll(readWriter)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(contents) != wantContents {
+		t.Errorf("Expected pipe to contain %q, but got %q", wantContents, string(contents))
+	}
+
+	// Should close the stream when the pipe closes
+	if err := pipe.Close(); err != nil {
+		t.Error(err)
+	}
+	if !closed {
+		t.Errorf("Expected pipe to close the underlying log stream")
+	}
+}
+
+func TestReporterGetLogsOptions(t *testing.T) {
+	oldGetNodeName := kubernetes.GetLocalPodUIDs
+	defer func() { kubernetes.GetLocalPodUIDs = oldGetNodeName }()
+	kubernetes.GetLocalPodUIDs = func(string) (map[string]struct{}, error) {
+		return map[string]struct{}{}, nil
+	}
+
+	client := newMockClient()
+	pipes := mockPipeClient{}
+	hr := controls.NewDefaultHandlerRegistry()
+	reporter := kubernetes.NewReporter(client, pipes, "", "", nil, hr, "", 0)
+	client.logs["ping;pong-a"] = ioutil.NopCloser(strings.NewReader(""))
+	getLogs := func(args map[string]string) xfer.Response {
+		return reporter.CapturePod(reporter.GetLogs)(xfer.Request{
+			AppID:       "appID",
+			NodeID:      report.MakePodNodeID(pod1UID),
+			Control:     kubernetes.GetLogs,
+			ControlArgs: args,
+		})
+	}
+
+	// Should follow all the containers by default
+	if resp := getLogs(nil); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if want := []string{"ponger", "sidecar"}; !reflect.DeepEqual(client.logContainers, want) {
+		t.Errorf("Expected logs of %v, got %v", want, client.logContainers)
+	}
+	if !client.logOptions.Follow || client.logOptions.TailLines != nil {
+		t.Errorf("Expected to follow all the lines, got %+v", client.logOptions)
+	}
+
+	// Should pass on the tail, follow and container options
+	if resp := getLogs(map[string]string{
+		kubernetes.LogsTail:      "10",
+		kubernetes.LogsFollow:    "false",
+		kubernetes.LogsContainer: "sidecar",
+	}); resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if want := []string{"sidecar"}; !reflect.DeepEqual(client.logContainers, want) {
+		t.Errorf("Expected logs of %v, got %v", want, client.logContainers)
+	}
+	if client.logOptions.Follow || client.logOptions.TailLines == nil || *client.logOptions.TailLines != 10 {
+		t.Errorf("Expected the last 10 lines without following, got %+v", client.logOptions)
+	}
+
+	// Should reject bad options
+	for _, args := range []map[string]string{
+		{kubernetes.LogsTail: "-1"},
+		{kubernetes.LogsFollow: "maybe"},
+		{kubernetes.LogsContainer: "notfound"},
+	} {
+		if resp := getLogs(args); resp.Error == "" {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
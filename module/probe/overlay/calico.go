@@ -0,0 +1,39 @@
+package overlay
+
+import (
+	"strings"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys for use in Node
+const (
+	CalicoLocalBlocks = "calico_local_blocks"
+)
+
+// CalicoBlocks returns the IPAM blocks of pod addresses which calico has
+// routes for: those affine to this host, and those of other hosts. Exposed
+// for testing.
+var CalicoBlocks = calicoBlocks
+
+// NewCalico makes a reporter for the calico network this host is on, which
+// covers the address blocks of this host and of all the others calico knows
+// routes to.
+func NewCalico(hostID string) *SubnetReporter {
+	return &SubnetReporter{
+		name:   "Calico",
+		prefix: report.CalicoOverlayPeerPrefix,
+		hostID: hostID,
+		read:   readCalicoSubnets,
+	}
+}
+
+func readCalicoSubnets() ([]string, map[string]string, error) {
+	local, remote, err := CalicoBlocks()
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(local, remote...), map[string]string{
+		CalicoLocalBlocks: strings.Join(local, ", "),
+	}, nil
+}
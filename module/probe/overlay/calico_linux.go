@@ -0,0 +1,30 @@
+package overlay
+
+import (
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// Felix installs the routes for VXLAN with this protocol; BIRD does it for
+// BGP and IPIP.
+const felixRouteProtocol = 80
+
+// Calico blackholes the blocks affine to this host, with routes to the
+// individual pods on top, and routes the blocks of other hosts to them.
+func calicoBlocks() (local, remote []string, err error) {
+	routes, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, route := range routes {
+		if route.Dst == nil || (route.Protocol != unix.RTPROT_BIRD && route.Protocol != felixRouteProtocol) {
+			continue
+		}
+		if route.Type == unix.RTN_BLACKHOLE {
+			local = append(local, route.Dst.String())
+		} else {
+			remote = append(remote, route.Dst.String())
+		}
+	}
+	return local, remote, nil
+}
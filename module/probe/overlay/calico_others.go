@@ -0,0 +1,11 @@
+// +build !linux
+
+package overlay
+
+import (
+	"errors"
+)
+
+func calicoBlocks() (local, remote []string, err error) {
+	return nil, nil, errors.New("calico routes not implemented on this platform")
+}
@@ -0,0 +1,60 @@
+package overlay
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Keys for use in Node
+const (
+	FlannelNetwork = "flannel_network"
+	FlannelSubnet  = "flannel_subnet"
+)
+
+// FlannelSubnetFile is where flanneld writes the network and the subnet
+// leased to this host. Exposed for testing.
+var FlannelSubnetFile = "/run/flannel/subnet.env"
+
+// NewFlannel makes a reporter for the flannel network this host is on,
+// which covers the subnets leased to every host with flannel.
+func NewFlannel(hostID string) *SubnetReporter {
+	return &SubnetReporter{
+		name:   "Flannel",
+		prefix: report.FlannelOverlayPeerPrefix,
+		hostID: hostID,
+		read:   readFlannelSubnets,
+	}
+}
+
+func readFlannelSubnets() ([]string, map[string]string, error) {
+	f, err := os.Open(FlannelSubnetFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	network, ok := env["FLANNEL_NETWORK"]
+	if !ok {
+		return nil, nil, fmt.Errorf("no FLANNEL_NETWORK in %s", FlannelSubnetFile)
+	}
+	return []string{network}, map[string]string{
+		FlannelNetwork: network,
+		FlannelSubnet:  env["FLANNEL_SUBNET"],
+	}, nil
+}
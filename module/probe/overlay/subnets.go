@@ -0,0 +1,41 @@
+package overlay
+
+import (
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/report"
+)
+
+// SubnetReporter reports the host as a peer of an overlay network which
+// isn't Weave Net, with the subnets that network hands out addresses from.
+// These go in the LocalNetworks of the peer, so that connections to pods and
+// containers on other hosts are joined up with them, rather than being
+// attributed to the Internet.
+type SubnetReporter struct {
+	name   string
+	prefix string
+	hostID string
+	read   func() (subnets []string, latests map[string]string, err error)
+}
+
+// Name of this reporter, for metrics gathering
+func (s *SubnetReporter) Name() string { return s.name }
+
+// Report implements Reporter.
+func (s *SubnetReporter) Report() (report.Report, error) {
+	r := report.MakeReport()
+	subnets, latests, err := s.read()
+	if err != nil {
+		return r, err
+	}
+	if len(subnets) == 0 {
+		return r, nil
+	}
+	hostNodeID := report.MakeHostNodeID(s.hostID)
+	r.Overlay.AddNode(
+		report.MakeNodeWith(report.MakeOverlayNodeID(s.prefix, s.hostID), latests).
+			WithLatests(map[string]string{report.HostNodeID: hostNodeID}).
+			WithSet(host.LocalNetworks, report.MakeStringSet(subnets...)).
+			WithParent(report.Host, hostNodeID),
+	)
+	return r, nil
+}
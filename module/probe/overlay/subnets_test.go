@@ -0,0 +1,72 @@
+package overlay_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weaveworks/scope/probe/host"
+	"github.com/weaveworks/scope/probe/overlay"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestFlannel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldFile := overlay.FlannelSubnetFile
+	defer func() { overlay.FlannelSubnetFile = oldFile }()
+	overlay.FlannelSubnetFile = filepath.Join(dir, "subnet.env")
+
+	if _, err := overlay.NewFlannel(mockHostID).Report(); err == nil {
+		t.Error("expected an error without a subnet file")
+	}
+
+	env := "FLANNEL_NETWORK=10.244.0.0/16\nFLANNEL_SUBNET=10.244.1.1/24\nFLANNEL_MTU=1450\nFLANNEL_IPMASQ=true\n"
+	if err := ioutil.WriteFile(overlay.FlannelSubnetFile, []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rpt, err := overlay.NewFlannel(mockHostID).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, ok := rpt.Overlay.Nodes[report.MakeOverlayNodeID(report.FlannelOverlayPeerPrefix, mockHostID)]
+	if !ok {
+		t.Fatalf("expected a flannel peer in %v", rpt.Overlay.Nodes)
+	}
+	if have, ok := node.Sets.Lookup(host.LocalNetworks); !ok || !have.Contains("10.244.0.0/16") || len(have) != 1 {
+		t.Errorf("unexpected local networks %v", have)
+	}
+	if have, _ := node.Latest.Lookup(overlay.FlannelSubnet); have != "10.244.1.1/24" {
+		t.Errorf("unexpected subnet %q", have)
+	}
+}
+
+func TestCalico(t *testing.T) {
+	oldBlocks := overlay.CalicoBlocks
+	defer func() { overlay.CalicoBlocks = oldBlocks }()
+	overlay.CalicoBlocks = func() ([]string, []string, error) {
+		return []string{"192.168.10.0/26"}, []string{"192.168.20.0/26", "192.168.30.64/26"}, nil
+	}
+
+	rpt, err := overlay.NewCalico(mockHostID).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, ok := rpt.Overlay.Nodes[report.MakeOverlayNodeID(report.CalicoOverlayPeerPrefix, mockHostID)]
+	if !ok {
+		t.Fatalf("expected a calico peer in %v", rpt.Overlay.Nodes)
+	}
+	if have, _ := node.Sets.Lookup(host.LocalNetworks); len(have) != 3 || !have.Contains("192.168.30.64/26") {
+		t.Errorf("unexpected local networks %v", have)
+	}
+	if have, _ := node.Latest.Lookup(overlay.CalicoLocalBlocks); have != "192.168.10.0/26" {
+		t.Errorf("unexpected local blocks %q", have)
+	}
+	if prefix, peer := report.ParseOverlayNodeID(node.ID); prefix != report.CalicoOverlayPeerPrefix || peer != mockHostID {
+		t.Errorf("unexpected overlay node ID %q", node.ID)
+	}
+}
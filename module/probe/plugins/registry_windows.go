@@ -0,0 +1,36 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// sockets recursively finds all unix sockets under the path provided
+func (r *Registry) sockets(path string) ([]string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			fpath := filepath.Join(path, file.Name())
+			s, err := r.sockets(fpath)
+			if err != nil {
+				log.Warningf("plugins: error loading path %s: %v", fpath, err)
+			}
+			result = append(result, s...)
+		}
+	case mode&os.ModeSocket != 0:
+		result = append(result, path)
+	}
+	return result, nil
+}
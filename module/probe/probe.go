@@ -0,0 +1,304 @@
+package probe
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/weaveworks/scope/report"
+)
+
+const (
+	spiedReportBufferSize    = 16
+	shortcutReportBufferSize = 1024
+)
+
+var reporterDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "scope",
+	Subsystem: "probe",
+	Name:      "reporter_duration_seconds",
+	Help:      "Time in seconds spent generating reports, per reporter.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"reporter"})
+
+func init() {
+	prometheus.MustRegister(reporterDuration)
+}
+
+// ReportPublisher publishes reports, probably to a remote collector.
+type ReportPublisher interface {
+	Publish(r report.Report) error
+}
+
+// Probe sits there, generating and publishing reports.
+type Probe struct {
+	spyInterval, publishInterval time.Duration
+	publisher                    ReportPublisher
+	rateLimiter                  *rate.Limiter
+	noControls                   bool
+	budget                       *CPUBudget // nil for none
+
+	tickers   []Ticker
+	reporters []Reporter
+	taggers   []Tagger
+
+	mtx             sync.Mutex          // guards the settings changing at runtime
+	disabled        map[string]struct{} // reporters, by name
+	settingsChanged chan struct{}
+
+	quit chan struct{}
+	done sync.WaitGroup
+
+	spiedReports    chan report.Report
+	shortcutReports chan report.Report
+}
+
+// Tagger tags nodes with value-add node metadata.
+type Tagger interface {
+	Name() string
+	Tag(r report.Report) (report.Report, error)
+}
+
+// Reporter generates Reports.
+type Reporter interface {
+	Name() string
+	Report() (report.Report, error)
+}
+
+// ReporterFunc uses a function to implement a Reporter
+func ReporterFunc(name string, f func() (report.Report, error)) Reporter {
+	return reporterFunc{name, f}
+}
+
+type reporterFunc struct {
+	name string
+	f    func() (report.Report, error)
+}
+
+func (r reporterFunc) Name() string                   { return r.name }
+func (r reporterFunc) Report() (report.Report, error) { return r.f() }
+
+// Ticker is something which will be invoked every spyDuration.
+// It's useful for things that should be updated on that interval.
+// For example, cached shared state between Taggers and Reporters.
+type Ticker interface {
+	Name() string
+	Tick() error
+}
+
+// New makes a new Probe.
+func New(
+	spyInterval, publishInterval time.Duration,
+	publisher ReportPublisher,
+	noControls bool,
+) *Probe {
+	result := &Probe{
+		spyInterval:     spyInterval,
+		publishInterval: publishInterval,
+		publisher:       publisher,
+		rateLimiter:     rate.NewLimiter(rate.Every(publishInterval/100), 1),
+		noControls:      noControls,
+		disabled:        map[string]struct{}{},
+		settingsChanged: make(chan struct{}, 1),
+		quit:            make(chan struct{}),
+		spiedReports:    make(chan report.Report, spiedReportBufferSize),
+		shortcutReports: make(chan report.Report, shortcutReportBufferSize),
+	}
+	return result
+}
+
+// AddTagger adds a new Tagger to the Probe
+func (p *Probe) AddTagger(ts ...Tagger) {
+	p.taggers = append(p.taggers, ts...)
+}
+
+// AddReporter adds a new Reported to the Probe
+func (p *Probe) AddReporter(rs ...Reporter) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.reporters = append(p.reporters, rs...)
+}
+
+// AddTicker adds a new Ticker to the Probe
+func (p *Probe) AddTicker(ts ...Ticker) {
+	p.tickers = append(p.tickers, ts...)
+}
+
+// SetCPUBudget makes the probe degrade to stay within the budget, by
+// slowing down, and giving up on the degradations of the budget. It tags
+// host nodes with the level of degradation.
+func (p *Probe) SetCPUBudget(budget *CPUBudget) {
+	p.budget = budget
+	p.AddTagger(budget)
+}
+
+// interval is how long to wait between spies or publishes, which slow
+// down together, lest spied reports pile up.
+func (p *Probe) interval(d time.Duration) time.Duration {
+	if p.budget == nil {
+		return d
+	}
+	return d * p.budget.Slowdown()
+}
+
+// Start starts the probe
+func (p *Probe) Start() {
+	p.done.Add(2)
+	go p.spyLoop()
+	go p.publishLoop()
+}
+
+// Stop stops the probe
+func (p *Probe) Stop() error {
+	close(p.quit)
+	p.done.Wait()
+	return nil
+}
+
+// Publish will queue a report for immediate publication,
+// bypassing the spy tick
+func (p *Probe) Publish(rpt report.Report) {
+	rpt = p.tag(rpt)
+	p.shortcutReports <- rpt
+}
+
+func (p *Probe) spyLoop() {
+	defer p.done.Done()
+	spyTimer := time.NewTimer(p.interval(p.spyInterval))
+	defer spyTimer.Stop()
+
+	for {
+		select {
+		case <-spyTimer.C:
+			if p.budget != nil {
+				p.budget.Check()
+			}
+			spyTimer.Reset(p.interval(p.spyInterval))
+			t := time.Now()
+			p.tick()
+			rpt := p.report()
+			rpt = p.tag(rpt)
+			p.spiedReports <- rpt
+			metrics.MeasureSince([]string{"Report Generaton"}, t)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+func (p *Probe) tick() {
+	for _, ticker := range p.tickers {
+		t := time.Now()
+		err := ticker.Tick()
+		metrics.MeasureSince([]string{ticker.Name(), "ticker"}, t)
+		if err != nil {
+			log.Errorf("error doing ticker: %v", err)
+		}
+	}
+}
+
+func (p *Probe) report() report.Report {
+	reporters := p.enabledReporters()
+	reports := make(chan report.Report, len(reporters))
+	for _, rep := range reporters {
+		go func(rep Reporter) {
+			t := time.Now()
+			timer := time.AfterFunc(p.spyInterval, func() { log.Warningf("%v reporter took longer than %v", rep.Name(), p.spyInterval) })
+			newReport, err := rep.Report()
+			if !timer.Stop() {
+				log.Warningf("%v reporter took %v (longer than %v)", rep.Name(), time.Now().Sub(t), p.spyInterval)
+			}
+			metrics.MeasureSince([]string{rep.Name(), "reporter"}, t)
+			reporterDuration.WithLabelValues(rep.Name()).Observe(time.Since(t).Seconds())
+			if err != nil {
+				log.Errorf("error generating report: %v", err)
+				newReport = report.MakeReport() // empty is OK to merge
+			}
+			reports <- newReport
+		}(rep)
+	}
+
+	result := report.MakeReport()
+	for i := 0; i < cap(reports); i++ {
+		result = result.Merge(<-reports)
+	}
+	return result
+}
+
+func (p *Probe) tag(r report.Report) report.Report {
+	var err error
+	for _, tagger := range p.taggers {
+		t := time.Now()
+		timer := time.AfterFunc(p.spyInterval, func() { log.Warningf("%v tagger took longer than %v", tagger.Name(), p.spyInterval) })
+		r, err = tagger.Tag(r)
+		if !timer.Stop() {
+			log.Warningf("%v tagger took %v (longer than %v)", tagger.Name(), time.Now().Sub(t), p.spyInterval)
+		}
+		metrics.MeasureSince([]string{tagger.Name(), "tagger"}, t)
+		if err != nil {
+			log.Errorf("error applying tagger: %v", err)
+		}
+	}
+	return r
+}
+
+func (p *Probe) drainAndPublish(rpt report.Report, rs chan report.Report) {
+	p.rateLimiter.Wait(context.Background())
+ForLoop:
+	for {
+		select {
+		case r := <-rs:
+			rpt = rpt.Merge(r)
+		default:
+			break ForLoop
+		}
+	}
+
+	if p.noControls {
+		rpt.WalkTopologies(func(t *report.Topology) {
+			t.Controls = report.Controls{}
+		})
+	}
+	if err := p.publisher.Publish(rpt); err != nil {
+		log.Infof("publish: %v", err)
+	}
+}
+
+// currentPublishInterval is the publish interval, which can change at
+// runtime.
+func (p *Probe) currentPublishInterval() time.Duration {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.publishInterval
+}
+
+func (p *Probe) publishLoop() {
+	defer p.done.Done()
+	pubTimer := time.NewTimer(p.interval(p.currentPublishInterval()))
+	defer pubTimer.Stop()
+
+	for {
+		select {
+		case <-pubTimer.C:
+			pubTimer.Reset(p.interval(p.currentPublishInterval()))
+			p.drainAndPublish(report.MakeReport(), p.spiedReports)
+
+		case <-p.settingsChanged:
+			if !pubTimer.Stop() {
+				<-pubTimer.C
+			}
+			pubTimer.Reset(p.interval(p.currentPublishInterval()))
+
+		case rpt := <-p.shortcutReports:
+			p.drainAndPublish(rpt, p.shortcutReports)
+
+		case <-p.quit:
+			return
+		}
+	}
+}
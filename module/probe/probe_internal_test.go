@@ -0,0 +1,191 @@
+package probe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestApply(t *testing.T) {
+	var (
+		endpointNodeID = "c"
+		endpointNode   = report.MakeNodeWith(endpointNodeID, map[string]string{"5": "6"})
+	)
+
+	p := New(0, 0, nil, false)
+	p.AddTagger(NewTopologyTagger())
+
+	r := report.MakeReport()
+	r.Endpoint.AddNode(endpointNode)
+	r = p.tag(r)
+
+	for _, tuple := range []struct {
+		want report.Node
+		from report.Topology
+		via  string
+	}{
+		{endpointNode.Merge(report.MakeNode("c").WithTopology(report.Endpoint)), r.Endpoint, endpointNodeID},
+	} {
+		if want, have := tuple.want, tuple.from.Nodes[tuple.via]; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %+v, have %+v", want, have)
+		}
+	}
+}
+
+type mockReporter struct {
+	r report.Report
+}
+
+func (m mockReporter) Report() (report.Report, error) {
+	return m.r.Copy(), nil
+}
+
+func (mockReporter) Name() string { return "Mock" }
+
+type mockPublisher struct {
+	have chan report.Report
+}
+
+func (m mockPublisher) Publish(r report.Report) error {
+	m.have <- r
+	return nil
+}
+
+func (m mockPublisher) Stop() {
+	close(m.have)
+}
+
+func TestProbe(t *testing.T) {
+	const probeID = "probeid"
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	want := report.MakeReport()
+	node := report.MakeNodeWith("a", map[string]string{"b": "c"})
+
+	// marshalling->unmarshaling is not idempotent due to `json:"omitempty"`
+	// tags, transforming empty slices into nils. So, we make DeepEqual
+	// happy by setting empty `json:"omitempty"` entries to nil
+	node.Metrics = nil
+	want.WalkTopologies(func(t *report.Topology) {
+		t.Controls = nil
+	})
+	want.Endpoint.AddNode(node)
+
+	pub := mockPublisher{make(chan report.Report, 10)}
+
+	p := New(10*time.Millisecond, 100*time.Millisecond, pub, false)
+	p.AddReporter(mockReporter{want})
+	p.Start()
+	defer p.Stop()
+
+	test.Poll(t, 300*time.Millisecond, want, func() interface{} {
+		return <-pub.have
+	})
+}
+
+func TestCPUBudget(t *testing.T) {
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	var (
+		cpuTime  time.Duration
+		degraded []string
+	)
+	budget := NewCPUBudget(0.05, func() (time.Duration, error) { return cpuTime, nil },
+		Degradation{Name: "process metrics", Degrade: func(d bool) {
+			if d {
+				degraded = append(degraded, "process metrics")
+			} else {
+				degraded = degraded[:0]
+			}
+		}},
+	)
+	// check uses a share of a CPU over a window, then checks the budget
+	check := func(usage float64) {
+		now = now.Add(budgetWindow)
+		mtime.NowForce(now)
+		cpuTime += time.Duration(usage * float64(budgetWindow))
+		budget.Check()
+	}
+
+	budget.Check()
+	for i, want := range []time.Duration{2, 4, 4} {
+		check(0.1)
+		if have := budget.Slowdown(); have != want {
+			t.Errorf("%d: expected a slowdown of %d, got %d", i, want, have)
+		}
+	}
+	if budget.Level() != 3 || len(degraded) != 1 {
+		t.Errorf("Expected level 3 without process metrics, got %d, %v", budget.Level(), degraded)
+	}
+
+	// Can't degrade any further
+	check(0.1)
+	if budget.Level() != 3 {
+		t.Errorf("Expected to stay at level 3, got %d", budget.Level())
+	}
+
+	r := report.MakeReport()
+	r.Host.AddNode(report.MakeNode("host"))
+	r, _ = budget.Tag(r)
+	if have, _ := r.Host.Nodes["host"].Latest.Lookup(report.ProbeDegradation); have != "level 3 (4x slower reports, no process metrics)" {
+		t.Errorf("Unexpected degradation %q", have)
+	}
+
+	// Between half the budget and the budget, the level stays. Under half
+	// of it, it recovers.
+	check(0.03)
+	check(0.01)
+	if budget.Level() != 2 || len(degraded) != 0 {
+		t.Errorf("Expected level 2 with process metrics, got %d, %v", budget.Level(), degraded)
+	}
+}
+
+type namedReporter struct {
+	mockReporter
+	name string
+}
+
+func (n namedReporter) Name() string { return n.name }
+
+func TestProbeSettings(t *testing.T) {
+	p := New(10*time.Millisecond, 100*time.Millisecond, nil, false)
+	endpoint, process := report.MakeReport(), report.MakeReport()
+	endpoint.Endpoint.AddNode(report.MakeNode("a"))
+	process.Process.AddNode(report.MakeNode("b"))
+	p.AddReporter(namedReporter{mockReporter{endpoint}, "Endpoint"}, namedReporter{mockReporter{process}, "Process"})
+
+	for _, args := range []map[string]string{
+		{PublishIntervalArg: "soon"},
+		{PublishIntervalArg: "1ms"},
+		{DisableReportersArg: "endpoint,docker"},
+	} {
+		if err := p.ApplySettings(args); err == nil {
+			t.Errorf("Expected an error applying %v", args)
+		}
+	}
+	if err := p.ApplySettings(map[string]string{PublishIntervalArg: "1s", DisableReportersArg: "Endpoint"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{PublishInterval: "1s", Reporters: map[string]bool{"endpoint": false, "process": true}}
+	if have := p.Settings(); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if have := p.report(); len(have.Endpoint.Nodes) != 0 || len(have.Process.Nodes) != 1 {
+		t.Errorf("Expected only the process report, got %v", have)
+	}
+
+	if err := p.ApplySettings(map[string]string{EnableReportersArg: "endpoint"}); err != nil {
+		t.Fatal(err)
+	}
+	if have := p.report(); len(have.Endpoint.Nodes) != 1 {
+		t.Errorf("Expected the endpoint report, got %v", have)
+	}
+}
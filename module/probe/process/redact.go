@@ -0,0 +1,150 @@
+package process
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the secrets redacted from command lines.
+const Redacted = "<redacted>"
+
+// Defaults of what to redact from command lines: the values of the flags
+// named like secrets, as mysql's -pSECRET, and the passwords in URLs.
+const (
+	DefaultRedactFlags   = "password,passwd,secret,token,api-key,apikey,access-key,secret-key,private-key,credentials,p"
+	DefaultRedactPattern = `://[^:/@\s]+:([^@\s]+)@`
+)
+
+// Redactor redacts the secrets from command lines before they leave the
+// probe: the values of the flags with the given names, as --name=value,
+// --name value or -name value, also when prefixed as in --db-name, the
+// values of the variables so named, as in NAME=value or -e DB_NAME=value,
+// and whatever matches a regular expression. Flags of a single letter are
+// short flags with their values attached, as in -nvalue: after them, the
+// next argument needn't be a value.
+type Redactor struct {
+	flags      []string
+	shortFlags []string
+	pattern    *regexp.Regexp
+}
+
+// NewRedactor makes a Redactor of the values of the comma-separated flags,
+// and of what matches pattern, if not empty. Only what its groups match is
+// redacted, when it has some.
+func NewRedactor(flags, pattern string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, flag := range strings.Split(flags, ",") {
+		if flag = strings.TrimSpace(flag); len(flag) == 1 {
+			r.shortFlags = append(r.shortFlags, flag)
+		} else if flag = normalizeFlag(flag); flag != "" {
+			r.flags = append(r.flags, flag)
+		}
+	}
+	if pattern != "" {
+		var err error
+		if r.pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+	}
+	return r, nil
+}
+
+// Redact returns cmdline without its secrets.
+func (r *Redactor) Redact(cmdline string) string {
+	args := strings.Split(cmdline, " ")
+	// The first one is the command itself
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			args[i] = r.redactVariable(arg)
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if eq := strings.IndexByte(name, '='); eq >= 0 {
+			prefix := arg[:len(arg)-len(name)+eq+1]
+			if r.secret(name[:eq]) {
+				args[i] = prefix + Redacted
+			} else {
+				// e.g. --env=NAME=value
+				args[i] = prefix + r.redactVariable(name[eq+1:])
+			}
+		} else if r.secret(name) {
+			// Whatever follows is the value, even if it looks like a flag
+			if i+1 < len(args) && args[i+1] != "" {
+				args[i+1] = Redacted
+				i++
+			}
+		} else if len(arg) > 2 && arg[1] != '-' && r.shortSecret(arg[1:2]) {
+			args[i] = arg[:2] + Redacted
+		}
+	}
+	cmdline = strings.Join(args, " ")
+	if r.pattern != nil {
+		cmdline = redactMatches(r.pattern, cmdline)
+	}
+	return cmdline
+}
+
+// redactVariable redacts the value of arg if it is a variable named like a
+// secret, as NAME=value.
+func (r *Redactor) redactVariable(arg string) string {
+	if eq := strings.IndexByte(arg, '='); eq > 0 && r.secret(arg[:eq]) {
+		return arg[:eq+1] + Redacted
+	}
+	return arg
+}
+
+// shortSecret tells whether the value attached to a short flag is a
+// secret, by its letter, which is case-sensitive.
+func (r *Redactor) shortSecret(letter string) bool {
+	for _, flag := range r.shortFlags {
+		if letter == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// secret tells whether the value of a flag is a secret, by its name.
+func (r *Redactor) secret(name string) bool {
+	name = normalizeFlag(name)
+	if name == "" {
+		return false
+	}
+	for _, flag := range r.flags {
+		if name == flag || strings.HasSuffix(name, "-"+flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeFlag(flag string) string {
+	return strings.Replace(strings.ToLower(strings.TrimSpace(flag)), "_", "-", -1)
+}
+
+// redactMatches redacts whatever re matches in s, or only what its groups
+// match, if it has some.
+func redactMatches(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, Redacted)
+	}
+	var result bytes.Buffer
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		for group := 1; group <= re.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			// Groups which didn't match, or within groups already redacted
+			if start < last {
+				continue
+			}
+			result.WriteString(s[last:start])
+			result.WriteString(Redacted)
+			last = end
+		}
+	}
+	result.WriteString(s[last:])
+	return result.String()
+}
@@ -0,0 +1,170 @@
+package process
+
+import (
+	"strconv"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/report"
+)
+
+// We use these keys in node metadata
+const (
+	PID              = report.PID
+	Name             = report.Name
+	PPID             = report.PPID
+	Cmdline          = report.Cmdline
+	Threads          = report.Threads
+	ContainerID      = report.DockerContainerID
+	CPUUsage         = "process_cpu_usage_percent"
+	MemoryUsage      = "process_memory_usage_bytes"
+	OpenFilesCount   = "open_files_count"
+	ThreadsCount     = "threads_count"
+	VoluntaryCtxSw   = "voluntary_context_switches"
+	InvoluntaryCtxSw = "involuntary_context_switches"
+)
+
+// Exposed for testing
+var (
+	MetadataTemplates = report.MetadataTemplates{
+		PID:     {ID: PID, Label: "PID", From: report.FromLatest, Datatype: report.Number, Priority: 1},
+		Cmdline: {ID: Cmdline, Label: "Command", From: report.FromLatest, Priority: 2},
+		PPID:    {ID: PPID, Label: "Parent PID", From: report.FromLatest, Datatype: report.Number, Priority: 3},
+		Threads: {ID: Threads, Label: "# Threads", From: report.FromLatest, Datatype: report.Number, Priority: 4},
+	}
+
+	MetricTemplates = report.MetricTemplates{
+		CPUUsage:         {ID: CPUUsage, Label: "CPU", Format: report.PercentFormat, Priority: 1},
+		MemoryUsage:      {ID: MemoryUsage, Label: "Memory", Format: report.FilesizeFormat, Priority: 2},
+		OpenFilesCount:   {ID: OpenFilesCount, Label: "Open files", Format: report.IntegerFormat, Priority: 3},
+		ThreadsCount:     {ID: ThreadsCount, Label: "Threads", Format: report.IntegerFormat, Priority: 4},
+		VoluntaryCtxSw:   {ID: VoluntaryCtxSw, Label: "Voluntary context switches", Format: report.IntegerFormat, Priority: 5},
+		InvoluntaryCtxSw: {ID: InvoluntaryCtxSw, Label: "Involuntary context switches", Format: report.IntegerFormat, Priority: 6},
+	}
+)
+
+// Reporter generates Reports containing the Process topology.
+type Reporter struct {
+	scope        string
+	walker       Walker
+	jiffies      Jiffies
+	commandLines CommandLines
+	noMetrics    bool
+}
+
+// CommandLines is what the reporter tells of the command lines of
+// processes.
+type CommandLines struct {
+	Omit          bool      // Leave them out altogether
+	OmitArguments bool      // Leave out their arguments
+	Redactor      *Redactor // Redact their secrets, if not nil
+}
+
+// Jiffies is the type for the function used to fetch the elapsed jiffies.
+type Jiffies func() (uint64, float64, error)
+
+// NewReporter makes a new Reporter.
+func NewReporter(walker Walker, scope string, jiffies Jiffies, commandLines CommandLines) *Reporter {
+	return &Reporter{
+		scope:        scope,
+		walker:       walker,
+		jiffies:      jiffies,
+		commandLines: commandLines,
+	}
+}
+
+// Name of this reporter, for metrics gathering
+func (Reporter) Name() string { return "Process" }
+
+// DisableMetrics leaves the metrics of processes out of the reports, or
+// back in. It must not be called during a report.
+func (r *Reporter) DisableMetrics(disabled bool) {
+	r.noMetrics = disabled
+}
+
+// Report implements Reporter.
+func (r *Reporter) Report() (report.Report, error) {
+	result := report.MakeReport()
+	processes, err := r.processTopology()
+	if err != nil {
+		return result, err
+	}
+	result.Process = result.Process.Merge(processes)
+	return result, nil
+}
+
+func (r *Reporter) processTopology() (report.Topology, error) {
+	t := report.MakeTopology().
+		WithMetadataTemplates(MetadataTemplates).
+		WithMetricTemplates(MetricTemplates)
+	now := mtime.Now()
+	deltaTotal, maxCPU, err := r.jiffies()
+	if err != nil {
+		return t, err
+	}
+
+	err = r.walker.Walk(func(p, prev Process) {
+		pidstr := strconv.Itoa(p.PID)
+		nodeID := report.MakeProcessNodeID(r.scope, pidstr)
+		node := report.MakeNode(nodeID)
+		node = node.WithLatest(PID, now, pidstr)
+		node = node.WithLatest(Threads, now, strconv.Itoa(p.Threads))
+		if p.Name != "" {
+			node = node.WithLatest(Name, now, p.Name)
+		}
+
+		if p.Cmdline != "" && !r.commandLines.Omit {
+			node = node.WithLatest(Cmdline, now, r.commandLine(p.Cmdline))
+		}
+
+		if p.PPID > 0 {
+			node = node.WithLatest(PPID, now, strconv.Itoa(p.PPID))
+		}
+
+		// So that processes map to their containers and pods without the
+		// docker probe, e.g. with containerd or CRI-O
+		if p.ContainerID != "" {
+			node = node.WithLatest(ContainerID, now, p.ContainerID)
+		}
+		if p.PodUID != "" {
+			node = node.WithParent(report.Pod, report.MakePodNodeID(p.PodUID))
+		}
+
+		if r.noMetrics {
+			t.AddNode(node)
+			return
+		}
+
+		var metrics = report.Metrics{
+			MemoryUsage:    report.MakeSingletonMetric(now, float64(p.RSSBytes)).WithMax(float64(p.RSSBytesLimit)),
+			OpenFilesCount: report.MakeSingletonMetric(now, float64(p.OpenFilesCount)).WithMax(float64(p.OpenFilesLimit)),
+			ThreadsCount:   report.MakeSingletonMetric(now, float64(p.Threads)),
+		}
+		if deltaTotal > 0 {
+			cpuUsage := float64(p.Jiffies-prev.Jiffies) / float64(deltaTotal) * 100.
+			metrics[CPUUsage] = report.MakeSingletonMetric(now, cpuUsage).WithMax(maxCPU)
+		}
+		// Context switches are counted between readings, which we can only
+		// do if the process was already around for the previous one
+		if p.CtxSwKnown {
+			metrics[VoluntaryCtxSw] = report.MakeSingletonMetric(now, float64(p.VoluntaryCtxSw))
+			metrics[InvoluntaryCtxSw] = report.MakeSingletonMetric(now, float64(p.InvoluntaryCtxSw))
+		}
+
+		node = node.WithMetrics(metrics)
+
+		t.AddNode(node)
+	})
+
+	return t, err
+}
+
+// commandLine is what to tell of cmdline.
+func (r *Reporter) commandLine(cmdline string) string {
+	if r.commandLines.OmitArguments {
+		return report.StripCommandArgs(cmdline)
+	}
+	if r.commandLines.Redactor != nil {
+		return r.commandLines.Redactor.Redact(cmdline)
+	}
+	return cmdline
+}
@@ -0,0 +1,239 @@
+package process_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/report"
+)
+
+type mockWalker struct {
+	processes []process.Process
+}
+
+func (m *mockWalker) Walk(f func(process.Process, process.Process)) error {
+	for _, p := range m.processes {
+		f(p, process.Process{})
+	}
+	return nil
+}
+
+var processes = []process.Process{
+	{PID: 1, PPID: 0, Name: "init"},
+	{PID: 2, PPID: 1, Name: "bash"},
+	{PID: 3, PPID: 1, Name: "apache", Threads: 2},
+	{PID: 4, PPID: 2, Name: "ping", Cmdline: "ping foo.bar.local"},
+	{PID: 5, PPID: 1, Cmdline: "tail -f /var/log/syslog"},
+}
+
+func testReporter(t *testing.T, noCommandLineArguments bool, test func(report.Report)) {
+	walker := &mockWalker{processes: processes}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+
+	rpt, err := process.NewReporter(walker, "", getDeltaTotalJiffies, process.CommandLines{OmitArguments: noCommandLineArguments}).Report()
+	if err != nil {
+		t.Error(err)
+	}
+	test(rpt)
+}
+
+func TestInit(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "1")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 1 init")
+		}
+		if name, ok := node.Latest.Lookup(process.Name); !ok || name != processes[0].Name {
+			t.Errorf("Expected %q got %q", processes[0].Name, name)
+		}
+	}
+	testReporter(t, false, test)
+}
+
+func TestProcesses(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "2")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 2 bash")
+		}
+		if name, ok := node.Latest.Lookup(process.Name); !ok || name != processes[1].Name {
+			t.Errorf("Expected %q got %q", processes[1].Name, name)
+		}
+		if ppid, ok := node.Latest.Lookup(process.PPID); !ok || ppid != fmt.Sprint(processes[1].PPID) {
+			t.Errorf("Expected %d got %q", processes[1].PPID, ppid)
+		}
+		if memoryUsage, ok := node.Metrics[process.MemoryUsage]; !ok {
+			t.Errorf("Expected memory usage metric, but not found")
+		} else if sample, ok := memoryUsage.LastSample(); !ok {
+			t.Errorf("Expected memory usage metric to have a sample, but there were none")
+		} else if sample.Value != 0. {
+			t.Errorf("Expected memory usage metric sample %f, got %f", 0., sample.Value)
+		}
+	}
+
+	testReporter(t, false, test)
+}
+
+func TestThreadCounts(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "3")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 3 apache")
+		}
+		if threads, ok := node.Latest.Lookup(process.Threads); !ok || threads != fmt.Sprint(processes[2].Threads) {
+			t.Errorf("Expected %d got %q", processes[2].Threads, threads)
+		}
+		if threads, ok := node.Metrics[process.ThreadsCount]; !ok {
+			t.Errorf("Expected threads metric, but not found")
+		} else if sample, ok := threads.LastSample(); !ok || sample.Value != float64(processes[2].Threads) {
+			t.Errorf("Expected threads metric sample %d, got %v", processes[2].Threads, sample)
+		}
+	}
+	testReporter(t, false, test)
+}
+
+func TestContainerAndPod(t *testing.T) {
+	walker := &mockWalker{processes: []process.Process{
+		{PID: 1, Name: "nginx", ContainerID: "abcdef", PodUID: "5d6b1c0a-8a9b-4c2d-9e8f-0123456789ab"},
+		{PID: 2, Name: "bash"},
+	}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	rpt, err := process.NewReporter(walker, "", getDeltaTotalJiffies, process.CommandLines{}).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := rpt.Process.Nodes[report.MakeProcessNodeID("", "1")]
+	if containerID, ok := node.Latest.Lookup(process.ContainerID); !ok || containerID != "abcdef" {
+		t.Errorf("Expected container abcdef, got %q", containerID)
+	}
+	if pods, ok := node.Parents.Lookup(report.Pod); !ok || !pods.Contains(report.MakePodNodeID("5d6b1c0a-8a9b-4c2d-9e8f-0123456789ab")) {
+		t.Errorf("Expected the pod parent, got %v", pods)
+	}
+	node = rpt.Process.Nodes[report.MakeProcessNodeID("", "2")]
+	if _, ok := node.Latest.Lookup(process.ContainerID); ok {
+		t.Errorf("Expected no container")
+	}
+	if _, ok := node.Parents.Lookup(report.Pod); ok {
+		t.Errorf("Expected no pod parent")
+	}
+}
+
+func TestCommandLines(t *testing.T) {
+	walker := &mockWalker{processes: []process.Process{
+		{PID: 1, Name: "mysqld", Cmdline: "mysqld --password=hunter2 --port 3306"},
+	}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	redactor, err := process.NewRedactor(process.DefaultRedactFlags, process.DefaultRedactPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		commandLines process.CommandLines
+		want         string
+	}{
+		{process.CommandLines{}, "mysqld --password=hunter2 --port 3306"},
+		{process.CommandLines{Redactor: redactor}, "mysqld --password=" + process.Redacted + " --port 3306"},
+		{process.CommandLines{OmitArguments: true, Redactor: redactor}, "mysqld"},
+		{process.CommandLines{Omit: true}, ""},
+	} {
+		rpt, err := process.NewReporter(walker, "", getDeltaTotalJiffies, c.commandLines).Report()
+		if err != nil {
+			t.Fatal(err)
+		}
+		node := rpt.Process.Nodes[report.MakeProcessNodeID("", "1")]
+		if have, _ := node.Latest.Lookup(process.Cmdline); have != c.want {
+			t.Errorf("%+v: want %q, have %q", c.commandLines, c.want, have)
+		}
+	}
+}
+
+func TestContextSwitches(t *testing.T) {
+	walker := &mockWalker{processes: []process.Process{
+		{PID: 1, Name: "init", VoluntaryCtxSw: 50, InvoluntaryCtxSw: 5, CtxSwKnown: true},
+	}}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	rpt, err := process.NewReporter(walker, "", getDeltaTotalJiffies, process.CommandLines{}).Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := rpt.Process.Nodes[report.MakeProcessNodeID("", "1")]
+	for key, want := range map[string]float64{
+		process.VoluntaryCtxSw:   50,
+		process.InvoluntaryCtxSw: 5,
+	} {
+		if metric, ok := node.Metrics[key]; !ok {
+			t.Errorf("Expected %s metric, but not found", key)
+		} else if sample, ok := metric.LastSample(); !ok || sample.Value != want {
+			t.Errorf("Expected %s metric sample %f, got %v", key, want, sample)
+		}
+	}
+
+	// Without a previous reading, there is nothing to count from
+	walker.processes[0].CtxSwKnown = false
+	rpt, _ = process.NewReporter(walker, "", getDeltaTotalJiffies, process.CommandLines{}).Report()
+	if _, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "1")].Metrics[process.VoluntaryCtxSw]; ok {
+		t.Errorf("Expected no context switches metric")
+	}
+}
+
+func TestCmdline(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "4")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 4 ping")
+		}
+		if cmdline, ok := node.Latest.Lookup(process.Cmdline); !ok || cmdline != fmt.Sprint(processes[3].Cmdline) {
+			t.Errorf("Expected %q got %q", processes[3].Cmdline, cmdline)
+		}
+	}
+	testReporter(t, false, test)
+}
+
+func TestAnonymous(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "5")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 5 tail")
+		}
+		if name, ok := node.Latest.Lookup(process.Name); ok {
+			t.Errorf("Expected no name, but got %q", name)
+		}
+		if cmdline, ok := node.Latest.Lookup(process.Cmdline); !ok || cmdline != fmt.Sprint(processes[4].Cmdline) {
+			t.Errorf("Expected %q got %q", processes[4].Cmdline, cmdline)
+		}
+	}
+	testReporter(t, false, test)
+}
+
+func TestCmdlineRemoval(t *testing.T) {
+	test := func(rpt report.Report) {
+		node, ok := rpt.Process.Nodes[report.MakeProcessNodeID("", "4")]
+		if !ok {
+			t.Errorf("Expected report to include the pid 4 ping")
+		}
+		if cmdline, ok := node.Latest.Lookup(process.Cmdline); !ok || cmdline != fmt.Sprint("ping") {
+			t.Errorf("Expected %q got %q", "ping", cmdline)
+		}
+	}
+	testReporter(t, true, test)
+}
+
+func BenchmarkReporter(t *testing.B) {
+	walker := &mockWalker{processes: processes}
+	getDeltaTotalJiffies := func() (uint64, float64, error) { return 0, 0., nil }
+	reporter := process.NewReporter(walker, "", getDeltaTotalJiffies, process.CommandLines{})
+	t.ResetTimer()
+
+	for i := 0; i < t.N; i++ {
+		_, err := reporter.Report()
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package process_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	fs_hook "github.com/weaveworks/common/fs"
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/common/test/fs"
+	"github.com/weaveworks/scope/probe/process"
+)
+
+const containerID = "7c0f5b2e3f4d1a6b9c8e7d6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b"
+
+var mockFS = fs.Dir("",
+	fs.Dir("proc",
+		fs.Dir("3",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "curl\000google.com",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "3 na R 2 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 2 2048",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: "Limit Soft-Limit Hard-Limit Units\nMax open files 32768 65536 files",
+			},
+			fs.File{
+				FName:     "status",
+				FContents: "Name:\tcurl\nThreads:\t1\nvoluntary_ctxt_switches:\t150\nnonvoluntary_ctxt_switches:\t545\n",
+			},
+			fs.Dir("fd", fs.File{FName: "0"}, fs.File{FName: "1"}, fs.File{FName: "2"}),
+		),
+		fs.Dir("5",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "nginx",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "5 na R 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: ``,
+			},
+			fs.File{
+				FName:     "cgroup",
+				FContents: "12:memory:/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod5d6b1c0a_8a9b_4c2d_9e8f_0123456789ab.slice/cri-containerd-" + containerID + ".scope\n1:name=systemd:/kubepods.slice\n",
+			},
+			fs.Dir("fd"),
+		),
+		fs.Dir("6",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "conmon",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "6 na R 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: ``,
+			},
+			fs.File{
+				FName:     "cgroup",
+				FContents: "0::/kubepods/besteffort/pod5d6b1c0a-8a9b-4c2d-9e8f-0123456789ab/crio-conmon-" + containerID + ".scope\n",
+			},
+			fs.Dir("fd"),
+		),
+		fs.Dir("2",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "bash",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "2 na R 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: ``,
+			},
+			fs.Dir("fd", fs.File{FName: "1"}, fs.File{FName: "2"}),
+		),
+		fs.Dir("4",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "apache",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "4 na R 3 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: ``,
+			},
+			fs.Dir("fd", fs.File{FName: "0"}),
+		),
+		fs.Dir("notapid"),
+		fs.Dir("1",
+			fs.File{
+				FName:     "cmdline",
+				FContents: "init",
+			},
+			fs.File{
+				FName:     "stat",
+				FContents: "1 na R 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0",
+			},
+			fs.File{
+				FName:     "limits",
+				FContents: ``,
+			},
+			fs.Dir("fd"),
+		),
+	),
+)
+
+func TestWalker(t *testing.T) {
+	fs_hook.Mock(mockFS)
+	defer fs_hook.Restore()
+	var pageSize uint64
+	pageSize = (uint64)(os.Getpagesize() * 2)
+
+	want := map[int]process.Process{
+		3: {PID: 3, PPID: 2, Name: "curl", Cmdline: "curl google.com", Threads: 1, RSSBytes: pageSize, RSSBytesLimit: 2048, OpenFilesCount: 3, OpenFilesLimit: 32768},
+		2: {PID: 2, PPID: 1, Name: "bash", Cmdline: "bash", Threads: 1, OpenFilesCount: 2},
+		4: {PID: 4, PPID: 3, Name: "apache", Cmdline: "apache", Threads: 1, OpenFilesCount: 1},
+		1: {PID: 1, PPID: 0, Name: "init", Cmdline: "init", Threads: 1, OpenFilesCount: 0},
+		5: {PID: 5, PPID: 1, Name: "nginx", Cmdline: "nginx", Threads: 1, ContainerID: containerID, PodUID: "5d6b1c0a-8a9b-4c2d-9e8f-0123456789ab"},
+		6: {PID: 6, PPID: 1, Name: "conmon", Cmdline: "conmon", Threads: 1, PodUID: "5d6b1c0a-8a9b-4c2d-9e8f-0123456789ab"},
+	}
+
+	have := map[int]process.Process{}
+	walker := process.NewWalker("/proc", false)
+	err := walker.Walk(func(p, _ process.Process) {
+		have[p.PID] = p
+	})
+
+	if err != nil || !reflect.DeepEqual(want, have) {
+		t.Errorf("%v (%v)", test.Diff(want, have), err)
+	}
+
+	// Context switches are counted between readings of the status, which
+	// are only made every so often
+	now := time.Now()
+	mtime.NowForce(now)
+	defer mtime.NowReset()
+	walk := func() process.Process {
+		var curl process.Process
+		walker.Walk(func(p, _ process.Process) {
+			if p.PID == 3 {
+				curl = p
+			}
+		})
+		return curl
+	}
+	walker = process.NewWalker("/proc", false)
+	walk()
+	mtime.NowForce(now.Add(time.Second))
+	if curl := walk(); curl.CtxSwKnown {
+		t.Errorf("Expected the status not to be read again yet, got %+v", curl)
+	}
+	mtime.NowForce(now.Add(time.Minute))
+	if curl := walk(); !curl.CtxSwKnown || curl.VoluntaryCtxSw != 0 || curl.InvoluntaryCtxSw != 0 {
+		t.Errorf("Expected no context switches between readings, got %+v", curl)
+	}
+}
@@ -0,0 +1,122 @@
+package process
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Not in the vendored golang.org/x/sys/windows
+const processQueryLimitedInformation = 0x1000
+
+var (
+	kernel32                    = windows.NewLazySystemDLL("kernel32.dll")
+	procGetSystemTimes          = kernel32.NewProc("GetSystemTimes")
+	procK32GetProcessMemoryInfo = kernel32.NewProc("K32GetProcessMemoryInfo")
+)
+
+// processMemoryCounters is the PROCESS_MEMORY_COUNTERS of
+// GetProcessMemoryInfo
+type processMemoryCounters struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// NewWalker returns a Windows (Toolhelp-based) walker.
+func NewWalker(_ string, _ bool) Walker {
+	return &walker{}
+}
+
+type walker struct{}
+
+// IsProcInAccept returns true if the process has a at least one thread
+// blocked on the accept() system call
+func IsProcInAccept(procRoot, pid string) (ret bool) {
+	// Not implemented on windows
+	return false
+}
+
+// Walk walks the processes of a snapshot of the system. Their CPU time, as
+// Jiffies of 100ns, and memory are only there for the processes the probe
+// may query, which is all of them when it runs as an administrator.
+func (walker) Walk(f func(Process, Process)) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ProcessEntry32{}
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		p := Process{
+			PID:     int(entry.ProcessID),
+			PPID:    int(entry.ParentProcessID),
+			Name:    windows.UTF16ToString(entry.ExeFile[:]),
+			Threads: int(entry.Threads),
+		}
+		// The System Idle Process, with PID 0, would account for all the
+		// idle time of the host
+		if p.PID != 0 {
+			readProcessTimesAndMemory(&p)
+		}
+		f(p, Process{})
+	}
+	if err != syscall.Errno(windows.ERROR_NO_MORE_FILES) {
+		return err
+	}
+	return nil
+}
+
+func readProcessTimesAndMemory(p *Process) {
+	handle, err := windows.OpenProcess(processQueryLimitedInformation, false, uint32(p.PID))
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(handle)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err == nil {
+		p.Jiffies = filetimeTicks(kernel) + filetimeTicks(user)
+	}
+	counters := processMemoryCounters{}
+	counters.CB = uint32(unsafe.Sizeof(counters))
+	if ok, _, _ := procK32GetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.CB)); ok != 0 {
+		p.RSSBytes = uint64(counters.WorkingSetSize)
+	}
+}
+
+func filetimeTicks(ft windows.Filetime) uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
+
+var previousTotal uint64
+
+// GetDeltaTotalJiffies returns the number of 100ns ticks of all the CPUs
+// since the previous call, as Windows doesn't have jiffies, and the max
+// CPU usage.
+func GetDeltaTotalJiffies() (uint64, float64, error) {
+	var idle, kernel, user windows.Filetime
+	if ok, _, err := procGetSystemTimes.Call(
+		uintptr(unsafe.Pointer(&idle)),
+		uintptr(unsafe.Pointer(&kernel)),
+		uintptr(unsafe.Pointer(&user)),
+	); ok == 0 {
+		return 0, 0.0, err
+	}
+	// The kernel time includes the idle time
+	currentTotal := filetimeTicks(kernel) + filetimeTicks(user)
+	delta := currentTotal - previousTotal
+	previousTotal = currentTotal
+	return delta, float64(runtime.NumCPU()) * 100., nil
+}
@@ -0,0 +1,159 @@
+package probe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/controls"
+)
+
+// Controls of the probe itself, which the app sends to change the settings
+// of probes without restarting them.
+const (
+	GetSettings = "probe_get_settings"
+	SetSettings = "probe_set_settings"
+)
+
+// Arguments of SetSettings, all optional. Reporters are named as they are
+// in the settings, e.g. "endpoint", "process", "docker", "k8s" or "host",
+// separated by commas.
+const (
+	PublishIntervalArg  = "publish_interval"
+	EnableReportersArg  = "enable"
+	DisableReportersArg = "disable"
+)
+
+// Settings of a probe which can be changed at runtime.
+type Settings struct {
+	PublishInterval string          `json:"publishInterval"`
+	Reporters       map[string]bool `json:"reporters"` // enabled, by name
+}
+
+// RegisterControls registers the controls to get and set the settings of
+// the probe.
+func (p *Probe) RegisterControls(handlerRegistry *controls.HandlerRegistry) {
+	handlerRegistry.Register(GetSettings, func(xfer.Request) xfer.Response {
+		return xfer.Response{Value: p.Settings()}
+	})
+	handlerRegistry.Register(SetSettings, func(req xfer.Request) xfer.Response {
+		if err := p.ApplySettings(req.ControlArgs); err != nil {
+			return xfer.ResponseError(err)
+		}
+		return xfer.Response{Value: p.Settings()}
+	})
+}
+
+// Settings returns the current settings of the probe.
+func (p *Probe) Settings() Settings {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	settings := Settings{
+		PublishInterval: p.publishInterval.String(),
+		Reporters:       map[string]bool{},
+	}
+	for _, rep := range p.reporters {
+		name := reporterName(rep)
+		_, disabled := p.disabled[name]
+		settings.Reporters[name] = !disabled
+	}
+	return settings
+}
+
+// ApplySettings changes the settings of the probe, as given by the
+// arguments of SetSettings. Nothing changes if any of them is invalid.
+func (p *Probe) ApplySettings(args map[string]string) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	publishInterval := p.publishInterval
+	if arg, ok := args[PublishIntervalArg]; ok {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", PublishIntervalArg, err)
+		}
+		if d < p.spyInterval {
+			return fmt.Errorf("%s %v is shorter than the spy interval %v", PublishIntervalArg, d, p.spyInterval)
+		}
+		publishInterval = d
+	}
+	known := map[string]struct{}{}
+	for _, rep := range p.reporters {
+		known[reporterName(rep)] = struct{}{}
+	}
+	enable, err := reporterNames(args[EnableReportersArg], known)
+	if err != nil {
+		return err
+	}
+	disable, err := reporterNames(args[DisableReportersArg], known)
+	if err != nil {
+		return err
+	}
+
+	if publishInterval != p.publishInterval {
+		log.Infof("Publishing reports every %v, instead of every %v", publishInterval, p.publishInterval)
+		p.publishInterval = publishInterval
+		p.rateLimiter.SetLimit(rate.Every(publishInterval / 100))
+		select {
+		case p.settingsChanged <- struct{}{}:
+		default:
+		}
+	}
+	for _, name := range enable {
+		if _, ok := p.disabled[name]; ok {
+			log.Infof("Enabling the %s reporter", name)
+			delete(p.disabled, name)
+		}
+	}
+	for _, name := range disable {
+		if _, ok := p.disabled[name]; !ok {
+			log.Infof("Disabling the %s reporter", name)
+			p.disabled[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// enabledReporters are the reporters which have not been disabled.
+func (p *Probe) enabledReporters() []Reporter {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	result := make([]Reporter, 0, len(p.reporters))
+	for _, rep := range p.reporters {
+		if _, disabled := p.disabled[reporterName(rep)]; !disabled {
+			result = append(result, rep)
+		}
+	}
+	return result
+}
+
+func reporterName(rep Reporter) string {
+	return strings.ToLower(rep.Name())
+}
+
+// reporterNames splits a comma separated list of the names of reporters,
+// all of which must be known.
+func reporterNames(arg string, known map[string]struct{}) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := known[name]; !ok {
+			knownNames := make([]string, 0, len(known))
+			for k := range known {
+				knownNames = append(knownNames, k)
+			}
+			sort.Strings(knownNames)
+			return nil, fmt.Errorf("unknown reporter %q, expected one of %s", name, strings.Join(knownNames, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
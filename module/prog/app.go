@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"net/url"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goji/httpauth"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/tylerb/graceful"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	billing "github.com/weaveworks/billing-client"
+	"github.com/weaveworks/common/aws"
+	"github.com/weaveworks/common/logging"
+	"github.com/weaveworks/common/middleware"
+	"github.com/weaveworks/common/network"
+	"github.com/weaveworks/common/signals"
+	"github.com/weaveworks/common/tracing"
+	"github.com/weaveworks/go-checkpoint"
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/app/multitenant"
+	"github.com/weaveworks/scope/common/weave"
+	"github.com/weaveworks/scope/common/xfer"
+	"github.com/weaveworks/scope/probe/docker"
+)
+
+const (
+	memcacheUpdateInterval = 1 * time.Minute
+	httpTimeout            = 90 * time.Second
+	sharedPollInterval     = 1 * time.Second
+	imageLookupTimeout     = 10 * time.Second
+)
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "scope",
+		Name:      "request_duration_seconds",
+		Help:      "Time in seconds spent serving HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status_code", "ws"})
+)
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+	billing.MustRegisterMetrics()
+}
+
+// Router creates the mux for all the various app components.
+func router(collector app.Collector, controlRouter app.ControlRouter, pipeRouter app.PipeRouter, auditLog *app.AuditLog, recorder *app.SessionRecorder, health *app.HealthConfig, annotations *app.AnnotationStore, history *app.MetricHistory, externalUI bool, capabilities map[string]bool, metricsGraphURL string, readOnly bool) http.Handler {
+	router := mux.NewRouter().SkipClean(true)
+
+	// We pull in the http.DefaultServeMux to get the pprof routes
+	router.PathPrefix("/debug/pprof").Handler(http.DefaultServeMux)
+	router.Path("/metrics").Handler(prometheus.Handler())
+
+	app.RegisterReportPostHandler(collector, router)
+	if readOnly {
+		app.RegisterReadOnlyControlRoutes(router, controlRouter)
+	} else {
+		app.RegisterControlRoutes(router, controlRouter)
+	}
+	app.RegisterPipeRoutes(router, pipeRouter)
+	app.RegisterAuditRoutes(router, auditLog)
+	if recorder != nil {
+		app.RegisterRecordingRoutes(router, recorder)
+	}
+	app.RegisterProbeSettingsRoutes(router, collector, controlRouter, readOnly)
+	app.RegisterHealthRoutes(router, health, readOnly)
+	app.RegisterAnnotationRoutes(router, annotations, readOnly)
+	if history != nil {
+		app.RegisterHistoryRoutes(router, history)
+	}
+	app.RegisterCustomTopologyRoutes(router, readOnly)
+	app.RegisterTopologyRoutes(router, app.WebReporter{Reporter: collector, MetricsGraphURL: metricsGraphURL, ReadOnly: readOnly, Health: health, Annotations: annotations}, capabilities)
+
+	uiHandler := http.FileServer(GetFS(externalUI))
+	router.PathPrefix("/ui").Name("static").Handler(
+		middleware.PathRewrite(regexp.MustCompile("^/ui"), "").Wrap(
+			uiHandler))
+	router.PathPrefix("/").Name("static").Handler(uiHandler)
+
+	middlewares := middleware.Merge(
+		middleware.Instrument{
+			RouteMatcher: router,
+			Duration:     requestDuration,
+		},
+		middleware.Tracer{},
+	)
+
+	return middlewares.Wrap(router)
+}
+
+func collectorFactory(userIDer multitenant.UserIDer, collectorURL, s3URL, natsHostname string,
+	memcacheConfig multitenant.MemcacheConfig, window time.Duration, maxTopNodes int, createTables, shared bool) (app.Collector, app.ReportStore, error) {
+	if collectorURL == "local" {
+		if shared {
+			return nil, nil, fmt.Errorf("A local collector can't be shared")
+		}
+		return app.NewCollector(window), nil, nil
+	}
+
+	parsed, err := url.Parse(collectorURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		if shared {
+			return nil, nil, fmt.Errorf("A file collector can't be shared")
+		}
+		collector, err := app.NewFileCollector(parsed.Path, window)
+		return collector, nil, err
+	case "s3":
+		store, err := multitenant.NewS3ReportStore(parsed)
+		if err != nil {
+			return nil, nil, err
+		}
+		if shared {
+			return app.NewClusteredCollector(store, window, sharedPollInterval), store, nil
+		}
+		return app.NewStoringCollector(app.NewCollector(window), store, window), store, nil
+	case "dynamodb":
+		s3, err := url.Parse(s3URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Valid URL for s3 required: %v", err)
+		}
+		dynamoDBConfig, err := aws.ConfigFromURL(parsed)
+		if err != nil {
+			return nil, nil, err
+		}
+		s3Config, err := aws.ConfigFromURL(s3)
+		if err != nil {
+			return nil, nil, err
+		}
+		bucketName := strings.TrimPrefix(s3.Path, "/")
+		tableName := strings.TrimPrefix(parsed.Path, "/")
+		s3Store := multitenant.NewS3Client(s3Config, bucketName)
+		var memcacheClient *multitenant.MemcacheClient
+		if memcacheConfig.Host != "" {
+			memcacheClient = multitenant.NewMemcacheClient(memcacheConfig)
+		}
+		awsCollector, err := multitenant.NewAWSCollector(
+			multitenant.AWSCollectorConfig{
+				UserIDer:       userIDer,
+				DynamoDBConfig: dynamoDBConfig,
+				DynamoTable:    tableName,
+				S3Store:        &s3Store,
+				NatsHost:       natsHostname,
+				MemcacheClient: memcacheClient,
+				Window:         window,
+				MaxTopNodes:    maxTopNodes,
+			},
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if createTables {
+			if err := awsCollector.CreateTables(); err != nil {
+				return nil, nil, err
+			}
+		}
+		return awsCollector, nil, nil
+	}
+
+	return nil, nil, fmt.Errorf("Invalid collector '%s'", collectorURL)
+}
+
+func emitterFactory(collector app.Collector, clientCfg billing.Config, userIDer multitenant.UserIDer, emitterCfg multitenant.BillingEmitterConfig) (*multitenant.BillingEmitter, error) {
+	billingClient, err := billing.NewClient(clientCfg)
+	if err != nil {
+		return nil, err
+	}
+	emitterCfg.UserIDer = userIDer
+	return multitenant.NewBillingEmitter(
+		collector,
+		billingClient,
+		emitterCfg,
+	)
+}
+
+func controlRouterFactory(userIDer multitenant.UserIDer, controlRouterURL string, controlRPCTimeout time.Duration) (app.ControlRouter, error) {
+	if controlRouterURL == "local" {
+		return app.NewLocalControlRouter(), nil
+	}
+
+	parsed, err := url.Parse(controlRouterURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Scheme == "sqs" {
+		prefix := strings.TrimPrefix(parsed.Path, "/")
+		sqsConfig, err := aws.ConfigFromURL(parsed)
+		if err != nil {
+			return nil, err
+		}
+		return multitenant.NewSQSControlRouter(sqsConfig, userIDer, prefix, controlRPCTimeout), nil
+	}
+
+	return nil, fmt.Errorf("Invalid control router '%s'", controlRouterURL)
+}
+
+func pipeRouterFactory(userIDer multitenant.UserIDer, pipeRouterURL, consulInf string) (app.PipeRouter, error) {
+	if pipeRouterURL == "local" {
+		return app.NewLocalPipeRouter(), nil
+	}
+
+	parsed, err := url.Parse(pipeRouterURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Scheme == "consul" {
+		consulClient, err := multitenant.NewConsulClient(parsed.Host)
+		if err != nil {
+			return nil, err
+		}
+		advertise, err := network.GetFirstAddressOf(consulInf)
+		if err != nil {
+			return nil, err
+		}
+		addr := fmt.Sprintf("%s:4444", advertise)
+		return multitenant.NewConsulPipeRouter(consulClient, strings.TrimPrefix(parsed.Path, "/"), addr, userIDer), nil
+	}
+
+	return nil, fmt.Errorf("Invalid pipe router '%s'", pipeRouterURL)
+}
+
+// auditSinkFactory makes the sink for the audit log: none, a file
+// (file:///path), syslog (syslog:// for the local one, or
+// syslog://host:port over UDP) or a webhook (http[s]://...).
+func auditSinkFactory(auditSinkURL string) (app.AuditSink, error) {
+	if auditSinkURL == "" {
+		return nil, nil
+	}
+
+	parsed, err := url.Parse(auditSinkURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		return app.NewFileAuditSink(parsed.Path)
+	case "syslog":
+		if parsed.Host == "" {
+			return app.NewSyslogAuditSink("", "")
+		}
+		return app.NewSyslogAuditSink("udp", parsed.Host)
+	case "http", "https":
+		return app.NewWebhookAuditSink(auditSinkURL), nil
+	}
+	return nil, fmt.Errorf("Invalid audit sink '%s'", auditSinkURL)
+}
+
+// Main runs the app
+func appMain(flags appFlags) {
+	setLogLevel(flags.logLevel)
+	setLogFormatter(flags.logPrefix)
+	runtime.SetBlockProfileRate(flags.blockProfileRate)
+
+	traceCloser := tracing.NewFromEnv(fmt.Sprintf("scope-%s", flags.serviceName))
+	defer traceCloser.Close()
+
+	defer log.Info("app exiting")
+	rand.Seed(time.Now().UnixNano())
+	app.UniqueID = strconv.FormatInt(rand.Int63(), 16)
+	app.Version = version
+	log.Infof("app starting, version %s, ID %s", app.Version, app.UniqueID)
+	logCensoredArgs()
+
+	var auth *app.Auth
+	if flags.authFile != "" {
+		if flags.basicAuth {
+			log.Fatal("--app.auth.tokens-file and --app.basicAuth are mutually exclusive")
+			return
+		}
+		var err error
+		if auth, err = app.LoadAuth(flags.authFile); err != nil {
+			log.Fatalf("Error loading tokens: %v", err)
+			return
+		}
+	}
+	tenants := auth != nil && auth.HasTenants()
+
+	userIDer := multitenant.NoopUserIDer
+	switch {
+	case tenants && flags.userIDHeader != "":
+		log.Fatal("tenants in --app.auth.tokens-file and --app.userid.header are mutually exclusive")
+		return
+	case tenants:
+		userIDer = func(ctx context.Context) (string, error) { return app.AuthTenant(ctx), nil }
+	case flags.userIDHeader != "":
+		userIDer = multitenant.UserIDHeader(flags.userIDHeader)
+	}
+
+	collector, reportStore, err := collectorFactory(
+		userIDer, flags.collectorURL, flags.s3URL, flags.natsHostname,
+		multitenant.MemcacheConfig{
+			Host:             flags.memcachedHostname,
+			Timeout:          flags.memcachedTimeout,
+			Expiration:       flags.memcachedExpiration,
+			UpdateInterval:   memcacheUpdateInterval,
+			Service:          flags.memcachedService,
+			CompressionLevel: flags.memcachedCompressionLevel,
+		},
+		flags.window, flags.maxTopNodes, flags.awsCreateTables, flags.collectorShared)
+	if err != nil {
+		log.Fatalf("Error creating collector: %v", err)
+		return
+	}
+	if tenants {
+		// The DynamoDB collector keeps tenants apart by the userIDer already
+		switch {
+		case flags.collectorURL == "local":
+			collector = app.NewTenantCollector(func() app.Collector { return app.NewCollector(flags.window) })
+		case !strings.HasPrefix(flags.collectorURL, "dynamodb:"):
+			log.Fatalf("Tenants need a local or DynamoDB collector, not %s", flags.collectorURL)
+			return
+		}
+	}
+
+	if flags.BillingEmitterConfig.Enabled {
+		billingEmitter, err := emitterFactory(collector, flags.BillingClientConfig, userIDer, flags.BillingEmitterConfig)
+		if err != nil {
+			log.Fatalf("Error creating emitter: %v", err)
+			return
+		}
+		defer billingEmitter.Close()
+		collector = billingEmitter
+	}
+
+	var history *app.MetricHistory
+	if flags.metricsRetention > 0 {
+		history = app.NewMetricHistory(flags.metricsRetention, flags.metricsResolution)
+		collector = app.HistoryCollector(collector, history)
+	}
+
+	if flags.imagesRegistryURL != "" {
+		source, err := app.NewRegistryImageInfoSource(flags.imagesRegistryURL, flags.imagesScannerURL, imageLookupTimeout)
+		if err != nil {
+			log.Fatalf("Error creating image registry client: %v", err)
+			return
+		}
+		images := app.NewImageMetadata(source, flags.imagesRefresh)
+		defer images.Stop()
+		collector = app.ImageMetadataCollector(collector, images)
+	} else if flags.imagesScannerURL != "" {
+		log.Fatalf("The image scanner needs the image registry, for the digests of images")
+		return
+	}
+
+	controlRouter, err := controlRouterFactory(userIDer, flags.controlRouterURL, flags.controlRPCTimeout)
+	if err != nil {
+		log.Fatalf("Error creating control router: %v", err)
+		return
+	}
+	if tenants && flags.controlRouterURL == "local" {
+		controlRouter = app.TenantControlRouter(controlRouter)
+	}
+
+	auditSink, err := auditSinkFactory(flags.auditSinkURL)
+	if err != nil {
+		log.Fatalf("Error creating audit sink: %v", err)
+		return
+	}
+	auditLog := app.NewAuditLog(auditSink)
+	controlRouter = app.AuditingControlRouter(controlRouter, auditLog)
+
+	pipeRouter, err := pipeRouterFactory(userIDer, flags.pipeRouterURL, flags.consulInf)
+	if err != nil {
+		log.Fatalf("Error creating pipe router: %v", err)
+		return
+	}
+	if tenants && flags.pipeRouterURL == "local" {
+		pipeRouter = app.TenantPipeRouter(pipeRouter)
+	}
+
+	var recorder *app.SessionRecorder
+	if flags.recordSessions {
+		// Recordings go with the reports, if their store keeps recordings,
+		// or else in memory
+		recordings, ok := reportStore.(app.RecordingStore)
+		if !ok {
+			recordings = app.NewMemoryReportStore(flags.window).(app.RecordingStore)
+		}
+		recorder = app.NewSessionRecorder(recordings)
+		controlRouter = app.RecordingControlRouter(controlRouter, recorder)
+		pipeRouter = app.RecordingPipeRouter(pipeRouter, recorder)
+	}
+
+	// Start background version checking
+	checkpoint.CheckInterval(&checkpoint.CheckParams{
+		Product: "scope-app",
+		Version: app.Version,
+		Flags:   makeBaseCheckpointFlags(),
+	}, versionCheckPeriod, func(r *checkpoint.CheckResponse, err error) {
+		if err != nil {
+			log.Errorf("Error checking version: %v", err)
+		} else if r.Outdated {
+			log.Infof("Scope version %s is available; please update at %s",
+				r.CurrentVersion, r.CurrentDownloadURL)
+			app.NewVersion(r.CurrentVersion, r.CurrentDownloadURL)
+		}
+	})
+
+	// Periodically try and register our IP address in WeaveDNS.
+	if flags.weaveEnabled && flags.weaveHostname != "" {
+		weave, err := newWeavePublisher(
+			flags.dockerEndpoint, flags.weaveAddr,
+			flags.weaveHostname, flags.containerName)
+		if err != nil {
+			log.Println("Failed to start weave integration:", err)
+		} else {
+			defer weave.Stop()
+		}
+	}
+
+	if flags.customTopologiesFile != "" {
+		customTopologies, err := app.LoadCustomTopologies(flags.customTopologiesFile)
+		if err == nil {
+			err = app.AddCustomTopologies(customTopologies...)
+		}
+		if err != nil {
+			log.Fatalf("Error loading custom topologies: %v", err)
+			return
+		}
+	}
+
+	if flags.federationDownstreams != "" {
+		downstreams, err := app.ParseDownstreams(flags.federationDownstreams)
+		if err != nil {
+			log.Fatalf("Error parsing downstream apps: %v", err)
+			return
+		}
+		if flags.federationTokensFile != "" {
+			if err := app.LoadDownstreamTokens(flags.federationTokensFile, downstreams); err != nil {
+				log.Fatalf("Error loading the tokens of the downstream apps: %v", err)
+				return
+			}
+		}
+		defer app.NewFederation(collector, downstreams, flags.federationInterval).Stop()
+	}
+
+	capabilities := map[string]bool{
+		xfer.HistoricReportsCapability: collector.HasHistoricReports(),
+		xfer.MetricHistoryCapability:   history != nil,
+	}
+	logger := logging.Logrus(log.StandardLogger())
+	health, err := app.NewHealthConfig(flags.healthRulesFile)
+	if err != nil {
+		log.Fatalf("Error loading health rules: %v", err)
+		return
+	}
+
+	handler := router(collector, controlRouter, pipeRouter, auditLog, recorder, health, app.NewAnnotationStore(), history, flags.externalUI, capabilities, flags.metricsGraphURL, flags.readOnly)
+	if flags.logHTTP {
+		handler = middleware.Log{
+			Log:               logger,
+			LogRequestHeaders: flags.logHTTPHeaders,
+		}.Wrap(handler)
+	}
+
+	var authorizeProbe func(string) (string, bool)
+	if flags.basicAuth {
+		log.Infof("Basic authentication enabled")
+		handler = httpauth.SimpleBasicAuth(flags.username, flags.password)(handler)
+		authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte(flags.username+":"+flags.password))
+		authorizeProbe = func(header string) (string, bool) { return "", header == authorization }
+	} else {
+		log.Infof("Basic authentication disabled")
+	}
+
+	if auth != nil {
+		log.Infof("Token authentication enabled")
+		handler = auth.Wrap(handler)
+		authorizeProbe = auth.ProbeTenant
+	}
+
+	if flags.grpcListen != "" {
+		grpcServer, err := grpcServerFactory(collector, controlRouter, authorizeProbe, flags)
+		if err != nil {
+			log.Fatalf("Error starting gRPC server: %v", err)
+			return
+		}
+		defer grpcServer.Stop()
+	}
+
+	server := &graceful.Server{
+		// we want to manage the stop condition ourselves below
+		NoSignalHandling: true,
+		Server: &http.Server{
+			Addr:           flags.listen,
+			Handler:        handler,
+			ReadTimeout:    httpTimeout,
+			WriteTimeout:   httpTimeout,
+			MaxHeaderBytes: 1 << 20,
+		},
+	}
+	go func() {
+		log.Infof("listening on %s", flags.listen)
+		if err := server.ListenAndServe(); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	// block until INT/TERM
+	signals.SignalHandlerLoop(
+		logger,
+		stopper{
+			Server:      server,
+			StopTimeout: flags.stopTimeout,
+		},
+	)
+}
+
+// stopper adapts graceful.Server's interface to signals.SignalReceiver's interface.
+type stopper struct {
+	Server      *graceful.Server
+	StopTimeout time.Duration
+}
+
+// Stop implements signals.SignalReceiver's Stop method.
+func (c stopper) Stop() error {
+	// stop listening, wait for any active connections to finish
+	c.Server.Stop(c.StopTimeout)
+	<-c.Server.StopChan()
+	return nil
+}
+
+// grpcServerFactory starts serving probes over gRPC, and advertises the
+// port it listens on to them.
+func grpcServerFactory(collector app.Collector, controlRouter app.ControlRouter, authorizeProbe func(string) (string, bool), flags appFlags) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", flags.grpcListen)
+	if err != nil {
+		return nil, err
+	}
+	var opts []grpc.ServerOption
+	if flags.grpcTLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(flags.grpcTLSCert, flags.grpcTLSKey)
+		if err != nil {
+			lis.Close()
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	server := app.NewGRPCServer(collector, controlRouter, authorizeProbe, opts...)
+	app.GRPCPort = lis.Addr().(*net.TCPAddr).Port
+	go func() {
+		log.Infof("listening for gRPC probes on %s", lis.Addr())
+		if err := server.Serve(lis); err != nil {
+			log.Error(err)
+		}
+	}()
+	return server, nil
+}
+
+func newWeavePublisher(dockerEndpoint, weaveAddr, weaveHostname, containerName string) (*app.WeavePublisher, error) {
+	dockerClient, err := docker.NewDockerClientStub(dockerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	weaveClient := weave.NewClient(weaveAddr)
+	return app.NewWeavePublisher(
+		weaveClient,
+		dockerClient,
+		app.Interfaces,
+		weaveHostname,
+		containerName,
+	), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/app"
+	"github.com/weaveworks/scope/report"
+)
+
+// renderMain renders the topologies of the reports at paths (files or
+// directories of them), merged together, as the app would serve them, so
+// that captured reports can be looked into, or benchmarked, offline.
+func renderMain(flags renderFlags, paths []string) {
+	if len(paths) == 0 {
+		log.Fatal("No reports to render: give files, or directories, of reports as arguments")
+	}
+	values, err := url.ParseQuery(flags.options)
+	if err != nil {
+		log.Fatalf("Invalid --render.options: %v", err)
+	}
+	var topologyIDs []string
+	if flags.topologies != "" {
+		topologyIDs = strings.Split(flags.topologies, ",")
+	}
+
+	begin := time.Now()
+	reports := []report.Report{}
+	for _, path := range paths {
+		rpts, err := app.ReadReports(path)
+		if err != nil {
+			log.Fatalf("Error reading reports: %v", err)
+		}
+		reports = append(reports, rpts...)
+	}
+	for i := range reports {
+		reports[i] = reports[i].Upgrade()
+	}
+	rpt := app.NewFastMerger().Merge(reports)
+	log.Infof("Read and merged %d reports in %v", len(reports), time.Since(begin))
+
+	begin = time.Now()
+	topologies, err := app.RenderTopologies(context.Background(), rpt, topologyIDs, values)
+	if err != nil {
+		log.Fatalf("Error rendering topologies: %v", err)
+	}
+	log.Infof("Rendered %d topologies in %v", len(topologies), time.Since(begin))
+
+	var w io.Writer = os.Stdout
+	if flags.output != "" {
+		f, err := os.Create(flags.output)
+		if err != nil {
+			log.Fatalf("Error creating output: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := codec.NewEncoder(w, &codec.JsonHandle{Indent: 2}).Encode(topologies); err != nil {
+		log.Fatalf("Error writing topologies: %v", err)
+	}
+}
@@ -0,0 +1,486 @@
+package render
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/report"
+)
+
+// Constants are used in the tests.
+const (
+	UncontainedID    = "uncontained"
+	UncontainedMajor = "Uncontained"
+
+	// Topology for IPs so we can differentiate them at the end
+	IP = "IP"
+)
+
+// UncontainedIDPrefix is the prefix of uncontained pseudo nodes
+var UncontainedIDPrefix = MakePseudoNodeID(UncontainedID, "")
+
+// ContainerRenderer is a Renderer which produces a renderable container
+// graph by merging the process graph and the container topology.
+// NB We only want processes in container _or_ processes with network connections
+// but we need to be careful to ensure we only include each edge once, by only
+// including the ProcessRenderer once.
+var ContainerRenderer = Memoise(MakeFilter(
+	func(n report.Node) bool {
+		// Drop deleted containers
+		state, ok := n.Latest.Lookup(docker.ContainerState)
+		return !ok || state != docker.StateDeleted
+	},
+	MakeReduce(
+		MakeMap(
+			MapProcess2Container,
+			ProcessRenderer,
+		),
+		connectionJoin{
+			toIPs:          MapContainer2IP,
+			toNetNamespace: MapContainer2NetNamespace,
+			fromProcess:    MapProcess2Container,
+			topology:       report.Container,
+		},
+	),
+))
+
+const originalNodeID = "original_node_id"
+
+// ConnectionJoin joins the given topology with connections from the
+// endpoints topology, using the toIPs function to extract IPs from
+// the nodes.
+func ConnectionJoin(toIPs func(report.Node) []string, topology string) Renderer {
+	return connectionJoin{toIPs: toIPs, topology: topology}
+}
+
+type connectionJoin struct {
+	toIPs func(report.Node) []string
+	// If set, the connections of the nodes sharing a network namespace are
+	// attributed to one of them, see netNamespaceOwners
+	toNetNamespace func(report.Node) (string, bool)
+	fromProcess    MapFunc // The node of a process, along with toNetNamespace
+	topology       string
+}
+
+func (c connectionJoin) Render(ctx context.Context, rpt report.Report) Nodes {
+	inputNodes := TopologySelector(c.topology).Render(ctx, rpt).Nodes
+	endpoints := SelectEndpoint.Render(ctx, rpt).Nodes
+	namespaceOwners, nodeOwners := c.netNamespaceOwners(inputNodes)
+	// Collect all the IPs we are trying to map to, and which ID they map from
+	var ipNodes = map[string]string{}
+	// The IPs of network namespaces shared by several nodes
+	var sharedIPs = map[string]struct{}{}
+	for _, n := range inputNodes {
+		id, shared := nodeOwners[n.ID]
+		if !shared {
+			id = n.ID
+		}
+		for _, ip := range c.toIPs(n) {
+			if existing, exists := ipNodes[ip]; exists && existing != id {
+				// If an IP is shared between multiple nodes, we can't reliably
+				// attribute an connection based on its IP
+				ipNodes[ip] = "" // blank out the mapping so we don't use it
+			} else {
+				ipNodes[ip] = id
+			}
+			if shared {
+				sharedIPs[ip] = struct{}{}
+			}
+		}
+	}
+	// The endpoints of processes go to the node of their process, rather
+	// than to the owner of the network namespace they are in.
+	hasProcess := func(m report.Node) bool {
+		_, ok := rpt.Process.Nodes[endpointProcessID(m, endpoints)]
+		return ok
+	}
+	return MapEndpoints(
+		func(m report.Node) string {
+			if namespace, ok := m.Latest.Lookup(endpoint.NetNamespace); ok && c.toNetNamespace != nil {
+				// The endpoints the probe found the network namespace of
+				// go to the node of their process, for their connections
+				// to the endpoints joined here to be, or else, if their
+				// process is gone, to the owner of the namespace.
+				if p, ok := rpt.Process.Nodes[endpointProcessID(m, endpoints)]; ok {
+					id := c.fromProcess(p).ID
+					if _, ok := inputNodes[id]; ok {
+						return id
+					}
+				} else if id := namespaceOwners[report.ExtractHostID(m)+report.ScopeDelim+namespace]; id != "" {
+					return id
+				}
+			}
+			scope, addr, port, ok := report.ParseEndpointNodeID(m.ID)
+			if !ok {
+				return ""
+			}
+			key := report.MakeScopedEndpointNodeID(scope, addr, "")
+			id, found := ipNodes[key]
+			// We also allow for joining on ip:port pairs.  This is
+			// useful for connections to the host IPs which have been
+			// port mapped to a container can only be unambiguously
+			// identified with the port.
+			if !found {
+				key = report.MakeScopedEndpointNodeID(scope, addr, port)
+				id, found = ipNodes[key]
+			}
+			if !found || id == "" {
+				return ""
+			}
+			if _, shared := sharedIPs[key]; shared && hasProcess(m) {
+				return ""
+			}
+			// Not an IP we blanked out earlier.
+			//
+			// MapEndpoints is guaranteed to find a node with this id
+			// (and hence not have to create one), since we got the id
+			// from ipNodes, which is populated from c.topology, which
+			// is where MapEndpoints will look.
+			return id
+		}, c.topology).Render(ctx, rpt)
+}
+
+// netNamespaceOwners finds the node the connections of each network
+// namespace are attributed to, by namespace, and the owner of the namespace
+// of each node sharing one with others. That is the only node of the
+// namespace which isn't the infrastructure container of a pod or a mesh
+// proxy, or else the one of those the others joined the namespace of. If it
+// is neither, the connections are ambiguous and the owner is blank.
+func (c connectionJoin) netNamespaceOwners(nodes report.Nodes) (map[string]string, map[string]string) {
+	if c.toNetNamespace == nil {
+		return nil, nil
+	}
+	members := map[string][]report.Node{}
+	for _, n := range nodes {
+		if namespace, _ := c.toNetNamespace(n); namespace != "" {
+			members[namespace] = append(members[namespace], n)
+		}
+	}
+	namespaceOwners := make(map[string]string, len(members))
+	nodeOwners := map[string]string{}
+	for namespace, ns := range members {
+		if len(ns) == 1 {
+			namespaceOwners[namespace] = ns[0].ID
+			continue
+		}
+		candidates := []report.Node{}
+		for _, n := range ns {
+			if !isPodInfraContainer(n) && !IsProxySidecar(n) {
+				candidates = append(candidates, n)
+			}
+		}
+		owner := ""
+		if len(candidates) == 1 {
+			owner = candidates[0].ID
+		} else {
+			for _, n := range candidates {
+				if _, joined := c.toNetNamespace(n); joined {
+					continue
+				} else if owner != "" {
+					owner = ""
+					break
+				}
+				owner = n.ID
+			}
+		}
+		namespaceOwners[namespace] = owner
+		for _, n := range ns {
+			nodeOwners[n.ID] = owner
+		}
+	}
+	return namespaceOwners, nodeOwners
+}
+
+// FilterEmpty is a Renderer which filters out nodes which have no children
+// from the specified topology.
+func FilterEmpty(topology string, r Renderer) Renderer {
+	return MakeFilter(HasChildren(topology), r)
+}
+
+// HasChildren returns true if the node has no children from the specified
+// topology.
+func HasChildren(topology string) FilterFunc {
+	return func(n report.Node) bool {
+		count := 0
+		n.Children.ForEach(func(child report.Node) {
+			if child.Topology == topology {
+				count++
+			}
+		})
+		return count > 0
+	}
+}
+
+type containerWithImageNameRenderer struct {
+	Renderer
+}
+
+// Render produces a container graph where the the latest metadata contains the
+// container image name, if found.
+func (r containerWithImageNameRenderer) Render(ctx context.Context, rpt report.Report) Nodes {
+	containers := r.Renderer.Render(ctx, rpt)
+	images := SelectContainerImage.Render(ctx, rpt)
+
+	outputs := make(report.Nodes, len(containers.Nodes))
+	for id, c := range containers.Nodes {
+		outputs[id] = c
+		imageID, ok := c.Latest.Lookup(docker.ImageID)
+		if !ok {
+			continue
+		}
+		image, ok := images.Nodes[report.MakeContainerImageNodeID(report.ClusterScope(c)+imageID)]
+		if !ok {
+			continue
+		}
+		imageName, ok := image.Latest.Lookup(docker.ImageName)
+		if !ok {
+			continue
+		}
+		imageNameWithoutTag := docker.ImageNameWithoutTag(imageName)
+		imageNodeID := report.MakeContainerImageNodeID(imageNameWithoutTag)
+
+		c.Latest = c.Latest.Propagate(image.Latest, docker.ImageName, docker.ImageTag,
+			docker.ImageSize, docker.ImageVirtualSize, docker.ImageLabelPrefix+"works.weave.role")
+
+		c.Parents = c.Parents.
+			Delete(report.ContainerImage).
+			AddString(report.ContainerImage, imageNodeID)
+		outputs[id] = c
+	}
+	return Nodes{Nodes: outputs, Filtered: containers.Filtered}
+}
+
+// ContainerWithImageNameRenderer is a Renderer which produces a container
+// graph where the ranks are the image names, not their IDs
+var ContainerWithImageNameRenderer = Memoise(containerWithImageNameRenderer{ContainerRenderer})
+
+// ContainerImageRenderer is a Renderer which produces a renderable container
+// image graph by merging the container graph and the container image topology.
+var ContainerImageRenderer = Memoise(FilterEmpty(report.Container,
+	MakeMap(
+		MapContainerImage2Name,
+		MakeReduce(
+			MakeMap(
+				MapContainer2ContainerImage,
+				ContainerWithImageNameRenderer,
+			),
+			SelectContainerImage,
+		),
+	),
+))
+
+// ContainerHostnameRenderer is a Renderer which produces a renderable container
+// by hostname graph..
+//
+// not memoised
+var ContainerHostnameRenderer = FilterEmpty(report.Container,
+	MakeReduce(
+		MakeMap(
+			MapContainer2Hostname,
+			ContainerWithImageNameRenderer,
+		),
+		// Grab *all* the hostnames, so we can count the number which were empty
+		// for accurate stats.
+		MakeMap(
+			MapToEmpty,
+			MakeMap(
+				MapContainer2Hostname,
+				ContainerRenderer,
+			),
+		),
+	),
+)
+
+var portMappingMatch = regexp.MustCompile(`([0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}):([0-9]+)->([0-9]+)/tcp`)
+
+// MapContainer2IP maps container nodes to their IP addresses (outputs
+// multiple nodes).  This allows container to be joined directly with
+// the endpoint topology.
+func MapContainer2IP(m report.Node) []string {
+	// if this container doesn't make connections, we can ignore it
+	_, doesntMakeConnections := m.Latest.Lookup(report.DoesNotMakeConnections)
+	// if this container belongs to the host's networking namespace
+	// we cannot use its IP to attribute connections
+	// (they could come from any other process on the host or DNAT-ed IPs)
+	_, isInHostNetwork := m.Latest.Lookup(docker.IsInHostNetwork)
+	if doesntMakeConnections || isInHostNetwork {
+		return nil
+	}
+
+	result := []string{}
+	if addrs, ok := m.Sets.Lookup(docker.ContainerIPsWithScopes); ok {
+		for _, addr := range addrs {
+			scope, addr, ok := report.ParseAddressNodeID(addr)
+			if !ok {
+				continue
+			}
+			// loopback addresses are shared among all namespaces
+			// so we can't use them to attribute connections to a container
+			if report.IsLoopback(addr) {
+				continue
+			}
+			id := report.MakeScopedEndpointNodeID(scope, addr, "")
+			result = append(result, id)
+		}
+	}
+
+	// Also output all the host:port port mappings (see above comment).
+	// In this case we assume this doesn't need a scope, as they are for host
+	// IPs, other than the cluster of federated containers.
+	ports, _ := m.Sets.Lookup(docker.ContainerPorts)
+	for _, portMapping := range ports {
+		if mapping := portMappingMatch.FindStringSubmatch(portMapping); mapping != nil {
+			ip, port := mapping[1], mapping[2]
+			id := report.MakeScopedEndpointNodeID(report.ClusterScope(m), ip, port)
+			result = append(result, id)
+		}
+	}
+
+	return result
+}
+
+// MapContainer2NetNamespace maps container nodes to their network
+// namespace, scoped by their host, and whether they joined the namespace
+// of another container rather than having their own.
+func MapContainer2NetNamespace(m report.Node) (string, bool) {
+	// As for their IPs, the namespace of the host is shared by all its
+	// processes
+	_, doesntMakeConnections := m.Latest.Lookup(report.DoesNotMakeConnections)
+	_, isInHostNetwork := m.Latest.Lookup(docker.IsInHostNetwork)
+	namespace, ok := m.Latest.Lookup(docker.ContainerNetNamespace)
+	hostID := report.ExtractHostID(m)
+	if doesntMakeConnections || isInHostNetwork || !ok || hostID == "" {
+		return "", false
+	}
+	networkMode, _ := m.Latest.Lookup(docker.ContainerNetworkMode)
+	return hostID + report.ScopeDelim + namespace, strings.HasPrefix(networkMode, "container:")
+}
+
+// The infrastructure containers of pods hold their network namespace,
+// which the containers of the pod join.
+func isPodInfraContainer(n report.Node) bool {
+	name, _ := n.Latest.Lookup(docker.LabelPrefix + k8sContainerNameLabel)
+	return name == "POD" || isPauseContainer(n)
+}
+
+// MapProcess2Container maps process Nodes to container
+// Nodes.
+//
+// Pseudo nodes are passed straight through.
+//
+// If this function is given a node without a docker_container_id, it
+// will produce an "Uncontained" pseudo node.
+//
+// Otherwise, this function will produce a node with the correct ID
+// format for a container, but without any Major or Minor labels.
+// It does not have enough info to do that, and the resulting graph
+// must be merged with a container graph to get that info.
+func MapProcess2Container(n report.Node) report.Node {
+	// Propagate pseudo nodes
+	if n.Topology == Pseudo {
+		return n
+	}
+
+	// Otherwise, if the process is not in a container, group it into
+	// a per-host "Uncontained" node.
+	var (
+		id   string
+		node report.Node
+	)
+	if containerID, ok := n.Latest.Lookup(docker.ContainerID); ok {
+		id = report.MakeContainerNodeID(report.ClusterScope(n) + containerID)
+		node = NewDerivedNode(id, n).WithTopology(report.Container)
+		// The process reporter finds the pod of a process in its cgroup,
+		// which attaches it to its pod even if its container isn't
+		// itself reported with a pod parent.
+		if pods, ok := n.Parents.Lookup(report.Pod); ok {
+			node = node.WithParents(report.MakeSets().Add(report.Pod, pods))
+		}
+	} else {
+		hostID, _, _ := report.ParseProcessNodeID(n.ID)
+		id = MakePseudoNodeID(UncontainedID, hostID)
+		node = NewDerivedPseudoNode(id, n)
+	}
+	return node
+}
+
+// MapContainer2ContainerImage maps container Nodes to container
+// image Nodes.
+//
+// Pseudo nodes are passed straight through.
+//
+// If this function is given a node without a docker_image_id
+// it will drop that node.
+//
+// Otherwise, this function will produce a node with the correct ID
+// format for a container image, but without any Major or Minor
+// labels.  It does not have enough info to do that, and the resulting
+// graph must be merged with a container image graph to get that info.
+func MapContainer2ContainerImage(n report.Node) report.Node {
+	// Propagate all pseudo nodes
+	if n.Topology == Pseudo {
+		return n
+	}
+
+	// Otherwise, if some some reason the container doesn't have a image_id
+	// (maybe slightly out of sync reports), just drop it
+	imageID, ok := n.Latest.Lookup(docker.ImageID)
+	if !ok {
+		return report.Node{}
+	}
+
+	// Add container id key to the counters, which will later be
+	// counted to produce the minor label
+	id := report.MakeContainerImageNodeID(report.ClusterScope(n) + imageID)
+	result := NewDerivedNode(id, n).WithTopology(report.ContainerImage)
+	result.Counters = result.Counters.Add(n.Topology, 1)
+	return result
+}
+
+// MapContainerImage2Name ignores image versions
+func MapContainerImage2Name(n report.Node) report.Node {
+	// Propagate all pseudo nodes
+	if n.Topology == Pseudo {
+		return n
+	}
+
+	imageName, ok := n.Latest.Lookup(docker.ImageName)
+	if !ok {
+		return report.Node{}
+	}
+
+	imageNameWithoutTag := docker.ImageNameWithoutTag(imageName)
+	n.ID = report.MakeContainerImageNodeID(imageNameWithoutTag)
+
+	return n
+}
+
+var containerHostnameTopology = MakeGroupNodeTopology(report.Container, docker.ContainerHostname)
+
+// MapContainer2Hostname maps container Nodes to 'hostname' renderabled nodes..
+func MapContainer2Hostname(n report.Node) report.Node {
+	// Propagate all pseudo nodes
+	if n.Topology == Pseudo {
+		return n
+	}
+
+	// Otherwise, if some some reason the container doesn't have a hostname
+	// (maybe slightly out of sync reports), just drop it
+	id, ok := n.Latest.Lookup(docker.ContainerHostname)
+	if !ok {
+		return report.Node{}
+	}
+
+	node := NewDerivedNode(id, n).WithTopology(containerHostnameTopology)
+	node.Counters = node.Counters.Add(n.Topology, 1)
+	return node
+}
+
+// MapToEmpty removes all the attributes, children, etc, of a node. Useful when
+// we just want to count the presence of nodes.
+func MapToEmpty(n report.Node) report.Node {
+	return report.MakeNode(n.ID).WithTopology(n.Topology)
+}
@@ -0,0 +1,176 @@
+package render_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/expected"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+	"github.com/weaveworks/scope/test/utils"
+)
+
+var (
+	filterApplication = render.Transformers([]render.Transformer{
+		render.AnyFilterFunc(render.IsPseudoTopology, render.IsApplication),
+		render.FilterUnconnectedPseudo,
+	})
+	filterSystem = render.Transformers([]render.Transformer{
+		render.AnyFilterFunc(render.IsPseudoTopology, render.IsSystem),
+		render.FilterUnconnectedPseudo,
+	})
+)
+
+func TestMapProcess2Container(t *testing.T) {
+	for _, input := range []testcase{
+		{"empty", report.MakeNode("empty"), true},
+		{"basic process", report.MakeNodeWith("basic", map[string]string{process.PID: "201", docker.ContainerID: "a1b2c3"}), true},
+		{"uncontained", report.MakeNodeWith("uncontained", map[string]string{process.PID: "201", report.HostNodeID: report.MakeHostNodeID("foo")}), true},
+	} {
+		testMap(t, render.MapProcess2Container, input)
+	}
+}
+
+type testcase struct {
+	name string
+	n    report.Node
+	ok   bool
+}
+
+func testMap(t *testing.T, f render.MapFunc, input testcase) {
+	if have := f(input.n); input.ok != (have.ID != "") {
+		name := input.name
+		if name == "" {
+			name = fmt.Sprintf("%v", input.n)
+		}
+		t.Errorf("%s: want %v, have %v", name, input.ok, have)
+	}
+}
+
+func TestContainerRenderer(t *testing.T) {
+	have := utils.Prune(render.ContainerWithImageNameRenderer.Render(context.Background(), fixture.Report).Nodes)
+	want := utils.Prune(expected.RenderedContainers)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerFilterRenderer(t *testing.T) {
+	// tag on of the containers in the topology and ensure
+	// it is filtered out correctly.
+	input := fixture.Report.Copy()
+	input.Container.Nodes[fixture.ClientContainerNodeID] = input.Container.Nodes[fixture.ClientContainerNodeID].WithLatests(map[string]string{
+		docker.LabelPrefix + "works.weave.role": "system",
+	})
+	have := utils.Prune(render.Render(context.Background(), input, render.ContainerWithImageNameRenderer, filterApplication).Nodes)
+	want := utils.Prune(expected.RenderedContainers.Copy())
+	delete(want, fixture.ClientContainerNodeID)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerHostnameRenderer(t *testing.T) {
+	have := utils.Prune(render.Render(context.Background(), fixture.Report, render.ContainerHostnameRenderer, render.Transformers(nil)).Nodes)
+	want := utils.Prune(expected.RenderedContainerHostnames)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerHostnameFilterRenderer(t *testing.T) {
+	have := utils.Prune(render.Render(context.Background(), fixture.Report, render.ContainerHostnameRenderer, filterSystem).Nodes)
+	want := utils.Prune(expected.RenderedContainerHostnames.Copy())
+	delete(want, fixture.ClientContainerHostname)
+	delete(want, fixture.ServerContainerHostname)
+	delete(want, render.IncomingInternetID)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerImageRenderer(t *testing.T) {
+	have := utils.Prune(render.Render(context.Background(), fixture.Report, render.ContainerImageRenderer, render.Transformers(nil)).Nodes)
+	want := utils.Prune(expected.RenderedContainerImages)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerImageFilterRenderer(t *testing.T) {
+	have := utils.Prune(render.Render(context.Background(), fixture.Report, render.ContainerImageRenderer, filterSystem).Nodes)
+	want := utils.Prune(expected.RenderedContainerHostnames.Copy())
+	delete(want, fixture.ClientContainerHostname)
+	delete(want, fixture.ServerContainerHostname)
+	delete(want, render.IncomingInternetID)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestContainerRendererSharedNetNamespace(t *testing.T) {
+	hostNodeID := report.MakeHostNodeID("host")
+	container := func(id, name, networkMode, ip, netns string) report.Node {
+		return report.MakeNodeWith(report.MakeContainerNodeID(id), map[string]string{
+			docker.ContainerID: id,
+			docker.LabelPrefix + "io.kubernetes.container.name": name,
+			docker.ContainerNetworkMode:                         networkMode,
+			docker.ContainerNetNamespace:                        netns,
+			report.HostNodeID:                                   hostNodeID,
+		}).WithTopology(report.Container).
+			WithSets(report.MakeSets().Add(docker.ContainerIPsWithScopes, report.MakeStringSet(report.MakeAddressNodeID("", ip))))
+	}
+	makeEndpoint := func(id string, latests map[string]string, adjacent ...string) report.Node {
+		latests[report.HostNodeID] = hostNodeID
+		return report.MakeNodeWith(id, latests).WithTopology(report.Endpoint).WithAdjacent(adjacent...)
+	}
+	var (
+		serverEndpointID   = report.MakeEndpointNodeID("host", "", "10.0.0.5", "80")
+		loopbackEndpointID = report.MakeEndpointNodeID("host", "4026531993", "127.0.0.1", "9090")
+	)
+	rpt := report.MakeReport()
+	// The pod of the server, of which the containers share the network namespace
+	rpt.Container.AddNode(container("pause", "POD", "default", "10.0.0.5", "4026531993"))
+	rpt.Container.AddNode(container("server", "server", "container:pause", "10.0.0.5", "4026531993"))
+	rpt.Container.AddNode(container("proxy", "istio-proxy", "container:pause", "10.0.0.5", "4026531993"))
+	rpt.Container.AddNode(container("client", "client", "default", "10.0.0.9", "4026532000"))
+	rpt.Process.AddNode(report.MakeNodeWith(report.MakeProcessNodeID("host", "10"), map[string]string{
+		process.PID: "10", docker.ContainerID: "client", report.HostNodeID: hostNodeID,
+	}))
+	rpt.Process.AddNode(report.MakeNodeWith(report.MakeProcessNodeID("host", "20"), map[string]string{
+		process.PID: "20", docker.ContainerID: "proxy", report.HostNodeID: hostNodeID,
+	}))
+	// The client connects to the IP of the pod
+	rpt.Endpoint.AddNode(makeEndpoint(report.MakeEndpointNodeID("host", "", "10.0.0.9", "40000"), map[string]string{process.PID: "10"}, serverEndpointID))
+	rpt.Endpoint.AddNode(makeEndpoint(serverEndpointID, map[string]string{}))
+	// The proxy connects to the server through the loopback of the pod, to
+	// a process which has gone since
+	rpt.Endpoint.AddNode(makeEndpoint(report.MakeEndpointNodeID("host", "4026531993", "127.0.0.1", "40001"), map[string]string{process.PID: "20", endpoint.NetNamespace: "4026531993"}, loopbackEndpointID))
+	rpt.Endpoint.AddNode(makeEndpoint(loopbackEndpointID, map[string]string{process.PID: "30", endpoint.NetNamespace: "4026531993"}))
+
+	have := render.ContainerRenderer.Render(context.Background(), rpt).Nodes
+	server := have[report.MakeContainerNodeID("server")]
+	for _, id := range []string{serverEndpointID, loopbackEndpointID} {
+		if _, ok := server.Children.Lookup(id); !ok {
+			t.Errorf("Expected %s to be attributed to the server, got %v", id, server.Children)
+		}
+	}
+	if !reflect.DeepEqual(report.MakeIDList(report.MakeContainerNodeID("server")), have[report.MakeContainerNodeID("client")].Adjacency) {
+		t.Errorf("Expected the client to connect to the server, got %v", have[report.MakeContainerNodeID("client")].Adjacency)
+	}
+	// The endpoints of processes stay with the containers of those
+	proxy := have[report.MakeContainerNodeID("proxy")]
+	if _, ok := proxy.Children.Lookup(report.MakeEndpointNodeID("host", "4026531993", "127.0.0.1", "40001")); !ok {
+		t.Errorf("Expected the endpoint of the proxy to stay with it, got %v", proxy.Children)
+	}
+	if !reflect.DeepEqual(report.MakeIDList(report.MakeContainerNodeID("server")), proxy.Adjacency) {
+		t.Errorf("Expected the proxy to connect to the server, got %v", proxy.Adjacency)
+	}
+}
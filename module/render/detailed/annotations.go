@@ -0,0 +1,80 @@
+package detailed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// The metadata rows of annotations have their key prefixed with this
+const annotationPrefix = "annotation_"
+
+// Annotation attaches a key and value, e.g. the version a pipeline deployed
+// or an ongoing incident, to the rendered nodes it selects, until it
+// expires.
+type Annotation struct {
+	ID       string            `json:"id"`
+	Topology string            `json:"topology,omitempty"` // e.g. "container"; empty for all of them
+	Node     string            `json:"node,omitempty"`     // the ID of the node, if only that one
+	Selector map[string]string `json:"selector,omitempty"` // latest keys, e.g. "docker_label_app", and the values the nodes have
+	Key      string            `json:"key"`
+	Value    string            `json:"value"`
+	Badge    bool              `json:"badge,omitempty"` // Show it on the nodes in the map, not only in their details
+	TTL      string            `json:"ttl,omitempty"`   // How long it is valid for once added, e.g. "2h"
+	Expires  time.Time         `json:"expires"`
+}
+
+// Annotations are the annotations of nodes.
+type Annotations []Annotation
+
+// NodeAnnotation is an annotation, as attached to a node summary.
+type NodeAnnotation struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Badge bool   `json:"badge,omitempty"`
+}
+
+// Validate checks the annotation makes sense.
+func (a Annotation) Validate() error {
+	if a.Key == "" {
+		return fmt.Errorf("no key")
+	}
+	if a.Node == "" && len(a.Selector) == 0 {
+		return fmt.Errorf("no node or selector: it would annotate all nodes")
+	}
+	if a.TTL != "" {
+		if ttl, err := time.ParseDuration(a.TTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid ttl %q", a.TTL)
+		}
+	}
+	return nil
+}
+
+func (a Annotation) matches(n report.Node) bool {
+	if a.Topology != "" && a.Topology != n.Topology {
+		return false
+	}
+	if a.Node != "" && a.Node != n.ID {
+		return false
+	}
+	for key, value := range a.Selector {
+		if v, ok := n.Latest.Lookup(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// annotate attaches the annotations matching n to its summary, and to its
+// metadata so that they show in its details.
+func (as Annotations) annotate(summary NodeSummary, n report.Node) NodeSummary {
+	for _, a := range as {
+		if !a.matches(n) {
+			continue
+		}
+		summary.Annotations = append(summary.Annotations, NodeAnnotation{Key: a.Key, Value: a.Value, Badge: a.Badge})
+		summary.Metadata = append(summary.Metadata, report.MetadataRow{ID: annotationPrefix + a.Key, Label: a.Key, Value: a.Value})
+	}
+	return summary
+}
@@ -0,0 +1,127 @@
+package detailed
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/weaveworks/scope/probe/endpoint"
+	"github.com/weaveworks/scope/report"
+)
+
+// EdgeMetrics are the round-trip times, retransmissions and traffic of the
+// connections along an edge, as measured by the probes at their
+// originating ends.
+type EdgeMetrics struct {
+	Connections     int     `json:"connections"`      // the connections with metrics
+	RTTP50          float64 `json:"rttP50,omitempty"` // milliseconds
+	RTTP99          float64 `json:"rttP99,omitempty"` // milliseconds
+	Retransmits     int     `json:"retransmits"`
+	BytesSent       int64   `json:"bytesSent,omitempty"`
+	BytesReceived   int64   `json:"bytesReceived,omitempty"`
+	PacketsSent     int64   `json:"packetsSent,omitempty"`
+	PacketsReceived int64   `json:"packetsReceived,omitempty"`
+}
+
+type edgeSamples struct {
+	rtts        []float64
+	connections int
+	retransmits float64
+	traffic     map[string]float64 // by accounting metric
+}
+
+// trafficMetrics are the accounting metrics of connections summed on edges.
+var trafficMetrics = []string{endpoint.EgressBytes, endpoint.IngressBytes, endpoint.EgressPackets, endpoint.IngressPackets}
+
+// edgeMetrics returns the metrics of the edges of each of the rendered nodes
+// rns, by source and destination node IDs, for the edges along which
+// connections have an RTT or accounting metrics.
+func edgeMetrics(rns report.Nodes) map[string]map[string]EdgeMetrics {
+	// The rendered node of each endpoint
+	owners := map[string]string{}
+	for id, n := range rns {
+		n.Children.ForEach(func(child report.Node) {
+			if child.Topology == report.Endpoint {
+				owners[child.ID] = id
+			}
+		})
+	}
+
+	result := map[string]map[string]EdgeMetrics{}
+	for id, n := range rns {
+		edges := map[string]*edgeSamples{}
+		for _, ep := range endpointChildrenOf(n) {
+			rtt, hasRTT := ep.Metrics[endpoint.TCPRTT].LastSample()
+			traffic := map[string]float64{}
+			for _, key := range trafficMetrics {
+				if sample, ok := ep.Metrics[key].LastSample(); ok {
+					traffic[key] = sample.Value
+				}
+			}
+			if !hasRTT && len(traffic) == 0 {
+				continue
+			}
+			weight := 1
+			if w, ok := ep.Latest.Lookup(report.SampledWeight); ok {
+				if i, err := strconv.Atoi(w); err == nil && i > 0 {
+					weight = i
+				}
+			}
+			retransmits, _ := ep.Metrics[endpoint.TCPRetransmits].LastSample()
+			for _, remoteID := range ep.Adjacency {
+				dst, ok := owners[remoteID]
+				if !ok || dst == id || !n.Adjacency.Contains(dst) {
+					continue
+				}
+				e, ok := edges[dst]
+				if !ok {
+					e = &edgeSamples{traffic: map[string]float64{}}
+					edges[dst] = e
+				}
+				// Sampled connections stand for weight connections each, and
+				// are as good a sample of the RTTs as all of them
+				if hasRTT {
+					e.rtts = append(e.rtts, rtt.Value)
+					e.retransmits += retransmits.Value * float64(weight)
+				}
+				for key, value := range traffic {
+					e.traffic[key] += value * float64(weight)
+				}
+				e.connections += weight
+				break // each connection counts once
+			}
+		}
+		if len(edges) == 0 {
+			continue
+		}
+		metrics := make(map[string]EdgeMetrics, len(edges))
+		for dst, e := range edges {
+			m := EdgeMetrics{
+				Connections:     e.connections,
+				Retransmits:     int(e.retransmits),
+				BytesSent:       int64(e.traffic[endpoint.EgressBytes]),
+				BytesReceived:   int64(e.traffic[endpoint.IngressBytes]),
+				PacketsSent:     int64(e.traffic[endpoint.EgressPackets]),
+				PacketsReceived: int64(e.traffic[endpoint.IngressPackets]),
+			}
+			if len(e.rtts) > 0 {
+				sort.Float64s(e.rtts)
+				m.RTTP50 = percentile(e.rtts, 0.5)
+				m.RTTP99 = percentile(e.rtts, 0.99)
+			}
+			metrics[dst] = m
+		}
+		result[id] = metrics
+	}
+	return result
+}
+
+// percentile returns the p-th (0 < p <= 1) percentile of the sorted values,
+// by the nearest rank.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
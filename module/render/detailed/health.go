@@ -0,0 +1,90 @@
+package detailed
+
+import (
+	"fmt"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// Health statuses of nodes, from best to worst
+const (
+	HealthOK       = "ok"
+	HealthWarn     = "warn"
+	HealthCritical = "critical"
+)
+
+var healthSeverity = map[string]int{
+	HealthOK:       0,
+	HealthWarn:     1,
+	HealthCritical: 2,
+}
+
+// HealthRule gives a node a status when one of its metrics goes above a
+// threshold.
+type HealthRule struct {
+	Topology string  `json:"topology,omitempty"` // e.g. "container"; empty for all of them
+	Metric   string  `json:"metric"`             // e.g. "docker_memory_usage"
+	Above    float64 `json:"above"`
+	OfMax    bool    `json:"ofMax,omitempty"` // Compare value/max, e.g. to 0.9 for 90% of the memory limit
+	Status   string  `json:"status"`          // warn or critical
+}
+
+// HealthRules are the rules for the health of nodes.
+type HealthRules []HealthRule
+
+// Validate checks the rules make sense.
+func (rs HealthRules) Validate() error {
+	for i, r := range rs {
+		if r.Metric == "" {
+			return fmt.Errorf("rule %d: no metric", i)
+		}
+		if r.Status != HealthWarn && r.Status != HealthCritical {
+			return fmt.Errorf("rule %d: status must be %s or %s, not %q", i, HealthWarn, HealthCritical, r.Status)
+		}
+	}
+	return nil
+}
+
+func (r HealthRule) matches(n report.Node) bool {
+	if r.Topology != "" && r.Topology != n.Topology {
+		return false
+	}
+	metric, ok := n.Metrics[r.Metric]
+	if !ok {
+		return false
+	}
+	sample, ok := metric.LastSample()
+	if !ok {
+		return false
+	}
+	value := sample.Value
+	if r.OfMax {
+		if metric.Max <= 0 {
+			return false
+		}
+		value /= metric.Max
+	}
+	return value > r.Above
+}
+
+func (rs HealthRules) status(n report.Node) string {
+	status := HealthOK
+	for _, r := range rs {
+		if healthSeverity[r.Status] > healthSeverity[status] && r.matches(n) {
+			status = r.Status
+		}
+	}
+	return status
+}
+
+// Health returns the worst status of n and of its children, so that e.g.
+// pods take on the status of their containers.
+func (rs HealthRules) Health(n report.Node) string {
+	status := rs.status(n)
+	n.Children.ForEach(func(child report.Node) {
+		if childStatus := rs.status(child); healthSeverity[childStatus] > healthSeverity[status] {
+			status = childStatus
+		}
+	})
+	return status
+}
@@ -0,0 +1,57 @@
+package detailed_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+func TestHealthRules(t *testing.T) {
+	now := time.Now()
+	rules := detailed.HealthRules{
+		{Topology: report.Container, Metric: "memory", Above: 0.9, OfMax: true, Status: detailed.HealthCritical},
+		{Topology: report.Container, Metric: "memory", Above: 0.5, OfMax: true, Status: detailed.HealthWarn},
+		{Topology: report.Host, Metric: "load1", Above: 8, Status: detailed.HealthWarn},
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	container := func(id string, memory float64) report.Node {
+		return report.MakeNode(id).WithTopology(report.Container).WithMetrics(report.Metrics{
+			"memory": report.MakeSingletonMetric(now, memory).WithMax(100),
+		})
+	}
+	pod := func(children ...report.Node) report.Node {
+		return report.MakeNode("pod").WithTopology(report.Pod).WithChildren(report.MakeNodeSet(children...))
+	}
+	host := func(load float64) report.Node {
+		return report.MakeNode("host").WithTopology(report.Host).WithMetrics(report.Metrics{
+			"load1": report.MakeSingletonMetric(now, load),
+		})
+	}
+
+	for _, c := range []struct {
+		name string
+		node report.Node
+		want string
+	}{
+		{"idle container", container("c1", 10), detailed.HealthOK},
+		{"busy container", container("c1", 60), detailed.HealthWarn},
+		{"full container", container("c1", 95), detailed.HealthCritical},
+		{"pod of idle containers", pod(container("c1", 10), container("c2", 20)), detailed.HealthOK},
+		{"pod with a full container", pod(container("c1", 60), container("c2", 95)), detailed.HealthCritical},
+		{"loaded host", host(12), detailed.HealthWarn},
+		{"host without rules for its topology", host(4), detailed.HealthOK},
+	} {
+		if have := rules.Health(c.node); have != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, have)
+		}
+	}
+
+	if err := (detailed.HealthRules{{Metric: "memory", Status: "bad"}}).Validate(); err == nil {
+		t.Error("expected an invalid status to be rejected")
+	}
+}
@@ -0,0 +1,293 @@
+package detailed
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+)
+
+const (
+	// Ideal distance between connected nodes.
+	layoutSpacing = 100.0
+	// Nodes further apart than this don't push each other away.
+	layoutCutoff = 3 * layoutSpacing
+	// How strongly the nodes are pulled towards the origin, to keep the
+	// components of the graph together.
+	layoutGravity = 0.05
+
+	layoutIterations            = 100
+	layoutIncrementalIterations = 30
+	// How much the nodes already placed next to nodes which come and go
+	// move in incremental layouts, for the graph not to jump around. The
+	// other nodes already placed stay where they are.
+	layoutDamping = 0.1
+)
+
+// Position is where a node is drawn.
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Layout is where the nodes of a topology are drawn. It is deterministic,
+// seeded by the IDs of the nodes, and kept from one rendering of the
+// topology to the next, for nodes to stay where they were.
+type Layout struct {
+	Positions map[string]Position
+	edges     map[Edge]struct{}
+}
+
+// Update lays out nodes, starting from l: nodes already there stay where
+// they are, or move but a little if they neighbour nodes which came or
+// went, and new nodes start next to their neighbours. Edges pull nodes
+// together as many times as there are edges between them.
+func (l Layout) Update(nodes NodeSummaries) Layout {
+	edges := edges(nodes)
+	for e := range edges {
+		if e.Source == e.Target {
+			delete(edges, e)
+		}
+	}
+	if l.Positions != nil && sameNodes(l.Positions, nodes) && reflect.DeepEqual(l.edges, edges) {
+		return Layout{Positions: l.Positions, edges: edges}
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	index := make(map[string]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+	links := weightedLinks(edges, index)
+
+	positions := make([]Position, len(ids))
+	mobility := make([]float64, len(ids))
+	placed := make([]bool, len(ids))
+	for i, id := range ids {
+		if p, ok := l.Positions[id]; ok {
+			positions[i], placed[i] = p, true
+		} else {
+			mobility[i] = 1
+		}
+	}
+	for id := range neighboursOfChanges(l, nodes, edges) {
+		if i, ok := index[id]; ok && placed[i] {
+			mobility[i] = layoutDamping
+		}
+	}
+	iterations := layoutIncrementalIterations
+	if len(l.Positions) == 0 {
+		iterations = layoutIterations
+		for i := range mobility {
+			mobility[i] = 1
+		}
+	}
+	placeNew(ids, positions, placed, links)
+
+	temperature := layoutSpacing * math.Sqrt(float64(len(ids))) / 10
+	if len(l.Positions) > 0 {
+		temperature = layoutSpacing / 4
+	}
+	for i := 0; i < iterations; i++ {
+		step(positions, mobility, links, temperature*float64(iterations-i)/float64(iterations))
+	}
+
+	result := Layout{Positions: make(map[string]Position, len(ids)), edges: edges}
+	for i, id := range ids {
+		result.Positions[id] = Position{X: round(positions[i].X), Y: round(positions[i].Y)}
+	}
+	return result
+}
+
+// Changes are the positions of l which are not those of previous: those of
+// new nodes, and of the nodes which moved.
+func (l Layout) Changes(previous Layout) map[string]Position {
+	changes := map[string]Position{}
+	for id, p := range l.Positions {
+		if q, ok := previous.Positions[id]; !ok || p != q {
+			changes[id] = p
+		}
+	}
+	return changes
+}
+
+// neighboursOfChanges are the nodes connected to the nodes which are in
+// nodes but not in l, or the other way round.
+func neighboursOfChanges(l Layout, nodes NodeSummaries, edges map[Edge]struct{}) map[string]struct{} {
+	changed := func(id string) bool {
+		_, before := l.Positions[id]
+		_, after := nodes[id]
+		return before != after
+	}
+	result := map[string]struct{}{}
+	for _, es := range []map[Edge]struct{}{l.edges, edges} {
+		for e := range es {
+			if changed(e.Source) {
+				result[e.Target] = struct{}{}
+			}
+			if changed(e.Target) {
+				result[e.Source] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
+func sameNodes(positions map[string]Position, nodes NodeSummaries) bool {
+	if len(positions) != len(nodes) {
+		return false
+	}
+	for id := range nodes {
+		if _, ok := positions[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// link is a pair of connected nodes, by index, and how many edges connect
+// them.
+type link struct {
+	a, b   int
+	weight float64
+}
+
+func weightedLinks(edges map[Edge]struct{}, index map[string]int) []link {
+	weights := map[[2]int]float64{}
+	for e := range edges {
+		a, b := index[e.Source], index[e.Target]
+		if a > b {
+			a, b = b, a
+		}
+		weights[[2]int{a, b}]++
+	}
+	links := make([]link, 0, len(weights))
+	for pair, weight := range weights {
+		links = append(links, link{a: pair[0], b: pair[1], weight: weight})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].a != links[j].a {
+			return links[i].a < links[j].a
+		}
+		return links[i].b < links[j].b
+	})
+	return links
+}
+
+// placeNew places the nodes not placed yet: next to the centre of their
+// placed neighbours if they have any, or else somewhere given by their ID.
+func placeNew(ids []string, positions []Position, placed []bool, links []link) {
+	neighbours := make([][]int, len(ids))
+	for _, l := range links {
+		neighbours[l.a] = append(neighbours[l.a], l.b)
+		neighbours[l.b] = append(neighbours[l.b], l.a)
+	}
+	radius := layoutSpacing * math.Sqrt(float64(len(ids)))
+	for i, id := range ids {
+		if placed[i] {
+			continue
+		}
+		x, y := hashPosition(id)
+		var centre Position
+		count := 0
+		for _, j := range neighbours[i] {
+			if placed[j] {
+				centre.X += positions[j].X
+				centre.Y += positions[j].Y
+				count++
+			}
+		}
+		if count > 0 {
+			positions[i] = Position{
+				X: centre.X/float64(count) + x*layoutSpacing/2,
+				Y: centre.Y/float64(count) + y*layoutSpacing/2,
+			}
+		} else {
+			positions[i] = Position{X: x * radius, Y: y * radius}
+		}
+		placed[i] = true
+	}
+}
+
+// hashPosition is a point in [-1, 1)² given by id.
+func hashPosition(id string) (float64, float64) {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	sum := h.Sum64()
+	return float64(sum>>32)/(1<<31) - 1, float64(sum&0xffffffff)/(1<<31) - 1
+}
+
+// step moves every node by the forces on it (Fruchterman-Reingold), by at
+// most temperature. Only nodes in neighbouring cells of a grid push each
+// other away, so large topologies take time in proportion to their size.
+func step(positions []Position, mobility []float64, links []link, temperature float64) {
+	type cell struct{ x, y int }
+	cellOf := func(p Position) cell {
+		return cell{int(math.Floor(p.X / layoutCutoff)), int(math.Floor(p.Y / layoutCutoff))}
+	}
+	grid := map[cell][]int{}
+	for i, p := range positions {
+		c := cellOf(p)
+		grid[c] = append(grid[c], i)
+	}
+
+	k2 := layoutSpacing * layoutSpacing
+	displacements := make([]Position, len(positions))
+	for i, p := range positions {
+		c := cellOf(p)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[cell{c.x + dx, c.y + dy}] {
+					if j == i {
+						continue
+					}
+					x, y := p.X-positions[j].X, p.Y-positions[j].Y
+					d2 := x*x + y*y
+					if d2 == 0 {
+						// Pull apart nodes on top of each other, the same
+						// way every time.
+						if i < j {
+							x, d2 = 1, 1
+						} else {
+							x, d2 = -1, 1
+						}
+					}
+					if d2 > layoutCutoff*layoutCutoff {
+						continue
+					}
+					displacements[i].X += x * k2 / d2
+					displacements[i].Y += y * k2 / d2
+				}
+			}
+		}
+		displacements[i].X -= layoutGravity * p.X
+		displacements[i].Y -= layoutGravity * p.Y
+	}
+	for _, l := range links {
+		x, y := positions[l.a].X-positions[l.b].X, positions[l.a].Y-positions[l.b].Y
+		d := math.Sqrt(x*x + y*y)
+		f := l.weight * d / layoutSpacing
+		displacements[l.a].X -= x * f
+		displacements[l.a].Y -= y * f
+		displacements[l.b].X += x * f
+		displacements[l.b].Y += y * f
+	}
+
+	for i, d := range displacements {
+		length := math.Sqrt(d.X*d.X + d.Y*d.Y)
+		if length == 0 {
+			continue
+		}
+		limit := math.Min(length, temperature) * mobility[i]
+		positions[i].X += d.X / length * limit
+		positions[i].Y += d.Y / length * limit
+	}
+}
+
+func round(f float64) float64 {
+	return math.Floor(f*10+0.5) / 10
+}
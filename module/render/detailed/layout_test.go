@@ -0,0 +1,79 @@
+package detailed_test
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/weaveworks/scope/render/detailed"
+	"github.com/weaveworks/scope/report"
+)
+
+// chain makes n nodes, each connected to the next.
+func chain(n int) detailed.NodeSummaries {
+	nodes := detailed.NodeSummaries{}
+	for i := 0; i < n; i++ {
+		s := detailed.NodeSummary{BasicNodeSummary: detailed.BasicNodeSummary{ID: fmt.Sprintf("node%d", i)}}
+		if i+1 < n {
+			s.Adjacency = report.MakeIDList(fmt.Sprintf("node%d", i+1))
+		}
+		nodes[s.ID] = s
+	}
+	return nodes
+}
+
+func distance(a, b detailed.Position) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+func TestLayout(t *testing.T) {
+	nodes := chain(20)
+	layout := detailed.Layout{}.Update(nodes)
+	if len(layout.Positions) != len(nodes) {
+		t.Fatalf("Expected %d positions, got %d", len(nodes), len(layout.Positions))
+	}
+
+	// The same nodes are laid out the same way, every time
+	if again := (detailed.Layout{}).Update(nodes); !reflect.DeepEqual(layout.Positions, again.Positions) {
+		t.Errorf("Expected the same layout, got %v and %v", layout.Positions, again.Positions)
+	}
+	// and don't move while nothing changes
+	if same := layout.Update(nodes); len(same.Changes(layout)) != 0 {
+		t.Errorf("Expected no changes, got %v", same.Changes(layout))
+	}
+
+	// Connected nodes are closer than the ends of the chain
+	if near, far := distance(layout.Positions["node0"], layout.Positions["node1"]), distance(layout.Positions["node0"], layout.Positions["node19"]); near >= far {
+		t.Errorf("Expected neighbours closer (%v) than the ends of the chain (%v)", near, far)
+	}
+
+	// A new node goes next to its neighbour, its neighbours and those of
+	// removed nodes move but a little, and the others not at all
+	nodes["new"] = detailed.NodeSummary{
+		BasicNodeSummary: detailed.BasicNodeSummary{ID: "new"},
+		Adjacency:        report.MakeIDList("node10"),
+	}
+	delete(nodes, "node19")
+	next := layout.Update(nodes)
+	changes := next.Changes(layout)
+	if _, ok := changes["new"]; !ok {
+		t.Errorf("Expected the position of the new node in the changes, got %v", changes)
+	}
+	if _, ok := next.Positions["node19"]; ok {
+		t.Error("Expected no position for the removed node")
+	}
+	if d := distance(next.Positions["new"], next.Positions["node10"]); d > 200 {
+		t.Errorf("Expected the new node next to its neighbour, got %v away", d)
+	}
+	for id, p := range layout.Positions {
+		if q, ok := next.Positions[id]; ok && distance(p, q) > 100 {
+			t.Errorf("Expected %s to stay about where it was, moved %v", id, distance(p, q))
+		}
+	}
+	for id := range changes {
+		if id != "new" && id != "node10" && id != "node18" {
+			t.Errorf("Expected only the new node and the neighbours of changes to move, %s moved", id)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package detailed
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff is returned by TopoDiff. It represents the changes between two
+// NodeSummary maps.
+type Diff struct {
+	Add    []NodeSummary `json:"add"`
+	Update []NodeSummary `json:"update"`
+	Remove []string      `json:"remove"`
+	Reset  bool          `json:"reset,omitempty"`
+
+	// Layout has the positions of the nodes added, and of those which
+	// moved, when the layout is asked for.
+	Layout map[string]Position `json:"layout,omitempty"`
+}
+
+// TopoDiff gives you the diff to get from A to B.
+func TopoDiff(a, b NodeSummaries) Diff {
+	diff := Diff{Reset: a == nil}
+
+	notSeen := map[string]struct{}{}
+	for k := range a {
+		notSeen[k] = struct{}{}
+	}
+
+	for k, node := range b {
+		if _, ok := a[k]; !ok {
+			diff.Add = append(diff.Add, node)
+		} else if !reflect.DeepEqual(node, a[k]) {
+			diff.Update = append(diff.Update, node)
+		}
+		delete(notSeen, k)
+	}
+
+	// leftover keys
+	for k := range notSeen {
+		diff.Remove = append(diff.Remove, k)
+	}
+
+	return diff
+}
+
+// Edge is an edge between two nodes of a topology.
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// EdgeDiff is returned by EdgesDiff. It represents the changes between the
+// edges of two NodeSummary maps.
+type EdgeDiff struct {
+	Add    []Edge `json:"add"`
+	Remove []Edge `json:"remove"`
+}
+
+// EdgesDiff gives you the edges to add and remove to get from A to B, sorted.
+// Only edges between the nodes of a map count: the others would dangle.
+func EdgesDiff(a, b NodeSummaries) EdgeDiff {
+	edgesA, edgesB := edges(a), edges(b)
+	diff := EdgeDiff{}
+	for e := range edgesB {
+		if _, ok := edgesA[e]; !ok {
+			diff.Add = append(diff.Add, e)
+		}
+	}
+	for e := range edgesA {
+		if _, ok := edgesB[e]; !ok {
+			diff.Remove = append(diff.Remove, e)
+		}
+	}
+	sortEdges(diff.Add)
+	sortEdges(diff.Remove)
+	return diff
+}
+
+func edges(nodes NodeSummaries) map[Edge]struct{} {
+	result := map[Edge]struct{}{}
+	for id, n := range nodes {
+		for _, target := range n.Adjacency {
+			if _, ok := nodes[target]; ok {
+				result[Edge{Source: id, Target: target}] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+}
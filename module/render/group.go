@@ -0,0 +1,45 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// GroupRenderer is a Renderer which groups the nodes rendered by r by the
+// value of key in their Latest, such as a label. Nodes without a value for
+// the key are dropped; pseudo nodes are kept.
+//
+// not memoised
+func GroupRenderer(key string, r Renderer) Renderer {
+	return MakeMap(MapGroup(key), r)
+}
+
+// MapGroup returns a MapFunc which maps nodes to group nodes, one for each
+// value of key.
+func MapGroup(key string) MapFunc {
+	return func(n report.Node) report.Node {
+		if n.Topology == Pseudo {
+			return n
+		}
+		value, ok := n.Latest.Lookup(key)
+		if !ok || value == "" {
+			return report.Node{}
+		}
+		node := NewDerivedNode(value, n).WithTopology(MakeGroupNodeTopology(n.Topology, key))
+		node.Counters = node.Counters.Add(n.Topology, 1)
+		return node
+	}
+}
+
+// ValidateGroupKey checks key can be used to group nodes.
+func ValidateGroupKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("no key to group by")
+	}
+	if strings.Contains(key, ":") {
+		return fmt.Errorf("key to group by can't contain ':', got %q", key)
+	}
+	return nil
+}
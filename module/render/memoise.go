@@ -0,0 +1,130 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/bluele/gcache"
+	"github.com/spaolacci/murmur3"
+
+	"github.com/weaveworks/scope/report"
+)
+
+// renderCache is keyed on the combination of Memoiser and report
+// id. It contains promises of report.Nodes, which result from
+// rendering the report with the Memoiser's renderer.
+//
+// The use of promises ensures that in the absence of cache evictions
+// a memoiser will only ever render a report once, even when Render()
+// is invoked concurrently.
+var renderCache = gcache.New(100).LRU().Build()
+
+// topologyReader is implemented by Renderers which know the topologies they
+// read from the report, for caching their output across reports which
+// only differ in other topologies.
+type topologyReader interface {
+	readTopologies() ([]string, bool)
+}
+
+// readTopologies returns all the topologies read by the renderers, sorted,
+// or false if any of them may read others or any other part of the report.
+func readTopologies(renderers ...Renderer) ([]string, bool) {
+	topologies := report.MakeStringSet()
+	for _, r := range renderers {
+		tr, ok := r.(topologyReader)
+		if !ok {
+			return nil, false
+		}
+		ts, ok := tr.readTopologies()
+		if !ok {
+			return nil, false
+		}
+		topologies = topologies.Add(ts...)
+	}
+	return append([]string{}, topologies...), true
+}
+
+type memoise struct {
+	sync.Mutex
+	Renderer
+	id         string
+	topologies []string // read by Renderer, or nil if we don't know
+}
+
+// Memoise wraps the renderer in a loving embrace of caching.
+func Memoise(r Renderer) Renderer {
+	if _, ok := r.(*memoise); ok {
+		return r // fixpoint
+	}
+	topologies, _ := readTopologies(r)
+	return &memoise{
+		Renderer:   r,
+		id:         fmt.Sprintf("%x", rand.Int63()),
+		topologies: topologies,
+	}
+}
+
+// Render produces a set of Nodes given a Report.  Ideally, it just
+// retrieves a promise from the cache and returns its value, otherwise
+// it stores a new promise and fulfils it by calling through to
+// m.Renderer.
+func (m *memoise) Render(ctx context.Context, rpt report.Report) Nodes {
+	key := fmt.Sprintf("%s-%s", m.reportKey(rpt), m.id)
+
+	m.Lock()
+	v, err := renderCache.Get(key)
+	if err == nil {
+		m.Unlock()
+		return v.(*promise).Get()
+	}
+	promise := newPromise()
+	renderCache.Set(key, promise)
+	m.Unlock()
+
+	output := m.Renderer.Render(ctx, rpt)
+
+	promise.Set(output)
+
+	return output
+}
+
+// reportKey identifies the parts of the report the renderer reads: just the
+// topologies it reads when we know them and the report says which
+// topologies changed, so new reports which leave those alone don't
+// invalidate the cache. Otherwise it's the whole report.
+func (m *memoise) reportKey(rpt report.Report) string {
+	if m.topologies == nil || rpt.TopologyIDs == nil {
+		return rpt.ID
+	}
+	key := murmur3.New64()
+	for _, topology := range m.topologies {
+		key.Write([]byte(topology))
+		key.Write([]byte(rpt.TopologyIDs[topology]))
+	}
+	return fmt.Sprintf("t%x", key.Sum64())
+}
+
+func (m *memoise) readTopologies() ([]string, bool) {
+	return m.topologies, m.topologies != nil
+}
+
+type promise struct {
+	val  Nodes
+	done chan struct{}
+}
+
+func newPromise() *promise {
+	return &promise{done: make(chan struct{})}
+}
+
+func (p *promise) Set(val Nodes) {
+	p.val = val
+	close(p.done)
+}
+
+func (p *promise) Get() Nodes {
+	<-p.done
+	return p.val
+}
@@ -0,0 +1,132 @@
+package render
+
+import (
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/report"
+)
+
+const k8sContainerNameLabel = "io.kubernetes.container.name"
+
+// ProxySidecarNames are the names of the containers of service mesh
+// proxies, which meshes inject into the pods they manage.
+var ProxySidecarNames = map[string]struct{}{
+	"istio-proxy":      {}, // Istio
+	"linkerd-proxy":    {}, // Linkerd
+	"envoy":            {}, // Consul, Kuma, App Mesh...
+	"envoy-sidecar":    {},
+	"consul-dataplane": {},
+}
+
+// IsProxySidecar checks if the node is the container of the service mesh
+// proxy of its pod.
+func IsProxySidecar(n report.Node) bool {
+	if n.Topology != report.Container {
+		return false
+	}
+	name, ok := n.Latest.Lookup(docker.LabelPrefix + k8sContainerNameLabel)
+	if !ok {
+		return false
+	}
+	_, ok = ProxySidecarNames[name]
+	return ok
+}
+
+// CollapseProxies is a Transformer which removes the proxy sidecars of
+// pods from a topology of containers, attributing their connections to the
+// other containers of their pod instead. This turns the pod, proxy, proxy,
+// pod hops of the connections of a service mesh into an edge from pod to
+// pod.
+var CollapseProxies = collapseProxies{}
+
+type collapseProxies struct{}
+
+func (collapseProxies) Transform(nodes Nodes) Nodes {
+	// The containers of the pods with proxies, other than the proxies
+	proxyPods := map[string]string{} // proxy ID -> pod ID
+	for id, n := range nodes.Nodes {
+		if !IsProxySidecar(n) {
+			continue
+		}
+		if pods, ok := n.Parents.Lookup(report.Pod); ok && len(pods) == 1 {
+			proxyPods[id] = pods[0]
+		}
+	}
+	if len(proxyPods) == 0 {
+		return nodes
+	}
+	podContainers := map[string][]string{}
+	containerPods := map[string]string{}
+	for id, n := range nodes.Nodes {
+		if _, ok := proxyPods[id]; ok || n.Topology != report.Container {
+			continue
+		}
+		if pods, ok := n.Parents.Lookup(report.Pod); ok && len(pods) == 1 {
+			podContainers[pods[0]] = append(podContainers[pods[0]], id)
+			containerPods[id] = pods[0]
+		}
+	}
+	// The proxies of pods without other containers, e.g. because they are
+	// filtered out, stay
+	through := map[string][]string{}
+	for id, pod := range proxyPods {
+		if containers, ok := podContainers[pod]; ok {
+			through[id] = containers
+		}
+	}
+	if len(through) == 0 {
+		return nodes
+	}
+
+	output := make(report.Nodes, len(nodes.Nodes))
+	for id, n := range nodes.Nodes {
+		if _, ok := through[id]; !ok {
+			output[id] = n
+		}
+	}
+	// The edges of the proxies go to and from the containers of their pods,
+	// except those within pods, and so do their endpoints, for the
+	// connections of those to be found.
+	for id, n := range nodes.Nodes {
+		srcs := []string{id}
+		if containers, ok := through[id]; ok {
+			srcs = containers
+		}
+		for _, src := range srcs {
+			node := output[src]
+			for _, adjacent := range n.Adjacency {
+				dsts, ok := through[adjacent]
+				if !ok {
+					if _, ok := through[id]; !ok {
+						continue // neither end is collapsed
+					}
+					dsts = []string{adjacent}
+				}
+				for _, dst := range dsts {
+					if pod, ok := containerPods[src]; !ok || pod != containerPods[dst] {
+						node.Adjacency = node.Adjacency.Add(dst)
+					}
+				}
+			}
+			if src != id {
+				n.Children.ForEach(func(child report.Node) {
+					if child.Topology == report.Endpoint {
+						node.Children = node.Children.Add(child)
+					}
+				})
+			}
+			output[src] = node
+		}
+	}
+	// Edges to the collapsed proxies are gone
+	for id, n := range output {
+		adjacency := report.MakeIDList()
+		for _, dst := range n.Adjacency {
+			if _, ok := through[dst]; !ok {
+				adjacency = adjacency.Add(dst)
+			}
+		}
+		n.Adjacency = adjacency
+		output[id] = n
+	}
+	return Nodes{Nodes: output, Filtered: nodes.Filtered + len(through)}
+}
@@ -0,0 +1,51 @@
+package render_test
+
+import (
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestCollapseProxies(t *testing.T) {
+	container := func(id, name, pod string, adjacent ...string) report.Node {
+		return report.MakeNodeWith(id, map[string]string{
+			docker.LabelPrefix + "io.kubernetes.container.name": name,
+		}).WithTopology(report.Container).
+			WithParent(report.Pod, pod).
+			WithAdjacent(adjacent...)
+	}
+	endpoint := report.MakeNode(";10.0.0.1;15001").WithTopology(report.Endpoint)
+	nodes := render.Nodes{Nodes: report.Nodes{
+		// frontend -> its proxy -> the proxy of backend -> backend
+		"frontend":       container("frontend", "frontend", "p1", "frontend-proxy"),
+		"frontend-proxy": container("frontend-proxy", "istio-proxy", "p1", "backend-proxy").WithChild(endpoint),
+		"backend-proxy":  container("backend-proxy", "istio-proxy", "p2", "backend"),
+		"backend":        container("backend", "backend", "p2"),
+		// A pod of which only the proxy is there, which stays
+		"gateway-proxy": container("gateway-proxy", "istio-proxy", "p3", "frontend-proxy"),
+	}}
+
+	have := render.CollapseProxies.Transform(nodes)
+	want := map[string]report.IDList{
+		"frontend":      report.MakeIDList("backend"),
+		"backend":       report.MakeIDList(),
+		"gateway-proxy": report.MakeIDList("frontend"),
+	}
+	adjacencies := map[string]report.IDList{}
+	for id, n := range have.Nodes {
+		adjacencies[id] = n.Adjacency
+	}
+	if !reflect.DeepEqual(want, adjacencies) {
+		t.Error(test.Diff(want, adjacencies))
+	}
+	if _, ok := have.Nodes["frontend"].Children.Lookup(endpoint.ID); !ok {
+		t.Error("Expected the endpoints of the proxy to go to the container of its pod")
+	}
+	if have.Filtered != 2 {
+		t.Errorf("Expected 2 proxies to be collapsed, got %d", have.Filtered)
+	}
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+// PodNetworkPolicyRenderer is a Renderer which produces the pods graph,
+// with the network policies selecting each pod, and the edges between pods
+// which these policies deny.
+var PodNetworkPolicyRenderer = Memoise(networkPolicyRenderer{PodRenderer})
+
+type networkPolicyRenderer struct {
+	Renderer
+}
+
+// Render renders the pods, and evaluates their edges against the network
+// policies of the report.
+func (r networkPolicyRenderer) Render(ctx context.Context, rpt report.Report) Nodes {
+	nodes := r.Renderer.Render(ctx, rpt)
+	if len(rpt.NetworkPolicy.Nodes) == 0 {
+		return nodes
+	}
+	policies := kubernetes.MakeNetworkPolicies(rpt.NetworkPolicy)
+
+	namespaceLabels := map[string]map[string]string{}
+	for _, n := range rpt.Namespace.Nodes {
+		if name, ok := n.Latest.Lookup(kubernetes.Name); ok {
+			namespaceLabels[name] = prefixedLatests(n, kubernetes.LabelPrefix)
+		}
+	}
+
+	// Network policies do not apply to pods in the host network.
+	peers := map[string]kubernetes.PolicyPeer{}
+	for id, n := range nodes.Nodes {
+		if hostNetwork, _ := n.Latest.Lookup(kubernetes.IsInHostNetwork); n.Topology != report.Pod || hostNetwork == "true" {
+			continue
+		}
+		namespace, _ := n.Latest.Lookup(kubernetes.Namespace)
+		ip, _ := n.Latest.Lookup(kubernetes.IP)
+		peers[id] = kubernetes.PolicyPeer{
+			Namespace:       namespace,
+			Labels:          prefixedLatests(n, kubernetes.LabelPrefix),
+			NamespaceLabels: namespaceLabels[namespace],
+			IP:              net.ParseIP(ip),
+		}
+	}
+
+	output := make(report.Nodes, len(nodes.Nodes))
+	for id, n := range nodes.Nodes {
+		from, ok := peers[id]
+		if !ok {
+			output[id] = n
+			continue
+		}
+		if names := policies.Selecting(from); len(names) > 0 {
+			n = n.WithSet(kubernetes.NetworkPolicyNames, report.MakeStringSet(names...))
+		}
+		denied := report.MakeStringSet()
+		failed, _ := n.Sets.Lookup(report.FailedAdjacency)
+		for _, adjacent := range [][]string{n.Adjacency, failed} {
+			for _, toID := range adjacent {
+				if to, ok := peers[toID]; ok && !policies.Allows(from, to) {
+					denied = denied.Add(toID)
+				}
+			}
+		}
+		if len(denied) > 0 {
+			n = n.WithSet(kubernetes.DeniedAdjacency, denied)
+		}
+		output[id] = n
+	}
+	return Nodes{Nodes: output, Filtered: nodes.Filtered}
+}
+
+func (r networkPolicyRenderer) readTopologies() ([]string, bool) {
+	topologies, ok := readTopologies(r.Renderer)
+	if !ok {
+		return nil, false
+	}
+	return append(topologies, report.NetworkPolicy, report.Namespace), true
+}
+
+// prefixedLatests returns the latest values of the node with keys with the
+// prefix, by the rest of their keys.
+func prefixedLatests(n report.Node, prefix string) map[string]string {
+	result := map[string]string{}
+	n.Latest.ForEach(func(key string, _ time.Time, value string) {
+		if k, ok := report.WithoutPrefix(key, prefix); ok {
+			result[k] = value
+		}
+	})
+	return result
+}
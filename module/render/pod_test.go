@@ -0,0 +1,133 @@
+package render_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/weaveworks/common/test"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/probe/process"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/render/expected"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/fixture"
+	"github.com/weaveworks/scope/test/reflect"
+	"github.com/weaveworks/scope/test/utils"
+)
+
+func TestPodRenderer(t *testing.T) {
+	have := utils.Prune(render.PodRenderer.Render(context.Background(), fixture.Report).Nodes)
+	want := utils.Prune(expected.RenderedPods)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestPodRendererProcessCgroups(t *testing.T) {
+	// Without the docker probe, as with containerd, processes only have
+	// their container and pod from their cgroup.
+	var (
+		podNodeID     = report.MakePodNodeID("pod-uid")
+		processNodeID = report.MakeProcessNodeID("host1", "42")
+		rpt           = report.MakeReport()
+	)
+	rpt.Pod.AddNode(report.MakeNodeWith(podNodeID, map[string]string{kubernetes.Name: "nginx"}).WithTopology(report.Pod))
+	rpt.Process.AddNode(report.MakeNodeWith(processNodeID, map[string]string{
+		process.PID:         "42",
+		process.ContainerID: "abcdef",
+	}).WithTopology(report.Process).WithParent(report.Pod, podNodeID))
+
+	pod, ok := render.PodRenderer.Render(context.Background(), rpt).Nodes[podNodeID]
+	if !ok {
+		t.Fatal("Expected the pod")
+	}
+	children := []string{}
+	pod.Children.ForEach(func(child report.Node) {
+		children = append(children, child.ID)
+	})
+	sort.Strings(children)
+	if want := []string{report.MakeContainerNodeID("abcdef"), processNodeID}; !reflect.DeepEqual(want, children) {
+		t.Error(test.Diff(want, children))
+	}
+}
+
+func TestPodNetworkPolicyRenderer(t *testing.T) {
+	input := fixture.Report.Copy()
+	input.Pod.Nodes[fixture.ServerPodNodeID] = input.Pod.Nodes[fixture.ServerPodNodeID].WithLatests(map[string]string{
+		kubernetes.LabelPrefix + "app": "server",
+	})
+	input.NetworkPolicy = report.MakeTopology()
+	input.NetworkPolicy.AddNode(kubernetes.NewNetworkPolicy(&networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend-only", Namespace: fixture.KubernetesNamespace, UID: types.UID("np1")},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "server"}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}},
+				},
+			}},
+		},
+	}).GetNode())
+
+	have := render.PodNetworkPolicyRenderer.Render(context.Background(), input).Nodes
+	if denied, _ := have[fixture.ClientPodNodeID].Sets.Lookup(kubernetes.DeniedAdjacency); !denied.Contains(fixture.ServerPodNodeID) {
+		t.Errorf("Expected the edge to the server pod to be denied, got %v", denied)
+	}
+	if names, _ := have[fixture.ServerPodNodeID].Sets.Lookup(kubernetes.NetworkPolicyNames); !reflect.DeepEqual(report.MakeStringSet("frontend-only"), names) {
+		t.Errorf("Expected the server pod to be selected by frontend-only, got %v", names)
+	}
+	if _, ok := have[fixture.ClientPodNodeID].Sets.Lookup(kubernetes.NetworkPolicyNames); ok {
+		t.Errorf("Expected the client pod not to be selected by any policy")
+	}
+}
+
+var filterNonKubeSystem = render.Transformers([]render.Transformer{
+	render.Complement(render.IsNamespace("kube-system")),
+	render.FilterUnconnectedPseudo,
+})
+
+func TestPodFilterRenderer(t *testing.T) {
+	// tag on containers or pod namespace in the topology and ensure
+	// it is filtered out correctly.
+	input := fixture.Report.Copy()
+	input.Pod.Nodes[fixture.ClientPodNodeID] = input.Pod.Nodes[fixture.ClientPodNodeID].WithLatests(map[string]string{
+		kubernetes.Namespace: "kube-system",
+	})
+
+	have := utils.Prune(render.Render(context.Background(), input, render.PodRenderer, filterNonKubeSystem).Nodes)
+	want := utils.Prune(expected.RenderedPods.Copy())
+	delete(want, fixture.ClientPodNodeID)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestPodServiceRenderer(t *testing.T) {
+	have := utils.Prune(render.PodServiceRenderer.Render(context.Background(), fixture.Report).Nodes)
+	want := utils.Prune(expected.RenderedPodServices)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func TestPodServiceFilterRenderer(t *testing.T) {
+	// tag on containers or pod namespace in the topology and ensure
+	// it is filtered out correctly.
+	input := fixture.Report.Copy()
+	input.Service.Nodes[fixture.ServiceNodeID] = input.Service.Nodes[fixture.ServiceNodeID].WithLatests(map[string]string{
+		kubernetes.Namespace: "kube-system",
+	})
+
+	have := utils.Prune(render.Render(context.Background(), input, render.PodServiceRenderer, filterNonKubeSystem).Nodes)
+	want := utils.Prune(expected.RenderedPodServices.Copy())
+	delete(want, fixture.ServiceNodeID)
+	delete(want, render.IncomingInternetID)
+	if !reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
@@ -0,0 +1,270 @@
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/report"
+)
+
+// queryOperators are the operators of query terms, longest first so that
+// >= is not taken for >. The first one in a term splits it.
+var queryOperators = []string{">=", "<=", "!=", ">", "<", "=", ":"}
+
+// queryLabelPrefixes are the prefixes of the latest keys of labels, which
+// the label field looks in.
+var queryLabelPrefixes = []string{docker.LabelPrefix, docker.ImageLabelPrefix, kubernetes.LabelPrefix}
+
+// ParseQuery parses a search query into a FilterFunc keeping the nodes
+// matching it. A query is made of terms:
+//
+//	web           a node ID or latest value containing web
+//	name:web      a latest value containing web, under a key with the
+//	              word name in it (e.g. docker_container_name)
+//	state=running a latest value equal to running
+//	state!=paused no latest value equal to paused
+//	cpu>80        a metric, or numeric latest value, over 80; also >=, <
+//	              and <=
+//	label:app=web a docker or Kubernetes label app equal to web, or with
+//	              label:web, any label containing web
+//
+// Terms are all to match, unless joined by OR; NOT, or a leading -,
+// negates a term, and parentheses group terms. Values with spaces are
+// quoted, as in name:"my app". Matches ignore case.
+//
+// Pseudo nodes always match, and are left to FilterUnconnectedPseudo.
+func ParseQuery(query string) (FilterFunc, error) {
+	p := queryParser{tokens: tokenizeQuery(query)}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in query", p.tokens[p.pos])
+	}
+	return func(n report.Node) bool {
+		return n.Topology == Pseudo || f(n)
+	}, nil
+}
+
+// tokenizeQuery splits a query into parentheses and words, keeping quoted
+// strings in words whole.
+func tokenizeQuery(query string) []string {
+	var (
+		tokens []string
+		word   []rune
+		quoted bool
+	)
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = nil
+		}
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word = append(word, r)
+		case quoted:
+			word = append(word, r)
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) or() (FilterFunc, error) {
+	f, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	fs := []FilterFunc{f}
+	for p.peek() == "OR" {
+		p.pos++
+		f, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return AnyFilterFunc(fs...), nil
+}
+
+func (p *queryParser) and() (FilterFunc, error) {
+	var fs []FilterFunc
+	for {
+		switch p.peek() {
+		case "", ")", "OR":
+			if len(fs) == 0 {
+				return nil, fmt.Errorf("expected a term in query")
+			}
+			return ComposeFilterFuncs(fs...), nil
+		case "AND":
+			p.pos++
+			continue
+		}
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+}
+
+func (p *queryParser) unary() (FilterFunc, error) {
+	token := p.peek()
+	switch {
+	case token == "" || token == ")" || token == "OR" || token == "AND":
+		return nil, fmt.Errorf("expected a term in query")
+	case token == "NOT":
+		p.pos++
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Complement(f), nil
+	case token == "(":
+		p.pos++
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected ) in query")
+		}
+		p.pos++
+		return f, nil
+	case strings.HasPrefix(token, "-") && len(token) > 1:
+		p.tokens[p.pos] = token[1:]
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Complement(f), nil
+	}
+	p.pos++
+	return parseQueryTerm(token)
+}
+
+func unquoteQuery(s string) string {
+	return strings.ToLower(strings.Replace(s, `"`, "", -1))
+}
+
+func parseQueryTerm(term string) (FilterFunc, error) {
+	field, op, value := "", "", unquoteQuery(term)
+	at := len(term)
+	for _, o := range queryOperators {
+		if i := strings.Index(term, o); i > 0 && i < at && !strings.Contains(term[:i], `"`) {
+			field, op, value, at = strings.ToLower(term[:i]), o, unquoteQuery(term[i+len(o):]), i
+		}
+	}
+
+	switch op {
+	case "":
+		return func(n report.Node) bool {
+			if strings.Contains(strings.ToLower(n.ID), value) {
+				return true
+			}
+			return anyLatest(n, func(string) bool { return true }, func(v string) bool {
+				return strings.Contains(v, value)
+			})
+		}, nil
+	case ":", "=", "!=":
+		matches := func(v string) bool { return strings.Contains(v, value) }
+		if op != ":" {
+			matches = func(v string) bool { return v == value }
+		}
+		keys := func(key string) bool { return fieldMatches(key, field) }
+		if field == "label" {
+			keys, matches = labelMatcher(value, matches)
+		}
+		f := FilterFunc(func(n report.Node) bool { return anyLatest(n, keys, matches) })
+		if op == "!=" {
+			return Complement(f), nil
+		}
+		return f, nil
+	}
+
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s is not a number", term, value)
+	}
+	compare := map[string]func(float64) bool{
+		">":  func(v float64) bool { return v > threshold },
+		">=": func(v float64) bool { return v >= threshold },
+		"<":  func(v float64) bool { return v < threshold },
+		"<=": func(v float64) bool { return v <= threshold },
+	}[op]
+	return func(n report.Node) bool {
+		for key, metric := range n.Metrics {
+			if sample, ok := metric.LastSample(); ok && fieldMatches(key, field) && compare(sample.Value) {
+				return true
+			}
+		}
+		return anyLatest(n, func(key string) bool { return fieldMatches(key, field) }, func(v string) bool {
+			f, err := strconv.ParseFloat(v, 64)
+			return err == nil && compare(f)
+		})
+	}, nil
+}
+
+// labelMatcher matches label:<key>=<value> by the label key and value, and
+// label:<value> by the value of any label.
+func labelMatcher(value string, matches func(string) bool) (func(string) bool, func(string) bool) {
+	var key string
+	if i := strings.Index(value, "="); i >= 0 {
+		key, value = value[:i], value[i+1:]
+		matches = func(v string) bool { return v == value }
+	}
+	return func(k string) bool {
+		for _, prefix := range queryLabelPrefixes {
+			if strings.HasPrefix(k, prefix) && (key == "" || k == prefix+key) {
+				return true
+			}
+		}
+		return false
+	}, matches
+}
+
+// anyLatest tells whether any of the latest values of n under a key
+// matching keys matches value, ignoring case.
+func anyLatest(n report.Node, keys, value func(string) bool) bool {
+	found := false
+	n.Latest.ForEach(func(k string, _ time.Time, v string) {
+		if !found && keys(strings.ToLower(k)) && value(strings.ToLower(v)) {
+			found = true
+		}
+	})
+	return found
+}
+
+// fieldMatches tells whether the words of field, separated by
+// underscores, are among those of key: cpu matches docker_cpu_total_usage.
+func fieldMatches(key, field string) bool {
+	return strings.Contains("_"+key+"_", "_"+field+"_")
+}
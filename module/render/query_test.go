@@ -0,0 +1,84 @@
+package render_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/probe/docker"
+	"github.com/weaveworks/scope/probe/kubernetes"
+	"github.com/weaveworks/scope/render"
+	"github.com/weaveworks/scope/report"
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestParseQuery(t *testing.T) {
+	now := time.Now()
+	cpu := func(value float64) report.Metric {
+		return report.MakeSingletonMetric(now, value)
+	}
+	nodes := []report.Node{
+		report.MakeNodeWith("frontend", map[string]string{
+			docker.ContainerName:           "web frontend",
+			docker.ContainerState:          "running",
+			docker.LabelPrefix + "app":     "web",
+			kubernetes.LabelPrefix + "env": "prod",
+		}).WithMetric(docker.CPUTotalUsage, cpu(90)),
+		report.MakeNodeWith("backend", map[string]string{
+			docker.ContainerName:           "api",
+			docker.ContainerState:          "paused",
+			docker.LabelPrefix + "app":     "api",
+			kubernetes.LabelPrefix + "env": "staging",
+		}).WithMetric(docker.CPUTotalUsage, cpu(20)),
+		report.MakeNodeWith("db", map[string]string{
+			docker.ContainerName:  "postgres",
+			docker.ContainerState: "running",
+		}).WithMetric(docker.CPUTotalUsage, cpu(50)),
+		report.MakeNode("the-internet").WithTopology(render.Pseudo),
+	}
+
+	for query, want := range map[string][]string{
+		"front":                        {"frontend", "the-internet"},
+		"FRONT":                        {"frontend", "the-internet"},
+		"name:api":                     {"backend", "the-internet"},
+		`name:"web frontend"`:          {"frontend", "the-internet"},
+		"state=running":                {"db", "frontend", "the-internet"},
+		"state!=running":               {"backend", "the-internet"},
+		"cpu>50":                       {"frontend", "the-internet"},
+		"cpu>=50":                      {"db", "frontend", "the-internet"},
+		"cpu<50":                       {"backend", "the-internet"},
+		"label:app=web":                {"frontend", "the-internet"},
+		"label:prod":                   {"frontend", "the-internet"},
+		"label:app":                    {"the-internet"},
+		"state=running cpu<80":         {"db", "the-internet"},
+		"state=running AND cpu<80":     {"db", "the-internet"},
+		"name:api OR name:postgres":    {"backend", "db", "the-internet"},
+		"-name:api":                    {"db", "frontend", "the-internet"},
+		"NOT name:api":                 {"db", "frontend", "the-internet"},
+		"NOT (name:api OR cpu>80)":     {"db", "the-internet"},
+		"(name:api OR cpu>80) label:*": {"the-internet"},
+	} {
+		filter, err := render.ParseQuery(query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		have := []string{}
+		for _, n := range nodes {
+			if filter(n) {
+				have = append(have, n.ID)
+			}
+		}
+		sort.Strings(have)
+		if !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: %s", query, test.Diff(want, have))
+		}
+	}
+
+	for _, query := range []string{"", "cpu>lots", "(name:api", "name:api)", "OR name:api", "NOT"} {
+		if _, err := render.ParseQuery(query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
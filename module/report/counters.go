@@ -0,0 +1,196 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ugorji/go/codec"
+)
+
+type countersEntry struct {
+	key   string
+	value int
+}
+
+// Counters is a string->int map, as a slice sorted by key.
+// It is immutable: the methods changing it return a copy.
+type Counters []countersEntry
+
+// MakeCounters returns an empty Counters.
+func MakeCounters() Counters {
+	return Counters{}
+}
+
+func (c Counters) search(key string) (int, bool) {
+	i := sort.Search(len(c), func(i int) bool {
+		return c[i].key >= key
+	})
+	return i, i < len(c) && c[i].key == key
+}
+
+// Add value to the counter 'key'
+func (c Counters) Add(key string, value int) Counters {
+	i, found := c.search(key)
+	var out Counters
+	if found {
+		out = make(Counters, len(c))
+		copy(out, c)
+		value += c[i].value
+	} else {
+		out = make(Counters, len(c)+1)
+		copy(out, c[:i])
+		copy(out[i+1:], c[i:])
+	}
+	out[i] = countersEntry{key: key, value: value}
+	return out
+}
+
+// Lookup the counter 'key'
+func (c Counters) Lookup(key string) (int, bool) {
+	if i, found := c.search(key); found {
+		return c[i].value, true
+	}
+	return 0, false
+}
+
+// Size returns the number of counters
+func (c Counters) Size() int {
+	return len(c)
+}
+
+// Merge produces a fresh Counters, containing the keys from both inputs. When
+// both inputs contain the same key, the values are added up.
+func (c Counters) Merge(other Counters) Counters {
+	switch {
+	case len(c) == 0:
+		return other
+	case len(other) == 0:
+		return c
+	}
+	out := make(Counters, 0, len(c)+len(other))
+	i, j := 0, 0
+	for i < len(c) && j < len(other) {
+		switch {
+		case c[i].key == other[j].key:
+			out = append(out, countersEntry{key: c[i].key, value: c[i].value + other[j].value})
+			i++
+			j++
+		case c[i].key < other[j].key:
+			out = append(out, c[i])
+			i++
+		default:
+			out = append(out, other[j])
+			j++
+		}
+	}
+	out = append(out, c[i:]...)
+	return append(out, other[j:]...)
+}
+
+// String serializes Counters into a string.
+func (c Counters) String() string {
+	buf := bytes.NewBufferString("{")
+	prefix := ""
+	for _, e := range c {
+		fmt.Fprintf(buf, "%s%s: %d", prefix, e.key, e.value)
+		prefix = ", "
+	}
+	fmt.Fprintf(buf, "}")
+	return buf.String()
+}
+
+// DeepEqual tests equality with other Counters
+func (c Counters) DeepEqual(d Counters) bool {
+	if len(c) != len(d) {
+		return false
+	}
+	for i := range c {
+		if c[i] != d[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Counters) fromIntermediate(in map[string]int) Counters {
+	out := make(Counters, 0, len(in))
+	for k, v := range in {
+		out = append(out, countersEntry{key: k, value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key < out[j].key })
+	return out
+}
+
+// CodecEncodeSelf implements codec.Selfer.
+// Duplicates the output for a built-in map without generating an
+// intermediate copy of the data structure, as for Sets.
+func (c Counters) CodecEncodeSelf(encoder *codec.Encoder) {
+	z, r := codec.GenHelperEncoder(encoder)
+	if c == nil {
+		r.EncodeNil()
+		return
+	}
+	r.EncodeMapStart(len(c))
+	for _, e := range c {
+		z.EncSendContainerState(containerMapKey)
+		r.EncodeString(cUTF8, e.key)
+		z.EncSendContainerState(containerMapValue)
+		r.EncodeInt(int64(e.value))
+	}
+	z.EncSendContainerState(containerMapEnd)
+}
+
+// CodecDecodeSelf implements codec.Selfer.
+// Decodes the input as for a built-in map, straight into the slice, as
+// for Sets.
+func (c *Counters) CodecDecodeSelf(decoder *codec.Decoder) {
+	*c = nil
+	z, r := codec.GenHelperDecoder(decoder)
+	if r.TryDecodeAsNil() {
+		return
+	}
+
+	length := r.ReadMapStart()
+	if length > 0 {
+		*c = make(Counters, 0, length)
+	}
+	for i := 0; length < 0 || i < length; i++ {
+		if length < 0 && r.CheckBreak() {
+			break
+		}
+		z.DecSendContainerState(containerMapKey)
+		var key string
+		if !r.TryDecodeAsNil() {
+			key = lookupCommonKey(r.DecodeStringAsBytes())
+		}
+		i := c.locate(key)
+		(*c)[i].key = key
+		z.DecSendContainerState(containerMapValue)
+		if !r.TryDecodeAsNil() {
+			(*c)[i].value = int(r.DecodeInt(64))
+		}
+	}
+	z.DecSendContainerState(containerMapEnd)
+}
+
+// locate the position where key should go, and make room for it if not there already
+func (c *Counters) locate(key string) int {
+	i, found := c.search(key)
+	if !found {
+		*c = append(*c, countersEntry{})
+		copy((*c)[i+1:], (*c)[i:])
+		(*c)[i] = countersEntry{}
+	}
+	return i
+}
+
+// MarshalJSON shouldn't be used, use CodecEncodeSelf instead
+func (Counters) MarshalJSON() ([]byte, error) {
+	panic("MarshalJSON shouldn't be used, use CodecEncodeSelf instead")
+}
+
+// UnmarshalJSON shouldn't be used, use CodecDecodeSelf instead
+func (*Counters) UnmarshalJSON(b []byte) error {
+	panic("UnmarshalJSON shouldn't be used, use CodecDecodeSelf instead")
+}
@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DeltaSchemaVersion is the first report schema in which apps understand
+// delta reports.
+const DeltaSchemaVersion = 2
+
+// IsDelta says if the report is a delta of another, rather than a full
+// report.
+func (r Report) IsDelta() bool {
+	return r.DeltaBase != ""
+}
+
+// Delta returns a delta report of r against base: r without the nodes which
+// are the same in base, and with the IDs of the nodes of base which are no
+// longer in r. Nodes only differing in the timestamps of their latest values
+// count as the same. Everything else about r, such as its templates and DNS
+// records, is kept as it is.
+func (r Report) Delta(base Report) Report {
+	delta := r
+	delta.DeltaBase = base.ID
+	delta.Removed = map[string][]string{}
+	delta.WalkNamedTopologies(func(name string, t *Topology) {
+		baseTopology, _ := base.Topology(name)
+		nodes := Nodes{}
+		for id, n := range t.Nodes {
+			if baseNode, ok := baseTopology.Nodes[id]; !ok || !sameNode(n, baseNode) {
+				nodes[id] = n
+			}
+		}
+		for id := range baseTopology.Nodes {
+			if _, ok := t.Nodes[id]; !ok {
+				delta.Removed[name] = append(delta.Removed[name], id)
+			}
+		}
+		t.Nodes = nodes
+	})
+	return delta
+}
+
+// ApplyDelta reconstructs the full report a delta report was made from,
+// given its base. It fails if base isn't the report the delta is of.
+func (r Report) ApplyDelta(base Report) (Report, error) {
+	if r.DeltaBase != base.ID {
+		return r, fmt.Errorf("report is a delta of %q, not %q", r.DeltaBase, base.ID)
+	}
+	full := r
+	full.DeltaBase, full.Removed = "", nil
+	full.WalkNamedTopologies(func(name string, t *Topology) {
+		baseTopology, _ := base.Topology(name)
+		nodes := make(Nodes, len(baseTopology.Nodes)+len(t.Nodes))
+		for id, n := range baseTopology.Nodes {
+			nodes[id] = n
+		}
+		for _, id := range r.Removed[name] {
+			delete(nodes, id)
+		}
+		for id, n := range t.Nodes {
+			nodes[id] = n
+		}
+		t.Nodes = nodes
+	})
+	return full, nil
+}
+
+// sameNode is true if a and b only differ in the timestamps of their latest
+// values.
+func sameNode(a, b Node) bool {
+	return a.Topology == b.Topology &&
+		sameLatest(a.Latest, b.Latest) &&
+		sameLatestControls(a.LatestControls, b.LatestControls) &&
+		reflect.DeepEqual(a.Counters, b.Counters) &&
+		reflect.DeepEqual(a.Sets, b.Sets) &&
+		reflect.DeepEqual(a.Adjacency, b.Adjacency) &&
+		reflect.DeepEqual(a.Metrics, b.Metrics) &&
+		reflect.DeepEqual(a.Parents, b.Parents) &&
+		reflect.DeepEqual(a.Children, b.Children)
+}
+
+func sameLatest(a, b StringLatestMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key || a[i].Value != b[i].Value {
+			return false
+		}
+	}
+	return true
+}
+
+func sameLatestControls(a, b NodeControlDataLatestMap) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].key != b[i].key || a[i].Value != b[i].Value {
+			return false
+		}
+	}
+	return true
+}
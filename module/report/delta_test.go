@@ -0,0 +1,57 @@
+package report_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/scope/report"
+)
+
+func TestDelta(t *testing.T) {
+	var (
+		now   = time.Now()
+		later = now.Add(time.Second)
+		base  = report.MakeReport()
+	)
+	base.Host.AddNode(report.MakeNode("unchanged").WithLatest("k", now, "v"))
+	base.Host.AddNode(report.MakeNode("changed").WithLatest("k", now, "v"))
+	base.Host.AddNode(report.MakeNode("removed").WithLatest("k", now, "v"))
+
+	next := base.Copy()
+	next.Host.Nodes = report.Nodes{}
+	next.Host.AddNode(report.MakeNode("unchanged").WithLatest("k", later, "v"))
+	next.Host.AddNode(report.MakeNode("changed").WithLatest("k", later, "w"))
+	next.Host.AddNode(report.MakeNode("added").WithLatest("k", later, "v"))
+
+	delta := next.Delta(base)
+	if !delta.IsDelta() || delta.DeltaBase != base.ID || delta.ID != next.ID {
+		t.Fatalf("Expected a delta of %s, got %q", base.ID, delta.DeltaBase)
+	}
+	if len(delta.Host.Nodes) != 2 || delta.Host.Nodes["changed"].ID == "" || delta.Host.Nodes["added"].ID == "" {
+		t.Errorf("Expected only the changed and added nodes, got %v", delta.Host.Nodes)
+	}
+	if want := map[string][]string{report.Host: {"removed"}}; !reflect.DeepEqual(want, delta.Removed) {
+		t.Errorf("Expected %v removed, got %v", want, delta.Removed)
+	}
+
+	full, err := delta.ApplyDelta(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if full.IsDelta() {
+		t.Error("Expected a full report")
+	}
+	for id, want := range map[string]string{"unchanged": "v", "changed": "w", "added": "v"} {
+		if have, _ := full.Host.Nodes[id].Latest.Lookup("k"); have != want {
+			t.Errorf("%s: expected %q, got %q", id, want, have)
+		}
+	}
+	if _, ok := full.Host.Nodes["removed"]; ok || len(full.Host.Nodes) != 3 {
+		t.Errorf("Expected the removed node to be gone, got %v", full.Host.Nodes)
+	}
+
+	if _, err := delta.ApplyDelta(next); err == nil {
+		t.Error("Expected an error applying a delta to the wrong base")
+	}
+}
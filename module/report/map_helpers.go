@@ -0,0 +1,124 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/weaveworks/ps"
+)
+
+// Helper functions for ps.Map, without considering what is inside
+
+// Return a new map containing all the elements of the two input maps
+// and where the same key is in both, pick 'b' where prefer(a,b) is true
+func mergeMaps(m, n ps.Map, prefer func(a, b interface{}) bool) ps.Map {
+	switch {
+	case m == nil:
+		return n
+	case n == nil:
+		return m
+	case m.Size() < n.Size():
+		m, n = n, m
+	}
+
+	n.ForEach(func(key string, val interface{}) {
+		if existingVal, found := m.Lookup(key); found {
+			if prefer(existingVal, val) {
+				m = m.Set(key, val)
+			}
+		} else {
+			m = m.Set(key, val)
+		}
+	})
+
+	return m
+}
+
+func mapEqual(m, n ps.Map, equalf func(a, b interface{}) bool) bool {
+	var mSize, nSize int
+	if m != nil {
+		mSize = m.Size()
+	}
+	if n != nil {
+		nSize = n.Size()
+	}
+	if mSize != nSize {
+		return false
+	}
+	if mSize == 0 {
+		return true
+	}
+	equal := true
+	m.ForEach(func(k string, val interface{}) {
+		if otherValue, ok := n.Lookup(k); !ok {
+			equal = false
+		} else {
+			equal = equal && equalf(val, otherValue)
+		}
+	})
+	return equal
+}
+
+func mapKeys(m ps.Map) []string {
+	if m == nil {
+		return nil
+	}
+	keys := m.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// constants from https://github.com/ugorji/go/blob/master/codec/helper.go#L207
+const (
+	containerMapKey   = 2
+	containerMapValue = 3
+	containerMapEnd   = 4
+	// from https://github.com/ugorji/go/blob/master/codec/helper.go#L152
+	cUTF8 = 2
+)
+
+// Now follow helpers for StringLatestMap
+
+// These let us sort a StringLatestMap strings by key
+func (m StringLatestMap) Len() int           { return len(m) }
+func (m StringLatestMap) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m StringLatestMap) Less(i, j int) bool { return m[i].key < m[j].key }
+
+// sort entries and shuffle down any duplicates. NOTE: may modify contents of m.
+func (m StringLatestMap) sortedAndDeduplicated() StringLatestMap {
+	sort.Sort(m)
+	for i := 1; i < len(m); {
+		if m[i-1].key == m[i].key {
+			if m[i-1].Timestamp.Before(m[i].Timestamp) {
+				m = append(m[:i-1], m[i:]...)
+			} else {
+				m = append(m[:i], m[i+1:]...)
+			}
+		} else {
+			i++
+		}
+	}
+	return m
+}
+
+// add several entries at the same timestamp
+func (m StringLatestMap) addMapEntries(ts time.Time, n map[string]string) StringLatestMap {
+	out := make(StringLatestMap, len(m), len(m)+len(n))
+	copy(out, m)
+	for k, v := range n {
+		out = append(out, stringLatestEntry{key: k, Value: v, Timestamp: ts})
+	}
+	return out.sortedAndDeduplicated()
+}
+
+// Propagate a set of latest values from one set to another.
+func (m StringLatestMap) Propagate(from StringLatestMap, keys ...string) StringLatestMap {
+	out := make(StringLatestMap, len(m), len(m)+len(keys))
+	copy(out, m)
+	for _, k := range keys {
+		if v, ts, ok := from.LookupEntry(k); ok {
+			out = append(out, stringLatestEntry{key: k, Value: v, Timestamp: ts})
+		}
+	}
+	return out.sortedAndDeduplicated()
+}
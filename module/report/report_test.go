@@ -0,0 +1,175 @@
+package report_test
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/weaveworks/common/mtime"
+	"github.com/weaveworks/common/test"
+	"github.com/weaveworks/scope/report"
+	s_reflect "github.com/weaveworks/scope/test/reflect"
+)
+
+func newu64(value uint64) *uint64 { return &value }
+
+// Make sure we don't add a topology and miss it in the Topologies method.
+func TestReportTopologies(t *testing.T) {
+	var (
+		reportType   = reflect.TypeOf(report.MakeReport())
+		topologyType = reflect.TypeOf(report.MakeTopology())
+	)
+
+	var want, have int
+	for i := 0; i < reportType.NumField(); i++ {
+		if reportType.Field(i).Type == topologyType {
+			want++
+		}
+	}
+
+	r := report.MakeReport()
+	r.WalkTopologies(func(_ *report.Topology) {
+		have++
+	})
+	if want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestReportTopology(t *testing.T) {
+	r := report.MakeReport()
+	if _, ok := r.Topology(report.Container); !ok {
+		t.Errorf("Expected %s topology to be found", report.Container)
+	}
+	if _, ok := r.Topology("foo"); ok {
+		t.Errorf("Expected %s topology not to be found", "foo")
+	}
+}
+
+func TestNode(t *testing.T) {
+	{
+		node := report.MakeNodeWith("foo", map[string]string{
+			"foo": "bar",
+		})
+
+		if v, _ := node.Latest.Lookup("foo"); v != "bar" {
+			t.Errorf("want foo, have %s", v)
+		}
+	}
+	{
+		node := report.MakeNode("foo").WithCounters(
+			map[string]int{"foo": 1},
+		)
+		if value, _ := node.Counters.Lookup("foo"); value != 1 {
+			t.Errorf("want foo, have %d", value)
+		}
+	}
+	{
+		node := report.MakeNode("foo").WithAdjacent("foo")
+		if node.Adjacency[0] != "foo" {
+			t.Errorf("want foo, have %v", node.Adjacency)
+		}
+	}
+}
+
+func TestReportUpgrade(t *testing.T) {
+	mtime.NowForce(time.Now())
+	defer mtime.NowReset()
+	parentsWithDeployment := report.MakeSets().Add(report.Deployment, report.MakeStringSet("id"))
+	rsNode := report.MakeNode("bar").
+		WithParents(parentsWithDeployment)
+	namespaceName := "ns"
+	namespaceID := report.MakeNamespaceNodeID(namespaceName)
+	podNode := report.MakeNode("foo").
+		WithLatests(map[string]string{report.KubernetesNamespace: namespaceName}).
+		WithParents(report.MakeSets().Add(report.ReplicaSet, report.MakeStringSet("bar")))
+	expectedPodNode := podNode.PruneParents().WithParents(parentsWithDeployment)
+	rpt := report.MakeReport()
+	rpt.ReplicaSet.AddNode(rsNode)
+	rpt.Pod.AddNode(podNode)
+	namespaceNode := report.MakeNode(namespaceID).
+		WithLatests(map[string]string{report.KubernetesName: namespaceName})
+	expected := report.MakeReport()
+	expected.ReplicaSet.AddNode(rsNode)
+	expected.Pod.AddNode(expectedPodNode)
+	expected.Namespace.AddNode(namespaceNode)
+	got := rpt.Upgrade()
+	if !s_reflect.DeepEqual(expected, got) {
+		t.Error(test.Diff(expected, got))
+	}
+
+	// Pods stay under the replica sets no deployment owns
+	rpt.ReplicaSet.AddNode(report.MakeNode("unmanaged"))
+	rpt.Pod.AddNode(report.MakeNode("baz").
+		WithParents(report.MakeSets().Add(report.ReplicaSet, report.MakeStringSet("unmanaged"))))
+	got = rpt.Upgrade()
+	if parents, _ := got.Pod.Nodes["baz"].Parents.Lookup(report.ReplicaSet); !s_reflect.DeepEqual(parents, report.MakeStringSet("unmanaged")) {
+		t.Errorf("Expected the pod under its replica set, got %v", got.Pod.Nodes["baz"].Parents)
+	}
+
+	// Reports of new probes, without the replica sets of deployments, are
+	// left as they are
+	rpt = report.MakeReport()
+	rpt.ReplicaSet.AddNode(report.MakeNode("unmanaged"))
+	rpt.Pod.AddNode(report.MakeNode("baz").
+		WithParents(report.MakeSets().Add(report.ReplicaSet, report.MakeStringSet("unmanaged"))))
+	got = rpt.Upgrade()
+	if parents, _ := got.Pod.Nodes["baz"].Parents.Lookup(report.ReplicaSet); !s_reflect.DeepEqual(parents, report.MakeStringSet("unmanaged")) {
+		t.Errorf("Expected the pod under its replica set, got %v", got.Pod.Nodes["baz"].Parents)
+	}
+}
+
+// makeHostReport makes a report with nodes in several topologies, some of
+// them (the containers) shared with other hosts'.
+func makeHostReport(nodes int) report.Report {
+	rpt := report.MakeReport()
+	rpt.Host.AddNode(report.MakeNode(fmt.Sprintf("%x;<host>", rand.Int63())))
+	for i := 0; i < nodes; i++ {
+		rpt.Endpoint.AddNode(report.MakeNodeWith(fmt.Sprintf("%x", rand.Int63()), map[string]string{"pid": "1"}))
+		rpt.Process.AddNode(report.MakeNodeWith(fmt.Sprintf("%x", rand.Int63()), map[string]string{"name": "foo"}))
+		rpt.Container.AddNode(report.MakeNodeWith(fmt.Sprintf("c%d", i), map[string]string{"name": "bar"}))
+	}
+	rpt.DNS[fmt.Sprintf("%x", rand.Int63())] = report.DNSRecord{Forward: report.MakeStringSet("example.com")}
+	rpt.Window = time.Second
+	return rpt
+}
+
+func TestReportUnsafeMergeAll(t *testing.T) {
+	reports := []report.Report{makeHostReport(10), makeHostReport(20), report.MakeReport(), makeHostReport(5)}
+	want := report.MakeReport()
+	for _, r := range reports {
+		want.UnsafeMerge(r)
+	}
+	have := report.MakeReport()
+	have.UnsafeMergeAll(reports)
+	if !s_reflect.DeepEqual(want, have) {
+		t.Error(test.Diff(want, have))
+	}
+}
+
+func benchmarkReportMerge(b *testing.B, merge func(*report.Report, []report.Report)) {
+	reports := make([]report.Report, 50)
+	for i := range reports {
+		reports[i] = makeHostReport(200)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rpt := report.MakeReport()
+		merge(&rpt, reports)
+	}
+}
+
+func BenchmarkReportUnsafeMerge(b *testing.B) {
+	benchmarkReportMerge(b, func(rpt *report.Report, reports []report.Report) {
+		for _, r := range reports {
+			rpt.UnsafeMerge(r)
+		}
+	})
+}
+
+func BenchmarkReportUnsafeMergeAll(b *testing.B) {
+	benchmarkReportMerge(b, (*report.Report).UnsafeMergeAll)
+}
@@ -0,0 +1,267 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/ugorji/go/codec"
+)
+
+type setsEntry struct {
+	key   string
+	value StringSet
+}
+
+// Sets is a string->set-of-strings map, as a slice sorted by key.
+// It is immutable: the methods changing it return a copy, and, where
+// they can, their input when nothing changed.
+type Sets []setsEntry
+
+// MakeSets returns an empty Sets.
+func MakeSets() Sets {
+	return Sets{}
+}
+
+// Keys returns the keys for this set, sorted.
+func (s Sets) Keys() []string {
+	if len(s) == 0 {
+		return nil
+	}
+	keys := make([]string, len(s))
+	for i := range s {
+		keys[i] = s[i].key
+	}
+	return keys
+}
+
+func (s Sets) search(key string) (int, bool) {
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].key >= key
+	})
+	return i, i < len(s) && s[i].key == key
+}
+
+// set returns a copy of s with value stored under the key at position i,
+// inserting it there unless found.
+func (s Sets) set(i int, found bool, key string, value StringSet) Sets {
+	var out Sets
+	if found {
+		out = make(Sets, len(s))
+		copy(out, s)
+	} else {
+		out = make(Sets, len(s)+1)
+		copy(out, s[:i])
+		copy(out[i+1:], s[i:])
+	}
+	out[i] = setsEntry{key: key, value: value}
+	return out
+}
+
+// Add the given value to the Sets.
+func (s Sets) Add(key string, value StringSet) Sets {
+	i, found := s.search(key)
+	if found {
+		var unchanged bool
+		value, unchanged = s[i].value.Merge(value)
+		if unchanged {
+			return s
+		}
+	}
+	return s.set(i, found, key, value)
+}
+
+// AddString adds a single string under a key, creating a new StringSet if necessary.
+func (s Sets) AddString(key string, str string) Sets {
+	i, found := s.search(key)
+	value := MakeStringSet()
+	if found {
+		if s[i].value.Contains(str) {
+			return s
+		}
+		value = s[i].value
+	}
+	return s.set(i, found, key, value.Add(str))
+}
+
+// Delete the given set from the Sets.
+func (s Sets) Delete(key string) Sets {
+	i, found := s.search(key)
+	if !found {
+		return s
+	}
+	if len(s) == 1 {
+		return MakeSets()
+	}
+	out := make(Sets, len(s)-1)
+	copy(out, s[:i])
+	copy(out[i:], s[i+1:])
+	return out
+}
+
+// Lookup returns the sets stored under key.
+func (s Sets) Lookup(key string) (StringSet, bool) {
+	if i, found := s.search(key); found {
+		return s[i].value, true
+	}
+	return MakeStringSet(), false
+}
+
+// Size returns the number of elements
+func (s Sets) Size() int {
+	return len(s)
+}
+
+// Merge merges two sets maps into a fresh set, performing set-union merges as
+// appropriate. Returns one of its inputs, if that already holds the result.
+func (s Sets) Merge(other Sets) Sets {
+	switch {
+	case len(s) == 0:
+		return other
+	case len(other) == 0:
+		return s
+	case len(s) < len(other):
+		s, other = other, s
+	}
+
+	// Skip what s already holds of other; most merges are of the same sets.
+	i, j := 0, 0
+loop:
+	for i < len(s) && j < len(other) {
+		switch {
+		case s[i].key == other[j].key:
+			if _, unchanged := s[i].value.Merge(other[j].value); !unchanged {
+				break loop
+			}
+			i++
+			j++
+		case s[i].key < other[j].key:
+			i++
+		default:
+			break loop
+		}
+	}
+	if j >= len(other) {
+		return s
+	}
+
+	out := make(Sets, i, len(s)+len(other)-j)
+	copy(out, s[:i])
+	for i < len(s) && j < len(other) {
+		switch {
+		case s[i].key == other[j].key:
+			value, _ := s[i].value.Merge(other[j].value)
+			out = append(out, setsEntry{key: s[i].key, value: value})
+			i++
+			j++
+		case s[i].key < other[j].key:
+			out = append(out, s[i])
+			i++
+		default:
+			out = append(out, other[j])
+			j++
+		}
+	}
+	out = append(out, s[i:]...)
+	return append(out, other[j:]...)
+}
+
+func (s Sets) String() string {
+	buf := bytes.NewBufferString("{")
+	for _, e := range s {
+		fmt.Fprintf(buf, "%s: %s,\n", e.key, e.value)
+	}
+	fmt.Fprintf(buf, "}")
+	return buf.String()
+}
+
+// DeepEqual tests equality with other Sets
+func (s Sets) DeepEqual(t Sets) bool {
+	if len(s) != len(t) {
+		return false
+	}
+	for i := range s {
+		if s[i].key != t[i].key || !reflect.DeepEqual(s[i].value, t[i].value) {
+			return false
+		}
+	}
+	return true
+}
+
+// CodecEncodeSelf implements codec.Selfer.
+// Duplicates the output for a built-in map without generating an
+// intermediate copy of the data structure, to save time. Note this
+// means we are using undocumented, internal APIs, which could break
+// in the future. See https://github.com/weaveworks/scope/pull/1709
+// for more information.
+func (s Sets) CodecEncodeSelf(encoder *codec.Encoder) {
+	z, r := codec.GenHelperEncoder(encoder)
+	if s == nil {
+		r.EncodeNil()
+		return
+	}
+	r.EncodeMapStart(len(s))
+	for _, e := range s {
+		z.EncSendContainerState(containerMapKey)
+		r.EncodeString(cUTF8, e.key)
+		z.EncSendContainerState(containerMapValue)
+		encoder.Encode(e.value)
+	}
+	z.EncSendContainerState(containerMapEnd)
+}
+
+// CodecDecodeSelf implements codec.Selfer.
+// Decodes the input as for a built-in map, straight into the slice, which
+// is cheap as reports hold the keys sorted already. Uses undocumented,
+// internal APIs as for CodecEncodeSelf.
+func (s *Sets) CodecDecodeSelf(decoder *codec.Decoder) {
+	*s = nil
+	z, r := codec.GenHelperDecoder(decoder)
+	if r.TryDecodeAsNil() {
+		return
+	}
+
+	length := r.ReadMapStart()
+	if length > 0 {
+		*s = make(Sets, 0, length)
+	}
+	for i := 0; length < 0 || i < length; i++ {
+		if length < 0 && r.CheckBreak() {
+			break
+		}
+		z.DecSendContainerState(containerMapKey)
+		var key string
+		if !r.TryDecodeAsNil() {
+			key = lookupCommonKey(r.DecodeStringAsBytes())
+		}
+		i := s.locate(key)
+		(*s)[i].key = key
+		z.DecSendContainerState(containerMapValue)
+		if !r.TryDecodeAsNil() {
+			decoder.Decode(&(*s)[i].value)
+		}
+	}
+	z.DecSendContainerState(containerMapEnd)
+}
+
+// locate the position where key should go, and make room for it if not there already
+func (s *Sets) locate(key string) int {
+	i, found := s.search(key)
+	if !found {
+		*s = append(*s, setsEntry{})
+		copy((*s)[i+1:], (*s)[i:])
+		(*s)[i] = setsEntry{}
+	}
+	return i
+}
+
+// MarshalJSON shouldn't be used, use CodecEncodeSelf instead
+func (Sets) MarshalJSON() ([]byte, error) {
+	panic("MarshalJSON shouldn't be used, use CodecEncodeSelf instead")
+}
+
+// UnmarshalJSON shouldn't be used, use CodecDecodeSelf instead
+func (*Sets) UnmarshalJSON(b []byte) error {
+	panic("UnmarshalJSON shouldn't be used, use CodecDecodeSelf instead")
+}
@@ -0,0 +1,56 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+
+	"github.com/weaveworks/scope/test/reflect"
+)
+
+func TestSets(t *testing.T) {
+	sets := MakeSets().Add("foo", MakeStringSet("bar"))
+	if v, _ := sets.Lookup("foo"); !reflect.DeepEqual(v, MakeStringSet("bar")) {
+		t.Fatal(v)
+	}
+
+	sets = sets.Merge(MakeSets().Add("foo", MakeStringSet("baz")))
+	if v, _ := sets.Lookup("foo"); !reflect.DeepEqual(v, MakeStringSet("bar", "baz")) {
+		t.Fatal(v)
+	}
+}
+
+func makeBenchmarkSets(start, finish int) Sets {
+	ret := MakeSets()
+	for i := start; i < finish; i++ {
+		ret = ret.Add(fmt.Sprint(i), MakeStringSet("a", fmt.Sprint(i)))
+	}
+	return ret
+}
+
+func BenchmarkSetsMerge(b *testing.B) {
+	// two large sets with some overlap
+	left := makeBenchmarkSets(0, 1000)
+	right := makeBenchmarkSets(700, 1700)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		left.Merge(right)
+	}
+}
+
+func BenchmarkSetsDecode(b *testing.B) {
+	sets := makeBenchmarkSets(0, 1000)
+	buf := &bytes.Buffer{}
+	codec.NewEncoder(buf, &codec.MsgpackHandle{}).Encode(&sets)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var sets Sets
+		codec.NewDecoderBytes(buf.Bytes(), &codec.MsgpackHandle{}).Decode(&sets)
+	}
+}
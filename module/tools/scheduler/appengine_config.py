@@ -0,0 +1,3 @@
+from google.appengine.ext import vendor
+
+vendor.add('lib')